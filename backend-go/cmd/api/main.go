@@ -0,0 +1,16 @@
+// Command api 只提供 HTTP API，不抓取 RSS
+//
+// 修改源后通过 Redis 通知 cmd/fetcher 重新调度；WebSub 回调与推送入库也在这里处理。
+package main
+
+import (
+	"log"
+
+	"github.com/junkfilter/backend-go/internal/app"
+)
+
+func main() {
+	if err := app.Run(app.ModeAPI); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
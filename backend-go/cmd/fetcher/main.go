@@ -0,0 +1,16 @@
+// Command fetcher 只运行 RSS 抓取调度器，不监听 HTTP 端口
+//
+// 可以独立于 cmd/api 重启和扩容；多个副本时开启 ingestion.coordination 按分片分配源。
+package main
+
+import (
+	"log"
+
+	"github.com/junkfilter/backend-go/internal/app"
+)
+
+func main() {
+	if err := app.Run(app.ModeFetcher); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/junkfilter/backend-go/models"
 	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/services"
+	"github.com/junkfilter/backend-go/utils"
 )
 
 // ContentHandler handles content-related HTTP requests
@@ -17,6 +20,8 @@ type ContentHandler struct {
 	contentRepo     *repositories.ContentRepository
 	evaluationRepo  *repositories.EvaluationRepository
 	sourceRepo      *repositories.SourceRepository
+	revisionRepo    *repositories.ContentRevisionRepository
+	images          *services.ImageProxy // nil leaves image URLs as ingested
 	db              *sql.DB
 }
 
@@ -25,12 +30,16 @@ func NewContentHandler(
 	contentRepo *repositories.ContentRepository,
 	evaluationRepo *repositories.EvaluationRepository,
 	sourceRepo *repositories.SourceRepository,
+	revisionRepo *repositories.ContentRevisionRepository,
+	images *services.ImageProxy,
 	db *sql.DB,
 ) *ContentHandler {
 	return &ContentHandler{
 		contentRepo:    contentRepo,
 		evaluationRepo: evaluationRepo,
 		sourceRepo:     sourceRepo,
+		revisionRepo:   revisionRepo,
+		images:         images,
 		db:             db,
 	}
 }
@@ -56,10 +65,107 @@ func (ch *ContentHandler) GetContent(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, content.ToResponse())
+	response := content.ToResponse()
+	ch.images.RewriteContent(c.Request.Context(), response)
+	c.JSON(http.StatusOK, response)
+}
+
+// ContentRevisionResponse is one version of an edited item with its changes against the previous version
+type ContentRevisionResponse struct {
+	models.ContentRevision
+	TitleChanged bool                `json:"title_changed"`
+	Diff         []utils.DiffSegment `json:"diff,omitempty"` // empty for revision 1
+}
+
+// GetContentRevisions returns the version history of an item, oldest first, each revision
+// diffed against the one before it. Items that were never edited have no revisions.
+func (ch *ContentHandler) GetContentRevisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid content ID"})
+		return
+	}
+
+	content, err := ch.contentRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error getting content: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get content"})
+		return
+	}
+	if content == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Content not found"})
+		return
+	}
+
+	revisions, err := ch.revisionRepo.ListByContent(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error listing content revisions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list revisions"})
+		return
+	}
+
+	data := make([]ContentRevisionResponse, len(revisions))
+	for i, rev := range revisions {
+		data[i].ContentRevision = rev
+		if i > 0 {
+			prev := revisions[i-1]
+			data[i].TitleChanged = rev.Title != prev.Title
+			data[i].Diff = utils.DiffText(prev.CleanContent, rev.CleanContent)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"content_id": id,
+		"data":       data,
+		"count":      len(data),
+	})
+}
+
+// defaultChunkRunes is the chunk size of GetContentChunks when max_runes isn't given
+const defaultChunkRunes = 2000
+
+// GetContentChunks splits the whole article into section-aware chunks of at most max_runes runes
+// (200-20000), each labeled with the heading path of its section
+func (ch *ContentHandler) GetContentChunks(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid content ID"})
+		return
+	}
+	maxRunes := defaultChunkRunes
+	if raw := c.Query("max_runes"); raw != "" {
+		maxRunes, err = strconv.Atoi(raw)
+		if err != nil || maxRunes < 200 || maxRunes > 20000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_runes must be between 200 and 20000"})
+			return
+		}
+	}
+
+	content, err := ch.contentRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error getting content: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get content"})
+		return
+	}
+	if content == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Content not found"})
+		return
+	}
+
+	chunks := utils.ChunkMarkdown(content.Body(), maxRunes)
+	if chunks == nil {
+		chunks = []utils.MarkdownChunk{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"content_id": id,
+		"max_runes":  maxRunes,
+		"data":       chunks,
+		"count":      len(chunks),
+	})
 }
 
 // GetContentStats 获取内容统计信息（RSS 抓取进度）
+// ?folder_id= / ?tag= 只统计该文件夹（含子文件夹）或标签下的源
 func (ch *ContentHandler) GetContentStats(c *gin.Context) {
 	// 查询各状态的数量
 	type StatsResult struct {
@@ -67,13 +173,24 @@ func (ch *ContentHandler) GetContentStats(c *gin.Context) {
 		Count  int    `db:"count"`
 	}
 
+	scope, ok := parseSourceScope(c)
+	if !ok {
+		return
+	}
+	where := ""
+	scopeCond, args := repositories.SourceScopeCondition("source_id", scope, 1)
+	if scopeCond != "" {
+		where = "WHERE " + scopeCond
+	}
+
 	query := `
 		SELECT status, COUNT(*) as count
 		FROM content
+		` + where + `
 		GROUP BY status
 	`
 
-	rows, err := ch.db.QueryContext(c.Request.Context(), query)
+	rows, err := ch.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		log.Printf("Error querying content stats: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stats"})
@@ -106,7 +223,7 @@ func (ch *ContentHandler) GetContentStats(c *gin.Context) {
 	})
 }
 
-// GetContentTimeline 获取近N天每天已评估文章数量趋势
+// GetContentTimeline 获取近N天每天已评估文章数量趋势，支持 ?folder_id= / ?tag=
 func (ch *ContentHandler) GetContentTimeline(c *gin.Context) {
 	days := 7
 	if d := c.Query("days"); d != "" {
@@ -115,16 +232,28 @@ func (ch *ContentHandler) GetContentTimeline(c *gin.Context) {
 		}
 	}
 
+	scope, ok := parseSourceScope(c)
+	if !ok {
+		return
+	}
+	args := []interface{}{days}
+	scopeCond, scopeArgs := repositories.SourceScopeCondition("source_id", scope, 2)
+	if scopeCond != "" {
+		scopeCond = "AND " + scopeCond
+		args = append(args, scopeArgs...)
+	}
+
 	query := `
 		SELECT DATE(updated_at) as date, COUNT(*) as count
 		FROM content
 		WHERE status = 'EVALUATED'
 		  AND updated_at >= NOW() - ($1 || ' days')::INTERVAL
+		  ` + scopeCond + `
 		GROUP BY DATE(updated_at)
 		ORDER BY date ASC
 	`
 
-	rows, err := ch.db.QueryContext(c.Request.Context(), query, days)
+	rows, err := ch.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		log.Printf("Error querying content timeline: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get timeline"})
@@ -159,7 +288,25 @@ func (ch *ContentHandler) GetContentTimeline(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"timeline": result, "days": days})
 }
 
+// parseLanguageFilter reads ?language=zh,en, writing the error response on an unsupported code
+func parseLanguageFilter(c *gin.Context) ([]string, bool) {
+	raw := c.Query("language")
+	if raw == "" {
+		return nil, true
+	}
+	languages, err := utils.NormalizeLanguages(strings.Split(raw, ","))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(languages) == 0 {
+		return nil, true
+	}
+	return languages, true
+}
+
 // ListContent lists content with optional filtering
+// GET /api/content?status=EVALUATED&source_id=3&language=zh,en&folder_id=2&tag=go
 func (ch *ContentHandler) ListContent(c *gin.Context) {
 	filter := &models.ContentFilter{
 		Status:   c.Query("status"),
@@ -185,6 +332,15 @@ func (ch *ContentHandler) ListContent(c *gin.Context) {
 		}
 	}
 
+	languages, ok := parseLanguageFilter(c)
+	if !ok {
+		return
+	}
+	filter.Languages = languages
+	if filter.Scope, ok = parseSourceScope(c); !ok {
+		return
+	}
+
 	contents, err := ch.contentRepo.List(c.Request.Context(), filter)
 	if err != nil {
 		log.Printf("Error listing content: %v", err)
@@ -236,6 +392,12 @@ func (ch *ContentHandler) ListContent(c *gin.Context) {
 		responses[i] = response
 	}
 
+	rewrite := make([]*models.ContentResponse, len(responses))
+	for i, response := range responses {
+		rewrite[i] = response.ContentResponse
+	}
+	ch.images.RewriteContent(c.Request.Context(), rewrite...)
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":  responses,
 		"count": len(responses),
@@ -322,6 +484,7 @@ func (ch *ContentHandler) GetContentWithEvaluation(c *gin.Context) {
 	response := &ContentWithEvaluation{
 		Content: content.ToResponse(),
 	}
+	ch.images.RewriteContent(c.Request.Context(), response.Content)
 
 	if evaluation != nil {
 		response.Evaluation = evaluation.ToResponse()
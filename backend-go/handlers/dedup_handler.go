@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/services"
+)
+
+// DedupHandler reports how the URL deduplication layers perform
+type DedupHandler struct {
+	dedup *services.DedupService
+}
+
+// NewDedupHandler creates a new dedup handler
+func NewDedupHandler(dedup *services.DedupService) *DedupHandler {
+	return &DedupHandler{dedup: dedup}
+}
+
+// GetDedupStats returns the L1/L2/L3 counters and hit rates of all replicas,
+// plus the bloom filter of this process when it fetches
+// GET /api/admin/dedup/stats
+func (dh *DedupHandler) GetDedupStats(c *gin.Context) {
+	stats, err := dh.dedup.Stats(c.Request.Context())
+	if err != nil {
+		log.Printf("Error reading dedup stats: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read dedup stats"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
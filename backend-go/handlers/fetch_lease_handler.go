@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/services"
+)
+
+// FetchLeaseHandler shows how sources are split between backend replicas
+type FetchLeaseHandler struct {
+	coordinator *services.FetchCoordinator
+	sourceRepo  *repositories.SourceRepository
+}
+
+// NewFetchLeaseHandler creates a new fetch lease handler
+func NewFetchLeaseHandler(coordinator *services.FetchCoordinator, sourceRepo *repositories.SourceRepository) *FetchLeaseHandler {
+	return &FetchLeaseHandler{coordinator: coordinator, sourceRepo: sourceRepo}
+}
+
+// shardLeaseResponse is one shard with its owner and the enabled sources it contains
+type shardLeaseResponse struct {
+	services.ShardLease
+	SourceIDs []int64 `json:"source_ids"`
+}
+
+// instanceOwnership summarizes what one replica fetches
+type instanceOwnership struct {
+	InstanceID string  `json:"instance_id"`
+	Shards     []int   `json:"shards"`
+	SourceIDs  []int64 `json:"source_ids"`
+}
+
+// GetFetchLeases reports which replica owns which shards and sources
+// GET /api/admin/fetch-leases
+func (fh *FetchLeaseHandler) GetFetchLeases(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	leases, instances, err := fh.coordinator.Leases(ctx)
+	if err != nil {
+		log.Printf("Error reading fetch leases: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read fetch leases"})
+		return
+	}
+
+	sources, err := fh.sourceRepo.GetAll(ctx, true)
+	if err != nil {
+		log.Printf("Error listing sources: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sources"})
+		return
+	}
+
+	shards := make([]shardLeaseResponse, len(leases))
+	for i, lease := range leases {
+		shards[i] = shardLeaseResponse{ShardLease: lease, SourceIDs: []int64{}}
+	}
+	for _, source := range sources {
+		shard := fh.coordinator.ShardOf(source.ID)
+		shards[shard].SourceIDs = append(shards[shard].SourceIDs, source.ID)
+	}
+
+	owners := make(map[string]*instanceOwnership)
+	for _, id := range instances {
+		owners[id] = &instanceOwnership{InstanceID: id, Shards: []int{}, SourceIDs: []int64{}}
+	}
+	unowned := []int{}
+	for _, shard := range shards {
+		if shard.Owner == "" {
+			unowned = append(unowned, shard.Shard)
+			continue
+		}
+		owner, ok := owners[shard.Owner]
+		if !ok {
+			// Lease still alive but the heartbeat expired: the replica is probably gone
+			owner = &instanceOwnership{InstanceID: shard.Owner, Shards: []int{}, SourceIDs: []int64{}}
+			owners[shard.Owner] = owner
+		}
+		owner.Shards = append(owner.Shards, shard.Shard)
+		owner.SourceIDs = append(owner.SourceIDs, shard.SourceIDs...)
+	}
+
+	instanceList := make([]*instanceOwnership, 0, len(owners))
+	for _, owner := range owners {
+		sort.Slice(owner.SourceIDs, func(i, j int) bool { return owner.SourceIDs[i] < owner.SourceIDs[j] })
+		instanceList = append(instanceList, owner)
+	}
+	sort.Slice(instanceList, func(i, j int) bool { return instanceList[i].InstanceID < instanceList[j].InstanceID })
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id":    fh.coordinator.InstanceID(),
+		"instances":      instanceList,
+		"shards":         shards,
+		"unowned_shards": unowned,
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+)
+
+// FetchRunHandler exposes the fetch run history recorded by the RSS fetcher
+type FetchRunHandler struct {
+	fetchRunRepo *repositories.FetchRunRepository
+}
+
+// NewFetchRunHandler creates a new fetch run handler
+func NewFetchRunHandler(fetchRunRepo *repositories.FetchRunRepository) *FetchRunHandler {
+	return &FetchRunHandler{fetchRunRepo: fetchRunRepo}
+}
+
+// GetSourceFetchHistory returns the latest fetch runs of one source
+// GET /api/sources/:id/fetch-history?limit=50
+func (fh *FetchRunHandler) GetSourceFetchHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+
+	runs, err := fh.fetchRunRepo.ListBySource(c.Request.Context(), id, parseRunLimit(c))
+	if err != nil {
+		log.Printf("Error listing fetch history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fetch history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": runs, "count": len(runs)})
+}
+
+// ListRecentFetchRuns returns the latest fetch runs across all sources
+// GET /api/fetch-runs?status=failed&limit=50
+func (fh *FetchRunHandler) ListRecentFetchRuns(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.FetchStatusSuccess &&
+		status != models.FetchStatusNotModified && status != models.FetchStatusFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be 'success', 'not_modified' or 'failed'"})
+		return
+	}
+
+	runs, err := fh.fetchRunRepo.ListRecent(c.Request.Context(), status, parseRunLimit(c))
+	if err != nil {
+		log.Printf("Error listing fetch runs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list fetch runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": runs, "count": len(runs)})
+}
+
+// parseRunLimit reads ?limit= with a default of 50 and a cap of 500
+func parseRunLimit(c *gin.Context) int {
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	return limit
+}
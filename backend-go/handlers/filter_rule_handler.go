@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/services"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// FilterRuleHandler manages the filter rules that skip feed items before evaluation
+type FilterRuleHandler struct {
+	ruleRepo   *repositories.FilterRuleRepository
+	sourceRepo *repositories.SourceRepository
+	rssService *services.RSSService
+}
+
+// NewFilterRuleHandler creates a new filter rule handler
+func NewFilterRuleHandler(ruleRepo *repositories.FilterRuleRepository, sourceRepo *repositories.SourceRepository, rssService *services.RSSService) *FilterRuleHandler {
+	return &FilterRuleHandler{ruleRepo: ruleRepo, sourceRepo: sourceRepo, rssService: rssService}
+}
+
+// ListFilterRules returns the rules of a source (?source_id=), the global ones (?scope=global) or all
+// GET /api/filter-rules
+func (fh *FilterRuleHandler) ListFilterRules(c *gin.Context) {
+	var sourceID int64
+	if s := c.Query("source_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+			return
+		}
+		sourceID = id
+	}
+
+	rules, err := fh.ruleRepo.List(c.Request.Context(), sourceID, c.Query("scope") == "global", false)
+	if err != nil {
+		log.Printf("Error listing filter rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list filter rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rules, "count": len(rules)})
+}
+
+// CreateFilterRule adds a rule; without source_id it applies to every source
+// POST /api/filter-rules {"name": "...", "source_id": 3, "condition": {"field": "title", "operator": "contains", "values": ["广告"]}}
+func (fh *FilterRuleHandler) CreateFilterRule(c *gin.Context) {
+	rule, ok := fh.bindFilterRule(c)
+	if !ok {
+		return
+	}
+
+	created, err := fh.ruleRepo.Create(c.Request.Context(), rule)
+	if err != nil {
+		log.Printf("Error creating filter rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create filter rule"})
+		return
+	}
+	fh.rssService.FilterRulesChanged(c.Request.Context())
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateFilterRule replaces a rule
+// PUT /api/filter-rules/:id
+func (fh *FilterRuleHandler) UpdateFilterRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+	rule, ok := fh.bindFilterRule(c)
+	if !ok {
+		return
+	}
+
+	updated, err := fh.ruleRepo.Update(c.Request.Context(), id, rule)
+	if err != nil {
+		log.Printf("Error updating filter rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update filter rule"})
+		return
+	}
+	if updated == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Filter rule not found"})
+		return
+	}
+	fh.rssService.FilterRulesChanged(c.Request.Context())
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteFilterRule removes a rule; its skip records are kept
+// DELETE /api/filter-rules/:id
+func (fh *FilterRuleHandler) DeleteFilterRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	deleted, err := fh.ruleRepo.Delete(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error deleting filter rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete filter rule"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Filter rule not found"})
+		return
+	}
+	fh.rssService.FilterRulesChanged(c.Request.Context())
+
+	c.JSON(http.StatusOK, gin.H{"message": "Filter rule deleted"})
+}
+
+// testFilterItem is the sample item of a rule test
+type testFilterItem struct {
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Author      string     `json:"author"`
+	URL         string     `json:"url"`
+	Categories  []string   `json:"categories"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// TestFilterRule checks a condition against a sample item without saving anything
+// POST /api/filter-rules/test {"condition": {...}, "item": {"title": "...", "content": "..."}}
+func (fh *FilterRuleHandler) TestFilterRule(c *gin.Context) {
+	var req struct {
+		Condition json.RawMessage `json:"condition" binding:"required"`
+		Item      testFilterItem  `json:"item"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	condition, err := decodeFilterCondition(req.Condition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	engine := services.NewFilterEngine(nil)
+	engine.Set([]*models.FilterRule{{Name: "test", Enabled: true, Condition: condition}})
+	item := &utils.FeedItem{
+		Title:       req.Item.Title,
+		Content:     req.Item.Content,
+		Author:      req.Item.Author,
+		URL:         req.Item.URL,
+		Categories:  req.Item.Categories,
+		PublishedAt: req.Item.PublishedAt,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"matched":  engine.Match(0, item, time.Now()) != nil,
+		"language": utils.DetectLanguage(item.Title + "\n" + item.Content),
+	})
+}
+
+// ListFilterSkips returns recently skipped items, optionally of one source or rule
+// GET /api/filter-skips?source_id=3&rule_id=7&limit=50
+func (fh *FilterRuleHandler) ListFilterSkips(c *gin.Context) {
+	sourceID, _ := strconv.ParseInt(c.Query("source_id"), 10, 64)
+	ruleID, _ := strconv.ParseInt(c.Query("rule_id"), 10, 64)
+
+	skips, err := fh.ruleRepo.ListSkips(c.Request.Context(), sourceID, ruleID, parseRunLimit(c))
+	if err != nil {
+		log.Printf("Error listing filter skips: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list filter skips"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": skips, "count": len(skips)})
+}
+
+// bindFilterRule reads and validates a rule from the request body, writing the error response on failure
+func (fh *FilterRuleHandler) bindFilterRule(c *gin.Context) (*models.FilterRule, bool) {
+	var req models.SaveFilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	condition, err := decodeFilterCondition(req.Condition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if req.SourceID != nil {
+		source, err := fh.sourceRepo.GetByID(c.Request.Context(), *req.SourceID)
+		if err != nil {
+			log.Printf("Error loading source %d: %v", *req.SourceID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load source"})
+			return nil, false
+		}
+		if source == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Source not found"})
+			return nil, false
+		}
+	}
+
+	rule := &models.FilterRule{
+		SourceID:  req.SourceID,
+		Name:      strings.TrimSpace(req.Name),
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		Condition: condition,
+	}
+	return rule, true
+}
+
+// decodeFilterCondition parses a condition tree, rejecting unknown keys so typos don't
+// silently turn into conditions that match everything
+func decodeFilterCondition(raw json.RawMessage) (models.FilterCondition, error) {
+	var condition models.FilterCondition
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&condition); err != nil {
+		return condition, fmt.Errorf("invalid condition: %w", err)
+	}
+	if err := services.ValidateFilterCondition(condition); err != nil {
+		return condition, fmt.Errorf("invalid condition: %w", err)
+	}
+	return condition, nil
+}
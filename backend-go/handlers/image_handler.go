@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/services"
+)
+
+// imageHashPattern matches services.ImageHash output
+var imageHashPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// imageCacheControl lets browsers keep proxied images: a hash always stands for the same URL
+const imageCacheControl = "public, max-age=2592000, immutable"
+
+// ImageHandler serves proxied content images
+type ImageHandler struct {
+	proxy *services.ImageProxy
+}
+
+// NewImageHandler creates a new image handler
+func NewImageHandler(proxy *services.ImageProxy) *ImageHandler {
+	return &ImageHandler{proxy: proxy}
+}
+
+// GetImage returns a content image, or its thumbnail with ?size=thumb
+// GET /api/images/:hash?size=thumb
+func (ih *ImageHandler) GetImage(c *gin.Context) {
+	hash := c.Param("hash")
+	if !imageHashPattern.MatchString(hash) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image hash"})
+		return
+	}
+	size := c.Query("size")
+	if size != "" && size != "thumb" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be \"thumb\" or omitted"})
+		return
+	}
+
+	etag := `"` + hash + size + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, contentType, err := ih.proxy.Image(c.Request.Context(), hash, size == "thumb")
+	if err != nil {
+		if errors.Is(err, services.ErrImageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch image"})
+		return
+	}
+
+	c.Header("Cache-Control", imageCacheControl)
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
+	"github.com/junkfilter/backend-go/models"
 )
 
 // NotificationHandler handles notification-related HTTP requests
@@ -130,12 +131,16 @@ func (nh *NotificationHandler) MarkAllAsRead(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "All marked as read"})
 }
 
-// NotificationSettings represents user-configurable notification rules
+// NotificationSettings represents user-configurable notification rules.
+// Watched sources, folders (subfolders included) and tags add up; when all three are empty
+// every source is watched.
 type NotificationSettings struct {
 	MinInnovationScore  int                      `json:"min_innovation_score"`
 	MinDepthScore       int                      `json:"min_depth_score"`
 	NotifyOnInteresting bool                     `json:"notify_on_interesting"`
 	WatchedSourceIDs    []int                    `json:"watched_source_ids"`
+	WatchedFolderIDs    []int64                  `json:"watched_folder_ids"`
+	WatchedTags         []string                 `json:"watched_tags"`
 	Enabled             bool                     `json:"enabled"`
 	PushChannels        []map[string]interface{} `json:"push_channels"`
 }
@@ -144,12 +149,14 @@ type NotificationSettings struct {
 // GET /api/notifications/settings
 func (nh *NotificationHandler) GetSettings(c *gin.Context) {
 	var settings NotificationSettings
-	var watchedJSON, pushJSON []byte
+	var watchedJSON, pushJSON, foldersJSON, tagsJSON []byte
 
 	err := nh.db.QueryRowContext(c.Request.Context(),
-		`SELECT min_innovation_score, min_depth_score, notify_on_interesting, watched_source_ids, enabled, push_channels
+		`SELECT min_innovation_score, min_depth_score, notify_on_interesting, watched_source_ids, enabled, push_channels,
+		        watched_folder_ids, watched_tags
 		 FROM notification_settings WHERE id = 1`,
-	).Scan(&settings.MinInnovationScore, &settings.MinDepthScore, &settings.NotifyOnInteresting, &watchedJSON, &settings.Enabled, &pushJSON)
+	).Scan(&settings.MinInnovationScore, &settings.MinDepthScore, &settings.NotifyOnInteresting, &watchedJSON, &settings.Enabled, &pushJSON,
+		&foldersJSON, &tagsJSON)
 
 	if err != nil {
 		if err != sql.ErrNoRows {
@@ -160,6 +167,8 @@ func (nh *NotificationHandler) GetSettings(c *gin.Context) {
 			MinDepthScore:       7,
 			NotifyOnInteresting: true,
 			WatchedSourceIDs:    []int{},
+			WatchedFolderIDs:    []int64{},
+			WatchedTags:         []string{},
 			Enabled:             true,
 			PushChannels:        []map[string]interface{}{},
 		})
@@ -174,6 +183,22 @@ func (nh *NotificationHandler) GetSettings(c *gin.Context) {
 	if settings.WatchedSourceIDs == nil {
 		settings.WatchedSourceIDs = []int{}
 	}
+	if foldersJSON != nil {
+		if err := json.Unmarshal(foldersJSON, &settings.WatchedFolderIDs); err != nil {
+			log.Printf("[Notification] Error parsing watched_folder_ids: %v", err)
+		}
+	}
+	if settings.WatchedFolderIDs == nil {
+		settings.WatchedFolderIDs = []int64{}
+	}
+	if tagsJSON != nil {
+		if err := json.Unmarshal(tagsJSON, &settings.WatchedTags); err != nil {
+			log.Printf("[Notification] Error parsing watched_tags: %v", err)
+		}
+	}
+	if settings.WatchedTags == nil {
+		settings.WatchedTags = []string{}
+	}
 	if pushJSON != nil {
 		if err := json.Unmarshal(pushJSON, &settings.PushChannels); err != nil {
 			log.Printf("[Notification] Error parsing push_channels: %v", err)
@@ -198,19 +223,33 @@ func (nh *NotificationHandler) UpdateSettings(c *gin.Context) {
 	if req.WatchedSourceIDs == nil {
 		req.WatchedSourceIDs = []int{}
 	}
+	if req.WatchedFolderIDs == nil {
+		req.WatchedFolderIDs = []int64{}
+	}
+	watchedTags, err := models.NormalizeTags(req.WatchedTags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.WatchedTags = watchedTags
 	if req.PushChannels == nil {
 		req.PushChannels = []map[string]interface{}{}
 	}
 	watchedJSON, _ := json.Marshal(req.WatchedSourceIDs)
+	foldersJSON, _ := json.Marshal(req.WatchedFolderIDs)
+	tagsJSON, _ := json.Marshal(req.WatchedTags)
 	pushJSON, _ := json.Marshal(req.PushChannels)
 
-	_, err := nh.db.ExecContext(c.Request.Context(),
-		`INSERT INTO notification_settings (id, min_innovation_score, min_depth_score, notify_on_interesting, watched_source_ids, enabled, push_channels, updated_at)
-		 VALUES (1, $1, $2, $3, $4, $5, $6, NOW())
+	_, err = nh.db.ExecContext(c.Request.Context(),
+		`INSERT INTO notification_settings (id, min_innovation_score, min_depth_score, notify_on_interesting, watched_source_ids, enabled, push_channels,
+		                                    watched_folder_ids, watched_tags, updated_at)
+		 VALUES (1, $1, $2, $3, $4, $5, $6, $7, $8, NOW())
 		 ON CONFLICT (id) DO UPDATE SET
 		   min_innovation_score = $1, min_depth_score = $2, notify_on_interesting = $3,
-		   watched_source_ids = $4, enabled = $5, push_channels = $6, updated_at = NOW()`,
+		   watched_source_ids = $4, enabled = $5, push_channels = $6,
+		   watched_folder_ids = $7, watched_tags = $8, updated_at = NOW()`,
 		req.MinInnovationScore, req.MinDepthScore, req.NotifyOnInteresting, watchedJSON, req.Enabled, pushJSON,
+		foldersJSON, tagsJSON,
 	)
 	if err != nil {
 		log.Printf("Error updating notification settings: %v", err)
@@ -269,6 +308,12 @@ func (nh *NotificationHandler) StreamNotifications(c *gin.Context) {
 		case <-ctx.Done():
 			return
 
+		case <-sseShutdown:
+			// EventSource reconnects on its own once the server is back
+			fmt.Fprintf(c.Writer, "data: {\"type\":\"shutdown\"}\n\n")
+			flusher.Flush()
+			return
+
 		case <-time.After(30 * time.Second):
 			// Heartbeat keeps the connection alive through proxies that close idle HTTP connections
 			fmt.Fprintf(c.Writer, "data: {\"type\":\"heartbeat\"}\n\n")
@@ -8,6 +8,9 @@ func RegisterSourceRoutes(router *gin.Engine, handler *SourceHandler) {
 	{
 		sources.GET("", handler.ListSources)
 		sources.GET("/search", handler.SearchSources)  // ← 必须在 /:id 之前
+		sources.GET("/export/opml", handler.ExportOPML)
+		sources.POST("/import/opml", handler.ImportOPML)
+		sources.POST("/discover", handler.DiscoverFeeds)
 		sources.POST("/:id/fetch", handler.FetchSourceNow)  // ← 手动同步
 		sources.PUT("/:id/author-filter", handler.UpdateAuthorFilter)
 		sources.GET("/:id/authors", handler.GetSourceAuthors)
@@ -28,6 +31,8 @@ func RegisterContentRoutes(router *gin.Engine, handler *ContentHandler) {
 		content.POST("/restart-evaluation", handler.RestartEvaluation)
 		content.GET("", handler.ListContent)
 		content.GET("/:id", handler.GetContent)
+		content.GET("/:id/revisions", handler.GetContentRevisions)
+		content.GET("/:id/chunks", handler.GetContentChunks)
 	}
 }
 
@@ -99,3 +104,66 @@ func RegisterThreadRoutes(router *gin.Engine, handler *ThreadHandler) {
 	router.GET("/api/threads/:id/messages", handler.GetThreadMessages)
 }
 
+// RegisterFetchRunRoutes registers fetch history routes
+func RegisterFetchRunRoutes(router *gin.Engine, handler *FetchRunHandler) {
+	router.GET("/api/sources/:id/fetch-history", handler.GetSourceFetchHistory)
+	router.GET("/api/fetch-runs", handler.ListRecentFetchRuns)
+}
+
+// RegisterWebSubRoutes registers the WebSub callback (outside /api: it is called by hubs, not the frontend)
+func RegisterWebSubRoutes(router *gin.Engine, handler *WebSubHandler) {
+	router.GET("/websub/callback/:sourceID", handler.VerifyIntent)
+	router.POST("/websub/callback/:sourceID", handler.ReceivePush)
+}
+
+// RegisterFetchLeaseRoutes registers the fetch coordination admin routes
+func RegisterFetchLeaseRoutes(router *gin.Engine, handler *FetchLeaseHandler) {
+	router.GET("/api/admin/fetch-leases", handler.GetFetchLeases)
+}
+
+// RegisterDedupRoutes registers the deduplication admin routes
+func RegisterDedupRoutes(router *gin.Engine, handler *DedupHandler) {
+	router.GET("/api/admin/dedup/stats", handler.GetDedupStats)
+}
+
+// RegisterImageRoutes registers the image proxy route
+func RegisterImageRoutes(router *gin.Engine, handler *ImageHandler) {
+	router.GET("/api/images/:hash", handler.GetImage)
+}
+
+// RegisterURLRuleRoutes registers the URL canonicalization rule routes
+func RegisterURLRuleRoutes(router *gin.Engine, handler *URLRuleHandler) {
+	router.GET("/api/url-rules", handler.ListURLRules)
+	router.POST("/api/url-rules", handler.CreateURLRule)
+	router.POST("/api/url-rules/preview", handler.PreviewURL)
+	router.PUT("/api/url-rules/:id", handler.UpdateURLRule)
+	router.DELETE("/api/url-rules/:id", handler.DeleteURLRule)
+}
+
+// RegisterFilterRuleRoutes registers the pre-evaluation filter rule routes
+func RegisterFilterRuleRoutes(router *gin.Engine, handler *FilterRuleHandler) {
+	router.GET("/api/filter-rules", handler.ListFilterRules)
+	router.POST("/api/filter-rules", handler.CreateFilterRule)
+	router.POST("/api/filter-rules/test", handler.TestFilterRule)
+	router.PUT("/api/filter-rules/:id", handler.UpdateFilterRule)
+	router.DELETE("/api/filter-rules/:id", handler.DeleteFilterRule)
+	router.GET("/api/filter-skips", handler.ListFilterSkips)
+}
+
+// RegisterSourceFolderRoutes registers source folder, tag and bulk assignment routes
+func RegisterSourceFolderRoutes(router *gin.Engine, handler *SourceFolderHandler) {
+	router.GET("/api/folders", handler.ListFolders)
+	router.POST("/api/folders", handler.CreateFolder)
+	router.PUT("/api/folders/:id", handler.UpdateFolder)
+	router.DELETE("/api/folders/:id", handler.DeleteFolder)
+	router.GET("/api/tags", handler.ListTags)
+	router.PUT("/api/tags/:tag", handler.RenameTag)
+	router.DELETE("/api/tags/:tag", handler.DeleteTag)
+	router.POST("/api/sources/bulk", handler.BulkAssignSources)
+}
+
+// RegisterSourceStatsRoutes registers the per-source analytics routes
+func RegisterSourceStatsRoutes(router *gin.Engine, handler *SourceStatsHandler) {
+	router.GET("/api/sources/leaderboard", handler.GetLeaderboard)
+	router.GET("/api/sources/:id/stats", handler.GetSourceStats)
+}
@@ -7,16 +7,20 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/lib/pq"
 )
 
 /**
  * 搜索接口
  *
- * GET /api/search?q=keyword&status=EVALUATED&limit=50
+ * GET /api/search?q=keyword&status=EVALUATED&language=zh,en&folder_id=2&tag=go&limit=50
  *
  * 功能：
- * - 在 title 和 content 中使用 PostgreSQL ILIKE 搜索
+ * - 在 title 和 content（含超出摘录的全文）中使用 PostgreSQL ILIKE 搜索
  * - 支持按状态过滤
+ * - 支持按语言过滤（逗号分隔的 ISO 639-1 代码）
+ * - 支持按源的文件夹（含子文件夹）和标签过滤
  * - 支持分页（limit, offset）
  *
  * 性能特点：
@@ -38,6 +42,15 @@ func SearchContent(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "50")
 	offsetStr := c.DefaultQuery("offset", "0")
 
+	languages, ok := parseLanguageFilter(c)
+	if !ok {
+		return
+	}
+	scope, ok := parseSourceScope(c)
+	if !ok {
+		return
+	}
+
 	limit, _ := strconv.Atoi(limitStr)
 	offset, _ := strconv.Atoi(offsetStr)
 
@@ -70,6 +83,13 @@ func SearchContent(c *gin.Context) {
 		return
 	}
 
+	args := []interface{}{searchPattern, status, limit, offset, pq.Array(languages)}
+	scopeCond, scopeArgs := repositories.SourceScopeCondition("c.source_id", scope, len(args)+1)
+	if scopeCond != "" {
+		scopeCond = "AND " + scopeCond
+		args = append(args, scopeArgs...)
+	}
+
 	// 构建 SQL 查询
 	sql := `
 		SELECT
@@ -82,6 +102,7 @@ func SearchContent(c *gin.Context) {
 			c.status,
 			c.published_at,
 			c.created_at,
+			COALESCE(c.language, '') as language,
 			COALESCE(e.id, 0) as evaluation_id,
 			COALESCE(e.innovation_score, 0) as innovation_score,
 			COALESCE(e.depth_score, 0) as depth_score,
@@ -91,8 +112,10 @@ func SearchContent(c *gin.Context) {
 		FROM content c
 		LEFT JOIN evaluation e ON c.id = e.content_id
 		LEFT JOIN sources s ON c.source_id = s.id
-		WHERE (c.title ILIKE $1 OR c.clean_content ILIKE $1 OR c.author_name ILIKE $1)
+		WHERE (c.title ILIKE $1 OR c.clean_content ILIKE $1 OR c.full_markdown ILIKE $1 OR c.author_name ILIKE $1)
 		  AND c.status = $2
+		  AND ($5::text[] IS NULL OR c.language = ANY($5))
+		  ` + scopeCond + `
 		ORDER BY
 			CASE
 				WHEN c.title ILIKE $1 THEN 1
@@ -103,7 +126,7 @@ func SearchContent(c *gin.Context) {
 	`
 
 	// 执行查询
-	rows, err := db.Query(sql, searchPattern, status, limit, offset)
+	rows, err := db.Query(sql, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "search failed: " + err.Error(),
@@ -118,7 +141,7 @@ func SearchContent(c *gin.Context) {
 		var r ContentSearchResult
 		err := rows.Scan(
 			&r.ID, &r.Title, &r.Content, &r.URL, &r.SourceID,
-			&r.AuthorName, &r.Status, &r.PublishedAt, &r.CreatedAt,
+			&r.AuthorName, &r.Status, &r.PublishedAt, &r.CreatedAt, &r.Language,
 			&r.EvaluationID, &r.InnovationScore, &r.DepthScore,
 			&r.Decision, &r.TLDR, &r.SourceName,
 		)
@@ -145,6 +168,7 @@ type ContentSearchResult struct {
 	Status           string `json:"status"`
 	PublishedAt      string `json:"published_at"`
 	CreatedAt        string `json:"created_at"`
+	Language         string `json:"language"`
 	EvaluationID     int    `json:"evaluation_id"`
 	InnovationScore  int    `json:"innovation_score"`
 	DepthScore       int    `json:"depth_score"`
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+)
+
+// maxFolderNameLength bounds a folder name, in runes
+const maxFolderNameLength = 200
+
+// SourceFolderHandler manages source folders, source tags and bulk assignment
+type SourceFolderHandler struct {
+	folderRepo *repositories.SourceFolderRepository
+	sourceRepo *repositories.SourceRepository
+}
+
+// NewSourceFolderHandler creates a new source folder handler
+func NewSourceFolderHandler(folderRepo *repositories.SourceFolderRepository, sourceRepo *repositories.SourceRepository) *SourceFolderHandler {
+	return &SourceFolderHandler{folderRepo: folderRepo, sourceRepo: sourceRepo}
+}
+
+// ListFolders returns every folder in tree order
+// GET /api/folders
+func (fh *SourceFolderHandler) ListFolders(c *gin.Context) {
+	folders, err := fh.folderRepo.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing folders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list folders"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": folders, "count": len(folders)})
+}
+
+// CreateFolder adds a folder
+// POST /api/folders {"name": "Go", "parent_id": 3}
+func (fh *SourceFolderHandler) CreateFolder(c *gin.Context) {
+	req, ok := bindFolder(c)
+	if !ok {
+		return
+	}
+
+	folder, err := fh.folderRepo.Create(c.Request.Context(), req)
+	if err != nil {
+		folderError(c, "creating", err)
+		return
+	}
+	c.JSON(http.StatusCreated, folder)
+}
+
+// UpdateFolder renames, moves or reorders a folder
+// PUT /api/folders/:id
+func (fh *SourceFolderHandler) UpdateFolder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+	req, ok := bindFolder(c)
+	if !ok {
+		return
+	}
+
+	folder, err := fh.folderRepo.Update(c.Request.Context(), id, req)
+	if err != nil {
+		folderError(c, "updating", err)
+		return
+	}
+	if folder == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder removes a folder; its sources and subfolders move up to its parent
+// DELETE /api/folders/:id
+func (fh *SourceFolderHandler) DeleteFolder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	err = fh.folderRepo.Delete(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		return
+	}
+	if err != nil {
+		folderError(c, "deleting", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted"})
+}
+
+// ListTags returns every source tag in use with its source count
+// GET /api/tags
+func (fh *SourceFolderHandler) ListTags(c *gin.Context) {
+	tags, err := fh.folderRepo.ListTags(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing tags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags, "count": len(tags)})
+}
+
+// RenameTag renames a tag on every source, merging it into an existing tag of the new name
+// PUT /api/tags/:tag {"tag": "golang"}
+func (fh *SourceFolderHandler) RenameTag(c *gin.Context) {
+	var req struct {
+		Tag string `json:"tag" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	oldTag, err := models.NormalizeTag(c.Param("tag"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	newTag, err := models.NormalizeTag(req.Tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := fh.folderRepo.RenameTag(c.Request.Context(), oldTag, newTag)
+	if err != nil {
+		log.Printf("Error renaming tag %q: %v", oldTag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename tag"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tag": newTag, "updated": updated})
+}
+
+// DeleteTag removes a tag from every source
+// DELETE /api/tags/:tag
+func (fh *SourceFolderHandler) DeleteTag(c *gin.Context) {
+	tag, err := models.NormalizeTag(c.Param("tag"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := fh.folderRepo.DeleteTag(c.Request.Context(), tag)
+	if err != nil {
+		log.Printf("Error deleting tag %q: %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted", "updated": updated})
+}
+
+// BulkAssignSources moves sources to a folder and/or adds and removes tags
+// POST /api/sources/bulk {"source_ids": [1, 2], "folder_id": 3, "add_tags": ["go"], "remove_tags": []}
+func (fh *SourceFolderHandler) BulkAssignSources(c *gin.Context) {
+	var req models.BulkSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var err error
+	if req.AddTags, err = models.NormalizeTags(req.AddTags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.RemoveTags, err = models.NormalizeTags(req.RemoveTags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.FolderID == nil && len(req.AddTags) == 0 && len(req.RemoveTags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to assign: set folder_id, add_tags or remove_tags"})
+		return
+	}
+
+	updated, err := fh.sourceRepo.BulkAssign(c.Request.Context(), &req)
+	if errors.Is(err, repositories.ErrFolderNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error bulk-assigning sources: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sources"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// bindFolder parses and validates a folder request body, writing a 400 response on failure
+func bindFolder(c *gin.Context) (*models.SourceFolderRequest, bool) {
+	var req models.SourceFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > maxFolderNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-200 characters"})
+		return nil, false
+	}
+	return &req, true
+}
+
+// folderError writes the response for a failed folder write
+func folderError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrFolderNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrFolderParentNotFound), errors.Is(err, repositories.ErrFolderCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error %s folder: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save folder"})
+	}
+}
+
+// parseSourceScope reads the ?folder_id= and ?tag= filters shared by the source, content and
+// stats listings, writing a 400 response when they are malformed
+func parseSourceScope(c *gin.Context) (models.SourceScope, bool) {
+	var scope models.SourceScope
+	if raw := c.Query("folder_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "folder_id must be a positive integer"})
+			return scope, false
+		}
+		scope.FolderID = id
+	}
+	if raw := c.Query("tag"); raw != "" {
+		tag, err := models.NormalizeTag(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return scope, false
+		}
+		scope.Tag = tag
+	}
+	return scope, true
+}
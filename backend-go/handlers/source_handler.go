@@ -1,27 +1,57 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/junkfilter/backend-go/models"
 	"github.com/junkfilter/backend-go/repositories"
 	"github.com/junkfilter/backend-go/services"
+	"github.com/junkfilter/backend-go/utils"
 )
 
+// maxOPMLUploadBytes caps OPML uploads; real subscription lists are a few hundred KB at most
+const maxOPMLUploadBytes = 5 << 20
+
+// OPML import result statuses
+const (
+	opmlCreated         = "created"
+	opmlWouldCreate     = "would_create"
+	opmlExists          = "exists"
+	opmlDuplicateInFile = "duplicate_in_file"
+	opmlInvalid         = "invalid"
+	opmlError           = "error"
+)
+
+// OPMLImportResult reports what happened to one OPML entry
+type OPMLImportResult struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Category string `json:"category,omitempty"`
+	Status   string `json:"status"`
+	SourceID int64  `json:"source_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
 // SourceHandler handles source-related HTTP requests
 type SourceHandler struct {
 	sourceRepo *repositories.SourceRepository
+	folderRepo *repositories.SourceFolderRepository
 	rssService *services.RSSService
 }
 
 // NewSourceHandler creates a new source handler
-func NewSourceHandler(sourceRepo *repositories.SourceRepository, rssService *services.RSSService) *SourceHandler {
+func NewSourceHandler(sourceRepo *repositories.SourceRepository, folderRepo *repositories.SourceFolderRepository, rssService *services.RSSService) *SourceHandler {
 	return &SourceHandler{
 		sourceRepo: sourceRepo,
+		folderRepo: folderRepo,
 		rssService: rssService,
 	}
 }
@@ -34,16 +64,117 @@ func (sh *SourceHandler) CreateSource(c *gin.Context) {
 		return
 	}
 
+	adapters := sh.rssService.Adapters()
+	if err := adapters.ValidateConfig(req.Platform, req.AdapterConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	languages, err := utils.NormalizeLanguages(req.AllowedLanguages)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.AllowedLanguages = languages
+	if !models.ValidStreamPayload(req.StreamPayload) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stream_payload must be \"excerpt\" or \"reference\""})
+		return
+	}
+	if req.Tags, err = models.NormalizeTags(req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	if req.HTTPOptions != nil {
+		if err := req.HTTPOptions.Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// A private feed can only be discovered with its credentials, still in plain text here
+		plain, err := req.HTTPOptions.Open(plainSecret, req.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx = utils.WithHTTPOptions(ctx, plain)
+		if !sh.sealHTTPOptions(c, req.HTTPOptions, nil) {
+			return
+		}
+	}
+
+	// Users often paste a homepage instead of the feed URL — resolve it before storing.
+	// Network failures keep the old behavior of storing the URL as posted.
+	// Platforms with their own adapter (github, reddit, ...) take page URLs as they are.
+	var discovery *utils.FeedDiscovery
+	if adapters.IsFeedPlatform(req.Platform) {
+		discovery, err = sh.rssService.DiscoverFeeds(ctx, req.URL)
+	}
+	if err != nil {
+		log.Printf("Feed discovery failed for %s, storing as-is: %v", req.URL, err)
+		discovery = nil
+	} else if discovery != nil && !discovery.IsFeed {
+		if discovery.Best == nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":     "No RSS/Atom/JSON feed found at this URL",
+				"discovery": discovery,
+			})
+			return
+		}
+		if req.AutoDiscover != nil && !*req.AutoDiscover {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":     "URL is a web page, choose one of the discovered feeds",
+				"discovery": discovery,
+			})
+			return
+		}
+		log.Printf("Discovered feed %s for page %s", discovery.Best.URL, req.URL)
+		req.URL = discovery.Best.URL
+	}
+
+	if discovery != nil {
+		if req.AuthorName == "" {
+			req.AuthorName = discovery.SiteTitle
+		}
+		if req.FaviconURL == "" {
+			req.FaviconURL = discovery.FaviconURL
+		}
+	}
+
 	source, err := sh.sourceRepo.Create(c.Request.Context(), &req)
+	if errors.Is(err, repositories.ErrFolderNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating source: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create source"})
 		return
 	}
+	sh.rssService.Reschedule(c.Request.Context(), source.ID)
 
 	c.JSON(http.StatusCreated, source.ToResponse())
 }
 
+// DiscoverFeeds previews the feeds found at a URL without creating a source
+// POST /api/sources/discover {"url": "https://example.com"}
+func (sh *SourceHandler) DiscoverFeeds(c *gin.Context) {
+	var req struct {
+		URL string `json:"url" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	discovery, err := sh.rssService.DiscoverFeeds(c.Request.Context(), req.URL)
+	if err != nil {
+		log.Printf("Error discovering feeds for %s: %v", req.URL, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch URL: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, discovery)
+}
+
 // GetSource retrieves a source by ID
 func (sh *SourceHandler) GetSource(c *gin.Context) {
 	idStr := c.Param("id")
@@ -68,11 +199,16 @@ func (sh *SourceHandler) GetSource(c *gin.Context) {
 	c.JSON(http.StatusOK, source.ToResponse())
 }
 
-// ListSources lists all sources
+// ListSources lists all sources, optionally those in a folder (?folder_id=, subfolders included)
+// or with a tag (?tag=)
 func (sh *SourceHandler) ListSources(c *gin.Context) {
 	enabledOnly := c.Query("enabled") == "true"
+	scope, ok := parseSourceScope(c)
+	if !ok {
+		return
+	}
 
-	sources, err := sh.sourceRepo.GetAll(c.Request.Context(), enabledOnly)
+	sources, err := sh.sourceRepo.List(c.Request.Context(), enabledOnly, scope)
 	if err != nil {
 		log.Printf("Error listing sources: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sources"})
@@ -102,16 +238,81 @@ func (sh *SourceHandler) UpdateSource(c *gin.Context) {
 		return
 	}
 
+	if req.AllowedLanguages != nil {
+		languages, err := utils.NormalizeLanguages(*req.AllowedLanguages)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.AllowedLanguages = &languages
+	}
+	if req.StreamPayload != nil && !models.ValidStreamPayload(*req.StreamPayload) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stream_payload must be \"excerpt\" or \"reference\""})
+		return
+	}
+	if req.Tags != nil {
+		tags, err := models.NormalizeTags(*req.Tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Tags = &tags
+	}
+
+	if len(req.AdapterConfig) > 0 || req.HTTPOptions != nil {
+		existing, err := sh.sourceRepo.GetByID(c.Request.Context(), id)
+		if err != nil || existing == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+			return
+		}
+		if len(req.AdapterConfig) > 0 {
+			if err := sh.rssService.Adapters().ValidateConfig(existing.Platform, req.AdapterConfig); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.HTTPOptions != nil {
+			if err := req.HTTPOptions.Normalize(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if !sh.sealHTTPOptions(c, req.HTTPOptions, existing.GetHTTPOptions()) {
+				return
+			}
+		}
+	}
+
 	source, err := sh.sourceRepo.Update(c.Request.Context(), id, &req)
+	if errors.Is(err, repositories.ErrFolderNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Error updating source: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update source"})
 		return
 	}
+	sh.rssService.Reschedule(c.Request.Context(), id)
 
 	c.JSON(http.StatusOK, source.ToResponse())
 }
 
+// sealHTTPOptions encrypts the secrets of normalized HTTP options in place, keeping the stored
+// value of every secret sent back masked. It writes a 400 response on failure, including when
+// no secret key is configured to encrypt with.
+func (sh *SourceHandler) sealHTTPOptions(c *gin.Context, opts, prev *models.SourceHTTPOptions) bool {
+	if err := opts.SealSecrets(prev, sh.rssService.Secrets().Seal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// plainSecret opens a secret that was never sealed
+func plainSecret(s string) (string, error) {
+	return s, nil
+}
+
 // DeleteSource deletes a source
 func (sh *SourceHandler) DeleteSource(c *gin.Context) {
 	idStr := c.Param("id")
@@ -126,6 +327,7 @@ func (sh *SourceHandler) DeleteSource(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete source"})
 		return
 	}
+	sh.rssService.Reschedule(c.Request.Context(), id)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Source deleted"})
 }
@@ -204,8 +406,8 @@ func (sh *SourceHandler) GetSourceAuthors(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"authors": authors})
 }
 
-// SearchSources searches for RSS sources by query and optional platform
-// GET /api/sources/search?query={keyword}&platform={platform}
+// SearchSources searches for RSS sources by query and optional platform, folder and tag
+// GET /api/sources/search?query={keyword}&platform={platform}&folder_id={id}&tag={tag}&limit={n}
 func (sh *SourceHandler) SearchSources(c *gin.Context) {
 	query := c.Query("query")
 	platform := c.Query("platform")
@@ -214,8 +416,17 @@ func (sh *SourceHandler) SearchSources(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter is required"})
 		return
 	}
+	scope, ok := parseSourceScope(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
 
-	sources, err := sh.sourceRepo.Search(c.Request.Context(), query, platform)
+	sources, err := sh.sourceRepo.Search(c.Request.Context(), query, platform, scope, limit)
 	if err != nil {
 		log.Printf("Error searching sources: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search sources"})
@@ -234,6 +445,10 @@ func (sh *SourceHandler) SearchSources(c *gin.Context) {
 			"fetch_interval_seconds": source.FetchIntervalSeconds,
 			"enabled":                source.Enabled,
 			"last_fetch_time":        source.LastFetchTime,
+			"health":                 source.Health(),
+			"last_error":             source.LastError,
+			"folder_id":              source.FolderID,
+			"tags":                   source.Tags,
 			"created_at":             source.CreatedAt,
 			"updated_at":             source.UpdatedAt,
 		})
@@ -241,3 +456,189 @@ func (sh *SourceHandler) SearchSources(c *gin.Context) {
 
 	c.JSON(http.StatusOK, responses)
 }
+
+// ImportOPML imports subscriptions from an OPML file
+// POST /api/sources/import/opml (multipart field "file", ?dry_run=true to preview)
+func (sh *SourceHandler) ImportOPML(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field 'file' is required"})
+		return
+	}
+	if fileHeader.Size > maxOPMLUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "OPML file too large (max 5MB)"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	feeds, err := utils.ParseOPML(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	existing, err := sh.sourceRepo.GetAll(ctx, false)
+	if err != nil {
+		log.Printf("Error loading sources for OPML import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load existing sources"})
+		return
+	}
+
+	existingIDs := make(map[string]int64, len(existing))
+	for _, s := range existing {
+		existingIDs[opmlDedupKey(s.URL)] = s.ID
+	}
+	seenInFile := make(map[string]bool, len(feeds))
+	folderIDs := map[string]*int64{}
+
+	results := make([]OPMLImportResult, 0, len(feeds))
+	counts := map[string]int{}
+	for _, feed := range feeds {
+		result := OPMLImportResult{Title: feed.Title, URL: feed.XMLURL, Category: feed.Category}
+		key := opmlDedupKey(feed.XMLURL)
+
+		parsed, parseErr := url.Parse(feed.XMLURL)
+		switch {
+		case parseErr != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https"):
+			result.Status = opmlInvalid
+			result.Error = "xmlUrl must be an absolute http(s) URL"
+		case existingIDs[key] != 0:
+			result.Status = opmlExists
+			result.SourceID = existingIDs[key]
+		case seenInFile[key]:
+			result.Status = opmlDuplicateInFile
+		case dryRun:
+			result.Status = opmlWouldCreate
+		default:
+			folderID := sh.opmlFolder(ctx, feed.Category, folderIDs)
+			source, err := sh.sourceRepo.Create(ctx, &models.CreateSourceRequest{
+				URL:        feed.XMLURL,
+				AuthorName: feed.Title,
+				Platform:   "blog",
+				FolderID:   folderID,
+			})
+			if err != nil {
+				log.Printf("Error creating source from OPML (%s): %v", feed.XMLURL, err)
+				result.Status = opmlError
+				result.Error = "Failed to create source"
+			} else {
+				result.Status = opmlCreated
+				result.SourceID = source.ID
+				sh.rssService.Reschedule(ctx, source.ID)
+				existingIDs[key] = source.ID
+			}
+		}
+
+		seenInFile[key] = true
+		counts[result.Status]++
+		results = append(results, result)
+	}
+
+	log.Printf("[OPML] Import (dry_run=%v): %d entries, %d created, %d existing",
+		dryRun, len(feeds), counts[opmlCreated]+counts[opmlWouldCreate], counts[opmlExists])
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"total":   len(feeds),
+		"created": counts[opmlCreated] + counts[opmlWouldCreate],
+		"skipped": counts[opmlExists] + counts[opmlDuplicateInFile],
+		"failed":  counts[opmlInvalid] + counts[opmlError],
+		"results": results,
+	})
+}
+
+// ExportOPML exports all sources as an OPML file, grouped by folder (by platform for sources
+// outside any folder)
+// GET /api/sources/export/opml
+func (sh *SourceHandler) ExportOPML(c *gin.Context) {
+	sources, err := sh.sourceRepo.GetAll(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error listing sources for OPML export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sources"})
+		return
+	}
+	folders, err := sh.folderRepo.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing folders for OPML export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list folders"})
+		return
+	}
+	folderPaths := make(map[int64]string, len(folders))
+	for _, f := range folders {
+		folderPaths[f.ID] = strings.ReplaceAll(f.Path, " / ", "/")
+	}
+
+	feeds := make([]utils.OPMLFeed, 0, len(sources))
+	for _, s := range sources {
+		category := s.Platform
+		if s.FolderID != nil && folderPaths[*s.FolderID] != "" {
+			category = folderPaths[*s.FolderID]
+		}
+		feeds = append(feeds, utils.OPMLFeed{
+			Title:    s.AuthorName,
+			XMLURL:   s.URL,
+			Category: category,
+		})
+	}
+
+	data, err := utils.BuildOPML("JunkFilter Subscriptions", feeds)
+	if err != nil {
+		log.Printf("Error building OPML: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build OPML"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="junkfilter-sources.opml"`)
+	c.Data(http.StatusOK, "text/x-opml; charset=utf-8", data)
+}
+
+// opmlFolder returns the folder for an OPML category, creating it on first use. Folder errors
+// are logged and the feed is imported outside any folder.
+func (sh *SourceHandler) opmlFolder(ctx context.Context, category string, cache map[string]*int64) *int64 {
+	if id, ok := cache[category]; ok {
+		return id
+	}
+	var folderID *int64
+	if names := opmlFolderPath(category); len(names) > 0 {
+		id, err := sh.folderRepo.EnsurePath(ctx, names)
+		if err != nil {
+			log.Printf("Error creating folder %q for OPML import: %v", category, err)
+		} else {
+			folderID = &id
+		}
+	}
+	cache[category] = folderID
+	return folderID
+}
+
+// opmlFolderPath splits an OPML category ("Tech/Go") into folder names
+func opmlFolderPath(category string) []string {
+	var names []string
+	for _, name := range strings.Split(category, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// opmlDedupKey normalizes a feed URL for duplicate detection during import:
+// case-insensitive scheme/host and no trailing slash
+func opmlDedupKey(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if parsed, err := url.Parse(rawURL); err == nil {
+		parsed.Scheme = strings.ToLower(parsed.Scheme)
+		parsed.Host = strings.ToLower(parsed.Host)
+		rawURL = parsed.String()
+	}
+	return strings.TrimSuffix(rawURL, "/")
+}
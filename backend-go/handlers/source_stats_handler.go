@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+)
+
+// Bounds of the ?days= period of the stats endpoints
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+)
+
+// SourceStatsHandler serves per-source quality analytics from the daily rollups
+type SourceStatsHandler struct {
+	statsRepo  *repositories.SourceStatsRepository
+	sourceRepo *repositories.SourceRepository
+}
+
+// NewSourceStatsHandler creates a new source stats handler
+func NewSourceStatsHandler(statsRepo *repositories.SourceStatsRepository, sourceRepo *repositories.SourceRepository) *SourceStatsHandler {
+	return &SourceStatsHandler{statsRepo: statsRepo, sourceRepo: sourceRepo}
+}
+
+// GetSourceStats returns a source's volume, decisions, scores and daily trend
+// GET /api/sources/:id/stats?days=30
+func (sh *SourceStatsHandler) GetSourceStats(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return
+	}
+	days, ok := parseStatsDays(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	source, err := sh.sourceRepo.GetByID(ctx, id)
+	if err != nil {
+		log.Printf("Error getting source: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get source"})
+		return
+	}
+	if source == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	now := time.Now()
+	daily, err := sh.statsRepo.ListDaily(ctx, id, now.AddDate(0, 0, 1-days))
+	if err != nil {
+		log.Printf("Error loading stats of source %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get source stats"})
+		return
+	}
+	lastHighScoreAt, err := sh.statsRepo.LastHighScoreAt(ctx, id)
+	if err != nil {
+		log.Printf("Error loading stats of source %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get source stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BuildSourceStats(id, days, now, daily, lastHighScoreAt))
+}
+
+// GetLeaderboard ranks sources by their rollups over the last days
+// GET /api/sources/leaderboard?days=30&sort=pass_rate&order=desc&min_evaluated=5&limit=20&folder_id=2&tag=go
+func (sh *SourceStatsHandler) GetLeaderboard(c *gin.Context) {
+	days, ok := parseStatsDays(c)
+	if !ok {
+		return
+	}
+	scope, ok := parseSourceScope(c)
+	if !ok {
+		return
+	}
+
+	q := &models.LeaderboardQuery{
+		Since: time.Now().AddDate(0, 0, 1-days),
+		Sort:  c.DefaultQuery("sort", models.LeaderboardByPassRate),
+		Scope: scope,
+	}
+	if !models.ValidLeaderboardSort(q.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be pass_rate, avg_score, high_scores or volume"})
+		return
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+	var err error
+	if q.MinEvaluated, err = strconv.Atoi(c.DefaultQuery("min_evaluated", "5")); err != nil || q.MinEvaluated < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_evaluated must be a non-negative integer"})
+		return
+	}
+	if q.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "20")); err != nil || q.Limit < 1 || q.Limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	entries, err := sh.statsRepo.Leaderboard(c.Request.Context(), q)
+	if err != nil {
+		log.Printf("Error building source leaderboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build leaderboard"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entries, "count": len(entries), "days": days, "sort": q.Sort})
+}
+
+// parseStatsDays reads ?days=, writing a 400 response when it is out of range
+func parseStatsDays(c *gin.Context) (int, bool) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultStatsDays)))
+	if err != nil || days < 1 || days > maxStatsDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+		return 0, false
+	}
+	return days, true
+}
@@ -5,8 +5,21 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 )
 
+// sseShutdown is closed when the server starts shutting down; long-lived SSE streams
+// watch it because http.Server.Shutdown does not cancel the contexts of active requests
+var (
+	sseShutdown     = make(chan struct{})
+	sseShutdownOnce sync.Once
+)
+
+// CloseSSEStreams ends every open SSE stream. Register it with http.Server.RegisterOnShutdown.
+func CloseSSEStreams() {
+	sseShutdownOnce.Do(func() { close(sseShutdown) })
+}
+
 // SSEEvent represents a server-sent event
 type SSEEvent struct {
 	Status string      `json:"status"`
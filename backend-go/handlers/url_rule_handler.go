@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/services"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// URLRuleHandler manages per-domain URL canonicalization rules
+type URLRuleHandler struct {
+	ruleRepo   *repositories.URLRuleRepository
+	rssService *services.RSSService
+}
+
+// NewURLRuleHandler creates a new URL rule handler
+func NewURLRuleHandler(ruleRepo *repositories.URLRuleRepository, rssService *services.RSSService) *URLRuleHandler {
+	return &URLRuleHandler{ruleRepo: ruleRepo, rssService: rssService}
+}
+
+// ListURLRules returns all rules
+// GET /api/url-rules
+func (uh *URLRuleHandler) ListURLRules(c *gin.Context) {
+	rules, err := uh.ruleRepo.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing URL rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list URL rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rules, "count": len(rules)})
+}
+
+// CreateURLRule adds a rule for a domain
+// POST /api/url-rules {"domain": ".example.com", "keep_params": ["p"]}
+func (uh *URLRuleHandler) CreateURLRule(c *gin.Context) {
+	rule, ok := bindURLRule(c)
+	if !ok {
+		return
+	}
+
+	created, err := uh.ruleRepo.Create(c.Request.Context(), rule)
+	if errors.Is(err, repositories.ErrURLRuleExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating URL rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create URL rule"})
+		return
+	}
+	uh.rssService.URLRulesChanged(c.Request.Context())
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateURLRule replaces a rule
+// PUT /api/url-rules/:id
+func (uh *URLRuleHandler) UpdateURLRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+	rule, ok := bindURLRule(c)
+	if !ok {
+		return
+	}
+
+	updated, err := uh.ruleRepo.Update(c.Request.Context(), id, rule)
+	if errors.Is(err, repositories.ErrURLRuleExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating URL rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update URL rule"})
+		return
+	}
+	if updated == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL rule not found"})
+		return
+	}
+	uh.rssService.URLRulesChanged(c.Request.Context())
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteURLRule removes a rule
+// DELETE /api/url-rules/:id
+func (uh *URLRuleHandler) DeleteURLRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	deleted, err := uh.ruleRepo.Delete(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error deleting URL rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete URL rule"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL rule not found"})
+		return
+	}
+	uh.rssService.URLRulesChanged(c.Request.Context())
+
+	c.JSON(http.StatusOK, gin.H{"message": "URL rule deleted"})
+}
+
+// PreviewURL shows the canonical form of a URL under the stored rules, without fetching it
+// POST /api/url-rules/preview {"url": "https://example.com/post?id=1&utm_source=x"}
+func (uh *URLRuleHandler) PreviewURL(c *gin.Context) {
+	var req struct {
+		URL string `json:"url" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stored, err := uh.ruleRepo.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing URL rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list URL rules"})
+		return
+	}
+	rules := make([]utils.URLRule, len(stored))
+	for i, rule := range stored {
+		rules[i] = rule.URLRule
+	}
+	canonicalizer := utils.NewURLCanonicalizer(rules)
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":               req.URL,
+		"canonical_url":     canonicalizer.Canonicalize(req.URL),
+		"resolve_canonical": canonicalizer.ResolvesCanonical(req.URL),
+	})
+}
+
+// bindURLRule reads and validates a rule from the request body, writing the 400 response on failure
+func bindURLRule(c *gin.Context) (*utils.URLRule, bool) {
+	var rule utils.URLRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	rule.Domain = strings.ToLower(strings.TrimSpace(rule.Domain))
+	rule.RedirectParam = strings.TrimSpace(rule.RedirectParam)
+	switch {
+	case strings.TrimPrefix(rule.Domain, ".") == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain is required"})
+		return nil, false
+	case strings.ContainsAny(rule.Domain, "/:?#@ "):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain must be a host name such as example.com or .example.com"})
+		return nil, false
+	case len(rule.KeepParams) > 0 && len(rule.DropParams) > 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_params and drop_params are mutually exclusive"})
+		return nil, false
+	}
+	return &rule, true
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/junkfilter/backend-go/services"
+)
+
+// maxWebSubPushBytes bounds a content distribution body; larger pushes are refused with 413
+// rather than truncated, which would only fail the signature check. The entries still arrive
+// with the next fallback poll.
+const maxWebSubPushBytes = 5 << 20
+
+// WebSubHandler serves the callback hubs use to verify subscriptions and push new entries
+type WebSubHandler struct {
+	websub *services.WebSubService
+}
+
+// NewWebSubHandler creates a new WebSub callback handler
+func NewWebSubHandler(websub *services.WebSubService) *WebSubHandler {
+	return &WebSubHandler{websub: websub}
+}
+
+// VerifyIntent answers subscribe/unsubscribe verification and denial notices
+// GET /websub/callback/:sourceID?hub.mode=subscribe&hub.topic=...&hub.challenge=...&hub.lease_seconds=...
+func (wh *WebSubHandler) VerifyIntent(c *gin.Context) {
+	sourceID, err := strconv.ParseInt(c.Param("sourceID"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	challenge, ok := wh.websub.VerifyIntent(c.Request.Context(), sourceID, c.Request.URL.Query())
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.String(http.StatusOK, challenge)
+}
+
+// ReceivePush accepts a content distribution signed with X-Hub-Signature
+// POST /websub/callback/:sourceID
+func (wh *WebSubHandler) ReceivePush(c *gin.Context) {
+	sourceID, err := strconv.ParseInt(c.Param("sourceID"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusGone)
+		return
+	}
+
+	// One byte over the limit tells an oversize body from one that is exactly at it
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebSubPushBytes+1))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebSubPushBytes {
+		log.Printf("[WebSub] Refused push for source %d: body exceeds %d bytes", sourceID, maxWebSubPushBytes)
+		c.Status(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	err = wh.websub.Receive(c.Request.Context(), sourceID, body, c.GetHeader("X-Hub-Signature"))
+	if errors.Is(err, services.ErrWebSubUnknownSubscription) {
+		// 410 tells the hub to drop the subscription (e.g. the source was deleted)
+		c.Status(http.StatusGone)
+		return
+	}
+	if err != nil {
+		log.Printf("[WebSub] Error receiving push for source %d: %v", sourceID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
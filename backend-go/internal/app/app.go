@@ -0,0 +1,196 @@
+// Package app 组装并运行后端进程
+//
+// 同一套依赖（internal/service.Factory）支持三种运行模式：
+//   - all：抓取 + HTTP API，即原来的单进程部署（根目录 main.go）
+//   - api：只提供 HTTP API（cmd/api），可随前端需要水平扩展
+//   - fetcher：只抓取 RSS（cmd/fetcher），可独立扩缩容和重启
+//
+// API 进程通过 Redis 频道通知抓取进程重新调度被修改的源，两者只共享 Postgres 和 Redis。
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/junkfilter/backend-go/handlers"
+	"github.com/junkfilter/backend-go/internal/config"
+	"github.com/junkfilter/backend-go/internal/service"
+)
+
+// Mode 运行模式
+type Mode string
+
+const (
+	ModeAll     Mode = "all"
+	ModeAPI     Mode = "api"
+	ModeFetcher Mode = "fetcher"
+)
+
+// ParseMode 解析 -mode 参数
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeAll, ModeAPI, ModeFetcher:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown mode %q (want all, api or fetcher)", s)
+}
+
+// fetches 该模式是否运行抓取调度器
+func (m Mode) fetches() bool {
+	return m == ModeAll || m == ModeFetcher
+}
+
+// serves 该模式是否提供 HTTP API
+func (m Mode) serves() bool {
+	return m == ModeAll || m == ModeAPI
+}
+
+// ingestsPushes 该模式是否在不运行抓取调度器的情况下入库 WebSub 推送
+func (m Mode) ingestsPushes(webSub bool) bool {
+	return webSub && m == ModeAPI
+}
+
+// Run 加载配置、初始化依赖并按模式启动组件，阻塞到收到 SIGINT/SIGTERM 后优雅退出
+func Run(mode Mode) error {
+	// 三层配置加载：默认值 → config.yaml → 环境变量
+	cfg := config.Load()
+	log.Printf("✓ Configuration loaded (mode: %s)", mode)
+
+	factory, err := service.NewFactory(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	log.Println("\n========== JunkFilter Backend ==========")
+	log.Printf("Mode: %s\n", mode)
+	log.Printf("Database: %s:%d/%s\n", cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)
+	log.Printf("Redis: %s\n", cfg.GetRedisAddr())
+	if cfg.Ingestion.ProxyURL != "" {
+		log.Printf("RSS Proxy: %s\n", cfg.Ingestion.ProxyURL)
+	} else {
+		log.Println("RSS Proxy: disabled (set RSS_PROXY_URL to enable)")
+	}
+	if mode.serves() {
+		log.Printf("Server: listening on :%d\n", cfg.Server.Port)
+	}
+	log.Println("========================================")
+
+	rssService := factory.RSSService()
+	coordinator := factory.Coordinator()
+	webSubService := factory.WebSubService()
+
+	// URL 规范化规则与过滤规则：抓取与 WebSub 推送入库都会用到，API 修改后通过 Redis 通知各进程重新加载
+	if err := rssService.ReloadURLRules(context.Background()); err != nil {
+		log.Printf("Warning: Failed to load URL rules: %v", err)
+	}
+	if err := rssService.ReloadFilterRules(context.Background()); err != nil {
+		log.Printf("Warning: Failed to load filter rules: %v", err)
+	}
+
+	// 抓取：调度器按每个源的下次到期时间抓取，fetch_interval 为未设置间隔的源的默认值。
+	// WebSub 的回调与推送入库由 API 处理，续订循环跟随抓取进程
+	if mode.ingestsPushes(webSubService != nil) {
+		// 只提供 API 的进程也会入库推送：需要自己的 Bloom 过滤器并跟随规则变更
+		rssService.StartPushIngestion(context.Background())
+	}
+	if mode.fetches() {
+		if coordinator != nil {
+			coordinator.Start(context.Background())
+		}
+		if err := rssService.Start(context.Background(), cfg.GetFetchInterval()); err != nil {
+			log.Printf("Error starting RSS service: %v", err)
+		}
+		if webSubService != nil {
+			webSubService.Start(context.Background())
+		}
+	}
+
+	// HTTP API 服务：Serve 在独立 goroutine 中运行
+	var server *http.Server
+	if mode.serves() {
+		server, err = startServer(NewRouter(factory), cfg.Server.Port)
+		if err != nil {
+			factory.Close()
+			return err
+		}
+	}
+
+	// 主 goroutine 阻塞到收到 SIGINT/SIGTERM（容器重启、Ctrl+C）
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigChan
+	signal.Stop(sigChan)
+
+	shutdownTimeout := cfg.GetShutdownTimeout()
+	log.Printf("Received %v, shutting down (timeout %v)...", sig, shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	shutdown(ctx, mode, server, factory)
+	return nil
+}
+
+// shutdown 按依赖顺序优雅退出，所有步骤共享同一个截止时间：
+//  1. 停止调度器，等待进行中的抓取与 Stream 发布完成（超时则取消抓取请求，已开始入库的条目仍会发布），
+//     然后释放分片租约，让其他实例立即接管
+//  2. http.Server.Shutdown：停止接收新请求，通知 SSE 连接结束，等待进行中的请求
+//  3. 停止 WebSub（等待已接收的推送处理完）
+//  4. 最后关闭 Redis 与 Postgres
+func shutdown(ctx context.Context, mode Mode, server *http.Server, factory *service.Factory) {
+	if mode.fetches() || mode.ingestsPushes(factory.WebSubService() != nil) {
+		if err := factory.RSSService().Stop(ctx); err != nil {
+			log.Printf("Warning: RSS service did not drain in time: %v", err)
+		} else {
+			log.Println("✓ RSS service stopped")
+		}
+		if coordinator := factory.Coordinator(); coordinator != nil {
+			coordinator.Stop(ctx)
+		}
+	}
+
+	if server != nil {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Warning: HTTP server shutdown: %v", err)
+			server.Close()
+		} else {
+			log.Println("✓ HTTP server stopped")
+		}
+	}
+
+	if webSubService := factory.WebSubService(); webSubService != nil {
+		webSubService.Stop()
+	}
+
+	factory.Close()
+	log.Println("✓ Shutdown complete")
+}
+
+// startServer 启动 HTTP API 服务，返回的 *http.Server 用于优雅关闭
+//
+// 使用自定义 net.Listen 而非 router.Run()，端口被占用时能在启动阶段直接报错。
+func startServer(handler http.Handler, port int) (*http.Server, error) {
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+	log.Printf("✓ Server starting on %s\n", addr)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create listener: %w", err)
+	}
+
+	server := &http.Server{Handler: handler}
+	// Shutdown 不会取消进行中请求的 context，SSE 长连接需要单独通知
+	server.RegisterOnShutdown(handlers.CloseSSEStreams)
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+	return server, nil
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"all", "api", "fetcher"} {
+		mode, err := ParseMode(s)
+		if err != nil || string(mode) != s {
+			t.Errorf("ParseMode(%q) = %q, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseMode("worker"); err == nil {
+		t.Error("ParseMode should reject unknown modes")
+	}
+
+	if !ModeFetcher.fetches() || ModeFetcher.serves() {
+		t.Error("fetcher mode should fetch without serving HTTP")
+	}
+	if ModeAPI.fetches() || !ModeAPI.serves() {
+		t.Error("api mode should serve HTTP without fetching")
+	}
+	if !ModeAll.fetches() || !ModeAll.serves() {
+		t.Error("all mode should do both")
+	}
+	if !ModeAPI.ingestsPushes(true) || ModeAPI.ingestsPushes(false) || ModeAll.ingestsPushes(true) {
+		t.Error("only api mode with WebSub should set up push ingestion on its own")
+	}
+}
+
+func TestSanitizeLogBody(t *testing.T) {
+	got := sanitizeLogBody([]byte(`{"name":"feed","api_key":"sk-123","tags":["a","b"]}`))
+	if strings.Contains(got, "sk-123") || !strings.Contains(got, `"api_key":"***"`) {
+		t.Errorf("api_key not masked: %s", got)
+	}
+	if !strings.Contains(got, `"tags":"[2 items]"`) {
+		t.Errorf("array not summarized: %s", got)
+	}
+	if got := sanitizeLogBody([]byte("not json")); got != "(non-JSON 8B)" {
+		t.Errorf("non-JSON body = %q", got)
+	}
+}
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/junkfilter/backend-go/handlers"
+	"github.com/junkfilter/backend-go/internal/config"
+	"github.com/junkfilter/backend-go/internal/service"
+)
+
+// NewRouter 创建 HTTP API 路由，所有 handler 的依赖都来自 factory
+func NewRouter(factory *service.Factory) *gin.Engine {
+	cfg := factory.Config()
+	db := factory.DB().Conn()
+	rdb := factory.Redis().Client()
+	repos := factory.Repositories()
+	rssService := factory.RSSService()
+
+	router := gin.Default()
+	router.Use(requestBodyLogger())
+	router.Use(corsMiddleware(cfg))
+
+	// 注册 handlers
+	sourceHandler := handlers.NewSourceHandler(repos.Source, repos.Folder, rssService)
+	imageProxy := factory.ImageProxy()
+	contentHandler := handlers.NewContentHandler(repos.Content, repos.Evaluation, repos.Source, repos.Revision, imageProxy, db)
+	evaluationHandler := handlers.NewEvaluationHandler(repos.Evaluation)
+	messageHandler := handlers.NewMessageHandler(repos.Message)
+	taskChatHandler := handlers.NewTaskChatHandler(
+		repos.Message,
+		repos.Source,
+		repos.Evaluation,
+		cfg.PythonAPI.URL,
+	)
+	aiTaskHandler := handlers.NewAITaskHandler(repos.Source, cfg.PythonAPI.URL)
+	configHandler := handlers.NewConfigHandler(db)
+
+	// 注册路由
+	handlers.RegisterSourceRoutes(router, sourceHandler)
+	handlers.RegisterContentRoutes(router, contentHandler)
+	handlers.RegisterEvaluationRoutes(router, evaluationHandler)
+	handlers.RegisterMessageRoutes(router, messageHandler)
+	handlers.RegisterTaskChatRoutes(router, taskChatHandler)
+	handlers.RegisterAITaskRoutes(router, aiTaskHandler)
+	handlers.RegisterConfigRoutes(router, configHandler)
+
+	threadHandler := handlers.NewThreadHandler(repos.Thread, repos.Message)
+	handlers.RegisterThreadRoutes(router, threadHandler)
+
+	fetchRunHandler := handlers.NewFetchRunHandler(repos.FetchRun)
+	handlers.RegisterFetchRunRoutes(router, fetchRunHandler)
+
+	urlRuleHandler := handlers.NewURLRuleHandler(repos.URLRule, rssService)
+	handlers.RegisterURLRuleRoutes(router, urlRuleHandler)
+
+	filterRuleHandler := handlers.NewFilterRuleHandler(repos.FilterRule, repos.Source, rssService)
+	handlers.RegisterFilterRuleRoutes(router, filterRuleHandler)
+
+	sourceFolderHandler := handlers.NewSourceFolderHandler(repos.Folder, repos.Source)
+	handlers.RegisterSourceFolderRoutes(router, sourceFolderHandler)
+
+	sourceStatsHandler := handlers.NewSourceStatsHandler(repos.Stats, repos.Source)
+	handlers.RegisterSourceStatsRoutes(router, sourceStatsHandler)
+
+	dedupHandler := handlers.NewDedupHandler(rssService.Dedup())
+	handlers.RegisterDedupRoutes(router, dedupHandler)
+
+	if imageProxy != nil {
+		imageHandler := handlers.NewImageHandler(imageProxy)
+		handlers.RegisterImageRoutes(router, imageHandler)
+	}
+
+	if coordinator := factory.Coordinator(); coordinator != nil {
+		fetchLeaseHandler := handlers.NewFetchLeaseHandler(coordinator, repos.Source)
+		handlers.RegisterFetchLeaseRoutes(router, fetchLeaseHandler)
+	}
+
+	if webSubService := factory.WebSubService(); webSubService != nil {
+		webSubHandler := handlers.NewWebSubHandler(webSubService)
+		handlers.RegisterWebSubRoutes(router, webSubHandler)
+	}
+
+	notificationHandler := handlers.NewNotificationHandler(db, rdb, cfg.PythonAPI.URL)
+	handlers.RegisterNotificationRoutes(router, notificationHandler)
+
+	// RSS 代理配置路由
+	// 注意：只作用于处理该请求的进程（all 模式即抓取进程；拆分部署时请改 config.yaml 或 RSS_PROXY_URL）
+	router.GET("/api/config/rss-proxy", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"proxy_url": rssService.GetProxyURL(),
+		})
+	})
+	router.PUT("/api/config/rss-proxy", func(c *gin.Context) {
+		var req struct {
+			ProxyURL string `json:"proxy_url"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		rssService.SetProxyURL(req.ProxyURL)
+		cfg.Ingestion.ProxyURL = req.ProxyURL
+		log.Printf("RSS Proxy updated: %s", req.ProxyURL)
+		c.JSON(200, gin.H{"message": "RSS proxy updated", "proxy_url": req.ProxyURL})
+	})
+
+	// 内容搜索路由（需要注入 db 到 context）
+	router.GET("/api/search", func(c *gin.Context) {
+		c.Set("db", db)
+		handlers.SearchContent(c)
+	})
+
+	// 健康检查：Docker/K8s 探针或前端心跳检测用
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status": "ok",
+			"time":   time.Now(),
+		})
+	})
+
+	// 管理端点：清空 Redis Stream 并重置消费者组
+	//
+	// 使用场景：Consumer 崩溃后 pending list 堆积、或需要强制重置评估队列。
+	// 注意：这会删除 Stream 中所有消息（包括未处理的），Consumer 下次启动时会通过
+	// _requeue_pending_content() 重新将 PENDING 文章入队，不会丢失 DB 中的内容。
+	router.POST("/api/admin/purge-stream", func(c *gin.Context) {
+		ctx := context.Background()
+		streamName := "ingestion_queue"
+		groupName := "evaluators"
+
+		// 删除整个 stream（包含所有消息和消费者组状态）
+		deleted, err := rdb.Del(ctx, streamName).Result()
+		if err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("failed to delete stream: %v", err)})
+			return
+		}
+
+		// 重新创建消费者组（MKSTREAM 自动创建空 stream）
+		_, err = rdb.XGroupCreateMkStream(ctx, streamName, groupName, "0-0").Result()
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("failed to recreate consumer group: %v", err)})
+			return
+		}
+
+		log.Printf("[Admin] Purged stream '%s' (deleted=%d), recreated consumer group '%s'", streamName, deleted, groupName)
+		c.JSON(200, gin.H{
+			"message":         "Stream purged and consumer group reset",
+			"stream_deleted":  deleted > 0,
+			"group_recreated": true,
+		})
+	})
+
+	return router
+}
+
+// corsMiddleware CORS 中间件：白名单模式，只允许特定 Origin 跨域
+// 生产环境通过 CORS_ALLOWED_ORIGINS 环境变量配置
+func corsMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+
+		isAllowed := false
+		for _, allowed := range cfg.CORS.AllowedOrigins {
+			if allowed == "*" || allowed == origin {
+				isAllowed = true
+				break
+			}
+		}
+
+		if isAllowed {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.CORS.AllowedMethods, ", "))
+			c.Writer.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.CORS.AllowedHeaders, ", "))
+			c.Writer.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", cfg.CORS.MaxAge))
+			if cfg.CORS.AllowCredentials {
+				c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		// Preflight 请求（OPTIONS）直接返回 204，不进入后续 handler
+		if c.Request.Method == "OPTIONS" {
+			if isAllowed {
+				c.AbortWithStatus(204)
+			} else {
+				c.AbortWithStatus(403)
+			}
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requestBodyLogger Gin 中间件：记录 POST/PUT/PATCH 请求体（脱敏处理）
+//
+// 脱敏逻辑见 sanitizeLogBody()：隐藏 api_key、password 等敏感字段，
+// 截断长字符串，避免日志中泄露敏感信息或打印巨量内容。
+func requestBodyLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == "POST" || method == "PUT" || method == "PATCH" {
+			bodyBytes, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+				log.Printf("[REQ] %s %s | ip=%s | %s",
+					method, c.Request.URL.Path, c.ClientIP(),
+					sanitizeLogBody(bodyBytes))
+			}
+		}
+		c.Next()
+	}
+}
+
+// sanitizeLogBody 对请求体进行脱敏和截断，防止敏感信息入日志
+//
+// 处理策略：
+//   - 敏感字段（api_key、password、token 等）替换为 ***
+//   - 字符串值超过 100 字符截断
+//   - 数组值替换为 [N items]
+//   - 整体 JSON 超过 300 字符截断
+//
+// 注意：非 JSON 请求体直接返回长度信息，不做解析。
+func sanitizeLogBody(body []byte) string {
+	if len(body) == 0 {
+		return "(empty)"
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return fmt.Sprintf("(non-JSON %dB)", len(body))
+	}
+	sensitive := map[string]bool{
+		"api_key": true, "apikey": true, "password": true,
+		"token": true, "secret": true, "authorization": true,
+	}
+	for k, v := range m {
+		if sensitive[strings.ToLower(k)] {
+			m[k] = "***"
+			continue
+		}
+		switch val := v.(type) {
+		case string:
+			if len(val) > 100 {
+				m[k] = val[:100] + "…"
+			}
+		case []interface{}:
+			m[k] = fmt.Sprintf("[%d items]", len(val))
+		}
+	}
+	out, _ := json.Marshal(m)
+	if len(out) > 300 {
+		return string(out[:300]) + "…"
+	}
+	return string(out)
+}
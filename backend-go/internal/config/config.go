@@ -5,12 +5,17 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/junkfilter/backend-go/utils"
 )
 
-// Config 应用配置（统一管理）
+// Config 应用配置（统一管理，cmd/ 下各命令和根目录 main.go 共用）
+//
+// 三层加载优先级：硬编码默认值 → config.yaml 覆盖 → 环境变量最终覆盖
 type Config struct {
 	Database struct {
 		Host         string `yaml:"host"`
@@ -23,19 +28,87 @@ type Config struct {
 		MaxIdleConns int    `yaml:"max_idle_conns"`  // P0: 连接池优化
 	} `yaml:"database"`
 	Redis struct {
-		Host string `yaml:"host"`
-		Port int    `yaml:"port"`
-		DB   int    `yaml:"db"`
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		DB       int    `yaml:"db"`
+		Password string `yaml:"password"`
 	} `yaml:"redis"`
 	Server struct {
-		Port int `yaml:"port"`
+		Port            int    `yaml:"port"`
+		ShutdownTimeout string `yaml:"shutdown_timeout"` // 收到 SIGTERM 后等待抓取与请求收尾的最长时间
 	} `yaml:"server"`
+	PythonAPI struct {
+		URL string `yaml:"url"` // Python 后端 API URL
+	} `yaml:"python_api"`
+	CORS struct {
+		AllowedOrigins   []string `yaml:"allowed_origins"`
+		AllowedMethods   []string `yaml:"allowed_methods"`
+		AllowedHeaders   []string `yaml:"allowed_headers"`
+		AllowCredentials bool     `yaml:"allow_credentials"`
+		MaxAge           int      `yaml:"max_age"`
+	} `yaml:"cors"`
 	Ingestion struct {
-		WorkerCount   int    `yaml:"worker_count"`    // P0: 优化值 20
-		Timeout       string `yaml:"timeout"`         // P0: 优化值 30s
+		WorkerCount   int    `yaml:"worker_count"` // P0: 优化值 20
+		Timeout       string `yaml:"timeout"`      // P0: 优化值 30s
 		RetryMax      int    `yaml:"retry_max"`
+		MaxFailures   int    `yaml:"max_failures"`   // 连续失败 N 次后自动停用源，0 表示不停用
 		FetchInterval string `yaml:"fetch_interval"` // P0: 优化值 30m
+		ProxyURL      string `yaml:"proxy_url"`
+		UserAgent     string `yaml:"user_agent"` // 留空使用 utils.DefaultUserAgent
+		MaxFeedMB     int    `yaml:"max_feed_mb"` // 订阅源响应体上限，超过即抓取失败
+		// 单个主机的并发数与每秒请求数，所有 worker 共享；host_limits 按主机名覆盖（".example.com" 匹配子域名）
+		HostLimit  utils.HostLimit            `yaml:"host_limit"`
+		HostLimits map[string]utils.HostLimit `yaml:"host_limits"`
+		// WebSub 推送订阅：callback_base_url 为 hub 能访问到的 API 公网地址，留空则不订阅
+		WebSub struct {
+			CallbackBaseURL  string `yaml:"callback_base_url"`
+			LeaseSeconds     int    `yaml:"lease_seconds"`
+			FallbackInterval string `yaml:"fallback_interval"` // 已订阅源的兜底轮询间隔
+		} `yaml:"websub"`
+		// 已入库文章被作者修改后记录修订版本；变化显著（标题改变或改动比例达到 min_change_ratio）时可重新评估
+		Revisions struct {
+			Requeue        bool    `yaml:"requeue"`
+			MinChangeRatio float64 `yaml:"min_change_ratio"`
+		} `yaml:"revisions"`
+		// 跨 URL 的近似重复检测（SimHash）：与已入库文章的汉明距离不超过 max_distance 即视为转载，
+		// 链接到原文而不再送去评估
+		NearDuplicates struct {
+			Enabled     bool `yaml:"enabled"`
+			MaxDistance int  `yaml:"max_distance"`
+		} `yaml:"near_duplicates"`
+		// URL 去重的 Bloom 过滤器（L1）：按 capacity/fpr 计算位数与哈希函数个数，window 按 slot 分段滚动过期，
+		// 定期快照到 Redis 与 snapshot_path，重启时无需重建
+		Dedup struct {
+			BloomCapacity int     `yaml:"bloom_capacity"` // 一个窗口内预计的 URL 数
+			BloomFPR      float64 `yaml:"bloom_fpr"`
+			Window        string  `yaml:"window"`
+			Slot          string  `yaml:"slot"`
+			SnapshotPath  string  `yaml:"snapshot_path"` // 留空只快照到 Redis
+			// 升级前入库的 URL 去掉了整个查询串；开启时新 URL 去掉查询串后与这些旧记录相同且标题正文一致即视为重复
+			LegacyURLFallback bool `yaml:"legacy_url_fallback"`
+		} `yaml:"dedup"`
+		// 多副本部署：源按 id 分片，每个分片通过 Redis 租约只分给一个实例抓取
+		Coordination struct {
+			Enabled    bool   `yaml:"enabled"`
+			InstanceID string `yaml:"instance_id"` // 留空使用 主机名-pid
+			Shards     int    `yaml:"shards"`
+			LeaseTTL   string `yaml:"lease_ttl"` // 实例宕机后其分片最多经过这么久被接管
+		} `yaml:"coordination"`
 	} `yaml:"ingestion"`
+	// 图片代理：/api/images/:hash 经 RSS 代理抓取内容图片并缓存到本地磁盘，超出 max_cache_mb 按 LRU 淘汰
+	Images struct {
+		Enabled        bool   `yaml:"enabled"`
+		CacheDir       string `yaml:"cache_dir"`
+		MaxCacheMB     int    `yaml:"max_cache_mb"`
+		MaxImageMB     int    `yaml:"max_image_mb"`    // 超过该大小的图片不代理
+		ThumbnailWidth int    `yaml:"thumbnail_width"` // ?size=thumb 返回的缩略图宽度（像素）
+		// 图片下载单独的每主机并发数与每秒请求数，与抓取订阅源的 host_limit 互不占用
+		HostLimit utils.HostLimit `yaml:"host_limit"`
+	} `yaml:"images"`
+	// 加密存储源的凭据（HTTP 认证、Cookie、自定义请求头、代理密码）；留空时不能为源配置凭据
+	Security struct {
+		SecretKey string `yaml:"secret_key"` // 足够长的随机字符串，更换后已保存的凭据无法解密
+	} `yaml:"security"`
 }
 
 // Load 加载配置（YAML + 环境变量覆盖）
@@ -74,11 +147,46 @@ func (c *Config) setDefaults() {
 	c.Redis.DB = 0
 
 	c.Server.Port = 8080
+	c.Server.ShutdownTimeout = "30s"
+
+	c.PythonAPI.URL = "http://localhost:8083"
+
+	// CORS 默认值：仅允许本地前端，生产环境通过环境变量覆盖
+	c.CORS.AllowedOrigins = []string{"http://localhost:5173"}
+	c.CORS.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	c.CORS.AllowedHeaders = []string{"Content-Type", "Authorization"}
+	c.CORS.MaxAge = 3600
 
 	c.Ingestion.WorkerCount = 20       // P0: 优化值（从 5 改为 20）
 	c.Ingestion.Timeout = "30s"        // P0: 优化值（从 10s 改为 30s）
 	c.Ingestion.RetryMax = 3
+	c.Ingestion.MaxFailures = 10
+	c.Ingestion.MaxFeedMB = 20
 	c.Ingestion.FetchInterval = "30m"  // P0: 优化值（从 1h 改为 30m）
+	c.Ingestion.HostLimit = utils.DefaultHostLimit
+	c.Ingestion.HostLimits = map[string]utils.HostLimit{
+		// HN 的 Firebase API 每个源要逐条拉取几十个 item，需要更高的并发
+		"hacker-news.firebaseio.com": {Concurrency: 8, RequestsPerSecond: 20},
+	}
+	c.Ingestion.WebSub.FallbackInterval = "6h"
+	c.Ingestion.Revisions.MinChangeRatio = 0.2
+	c.Ingestion.NearDuplicates.Enabled = true
+	c.Ingestion.NearDuplicates.MaxDistance = 3
+	c.Ingestion.Dedup.BloomCapacity = 1000000
+	c.Ingestion.Dedup.BloomFPR = 0.001
+	c.Ingestion.Dedup.Window = "168h"
+	c.Ingestion.Dedup.Slot = "24h"
+	c.Ingestion.Dedup.SnapshotPath = "data/bloom.snapshot"
+	c.Ingestion.Dedup.LegacyURLFallback = true
+	c.Ingestion.Coordination.Shards = 16
+	c.Ingestion.Coordination.LeaseTTL = "30s"
+
+	c.Images.Enabled = true
+	c.Images.CacheDir = "data/images"
+	c.Images.MaxCacheMB = 1024
+	c.Images.MaxImageMB = 10
+	c.Images.ThumbnailWidth = 320
+	c.Images.HostLimit = utils.DefaultImageHostLimit
 }
 
 // applyEnvironmentOverrides 应用环境变量覆盖
@@ -101,6 +209,9 @@ func (c *Config) applyEnvironmentOverrides() {
 	if dbname := os.Getenv("DB_NAME"); dbname != "" {
 		c.Database.DBName = dbname
 	}
+	if sslMode := os.Getenv("DB_SSL_MODE"); sslMode != "" {
+		c.Database.SSLMode = sslMode
+	}
 
 	// Redis
 	if host := os.Getenv("REDIS_HOST"); host != "" {
@@ -111,6 +222,9 @@ func (c *Config) applyEnvironmentOverrides() {
 			c.Redis.Port = p
 		}
 	}
+	if pwd := os.Getenv("REDIS_PASSWORD"); pwd != "" {
+		c.Redis.Password = pwd
+	}
 
 	// Server
 	if port := os.Getenv("SERVER_PORT"); port != "" {
@@ -118,6 +232,9 @@ func (c *Config) applyEnvironmentOverrides() {
 			c.Server.Port = p
 		}
 	}
+	if pythonAPI := os.Getenv("PYTHON_API_URL"); pythonAPI != "" {
+		c.PythonAPI.URL = strings.TrimSpace(pythonAPI)
+	}
 
 	// Ingestion
 	if workers := os.Getenv("INGESTION_WORKERS"); workers != "" {
@@ -131,6 +248,37 @@ func (c *Config) applyEnvironmentOverrides() {
 	if interval := os.Getenv("INGESTION_FETCH_INTERVAL"); interval != "" {
 		c.Ingestion.FetchInterval = interval
 	}
+	if proxyURL := os.Getenv("RSS_PROXY_URL"); proxyURL != "" {
+		c.Ingestion.ProxyURL = proxyURL
+	}
+	if userAgent := os.Getenv("RSS_USER_AGENT"); userAgent != "" {
+		c.Ingestion.UserAgent = strings.TrimSpace(userAgent)
+	}
+	if callbackURL := os.Getenv("WEBSUB_CALLBACK_BASE_URL"); callbackURL != "" {
+		c.Ingestion.WebSub.CallbackBaseURL = strings.TrimSpace(callbackURL)
+	}
+	if enabled := os.Getenv("FETCH_COORDINATION_ENABLED"); enabled != "" {
+		c.Ingestion.Coordination.Enabled = enabled == "true" || enabled == "1"
+	}
+	if instanceID := os.Getenv("FETCH_INSTANCE_ID"); instanceID != "" {
+		c.Ingestion.Coordination.InstanceID = strings.TrimSpace(instanceID)
+	}
+
+	// Security
+	if secretKey := os.Getenv("JUNKFILTER_SECRET_KEY"); secretKey != "" {
+		c.Security.SecretKey = secretKey
+	}
+
+	// CORS
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		c.CORS.AllowedOrigins = strings.Split(origins, ",")
+	}
+	if methods := os.Getenv("CORS_ALLOWED_METHODS"); methods != "" {
+		c.CORS.AllowedMethods = strings.Split(methods, ",")
+	}
+	if headers := os.Getenv("CORS_ALLOWED_HEADERS"); headers != "" {
+		c.CORS.AllowedHeaders = strings.Split(headers, ",")
+	}
 }
 
 // GetDSN 获取数据库连接字符串
@@ -162,3 +310,30 @@ func (c *Config) GetFetchInterval() time.Duration {
 	}
 	return 30 * time.Minute
 }
+
+// GetShutdownTimeout 获取优雅关闭的最长等待时间
+func (c *Config) GetShutdownTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.Server.ShutdownTimeout); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// GetWebSubFallbackInterval 获取已订阅 WebSub 的源的兜底轮询间隔，0 表示使用 WebSubService 的默认值
+func (c *Config) GetWebSubFallbackInterval() time.Duration {
+	d, _ := time.ParseDuration(c.Ingestion.WebSub.FallbackInterval)
+	return d
+}
+
+// GetDedupWindow 获取 Bloom 过滤器的时间窗口与分段长度，0 表示使用默认值
+func (c *Config) GetDedupWindow() (window, slot time.Duration) {
+	window, _ = time.ParseDuration(c.Ingestion.Dedup.Window)
+	slot, _ = time.ParseDuration(c.Ingestion.Dedup.Slot)
+	return window, slot
+}
+
+// GetLeaseTTL 获取分片租约 TTL，0 表示使用 FetchCoordinator 的默认值
+func (c *Config) GetLeaseTTL() time.Duration {
+	d, _ := time.ParseDuration(c.Ingestion.Coordination.LeaseTTL)
+	return d
+}
@@ -71,8 +71,9 @@ type Redis struct {
 // NewRedis 创建并初始化 Redis 客户端
 func NewRedis(cfg *config.Config) (*Redis, error) {
 	client := redis.NewClient(&redis.Options{
-		Addr: cfg.GetRedisAddr(),
-		DB:   cfg.Redis.DB,
+		Addr:     cfg.GetRedisAddr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
 	})
 
 	// 测试连接
@@ -2,12 +2,14 @@ package service
 
 import (
 	"log"
+	"sync"
 
 	"github.com/junkfilter/backend-go/internal/config"
 	"github.com/junkfilter/backend-go/internal/domain"
 	"github.com/junkfilter/backend-go/internal/infra"
 	"github.com/junkfilter/backend-go/repositories"
 	"github.com/junkfilter/backend-go/services"
+	"github.com/junkfilter/backend-go/utils"
 )
 
 // ============================================================================
@@ -31,6 +33,34 @@ type Factory struct {
 	// 服务（缓存）
 	publisher      domain.StreamPublisher
 	deduplicator   domain.ContentDeduplicator
+
+	// 抓取与 HTTP API 共用的具体依赖（cmd/api、cmd/fetcher 通过它们组装进程）
+	repos       *Repositories
+	rssService  *services.RSSService
+	webSub      *services.WebSubService    // nil 表示未启用 WebSub
+	coordinator *services.FetchCoordinator // nil 表示单实例运行
+
+	// 图片代理只在 API 进程用到，首次使用时才打开磁盘缓存
+	imageProxyOnce sync.Once
+	imageProxy     *services.ImageProxy // nil 表示未启用
+}
+
+// Repositories 具体仓储集合
+// HTTP handler 和 services 包依赖具体类型，domain 接口只覆盖了抓取所需的一小部分
+type Repositories struct {
+	Source     *repositories.SourceRepository
+	Content    *repositories.ContentRepository
+	Evaluation *repositories.EvaluationRepository
+	Message    *repositories.MessageRepository
+	Thread     *repositories.ThreadRepository
+	FetchRun   *repositories.FetchRunRepository
+	WebSub     *repositories.WebSubRepository
+	Revision   *repositories.ContentRevisionRepository
+	URLRule    *repositories.URLRuleRepository
+	FilterRule *repositories.FilterRuleRepository
+	Image      *repositories.ProxiedImageRepository
+	Folder     *repositories.SourceFolderRepository
+	Stats      *repositories.SourceStatsRepository
 }
 
 // NewFactory 创建服务工厂
@@ -53,8 +83,24 @@ func NewFactory(cfg *config.Config) (*Factory, error) {
 	// Step 2: 初始化仓储
 	log.Println("\n========== Initializing Repositories ==========")
 
-	sourceRepo := repositories.NewSourceRepository(db.Conn())
-	contentRepo := repositories.NewContentRepository(db.Conn())
+	conn := db.Conn()
+	repos := &Repositories{
+		Source:     repositories.NewSourceRepository(conn),
+		Content:    repositories.NewContentRepository(conn),
+		Evaluation: repositories.NewEvaluationRepository(conn),
+		Message:    repositories.NewMessageRepository(conn),
+		Thread:     repositories.NewThreadRepository(conn),
+		FetchRun:   repositories.NewFetchRunRepository(conn),
+		WebSub:     repositories.NewWebSubRepository(conn),
+		Revision:   repositories.NewContentRevisionRepository(conn),
+		URLRule:    repositories.NewURLRuleRepository(conn),
+		FilterRule: repositories.NewFilterRuleRepository(conn),
+		Image:      repositories.NewProxiedImageRepository(conn),
+		Folder:     repositories.NewSourceFolderRepository(conn),
+		Stats:      repositories.NewSourceStatsRepository(conn),
+	}
+	sourceRepo := repos.Source
+	contentRepo := repos.Content
 
 	log.Println("✓ Repositories initialized")
 
@@ -68,15 +114,88 @@ func NewFactory(cfg *config.Config) (*Factory, error) {
 	// 因为 DedupService 期望具体的 *repositories.ContentRepository，而不是接口
 	deduplicator := services.NewDedupService(redis.Client(), contentRepo)
 
-	return &Factory{
-		db:             db,
-		redis:          redis,
-		cfg:            cfg,
-		sourceRepo:     sourceRepo,
-		contentRepo:    contentRepo,
-		publisher:      publisher,
-		deduplicator:   deduplicator,
-	}, nil
+	f := &Factory{
+		db:           db,
+		redis:        redis,
+		cfg:          cfg,
+		sourceRepo:   sourceRepo,
+		contentRepo:  contentRepo,
+		publisher:    publisher,
+		deduplicator: deduplicator,
+		repos:        repos,
+	}
+	f.initIngestion()
+
+	return f, nil
+}
+
+// initIngestion 组装完整的 RSS 抓取链路：RSSService 以及可选的 WebSub 与多副本协调
+// 这里只创建对象，不启动任何 goroutine；由调用方根据运行模式决定 Start 哪些组件
+func (f *Factory) initIngestion() {
+	cfg := f.cfg
+	rdb := f.redis.Client()
+
+	f.rssService = services.NewRSSService(
+		f.repos.Source,
+		f.repos.Content,
+		f.repos.FetchRun,
+		rdb,
+		services.NewContentService(rdb),
+		cfg.Ingestion.WorkerCount,
+		cfg.GetFetchTimeout(),
+		cfg.Ingestion.RetryMax,
+		cfg.Ingestion.MaxFailures,
+		cfg.Ingestion.ProxyURL,
+	)
+	f.rssService.ConfigureFetching(cfg.Ingestion.UserAgent, cfg.Ingestion.HostLimit, cfg.Ingestion.HostLimits)
+	f.rssService.SetMaxFeedBytes(int64(cfg.Ingestion.MaxFeedMB) << 20)
+	f.rssService.SetImageHostLimit(cfg.Images.HostLimit)
+	f.rssService.SetRevisionTracking(f.repos.Revision, services.RevisionPolicy{
+		Requeue:        cfg.Ingestion.Revisions.Requeue,
+		MinChangeRatio: cfg.Ingestion.Revisions.MinChangeRatio,
+	})
+	f.rssService.SetURLRules(f.repos.URLRule)
+	f.rssService.SetFilterRules(services.NewFilterEngine(f.repos.FilterRule), f.repos.FilterRule)
+	f.rssService.SetSourceStats(f.repos.Stats)
+	// 未配置密钥时，带凭据的源在保存时会被拒绝
+	if box, err := utils.NewSecretBox(cfg.Security.SecretKey); err == nil {
+		f.rssService.SetSecretBox(box)
+	}
+	window, slot := cfg.GetDedupWindow()
+	f.rssService.ConfigureDedup(services.BloomConfig{
+		Capacity:     cfg.Ingestion.Dedup.BloomCapacity,
+		FPR:          cfg.Ingestion.Dedup.BloomFPR,
+		Window:       window,
+		SlotDuration: slot,
+		SnapshotPath: cfg.Ingestion.Dedup.SnapshotPath,
+		Replicated:   cfg.Ingestion.Coordination.Enabled,
+	})
+	f.rssService.SetLegacyURLFallback(cfg.Ingestion.Dedup.LegacyURLFallback)
+	if cfg.Ingestion.NearDuplicates.Enabled {
+		f.rssService.SetNearDuplicates(services.NewNearDuplicateIndex(rdb, cfg.Ingestion.NearDuplicates.MaxDistance))
+	}
+
+	// WebSub：hub 推送新条目，已订阅的源只按 fallback_interval 兜底轮询
+	if cfg.Ingestion.WebSub.CallbackBaseURL != "" {
+		f.webSub = services.NewWebSubService(f.repos.WebSub, f.repos.Source, f.rssService, services.WebSubConfig{
+			CallbackBaseURL:  cfg.Ingestion.WebSub.CallbackBaseURL,
+			LeaseSeconds:     cfg.Ingestion.WebSub.LeaseSeconds,
+			FallbackInterval: cfg.GetWebSubFallbackInterval(),
+		})
+		f.rssService.SetWebSub(f.webSub)
+	}
+
+	// 多副本抓取协调：未启用时本实例抓取全部源
+	if cfg.Ingestion.Coordination.Enabled {
+		f.coordinator = services.NewFetchCoordinator(rdb, services.FetchCoordinatorConfig{
+			InstanceID: cfg.Ingestion.Coordination.InstanceID,
+			Shards:     cfg.Ingestion.Coordination.Shards,
+			LeaseTTL:   cfg.GetLeaseTTL(),
+		})
+		f.rssService.SetCoordinator(f.coordinator)
+	}
+
+	log.Println("✓ RSSService created")
 }
 
 // CreateRSSFetcher 创建 RSS 抓取器
@@ -160,3 +279,44 @@ func (f *Factory) Publisher() domain.StreamPublisher {
 func (f *Factory) Deduplicator() domain.ContentDeduplicator {
 	return f.deduplicator
 }
+
+// Repositories 返回具体仓储集合
+func (f *Factory) Repositories() *Repositories {
+	return f.repos
+}
+
+// RSSService 返回完整的 RSS 抓取服务（调度器、WebSub、多副本协调均已接好）
+func (f *Factory) RSSService() *services.RSSService {
+	return f.rssService
+}
+
+// WebSubService 返回 WebSub 服务，未配置 callback_base_url 时为 nil
+func (f *Factory) WebSubService() *services.WebSubService {
+	return f.webSub
+}
+
+// Coordinator 返回多副本抓取协调器，未启用时为 nil
+func (f *Factory) Coordinator() *services.FetchCoordinator {
+	return f.coordinator
+}
+
+// ImageProxy 返回图片代理，未启用或缓存目录不可用时返回 nil（内容接口保留原始图片地址）
+func (f *Factory) ImageProxy() *services.ImageProxy {
+	f.imageProxyOnce.Do(func() {
+		if !f.cfg.Images.Enabled {
+			return
+		}
+		proxy, err := services.NewImageProxy(f.repos.Image, f.rssService, services.ImageProxyConfig{
+			CacheDir:       f.cfg.Images.CacheDir,
+			MaxCacheBytes:  int64(f.cfg.Images.MaxCacheMB) << 20,
+			MaxImageBytes:  int64(f.cfg.Images.MaxImageMB) << 20,
+			ThumbnailWidth: f.cfg.Images.ThumbnailWidth,
+		})
+		if err != nil {
+			log.Printf("Warning: Image proxy disabled: %v", err)
+			return
+		}
+		f.imageProxy = proxy
+	})
+	return f.imageProxy
+}
@@ -72,7 +72,7 @@ func TestFactoryCreateRSSFetcher(t *testing.T) {
 	}
 
 	// 验证返回的是接口类型
-	if _, ok := interface{}(fetcher).(RSSFetcherImpl); !ok {
+	if _, ok := interface{}(fetcher).(*RSSFetcherImpl); !ok {
 		// 允许返回的是任何实现 domain.RSSFetcher 的类型
 	}
 
@@ -175,7 +175,7 @@ func (rf *RSSFetcherImpl) fetchSourceWithRetry(ctx context.Context, source *mode
 	var lastErr error
 
 	for attempt := 1; attempt <= rf.maxRetries; attempt++ {
-		items, err := rf.parser.ParseFeed(source.URL)
+		items, err := rf.parser.ParseFeed(fetchCtx, source.URL)
 		if err != nil {
 			lastErr = err
 			log.Printf("Attempt %d: Failed to fetch %s: %v", attempt, source.URL, err)
@@ -226,7 +226,10 @@ func (rf *RSSFetcherImpl) processItem(ctx context.Context, source *models.Source
 		OriginalURL:  item.URL,
 		ContentHash:  contentHash,
 		CleanContent: item.Content,
+		FullMarkdown: item.FullContent,
 		PublishedAt:  item.PublishedAt,
+		Language:     item.Language,
+		Enclosures:   item.Enclosures,
 	}
 
 	content, err := rf.contentRepo.Create(ctx, req)
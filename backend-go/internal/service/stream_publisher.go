@@ -28,17 +28,7 @@ func NewStreamPublisher(redis *redis.Client) domain.StreamPublisher {
 // PublishToStream 发布内容到 Redis Stream
 // 关键点：接口隔离，便于测试和替换
 func (sp *StreamPublisherImpl) PublishToStream(ctx context.Context, content *models.Content) error {
-	message := &models.StreamMessage{
-		ContentID:   content.ID,
-		TaskID:      content.TaskID.String(),
-		Title:       content.Title,
-		URL:         content.OriginalURL,
-		Content:     content.CleanContent,
-		PublishedAt: content.PublishedAt.Format("2006-01-02T15:04:05Z"),
-		Platform:    content.Platform,
-		AuthorName:  content.AuthorName,
-		ContentHash: content.ContentHash,
-	}
+	message := content.StreamMessage(models.StreamPayloadExcerpt)
 
 	// 序列化为 JSON
 	data, err := json.Marshal(message)
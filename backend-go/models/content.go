@@ -8,6 +8,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+
+	"github.com/junkfilter/backend-go/utils"
 )
 
 // StringArray is a custom type that supports both PostgreSQL text[] arrays and JSON arrays
@@ -58,6 +60,37 @@ func (a *StringArray) Scan(src interface{}) error {
 	return json.Unmarshal(data, a)
 }
 
+// Enclosures are the media files of an item (podcast audio, video), stored as a JSONB array
+type Enclosures []utils.Enclosure
+
+func (e Enclosures) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal([]utils.Enclosure(e))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (e *Enclosures) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		*e = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*[]utils.Enclosure)(e))
+}
+
 // Content represents an article/feed item
 type Content struct {
 	ID           int64
@@ -68,15 +101,64 @@ type Content struct {
 	Title        string
 	OriginalURL  string
 	ContentHash  string
-	CleanContent string
+	CleanContent string // the first utils.ContentExcerptRunes of the article
+	FullMarkdown string // the whole article when CleanContent is cut; only loaded by GetByID
 	ImageURLs    StringArray
 	PublishedAt  *time.Time
 	IngestedAt   time.Time
-	Status       string // PENDING, PROCESSING, EVALUATED, DISCARDED
+	Status       string // PENDING, PROCESSING, EVALUATED, DISCARDED, DUPLICATE
+	CanonicalID  *int64 // set for near-duplicates: the earlier item this one copies
+	Language     string // ISO 639-1 code detected at ingestion, "" when undetected
+	Enclosures   Enclosures
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
 
+// Body returns the whole article
+func (c *Content) Body() string {
+	if c.FullMarkdown != "" {
+		return c.FullMarkdown
+	}
+	return c.CleanContent
+}
+
+// StreamExcerptRunes bounds the text of excerpt stream messages
+const StreamExcerptRunes = 2500
+
+// StreamMessage builds the evaluation message of the content. With StreamPayloadReference it
+// carries no text and the evaluator reads the article from the database; otherwise the content
+// is a section-aware excerpt of at most StreamExcerptRunes runes, see utils.EvaluationExcerpt.
+func (c *Content) StreamMessage(payload string) *StreamMessage {
+	publishedAt := ""
+	if c.PublishedAt != nil {
+		publishedAt = c.PublishedAt.Format("2006-01-02T15:04:05Z")
+	}
+	body := c.Body()
+	message := &StreamMessage{
+		ContentID:   c.ID,
+		TaskID:      c.TaskID.String(),
+		Title:       c.Title,
+		URL:         c.OriginalURL,
+		PublishedAt: publishedAt,
+		Platform:    c.Platform,
+		AuthorName:  c.AuthorName,
+		ContentHash: c.ContentHash,
+		Language:    c.Language,
+		Enclosures:  c.Enclosures,
+		BodyRunes:   len([]rune(body)),
+	}
+	if payload == StreamPayloadReference {
+		message.FullBody = true
+	} else {
+		message.Content = utils.EvaluationExcerpt(body, StreamExcerptRunes)
+	}
+	return message
+}
+
+// ContentStatusDuplicate marks a near-duplicate of an earlier item; it is linked to that item
+// through CanonicalID and never sent for evaluation
+const ContentStatusDuplicate = "DUPLICATE"
+
 // CreateContentRequest is the request body for creating content
 type CreateContentRequest struct {
 	SourceID     int64       `json:"source_id" binding:"required"`
@@ -86,8 +168,14 @@ type CreateContentRequest struct {
 	OriginalURL  string      `json:"original_url" binding:"required"`
 	ContentHash  string      `json:"content_hash"`
 	CleanContent string      `json:"clean_content" binding:"required"`
+	FullMarkdown string      `json:"full_markdown"` // set when clean_content is an excerpt
 	ImageURLs    StringArray `json:"image_urls"`
 	PublishedAt  *time.Time  `json:"published_at"`
+	BodyHash     string      `json:"body_hash"`    // fingerprint of title + content, see utils.GenerateBodyHash
+	SimHash      *uint64     `json:"simhash"`      // nil when the text is too short to fingerprint
+	CanonicalID  *int64      `json:"canonical_id"` // stores the item as a near-duplicate of this one
+	Language     string      `json:"language"`
+	Enclosures   Enclosures  `json:"enclosures"`
 }
 
 // ContentResponse is the response body for content
@@ -101,20 +189,27 @@ type ContentResponse struct {
 	OriginalURL  string      `json:"original_url"`
 	ContentHash  string      `json:"content_hash"`
 	CleanContent string      `json:"clean_content"`
+	FullContent  string      `json:"full_content,omitempty"` // whole article when clean_content is an excerpt; single-item endpoints only
 	ImageURLs    StringArray `json:"image_urls"`
 	PublishedAt  *time.Time  `json:"published_at"`
 	IngestedAt   time.Time   `json:"ingested_at"`
 	Status       string      `json:"status"`
+	CanonicalID  *int64      `json:"canonical_id,omitempty"`
+	Language     string      `json:"language,omitempty"`
+	Enclosures   Enclosures  `json:"enclosures,omitempty"`
+	ThumbnailURL string      `json:"thumbnail_url,omitempty"` // proxied thumbnail of the first image, when images are proxied
 	CreatedAt    time.Time   `json:"created_at"`
 	UpdatedAt    time.Time   `json:"updated_at"`
 }
 
 // ContentFilter for querying content
 type ContentFilter struct {
-	Status   string `form:"status"`
-	SourceID int64  `form:"source_id"`
-	Limit    int    `form:"limit,default=50"`
-	Offset   int    `form:"offset,default=0"`
+	Status    string      `form:"status"`
+	SourceID  int64       `form:"source_id"`
+	Languages []string    // only content detected as one of these languages
+	Scope     SourceScope // only content from sources in this folder and/or with this tag
+	Limit     int         `form:"limit,default=50"`
+	Offset    int         `form:"offset,default=0"`
 }
 
 func (c *Content) ToResponse() *ContentResponse {
@@ -128,10 +223,14 @@ func (c *Content) ToResponse() *ContentResponse {
 		OriginalURL:  c.OriginalURL,
 		ContentHash:  c.ContentHash,
 		CleanContent: c.CleanContent,
+		FullContent:  c.FullMarkdown,
 		ImageURLs:    c.ImageURLs,
 		PublishedAt:  c.PublishedAt,
 		IngestedAt:   c.IngestedAt,
 		Status:       c.Status,
+		CanonicalID:  c.CanonicalID,
+		Language:     c.Language,
+		Enclosures:   c.Enclosures,
 		CreatedAt:    c.CreatedAt,
 		UpdatedAt:    c.UpdatedAt,
 	}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ContentRevision is one version of a feed item. Revision 1 is the item as first ingested;
+// later ones are recorded when the feed serves different text for the same URL.
+type ContentRevision struct {
+	ID           int64     `json:"id"`
+	ContentID    int64     `json:"content_id"`
+	Revision     int       `json:"revision"`
+	Title        string    `json:"title"`
+	CleanContent string    `json:"clean_content"`
+	FullMarkdown string    `json:"-"` // whole text of a new revision whose clean_content is cut; not kept per revision
+	BodyHash     string    `json:"body_hash"`
+	ChangeRatio  float64   `json:"change_ratio"` // share of text changed since the previous revision
+	Requeued     bool      `json:"requeued"`     // sent back for evaluation because the change was significant
+	DetectedAt   time.Time `json:"detected_at"`
+}
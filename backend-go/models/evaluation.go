@@ -77,15 +77,19 @@ func (e *Evaluation) ToResponse() *EvaluationResponse {
 
 // StreamMessage represents a message in Redis Stream
 type StreamMessage struct {
-	ContentID    int64  `json:"content_id"`
-	TaskID       string `json:"task_id"`
-	Title        string `json:"title"`
-	URL          string `json:"url"`
-	Content      string `json:"content"`
-	PublishedAt  string `json:"published_at"`
-	Platform     string `json:"platform"`
-	AuthorName   string `json:"author_name"`
-	ContentHash  string `json:"content_hash"`
+	ContentID    int64      `json:"content_id"`
+	TaskID       string     `json:"task_id"`
+	Title        string     `json:"title"`
+	URL          string     `json:"url"`
+	Content      string     `json:"content"`
+	PublishedAt  string     `json:"published_at"`
+	Platform     string     `json:"platform"`
+	AuthorName   string     `json:"author_name"`
+	ContentHash  string     `json:"content_hash"`
+	Language     string     `json:"language"`             // ISO 639-1 code, "" when undetected
+	Enclosures   Enclosures `json:"enclosures,omitempty"` // audio/video files; the content is then the show notes
+	FullBody     bool       `json:"full_body,omitempty"`  // content is empty: read the article from content.full_markdown
+	BodyRunes    int        `json:"body_runes,omitempty"` // length of the whole article
 }
 
 func (s *StreamMessage) MarshalBinary() ([]byte, error) {
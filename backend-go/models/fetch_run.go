@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Fetch run triggers
+const (
+	FetchTriggerScheduled = "scheduled"
+	FetchTriggerManual    = "manual"
+	FetchTriggerPush      = "push" // WebSub content distribution
+)
+
+// Fetch run outcomes
+const (
+	FetchStatusSuccess     = "success"
+	FetchStatusNotModified = "not_modified"
+	FetchStatusFailed      = "failed"
+)
+
+// FetchRun records what a single poll of a source did, for "why didn't this article show up" debugging
+type FetchRun struct {
+	ID                    int64      `json:"id"`
+	SourceID              int64      `json:"source_id"`
+	SourceName            string     `json:"source_name,omitempty"` // only filled by the global recent-runs query
+	Trigger               string     `json:"trigger"`               // 'scheduled', 'manual'
+	Status                string     `json:"status"`                // 'success', 'not_modified', 'failed'
+	StartedAt             time.Time  `json:"started_at"`
+	FinishedAt            *time.Time `json:"finished_at"`
+	DurationMs            int64      `json:"duration_ms"`
+	Attempts              int        `json:"attempts"`
+	HTTPStatus            int        `json:"http_status"`
+	Bytes                 int64      `json:"bytes"`
+	ItemsSeen             int        `json:"items_seen"`
+	ItemsNew              int        `json:"items_new"`
+	ItemsDuplicate        int        `json:"items_duplicate"`
+	ItemsRevised          int        `json:"items_revised"`        // already ingested, but the text changed
+	ItemsNearDuplicate    int        `json:"items_near_duplicate"` // stored as a copy of an earlier item, not evaluated
+	ItemsTooShort         int        `json:"items_too_short"`
+	ItemsAuthorFiltered   int        `json:"items_author_filtered"`
+	ItemsRuleFiltered     int        `json:"items_rule_filtered"`     // skipped by a filter rule
+	ItemsLanguageFiltered int        `json:"items_language_filtered"` // not in the source's allowed languages
+	ItemsErrored          int        `json:"items_errored"`
+	DistinctDuplicate     int        `json:"distinct_duplicate"` // duplicates the source listed for the first time
+	DistinctTooShort      int        `json:"distinct_too_short"` // too-short items listed for the first time
+	DistinctFiltered      int        `json:"distinct_filtered"`  // filtered items listed for the first time
+	Error                 *string    `json:"error,omitempty"`
+}
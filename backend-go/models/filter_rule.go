@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Filter rule fields, matched against a feed item before dedup
+const (
+	FilterFieldTitle    = "title"
+	FilterFieldContent  = "content"
+	FilterFieldAuthor   = "author"
+	FilterFieldURL      = "url"
+	FilterFieldCategory = "category" // matches if any of the item's categories does
+)
+
+// Filter rule operators
+const (
+	FilterOpContains  = "contains"   // case-insensitive substring, any of Values
+	FilterOpEquals    = "equals"     // case-insensitive equality, any of Values
+	FilterOpRegex     = "regex"      // RE2 pattern in Value; add (?i) for case-insensitivity
+	FilterOpMinLength = "min_length" // field has at least Value runes
+	FilterOpMaxLength = "max_length" // field has at most Value runes
+	FilterOpLanguage  = "language"   // detected language of title+content is any of Values (ISO 639-1)
+	FilterOpOlderThan = "older_than" // published longer ago than Value ("72h", "7d"); no field
+)
+
+// FilterCondition is a node of a rule's condition tree: either a group combining Conditions
+// with Match "all" (AND) or "any" (OR), or a leaf testing Field with Operator
+type FilterCondition struct {
+	Match      string            `json:"match,omitempty"` // "all" or "any" for groups
+	Conditions []FilterCondition `json:"conditions,omitempty"`
+	Field      string            `json:"field,omitempty"`
+	Operator   string            `json:"operator,omitempty"`
+	Value      string            `json:"value,omitempty"`
+	Values     []string          `json:"values,omitempty"`
+	Negate     bool              `json:"negate,omitempty"` // invert the result of this node
+}
+
+// FilterRule skips matching feed items before they are stored or sent for evaluation.
+// Rules without a source apply to every source.
+type FilterRule struct {
+	ID        int64           `json:"id"`
+	SourceID  *int64          `json:"source_id"` // nil for global rules
+	Name      string          `json:"name"`
+	Enabled   bool            `json:"enabled"`
+	Condition FilterCondition `json:"condition"`
+	HitCount  int64           `json:"hit_count"`
+	LastHitAt *time.Time      `json:"last_hit_at"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// SaveFilterRuleRequest creates or replaces a filter rule
+type SaveFilterRuleRequest struct {
+	SourceID  *int64          `json:"source_id"`
+	Name      string          `json:"name" binding:"required"`
+	Enabled   *bool           `json:"enabled"` // defaults to true
+	Condition json.RawMessage `json:"condition" binding:"required"`
+}
+
+// FilterSkip records a feed item skipped by a filter rule
+type FilterSkip struct {
+	ID        int64     `json:"id"`
+	RuleID    *int64    `json:"rule_id"` // nil once the rule is deleted
+	RuleName  string    `json:"rule_name"`
+	SourceID  int64     `json:"source_id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	SkippedAt time.Time `json:"skipped_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ProxiedImage maps the hash served by /api/images/:hash to the image it stands for
+type ProxiedImage struct {
+	Hash      string    `json:"hash"`
+	URL       string    `json:"url"`
+	PageURL   string    `json:"page_url"` // article the image appeared in; its site is sent as Referer
+	CreatedAt time.Time `json:"created_at"`
+}
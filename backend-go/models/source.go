@@ -6,6 +6,27 @@ import (
 	"time"
 )
 
+// Source platforms with a dedicated ingestion adapter; any other value (including "blog"
+// and "rss") is fetched as an RSS/Atom/JSON Feed
+const (
+	PlatformRSS        = "rss"
+	PlatformGitHub     = "github"
+	PlatformHackerNews = "hackernews"
+	PlatformReddit     = "reddit"
+	PlatformJSON       = "json"
+)
+
+// Stream payloads: what the evaluation message of an article carries
+const (
+	StreamPayloadExcerpt   = "excerpt"   // a section-aware excerpt of the article (the default)
+	StreamPayloadReference = "reference" // no text: the evaluator reads the full article from the database
+)
+
+// ValidStreamPayload reports whether p is a stream payload; "" selects the default
+func ValidStreamPayload(p string) bool {
+	return p == "" || p == StreamPayloadExcerpt || p == StreamPayloadReference
+}
+
 // AuthorFilter defines author-level filtering rules for a source
 type AuthorFilter struct {
 	Mode    string   `json:"mode"`    // "whitelist", "blacklist", or "" (no filter)
@@ -25,6 +46,19 @@ type Source struct {
 	Enabled              bool
 	FaviconURL           *string
 	AuthorFilterJSON     *string // raw JSONB from DB
+	ETag                 string  // ETag from the last 200 response, sent back as If-None-Match
+	LastModified         string  // Last-Modified from the last 200 response, sent back as If-Modified-Since
+	ConsecutiveFailures  int        // failed polls since the last success
+	LastError            *string    // error message of the most recent failed poll
+	NextRetryAt          *time.Time // backoff gate: the scheduler skips the source until then
+	AutoDisabledAt       *time.Time // set when the source was disabled for failing too often
+	FetchFullText        bool       // excerpt-only feed: download the article page and extract the body
+	AdapterConfigJSON    *string    // raw JSONB: platform-specific adapter settings
+	AllowedLanguages     []string   // ISO 639-1 codes to ingest; empty allows every language
+	StreamPayload        string     // StreamPayloadExcerpt or StreamPayloadReference, "" for the default
+	FolderID             *int64     // nil for sources outside any folder
+	Tags                 []string   // free-form labels, normalized by NormalizeTags
+	HTTPOptionsJSON      *string    // raw JSONB: per-source proxy, headers, cookies and auth, secrets sealed
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
 }
@@ -63,6 +97,40 @@ func (s *Source) ShouldFilterAuthor(author string) bool {
 	return found // filter out if IN blacklist
 }
 
+// AllowsLanguage reports whether the source's language policy lets an item in. Items whose
+// language couldn't be detected are always let in, so short or mixed texts aren't lost.
+func (s *Source) AllowsLanguage(language string) bool {
+	if len(s.AllowedLanguages) == 0 || language == "" {
+		return true
+	}
+	for _, allowed := range s.AllowedLanguages {
+		if allowed == language {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveStreamPayload returns the source's stream payload, StreamPayloadExcerpt when unset
+func (s *Source) EffectiveStreamPayload() string {
+	if s.StreamPayload == "" {
+		return StreamPayloadExcerpt
+	}
+	return s.StreamPayload
+}
+
+// Health summarizes fetch health for the UI:
+// "disabled" after auto-disable, "failing" while in backoff, otherwise "ok"
+func (s *Source) Health() string {
+	if s.AutoDisabledAt != nil && !s.Enabled {
+		return "disabled"
+	}
+	if s.ConsecutiveFailures > 0 {
+		return "failing"
+	}
+	return "ok"
+}
+
 // CreateSourceRequest is the request body for creating a source
 type CreateSourceRequest struct {
 	URL          string `json:"url" binding:"required"`
@@ -70,6 +138,17 @@ type CreateSourceRequest struct {
 	Priority     int    `json:"priority" binding:"min=1,max=10"`
 	FetchIntervalSeconds int `json:"fetch_interval_seconds"`
 	Platform     string `json:"platform"`
+	FaviconURL   string `json:"favicon_url"`
+	FetchFullText bool  `json:"fetch_full_text"`
+	AdapterConfig json.RawMessage `json:"adapter_config"` // platform-specific settings, see services/source_adapter.go
+	AllowedLanguages []string `json:"allowed_languages"` // e.g. ["zh", "en"]; empty ingests every language
+	StreamPayload string `json:"stream_payload"` // "excerpt" (default) or "reference"
+	FolderID *int64 `json:"folder_id"`
+	Tags []string `json:"tags"`
+	HTTPOptions *SourceHTTPOptions `json:"http_options"` // proxy, headers, cookies, auth; secrets are stored encrypted
+	// AutoDiscover (default true): when URL is a website rather than a feed, subscribe to the
+	// best discovered feed; when false, the candidates are returned for the user to choose
+	AutoDiscover *bool `json:"auto_discover"`
 }
 
 // UpdateSourceRequest is the request body for updating a source
@@ -78,6 +157,13 @@ type UpdateSourceRequest struct {
 	Priority     int    `json:"priority"`
 	Enabled      bool   `json:"enabled"`
 	FetchIntervalSeconds int `json:"fetch_interval_seconds"`
+	FetchFullText *bool `json:"fetch_full_text"` // nil leaves the setting unchanged
+	AdapterConfig json.RawMessage `json:"adapter_config"` // omitted leaves the config unchanged
+	AllowedLanguages *[]string `json:"allowed_languages"` // nil leaves the policy unchanged, [] removes it
+	StreamPayload *string `json:"stream_payload"` // nil leaves the setting unchanged
+	FolderID *int64 `json:"folder_id"` // nil leaves the folder unchanged, 0 takes the source out of its folder
+	Tags *[]string `json:"tags"` // nil leaves the tags unchanged
+	HTTPOptions *SourceHTTPOptions `json:"http_options"` // nil leaves the options unchanged, {} removes them; masked secrets are kept
 }
 
 // SourceResponse is the response body for a source
@@ -92,6 +178,18 @@ type SourceResponse struct {
 	Enabled              bool          `json:"enabled"`
 	FaviconURL           *string       `json:"favicon_url"`
 	AuthorFilter         *AuthorFilter `json:"author_filter,omitempty"`
+	Health               string        `json:"health"` // "ok", "failing" or "disabled"
+	ConsecutiveFailures  int           `json:"consecutive_failures"`
+	LastError            *string       `json:"last_error"`
+	NextRetryAt          *time.Time    `json:"next_retry_at"`
+	AutoDisabledAt       *time.Time    `json:"auto_disabled_at"`
+	FetchFullText        bool          `json:"fetch_full_text"`
+	AdapterConfig        json.RawMessage `json:"adapter_config,omitempty"`
+	AllowedLanguages     []string      `json:"allowed_languages"`
+	StreamPayload        string        `json:"stream_payload"`
+	FolderID             *int64        `json:"folder_id"`
+	Tags                 []string      `json:"tags"`
+	HTTPOptions          *SourceHTTPOptions `json:"http_options,omitempty"` // secrets masked
 	CreatedAt            time.Time     `json:"created_at"`
 	UpdatedAt            time.Time     `json:"updated_at"`
 }
@@ -107,9 +205,23 @@ func (s *Source) ToResponse() *SourceResponse {
 		FetchIntervalSeconds: s.FetchIntervalSeconds,
 		Enabled:              s.Enabled,
 		FaviconURL:           s.FaviconURL,
+		Health:               s.Health(),
+		ConsecutiveFailures:  s.ConsecutiveFailures,
+		LastError:            s.LastError,
+		NextRetryAt:          s.NextRetryAt,
+		AutoDisabledAt:       s.AutoDisabledAt,
+		FetchFullText:        s.FetchFullText,
+		AllowedLanguages:     s.AllowedLanguages,
+		StreamPayload:        s.StreamPayload,
+		FolderID:             s.FolderID,
+		Tags:                 s.Tags,
 		CreatedAt:            s.CreatedAt,
 		UpdatedAt:            s.UpdatedAt,
 	}
+	if s.AdapterConfigJSON != nil && *s.AdapterConfigJSON != "" {
+		resp.AdapterConfig = json.RawMessage(*s.AdapterConfigJSON)
+	}
+	resp.HTTPOptions = s.GetHTTPOptions().Masked()
 	af := s.GetAuthorFilter()
 	if af.Mode != "" {
 		resp.AuthorFilter = &af
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTagLength bounds a source tag, in runes
+const maxTagLength = 50
+
+// SourceFolder groups sources; folders nest through ParentID
+type SourceFolder struct {
+	ID          int64     `json:"id"`
+	ParentID    *int64    `json:"parent_id"` // nil for top-level folders
+	Name        string    `json:"name"`
+	Position    int       `json:"position"`     // sort order among siblings
+	Path        string    `json:"path"`         // names from the top-level folder down, e.g. "Tech / Go"
+	SourceCount int       `json:"source_count"` // sources directly in the folder
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// OrderFolderTree sorts folders depth-first, siblings by position then name, and fills in their
+// Path. Folders whose parent is missing from the list are treated as top-level.
+func OrderFolderTree(folders []SourceFolder) []SourceFolder {
+	byID := make(map[int64]bool, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = true
+	}
+	children := make(map[int64][]SourceFolder)
+	for _, f := range folders {
+		var parent int64
+		if f.ParentID != nil && byID[*f.ParentID] {
+			parent = *f.ParentID
+		}
+		children[parent] = append(children[parent], f)
+	}
+
+	ordered := make([]SourceFolder, 0, len(folders))
+	var walk func(parent int64, path string)
+	walk = func(parent int64, path string) {
+		siblings := children[parent]
+		sort.SliceStable(siblings, func(i, j int) bool {
+			if siblings[i].Position != siblings[j].Position {
+				return siblings[i].Position < siblings[j].Position
+			}
+			return strings.ToLower(siblings[i].Name) < strings.ToLower(siblings[j].Name)
+		})
+		for _, f := range siblings {
+			f.Path = f.Name
+			if path != "" {
+				f.Path = path + " / " + f.Name
+			}
+			ordered = append(ordered, f)
+			walk(f.ID, f.Path)
+		}
+	}
+	walk(0, "")
+	return ordered
+}
+
+// SourceFolderRequest is the request body for creating or replacing a folder
+type SourceFolderRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *int64 `json:"parent_id"` // nil or omitted makes a top-level folder
+	Position int    `json:"position"`
+}
+
+// SourceTag is a tag in use and how many sources carry it
+type SourceTag struct {
+	Tag         string `json:"tag"`
+	SourceCount int    `json:"source_count"`
+}
+
+// BulkSourceRequest assigns many sources at once
+type BulkSourceRequest struct {
+	SourceIDs  []int64  `json:"source_ids" binding:"required,min=1"`
+	FolderID   *int64   `json:"folder_id"` // nil leaves folders unchanged, 0 takes the sources out of their folder
+	AddTags    []string `json:"add_tags"`
+	RemoveTags []string `json:"remove_tags"`
+}
+
+// SourceScope narrows sources to a folder (its subfolders included) and/or a tag; the zero
+// value selects every source
+type SourceScope struct {
+	FolderID int64
+	Tag      string
+}
+
+// IsZero reports whether the scope selects every source
+func (s SourceScope) IsZero() bool {
+	return s.FolderID == 0 && s.Tag == ""
+}
+
+// NormalizeTag trims a tag, collapses its inner whitespace and lowercases it
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if tag == "" {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	if len([]rune(tag)) > maxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes tags with NormalizeTag and drops duplicates, keeping the first
+// occurrence's position
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		tag, err := NormalizeTag(t)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
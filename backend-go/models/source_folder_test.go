@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestOrderFolderTree(t *testing.T) {
+	id := func(v int64) *int64 { return &v }
+	folders := OrderFolderTree([]SourceFolder{
+		{ID: 4, ParentID: id(1), Name: "rust", Position: 1},
+		{ID: 1, Name: "Tech"},
+		{ID: 2, Name: "News"},
+		{ID: 3, ParentID: id(1), Name: "Go"},
+		{ID: 5, ParentID: id(99), Name: "Orphan"},
+	})
+
+	want := []string{"News", "Orphan", "Tech", "Tech / Go", "Tech / rust"}
+	if len(folders) != len(want) {
+		t.Fatalf("got %d folders: %+v", len(folders), folders)
+	}
+	for i, f := range folders {
+		if f.Path != want[i] {
+			t.Errorf("folder %d path = %q, want %q", i, f.Path, want[i])
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"  Machine   Learning ", "go", "GO", "machine learning"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0] != "machine learning" || tags[1] != "go" {
+		t.Errorf("got %q", tags)
+	}
+
+	if _, err := NormalizeTags([]string{"ok", "   "}); err == nil {
+		t.Error("blank tag accepted")
+	}
+}
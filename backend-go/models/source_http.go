@@ -0,0 +1,263 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// MaskedSecret stands in for stored credentials in responses. Sending it back unchanged in an
+// update keeps the stored value.
+const MaskedSecret = "********"
+
+// Source HTTP auth types
+const (
+	HTTPAuthBasic  = "basic"
+	HTTPAuthBearer = "bearer"
+)
+
+// SourceHTTPOptions are a source's own request settings, for private feeds (Patreon, paid
+// newsletters, internal GitLab) and geo-blocked sites. In sources.http_options every secret is
+// sealed by a utils.SecretBox; in responses it is MaskedSecret.
+type SourceHTTPOptions struct {
+	ProxyURL           string            `json:"proxy_url,omitempty"`      // http://, https:// or socks5://; a password in it moves to ProxyPassword
+	ProxyPassword      string            `json:"proxy_password,omitempty"` // secret
+	Headers            map[string]string `json:"headers,omitempty"`        // values are secret
+	Cookies            map[string]string `json:"cookies,omitempty"`        // values are secret
+	AuthType           string            `json:"auth_type,omitempty"`      // "basic", "bearer" or "" for none
+	Username           string            `json:"username,omitempty"`       // basic auth
+	Password           string            `json:"password,omitempty"`       // secret, basic auth
+	Token              string            `json:"token,omitempty"`          // secret, bearer auth
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+}
+
+// headersManagedByClient can't be set as custom headers
+var headersManagedByClient = map[string]bool{
+	"Host": true, "Content-Length": true, "Transfer-Encoding": true, "Connection": true,
+	"Authorization": true, "Cookie": true, "Proxy-Authorization": true,
+}
+
+// IsZero reports whether the options change nothing
+func (o *SourceHTTPOptions) IsZero() bool {
+	return o == nil || (o.ProxyURL == "" && o.ProxyPassword == "" && len(o.Headers) == 0 && len(o.Cookies) == 0 &&
+		o.AuthType == "" && !o.InsecureSkipVerify)
+}
+
+// Normalize validates the options as sent by a client. Authorization and cookies have their
+// own fields, so they can't be smuggled in as custom headers.
+func (o *SourceHTTPOptions) Normalize() error {
+	o.ProxyURL = strings.TrimSpace(o.ProxyURL)
+	if o.ProxyURL != "" {
+		proxy, err := url.Parse(o.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return errors.New("proxy_url must be an absolute URL")
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return errors.New("proxy_url must use http, https or socks5")
+		}
+		if password, ok := proxy.User.Password(); ok {
+			o.ProxyPassword = password
+			proxy.User = url.User(proxy.User.Username())
+		}
+		o.ProxyURL = proxy.String()
+	} else if o.ProxyPassword != "" {
+		return errors.New("proxy_password needs a proxy_url")
+	}
+
+	headers := make(map[string]string, len(o.Headers))
+	for name, value := range o.Headers {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if !validHTTPToken(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if headersManagedByClient[name] {
+			return fmt.Errorf("header %s can't be set; use auth_type or cookies instead", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s has a line break in its value", name)
+		}
+		headers[name] = value
+	}
+	o.Headers = headers
+
+	for name, value := range o.Cookies {
+		if !validHTTPToken(name) {
+			return fmt.Errorf("invalid cookie name %q", name)
+		}
+		if strings.ContainsAny(value, "\";\\ \t\r\n,") {
+			return fmt.Errorf("cookie %s has an invalid character in its value", name)
+		}
+	}
+
+	o.AuthType = strings.ToLower(strings.TrimSpace(o.AuthType))
+	switch o.AuthType {
+	case HTTPAuthBasic:
+		if o.Username == "" {
+			return errors.New("basic auth needs a username")
+		}
+		o.Token = ""
+	case HTTPAuthBearer:
+		if o.Token == "" {
+			return errors.New("bearer auth needs a token")
+		}
+		o.Username, o.Password = "", ""
+	case "":
+		if o.Username != "" || o.Password != "" || o.Token != "" {
+			return errors.New("auth_type must be \"basic\" or \"bearer\" to use credentials")
+		}
+	default:
+		return errors.New("auth_type must be \"basic\" or \"bearer\"")
+	}
+	return nil
+}
+
+// validHTTPToken reports whether s is a non-empty RFC 7230 token, as header and cookie names are
+func validHTTPToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// eachSecret replaces every non-empty secret by fn's result. key identifies the secret's place,
+// e.g. "header:X-Api-Key".
+func (o *SourceHTTPOptions) eachSecret(fn func(key, value string) (string, error)) error {
+	replace := func(key string, value *string) error {
+		if *value == "" {
+			return nil
+		}
+		v, err := fn(key, *value)
+		*value = v
+		return err
+	}
+	if err := replace("proxy_password", &o.ProxyPassword); err != nil {
+		return err
+	}
+	if err := replace("password", &o.Password); err != nil {
+		return err
+	}
+	if err := replace("token", &o.Token); err != nil {
+		return err
+	}
+	for _, m := range []struct {
+		prefix string
+		values map[string]string
+	}{{"header:", o.Headers}, {"cookie:", o.Cookies}} {
+		names := make([]string, 0, len(m.values))
+		for name := range m.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := m.values[name]
+			if err := replace(m.prefix+name, &value); err != nil {
+				return err
+			}
+			m.values[name] = value
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy of o
+func (o *SourceHTTPOptions) clone() *SourceHTTPOptions {
+	c := *o
+	c.Headers = make(map[string]string, len(o.Headers))
+	for name, value := range o.Headers {
+		c.Headers[name] = value
+	}
+	c.Cookies = make(map[string]string, len(o.Cookies))
+	for name, value := range o.Cookies {
+		c.Cookies[name] = value
+	}
+	return &c
+}
+
+// Masked returns a copy of o with every secret replaced by MaskedSecret, nil for nil options
+func (o *SourceHTTPOptions) Masked() *SourceHTTPOptions {
+	if o == nil {
+		return nil
+	}
+	masked := o.clone()
+	masked.eachSecret(func(string, string) (string, error) { return MaskedSecret, nil })
+	return masked
+}
+
+// SealSecrets encrypts the secrets of normalized options with seal. A secret sent back as
+// MaskedSecret keeps its sealed value from prev, the options stored so far.
+func (o *SourceHTTPOptions) SealSecrets(prev *SourceHTTPOptions, seal func(string) (string, error)) error {
+	stored := map[string]string{}
+	if prev != nil {
+		prev.clone().eachSecret(func(key, value string) (string, error) {
+			stored[key] = value
+			return value, nil
+		})
+	}
+	return o.eachSecret(func(key, value string) (string, error) {
+		if value != MaskedSecret {
+			return seal(value)
+		}
+		if sealed, ok := stored[key]; ok {
+			return sealed, nil
+		}
+		return "", fmt.Errorf("%s is masked but no value is stored for it", strings.Replace(key, ":", " ", 1))
+	})
+}
+
+// Open decrypts the sealed secrets with open and builds the request settings of the source
+// at sourceURL
+func (o *SourceHTTPOptions) Open(open func(string) (string, error), sourceURL string) (*utils.HTTPOptions, error) {
+	plain := o.clone()
+	if err := plain.eachSecret(func(_, value string) (string, error) { return open(value) }); err != nil {
+		return nil, err
+	}
+
+	opts := &utils.HTTPOptions{
+		InsecureSkipVerify: plain.InsecureSkipVerify,
+		CredentialHost:     utils.CredentialHostOf(sourceURL),
+		Headers:            plain.Headers,
+		Cookies:            plain.Cookies,
+	}
+	if plain.ProxyURL != "" {
+		proxy, err := url.Parse(plain.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy_url: %w", err)
+		}
+		if plain.ProxyPassword != "" {
+			proxy.User = url.UserPassword(proxy.User.Username(), plain.ProxyPassword)
+		}
+		opts.ProxyURL = proxy
+	}
+	switch plain.AuthType {
+	case HTTPAuthBasic:
+		opts.Username, opts.Password = plain.Username, plain.Password
+	case HTTPAuthBearer:
+		opts.BearerToken = plain.Token
+	}
+	return opts, nil
+}
+
+// GetHTTPOptions parses the http_options JSONB field, nil when the source has none
+func (s *Source) GetHTTPOptions() *SourceHTTPOptions {
+	if s.HTTPOptionsJSON == nil || *s.HTTPOptionsJSON == "" {
+		return nil
+	}
+	var opts SourceHTTPOptions
+	if err := json.Unmarshal([]byte(*s.HTTPOptionsJSON), &opts); err != nil || opts.IsZero() {
+		return nil
+	}
+	return &opts
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSourceHTTPOptionsNormalize(t *testing.T) {
+	opts := &SourceHTTPOptions{
+		ProxyURL: " socks5://user:pw@10.0.0.1:1080 ",
+		Headers:  map[string]string{"private-token": "glpat"},
+		AuthType: "Basic",
+		Username: "me",
+		Password: "secret",
+		Token:    "stale",
+	}
+	if err := opts.Normalize(); err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+	if opts.ProxyURL != "socks5://user@10.0.0.1:1080" || opts.ProxyPassword != "pw" {
+		t.Errorf("proxy = %q / %q, want the password moved out of the URL", opts.ProxyURL, opts.ProxyPassword)
+	}
+	if opts.Headers["Private-Token"] != "glpat" || opts.AuthType != HTTPAuthBasic || opts.Token != "" {
+		t.Errorf("Normalize() = %+v", opts)
+	}
+
+	invalid := []*SourceHTTPOptions{
+		{ProxyURL: "ftp://proxy:21"},
+		{ProxyURL: "not a url"},
+		{ProxyPassword: "pw"},
+		{Headers: map[string]string{"Authorization": "Bearer x"}},
+		{Headers: map[string]string{"Bad Name": "x"}},
+		{Headers: map[string]string{"X-Split": "a\r\nInjected: b"}},
+		{Cookies: map[string]string{"session": "a; b"}},
+		{AuthType: HTTPAuthBearer},
+		{AuthType: "digest", Username: "me"},
+		{Username: "me", Password: "pw"},
+	}
+	for _, o := range invalid {
+		if err := o.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) succeeded, want an error", o)
+		}
+	}
+}
+
+func TestSourceHTTPOptionsSecrets(t *testing.T) {
+	seal := func(s string) (string, error) { return "sealed(" + s + ")", nil }
+	open := func(s string) (string, error) {
+		return strings.TrimSuffix(strings.TrimPrefix(s, "sealed("), ")"), nil
+	}
+
+	stored := &SourceHTTPOptions{
+		ProxyURL:      "http://user@proxy:3128",
+		ProxyPassword: "pw",
+		Headers:       map[string]string{"X-Api-Key": "k1"},
+		Cookies:       map[string]string{"session": "s1"},
+		AuthType:      HTTPAuthBearer,
+		Token:         "t1",
+	}
+	if err := stored.SealSecrets(nil, seal); err != nil {
+		t.Fatalf("SealSecrets() error = %v", err)
+	}
+	if stored.Token != "sealed(t1)" || stored.Headers["X-Api-Key"] != "sealed(k1)" || stored.ProxyURL != "http://user@proxy:3128" {
+		t.Fatalf("SealSecrets() = %+v", stored)
+	}
+
+	masked := stored.Masked()
+	if masked.Token != MaskedSecret || masked.ProxyPassword != MaskedSecret || masked.Cookies["session"] != MaskedSecret {
+		t.Errorf("Masked() = %+v", masked)
+	}
+	if stored.Token != "sealed(t1)" {
+		t.Error("Masked() modified the options it masks")
+	}
+
+	// The client sends the masked options back with one header changed and a cookie added
+	update := stored.Masked()
+	update.Headers["X-Api-Key"] = "k2"
+	update.Cookies["lang"] = "en"
+	if err := update.SealSecrets(stored, seal); err != nil {
+		t.Fatalf("SealSecrets(update) error = %v", err)
+	}
+	if update.Token != "sealed(t1)" || update.ProxyPassword != "sealed(pw)" || update.Headers["X-Api-Key"] != "sealed(k2)" ||
+		update.Cookies["lang"] != "sealed(en)" {
+		t.Errorf("SealSecrets(update) = %+v", update)
+	}
+
+	unknown := &SourceHTTPOptions{AuthType: HTTPAuthBasic, Username: "me", Password: MaskedSecret}
+	if err := unknown.SealSecrets(stored, seal); err == nil {
+		t.Error("SealSecrets() kept a masked secret that was never stored")
+	}
+
+	opts, err := update.Open(open, "https://www.example.com/feed.xml")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if opts.ProxyURL.String() != "http://user:pw@proxy:3128" || opts.BearerToken != "t1" || opts.Headers["X-Api-Key"] != "k2" ||
+		opts.CredentialHost != "example.com" {
+		t.Errorf("Open() = %+v", opts)
+	}
+}
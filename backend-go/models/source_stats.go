@@ -0,0 +1,158 @@
+package models
+
+import "time"
+
+// An article is a high scorer when both of its scores reach these thresholds, the defaults of the
+// notification settings
+const (
+	HighScoreMinInnovation = 8
+	HighScoreMinDepth      = 7
+)
+
+// Leaderboard sort keys
+const (
+	LeaderboardByPassRate   = "pass_rate"
+	LeaderboardByAvgScore   = "avg_score"
+	LeaderboardByHighScores = "high_scores"
+	LeaderboardByVolume     = "volume"
+)
+
+// ValidLeaderboardSort reports whether sort is a leaderboard sort key
+func ValidLeaderboardSort(sort string) bool {
+	switch sort {
+	case LeaderboardByPassRate, LeaderboardByAvgScore, LeaderboardByHighScores, LeaderboardByVolume:
+		return true
+	}
+	return false
+}
+
+// SourceStatsCounts are the additive counters of the daily rollups
+type SourceStatsCounts struct {
+	Ingested      int   `json:"ingested"`    // stored items, near-duplicates excluded
+	Evaluated     int   `json:"evaluated"`   // ingested items with an evaluation
+	Interesting   int   `json:"interesting"` // evaluations by decision
+	Bookmarked    int   `json:"bookmarked"`
+	Skipped       int   `json:"skipped"`
+	HighScores    int   `json:"high_scores"` // see HighScoreMinInnovation
+	Duplicates    int   `json:"duplicates"`  // distinct items already stored from elsewhere, and near-duplicates
+	TooShort      int   `json:"too_short"`   // distinct items, however many polls listed them
+	Filtered      int   `json:"filtered"`    // distinct items dropped by author, language or filter rules
+	InnovationSum int64 `json:"-"`
+	DepthSum      int64 `json:"-"`
+}
+
+// Add adds other's counters to c
+func (c *SourceStatsCounts) Add(other SourceStatsCounts) {
+	c.Ingested += other.Ingested
+	c.Evaluated += other.Evaluated
+	c.Interesting += other.Interesting
+	c.Bookmarked += other.Bookmarked
+	c.Skipped += other.Skipped
+	c.HighScores += other.HighScores
+	c.Duplicates += other.Duplicates
+	c.TooShort += other.TooShort
+	c.Filtered += other.Filtered
+	c.InnovationSum += other.InnovationSum
+	c.DepthSum += other.DepthSum
+}
+
+// SourceStatsSummary is a set of counters with the rates derived from them. The rates are nil
+// when nothing was evaluated.
+type SourceStatsSummary struct {
+	SourceStatsCounts
+	PassRate      *float64 `json:"pass_rate"` // share of evaluations not decided SKIP
+	AvgInnovation *float64 `json:"avg_innovation"`
+	AvgDepth      *float64 `json:"avg_depth"`
+}
+
+// Summarize derives the rates of c
+func (c SourceStatsCounts) Summarize() SourceStatsSummary {
+	summary := SourceStatsSummary{SourceStatsCounts: c}
+	if c.Evaluated > 0 {
+		n := float64(c.Evaluated)
+		passRate := float64(c.Evaluated-c.Skipped) / n
+		avgInnovation := float64(c.InnovationSum) / n
+		avgDepth := float64(c.DepthSum) / n
+		summary.PassRate = &passRate
+		summary.AvgInnovation = &avgInnovation
+		summary.AvgDepth = &avgDepth
+	}
+	return summary
+}
+
+// SourceDailyStats is one row of source_daily_stats
+type SourceDailyStats struct {
+	SourceID int64
+	Day      time.Time
+	SourceStatsCounts
+	LastHighScoreAt *time.Time
+	UpdatedAt       time.Time
+}
+
+// SourceStatsPoint is one day of a source's trend
+type SourceStatsPoint struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	SourceStatsSummary
+}
+
+// SourceStats is the response of GET /api/sources/:id/stats
+type SourceStats struct {
+	SourceID            int64              `json:"source_id"`
+	Days                int                `json:"days"`
+	Totals              SourceStatsSummary `json:"totals"` // over the last Days days
+	LastHighScoreAt     *time.Time         `json:"last_high_score_at"`
+	HoursSinceHighScore *float64           `json:"hours_since_high_score"` // nil when the source never had one
+	Trend               []SourceStatsPoint `json:"trend"`                  // one point per day, oldest first
+	UpdatedAt           *time.Time         `json:"updated_at"`             // when the newest rollup was computed
+}
+
+// BuildSourceStats totals the daily rollups of the last days days (today included) and lays
+// them out as a trend with a point for every day. lastHighScoreAt is the source's latest high
+// scorer of all time.
+func BuildSourceStats(sourceID int64, days int, now time.Time, daily []SourceDailyStats, lastHighScoreAt *time.Time) *SourceStats {
+	stats := &SourceStats{SourceID: sourceID, Days: days, LastHighScoreAt: lastHighScoreAt, Trend: make([]SourceStatsPoint, days)}
+
+	byDate := make(map[string]SourceStatsCounts, len(daily))
+	for _, d := range daily {
+		byDate[d.Day.Format("2006-01-02")] = d.SourceStatsCounts
+		if stats.UpdatedAt == nil || d.UpdatedAt.After(*stats.UpdatedAt) {
+			updatedAt := d.UpdatedAt
+			stats.UpdatedAt = &updatedAt
+		}
+	}
+
+	var totals SourceStatsCounts
+	for i := 0; i < days; i++ {
+		date := now.AddDate(0, 0, i-days+1).Format("2006-01-02")
+		counts := byDate[date]
+		totals.Add(counts)
+		stats.Trend[i] = SourceStatsPoint{Date: date, SourceStatsSummary: counts.Summarize()}
+	}
+	stats.Totals = totals.Summarize()
+
+	if lastHighScoreAt != nil {
+		hours := now.Sub(*lastHighScoreAt).Hours()
+		stats.HoursSinceHighScore = &hours
+	}
+	return stats
+}
+
+// SourceLeaderboardEntry is one source of the leaderboard
+type SourceLeaderboardEntry struct {
+	SourceID        int64      `json:"source_id"`
+	Name            string     `json:"name"`
+	URL             string     `json:"url"`
+	Enabled         bool       `json:"enabled"`
+	LastHighScoreAt *time.Time `json:"last_high_score_at"` // of all time
+	SourceStatsSummary
+}
+
+// LeaderboardQuery selects and orders the leaderboard
+type LeaderboardQuery struct {
+	Since        time.Time // first day counted
+	Sort         string    // one of the LeaderboardBy keys
+	Ascending    bool      // worst sources first
+	MinEvaluated int       // sources with fewer evaluations in the period are left out
+	Limit        int
+	Scope        SourceScope
+}
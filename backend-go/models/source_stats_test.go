@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildSourceStats(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	highScoreAt := time.Date(2026, 3, 9, 3, 0, 0, 0, time.UTC)
+
+	stats := BuildSourceStats(7, 3, now, []SourceDailyStats{
+		{Day: day(5), SourceStatsCounts: SourceStatsCounts{Ingested: 50, Evaluated: 50}}, // before the period
+		{Day: day(8), SourceStatsCounts: SourceStatsCounts{Ingested: 4, Evaluated: 4, Skipped: 3, InnovationSum: 20, DepthSum: 16, TooShort: 2}},
+		{Day: day(10), SourceStatsCounts: SourceStatsCounts{Ingested: 2, Evaluated: 1, Interesting: 1, InnovationSum: 9, DepthSum: 8, HighScores: 1}},
+	}, &highScoreAt)
+
+	if len(stats.Trend) != 3 || stats.Trend[0].Date != "2026-03-08" || stats.Trend[2].Date != "2026-03-10" {
+		t.Fatalf("trend = %+v", stats.Trend)
+	}
+	if stats.Trend[1].Ingested != 0 || stats.Trend[1].PassRate != nil {
+		t.Errorf("empty day = %+v", stats.Trend[1])
+	}
+
+	totals := stats.Totals
+	if totals.Ingested != 6 || totals.Evaluated != 5 || totals.TooShort != 2 || totals.HighScores != 1 {
+		t.Errorf("totals = %+v", totals.SourceStatsCounts)
+	}
+	if totals.PassRate == nil || *totals.PassRate != 0.4 {
+		t.Errorf("pass rate = %v, want 0.4", totals.PassRate)
+	}
+	if totals.AvgInnovation == nil || *totals.AvgInnovation != 5.8 || *totals.AvgDepth != 4.8 {
+		t.Errorf("averages = %v / %v", totals.AvgInnovation, totals.AvgDepth)
+	}
+	if stats.HoursSinceHighScore == nil || *stats.HoursSinceHighScore != 36 {
+		t.Errorf("hours since high score = %v, want 36", stats.HoursSinceHighScore)
+	}
+}
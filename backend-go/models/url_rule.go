@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// URLRule is a stored per-domain URL canonicalization rule
+type URLRule struct {
+	ID int64 `json:"id"`
+	utils.URLRule
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// WebSub subscription states
+const (
+	WebSubStatePending = "pending" // subscription request sent, waiting for the hub's verification
+	WebSubStateActive  = "active"  // verified; the hub pushes new entries until ExpiresAt
+	WebSubStateDenied  = "denied"  // the hub refused the subscription
+)
+
+// WebSubSubscription is a push subscription for one source at the hub its feed advertises
+type WebSubSubscription struct {
+	SourceID     int64      `json:"source_id"`
+	HubURL       string     `json:"hub_url"`
+	TopicURL     string     `json:"topic_url"`
+	Secret       string     `json:"-"`
+	State        string     `json:"state"`
+	LeaseSeconds int        `json:"lease_seconds"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	LastPushAt   *time.Time `json:"last_push_at"`
+	LastError    *string    `json:"last_error"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// IsActive reports whether the hub is currently expected to push for this source
+func (s *WebSubSubscription) IsActive(now time.Time) bool {
+	return s.State == WebSubStateActive && s.ExpiresAt != nil && now.Before(*s.ExpiresAt)
+}
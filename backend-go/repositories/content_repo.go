@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/junkfilter/backend-go/models"
+	"github.com/lib/pq"
 )
 
 type ContentRepository struct {
@@ -30,22 +31,37 @@ func (cr *ContentRepository) Create(ctx context.Context, req *models.CreateConte
 		OriginalURL:  req.OriginalURL,
 		ContentHash:  req.ContentHash,
 		CleanContent: req.CleanContent,
+		FullMarkdown: req.FullMarkdown,
 		ImageURLs:    req.ImageURLs,
 		PublishedAt:  req.PublishedAt,
 		IngestedAt:   time.Now(),
 		Status:       "PENDING",
+		CanonicalID:  req.CanonicalID,
+		Language:     req.Language,
+		Enclosures:   req.Enclosures,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
+	if req.CanonicalID != nil {
+		content.Status = models.ContentStatusDuplicate
+	}
+	var simHash sql.NullInt64
+	if req.SimHash != nil {
+		simHash = sql.NullInt64{Int64: int64(*req.SimHash), Valid: true} // BIGINT holds the bits as signed
+	}
+
 	err := cr.db.QueryRowContext(ctx,
 		`INSERT INTO content (task_id, source_id, platform, author_name, title, original_url,
-		                      content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		                      content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at,
+		                      body_hash, simhash, canonical_id, language, enclosures, full_markdown)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''), $16, $17, NULLIF($18, ''), $19,
+		         NULLIF($20, ''))
 		 RETURNING id, task_id, created_at, updated_at`,
 		content.TaskID, content.SourceID, content.Platform, content.AuthorName, content.Title,
 		content.OriginalURL, content.ContentHash, content.CleanContent, content.ImageURLs, content.PublishedAt,
-		content.IngestedAt, content.Status, content.CreatedAt, content.UpdatedAt,
+		content.IngestedAt, content.Status, content.CreatedAt, content.UpdatedAt, req.BodyHash,
+		simHash, req.CanonicalID, req.Language, content.Enclosures, req.FullMarkdown,
 	).Scan(&content.ID, &content.TaskID, &content.CreatedAt, &content.UpdatedAt)
 
 	if err != nil {
@@ -58,15 +74,18 @@ func (cr *ContentRepository) Create(ctx context.Context, req *models.CreateConte
 func (cr *ContentRepository) GetByID(ctx context.Context, id int64) (*models.Content, error) {
 	content := &models.Content{}
 	var publishedAt sql.NullTime
+	var canonicalID sql.NullInt64
 
 	err := cr.db.QueryRowContext(ctx,
 		`SELECT id, task_id, source_id, platform, author_name, title, original_url,
-		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at
+		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at, canonical_id,
+		        COALESCE(language, ''), enclosures, COALESCE(full_markdown, '')
 		 FROM content WHERE id = $1`,
 		id,
 	).Scan(&content.ID, &content.TaskID, &content.SourceID, &content.Platform, &content.AuthorName,
 		&content.Title, &content.OriginalURL, &content.ContentHash, &content.CleanContent,
-		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt)
+		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt, &canonicalID,
+		&content.Language, &content.Enclosures, &content.FullMarkdown)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -78,6 +97,9 @@ func (cr *ContentRepository) GetByID(ctx context.Context, id int64) (*models.Con
 	if publishedAt.Valid {
 		content.PublishedAt = &publishedAt.Time
 	}
+	if canonicalID.Valid {
+		content.CanonicalID = &canonicalID.Int64
+	}
 
 	return content, nil
 }
@@ -86,15 +108,18 @@ func (cr *ContentRepository) GetByID(ctx context.Context, id int64) (*models.Con
 func (cr *ContentRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) (*models.Content, error) {
 	content := &models.Content{}
 	var publishedAt sql.NullTime
+	var canonicalID sql.NullInt64
 
 	err := cr.db.QueryRowContext(ctx,
 		`SELECT id, task_id, source_id, platform, author_name, title, original_url,
-		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at
+		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at, canonical_id,
+		        COALESCE(language, ''), enclosures
 		 FROM content WHERE task_id = $1`,
 		taskID,
 	).Scan(&content.ID, &content.TaskID, &content.SourceID, &content.Platform, &content.AuthorName,
 		&content.Title, &content.OriginalURL, &content.ContentHash, &content.CleanContent,
-		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt)
+		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt, &canonicalID,
+		&content.Language, &content.Enclosures)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -106,6 +131,9 @@ func (cr *ContentRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID)
 	if publishedAt.Valid {
 		content.PublishedAt = &publishedAt.Time
 	}
+	if canonicalID.Valid {
+		content.CanonicalID = &canonicalID.Int64
+	}
 
 	return content, nil
 }
@@ -114,15 +142,18 @@ func (cr *ContentRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID)
 func (cr *ContentRepository) GetByURL(ctx context.Context, url string) (*models.Content, error) {
 	content := &models.Content{}
 	var publishedAt sql.NullTime
+	var canonicalID sql.NullInt64
 
 	err := cr.db.QueryRowContext(ctx,
 		`SELECT id, task_id, source_id, platform, author_name, title, original_url,
-		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at
+		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at, canonical_id,
+		        COALESCE(language, ''), enclosures
 		 FROM content WHERE original_url = $1`,
 		url,
 	).Scan(&content.ID, &content.TaskID, &content.SourceID, &content.Platform, &content.AuthorName,
 		&content.Title, &content.OriginalURL, &content.ContentHash, &content.CleanContent,
-		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt)
+		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt, &canonicalID,
+		&content.Language, &content.Enclosures)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -134,6 +165,9 @@ func (cr *ContentRepository) GetByURL(ctx context.Context, url string) (*models.
 	if publishedAt.Valid {
 		content.PublishedAt = &publishedAt.Time
 	}
+	if canonicalID.Valid {
+		content.CanonicalID = &canonicalID.Int64
+	}
 
 	return content, nil
 }
@@ -142,15 +176,18 @@ func (cr *ContentRepository) GetByURL(ctx context.Context, url string) (*models.
 func (cr *ContentRepository) GetByHash(ctx context.Context, hash string) (*models.Content, error) {
 	content := &models.Content{}
 	var publishedAt sql.NullTime
+	var canonicalID sql.NullInt64
 
 	err := cr.db.QueryRowContext(ctx,
 		`SELECT id, task_id, source_id, platform, author_name, title, original_url,
-		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at
+		        content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at, canonical_id,
+		        COALESCE(language, ''), enclosures
 		 FROM content WHERE content_hash = $1`,
 		hash,
 	).Scan(&content.ID, &content.TaskID, &content.SourceID, &content.Platform, &content.AuthorName,
 		&content.Title, &content.OriginalURL, &content.ContentHash, &content.CleanContent,
-		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt)
+		&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt, &canonicalID,
+		&content.Language, &content.Enclosures)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -162,14 +199,34 @@ func (cr *ContentRepository) GetByHash(ctx context.Context, hash string) (*model
 	if publishedAt.Valid {
 		content.PublishedAt = &publishedAt.Time
 	}
+	if canonicalID.Valid {
+		content.CanonicalID = &canonicalID.Int64
+	}
 
 	return content, nil
 }
 
+// GetLegacyBody returns the title and body hash of the content stored before the url_rules
+// canonicalization with url as its original_url. found is false when there is none; bodyHash is
+// "" for content stored before body hashes were kept.
+func (cr *ContentRepository) GetLegacyBody(ctx context.Context, url string) (title, bodyHash string, found bool, err error) {
+	err = cr.db.QueryRowContext(ctx,
+		"SELECT title, COALESCE(body_hash, '') FROM content WHERE original_url = $1 AND legacy_url LIMIT 1", url,
+	).Scan(&title, &bodyHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return title, bodyHash, true, nil
+}
+
 // List retrieves multiple contents with filtering
 func (cr *ContentRepository) List(ctx context.Context, filter *models.ContentFilter) ([]*models.Content, error) {
 	query := `SELECT id, task_id, source_id, platform, author_name, title, original_url,
-	                 content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at
+	                 content_hash, clean_content, image_urls, published_at, ingested_at, status, created_at, updated_at, canonical_id,
+	                 COALESCE(language, ''), enclosures
 	          FROM content WHERE 1=1`
 
 	args := []interface{}{}
@@ -187,6 +244,18 @@ func (cr *ContentRepository) List(ctx context.Context, filter *models.ContentFil
 		argIndex++
 	}
 
+	if len(filter.Languages) > 0 {
+		query += " AND language = ANY($" + strconv.Itoa(argIndex) + ")"
+		args = append(args, pq.Array(filter.Languages))
+		argIndex++
+	}
+
+	if scopeCond, scopeArgs := SourceScopeCondition("source_id", filter.Scope, argIndex); scopeCond != "" {
+		query += " AND " + scopeCond
+		args = append(args, scopeArgs...)
+		argIndex += len(scopeArgs)
+	}
+
 	query += " ORDER BY created_at DESC LIMIT $" + strconv.Itoa(argIndex) + " OFFSET $" + strconv.Itoa(argIndex+1)
 	args = append(args, filter.Limit, filter.Offset)
 
@@ -200,11 +269,13 @@ func (cr *ContentRepository) List(ctx context.Context, filter *models.ContentFil
 	for rows.Next() {
 		content := &models.Content{}
 		var publishedAt sql.NullTime
+		var canonicalID sql.NullInt64
 		var sourceID sql.NullInt64
 
 		err := rows.Scan(&content.ID, &content.TaskID, &sourceID, &content.Platform, &content.AuthorName,
 			&content.Title, &content.OriginalURL, &content.ContentHash, &content.CleanContent,
-			&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt)
+			&content.ImageURLs, &publishedAt, &content.IngestedAt, &content.Status, &content.CreatedAt, &content.UpdatedAt, &canonicalID,
+			&content.Language, &content.Enclosures)
 		if err != nil {
 			return nil, err
 		}
@@ -216,6 +287,9 @@ func (cr *ContentRepository) List(ctx context.Context, filter *models.ContentFil
 		if publishedAt.Valid {
 			content.PublishedAt = &publishedAt.Time
 		}
+		if canonicalID.Valid {
+			content.CanonicalID = &canonicalID.Int64
+		}
 
 		contents = append(contents, content)
 	}
@@ -223,6 +297,32 @@ func (cr *ContentRepository) List(ctx context.Context, filter *models.ContentFil
 	return contents, rows.Err()
 }
 
+// EachURLSince streams the URL and ingestion time of content ingested after since, oldest first
+func (cr *ContentRepository) EachURLSince(ctx context.Context, since time.Time, fn func(url string, ingestedAt time.Time) error) error {
+	rows, err := cr.db.QueryContext(ctx,
+		`SELECT original_url, ingested_at FROM content
+		 WHERE ingested_at > $1 AND original_url <> ''
+		 ORDER BY ingested_at`,
+		since,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url string
+		var ingestedAt time.Time
+		if err := rows.Scan(&url, &ingestedAt); err != nil {
+			return err
+		}
+		if err := fn(url, ingestedAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // UpdateStatus updates the status of a content
 func (cr *ContentRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
 	_, err := cr.db.ExecContext(ctx,
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+// ContentRevisionRepository handles content_revisions and the revision columns of content
+type ContentRevisionRepository struct {
+	db *sql.DB
+}
+
+// NewContentRevisionRepository creates a new content revision repository
+func NewContentRevisionRepository(db *sql.DB) *ContentRevisionRepository {
+	return &ContentRevisionRepository{db: db}
+}
+
+// Current returns the latest version of the item stored under a URL, or nil if there is none.
+// BodyHash is empty for rows ingested before body hashes were recorded.
+func (rr *ContentRevisionRepository) Current(ctx context.Context, url string) (*models.ContentRevision, error) {
+	rev := &models.ContentRevision{}
+	var title, cleanContent sql.NullString
+	err := rr.db.QueryRowContext(ctx,
+		`SELECT id, revision, title, clean_content, COALESCE(body_hash, ''), updated_at
+		 FROM content WHERE original_url = $1`,
+		url,
+	).Scan(&rev.ContentID, &rev.Revision, &title, &cleanContent, &rev.BodyHash, &rev.DetectedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	rev.Title = title.String
+	rev.CleanContent = cleanContent.String
+	return rev, nil
+}
+
+// Record stores next as the new latest version of previous.ContentID. The first time an item
+// changes, its original version is kept as revision 1. With requeue set the item goes back to
+// PENDING and its evaluation is dropped so the evaluator scores the new text.
+// It returns false when another writer recorded a revision first.
+func (rr *ContentRevisionRepository) Record(ctx context.Context, previous, next *models.ContentRevision, requeue bool) (bool, error) {
+	tx, err := rr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// The original version, dated when it was ingested
+	if previous.Revision == 1 {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO content_revisions (content_id, revision, title, clean_content, body_hash, detected_at)
+			 SELECT id, 1, title, clean_content, $2, ingested_at FROM content WHERE id = $1
+			 ON CONFLICT (content_id, revision) DO NOTHING`,
+			previous.ContentID, previous.BodyHash,
+		); err != nil {
+			return false, err
+		}
+	}
+
+	status := ""
+	if requeue {
+		status = "PENDING"
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE content
+		 SET title = $3, clean_content = $4, body_hash = $5, revision = $6,
+		     status = COALESCE(NULLIF($7, ''), status), full_markdown = NULLIF($8, ''), updated_at = NOW()
+		 WHERE id = $1 AND revision = $2`,
+		previous.ContentID, previous.Revision, next.Title, next.CleanContent, next.BodyHash, next.Revision, status,
+		next.FullMarkdown,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO content_revisions (content_id, revision, title, clean_content, body_hash, change_ratio, requeued, detected_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		 RETURNING id, detected_at`,
+		previous.ContentID, next.Revision, next.Title, next.CleanContent, next.BodyHash, next.ChangeRatio, requeue,
+	).Scan(&next.ID, &next.DetectedAt); err != nil {
+		return false, err
+	}
+
+	if requeue {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM evaluation WHERE content_id = $1", previous.ContentID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	next.ContentID = previous.ContentID
+	next.Requeued = requeue
+	return true, nil
+}
+
+// UpdateBodyHash fills in the body hash of an item stored before body hashes were recorded
+func (rr *ContentRevisionRepository) UpdateBodyHash(ctx context.Context, contentID int64, bodyHash string) error {
+	_, err := rr.db.ExecContext(ctx,
+		"UPDATE content SET body_hash = $2 WHERE id = $1 AND body_hash IS NULL", contentID, bodyHash)
+	return err
+}
+
+// ListByContent returns every recorded version of an item, oldest first.
+// It is empty for items that never changed.
+func (rr *ContentRevisionRepository) ListByContent(ctx context.Context, contentID int64) ([]models.ContentRevision, error) {
+	rows, err := rr.db.QueryContext(ctx,
+		`SELECT id, content_id, revision, COALESCE(title, ''), COALESCE(clean_content, ''),
+		        COALESCE(body_hash, ''), change_ratio, requeued, detected_at
+		 FROM content_revisions
+		 WHERE content_id = $1
+		 ORDER BY revision`,
+		contentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.ContentRevision{}
+	for rows.Next() {
+		var rev models.ContentRevision
+		if err := rows.Scan(&rev.ID, &rev.ContentID, &rev.Revision, &rev.Title, &rev.CleanContent,
+			&rev.BodyHash, &rev.ChangeRatio, &rev.Requeued, &rev.DetectedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
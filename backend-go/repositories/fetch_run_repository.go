@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+// FetchRunRepository handles fetch_runs database operations
+type FetchRunRepository struct {
+	db *sql.DB
+}
+
+// NewFetchRunRepository creates a new fetch run repository
+func NewFetchRunRepository(db *sql.DB) *FetchRunRepository {
+	return &FetchRunRepository{db: db}
+}
+
+// fetchRunColumns is the column list shared by the fetch_runs SELECTs (prefixed with alias r)
+const fetchRunColumns = `r.id, r.source_id, r.trigger, r.status, r.started_at, r.finished_at, r.duration_ms,
+	r.attempts, r.http_status, r.bytes, r.items_seen, r.items_new, r.items_duplicate,
+	r.items_too_short, r.items_author_filtered, r.items_errored, r.error, r.items_revised, r.items_near_duplicate,
+	r.items_rule_filtered, r.items_language_filtered, r.distinct_duplicate, r.distinct_too_short, r.distinct_filtered`
+
+// Create inserts a finished fetch run and sets its ID
+func (fr *FetchRunRepository) Create(ctx context.Context, run *models.FetchRun) error {
+	return fr.db.QueryRowContext(ctx,
+		`INSERT INTO fetch_runs (source_id, trigger, status, started_at, finished_at, duration_ms, attempts,
+		                         http_status, bytes, items_seen, items_new, items_duplicate,
+		                         items_too_short, items_author_filtered, items_errored, error, items_revised,
+		                         items_near_duplicate, items_rule_filtered, items_language_filtered,
+		                         distinct_duplicate, distinct_too_short, distinct_filtered)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		 RETURNING id`,
+		run.SourceID, run.Trigger, run.Status, run.StartedAt, run.FinishedAt, run.DurationMs, run.Attempts,
+		run.HTTPStatus, run.Bytes, run.ItemsSeen, run.ItemsNew, run.ItemsDuplicate,
+		run.ItemsTooShort, run.ItemsAuthorFiltered, run.ItemsErrored, run.Error, run.ItemsRevised,
+		run.ItemsNearDuplicate, run.ItemsRuleFiltered, run.ItemsLanguageFiltered,
+		run.DistinctDuplicate, run.DistinctTooShort, run.DistinctFiltered,
+	).Scan(&run.ID)
+}
+
+// ListBySource returns the most recent runs of one source, newest first
+func (fr *FetchRunRepository) ListBySource(ctx context.Context, sourceID int64, limit int) ([]models.FetchRun, error) {
+	rows, err := fr.db.QueryContext(ctx,
+		`SELECT `+fetchRunColumns+`, '' AS source_name
+		 FROM fetch_runs r
+		 WHERE r.source_id = $1
+		 ORDER BY r.started_at DESC
+		 LIMIT $2`,
+		sourceID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFetchRuns(rows)
+}
+
+// ListRecent returns the most recent runs across all sources, optionally only a given status
+func (fr *FetchRunRepository) ListRecent(ctx context.Context, status string, limit int) ([]models.FetchRun, error) {
+	query := `SELECT ` + fetchRunColumns + `, COALESCE(s.author_name, '') AS source_name
+	          FROM fetch_runs r
+	          LEFT JOIN sources s ON s.id = r.source_id`
+	args := []interface{}{}
+
+	if status != "" {
+		query += " WHERE r.status = $1"
+		args = append(args, status)
+	}
+
+	query += " ORDER BY r.started_at DESC LIMIT $" + strconv.Itoa(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := fr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFetchRuns(rows)
+}
+
+// DeleteBefore prunes runs that started before the cutoff, returning how many were removed
+func (fr *FetchRunRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := fr.db.ExecContext(ctx, "DELETE FROM fetch_runs WHERE started_at < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanFetchRuns(rows *sql.Rows) ([]models.FetchRun, error) {
+	runs := []models.FetchRun{}
+	for rows.Next() {
+		var run models.FetchRun
+		var finishedAt sql.NullTime
+		var errMsg sql.NullString
+
+		err := rows.Scan(&run.ID, &run.SourceID, &run.Trigger, &run.Status, &run.StartedAt, &finishedAt,
+			&run.DurationMs, &run.Attempts, &run.HTTPStatus, &run.Bytes, &run.ItemsSeen, &run.ItemsNew,
+			&run.ItemsDuplicate, &run.ItemsTooShort, &run.ItemsAuthorFiltered, &run.ItemsErrored, &errMsg,
+			&run.ItemsRevised, &run.ItemsNearDuplicate, &run.ItemsRuleFiltered, &run.ItemsLanguageFiltered,
+			&run.DistinctDuplicate, &run.DistinctTooShort, &run.DistinctFiltered, &run.SourceName)
+		if err != nil {
+			return nil, err
+		}
+
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		if errMsg.Valid {
+			run.Error = &errMsg.String
+		}
+
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+// FilterRuleRepository handles filter_rules and filter_skips database operations
+type FilterRuleRepository struct {
+	db *sql.DB
+}
+
+// NewFilterRuleRepository creates a new filter rule repository
+func NewFilterRuleRepository(db *sql.DB) *FilterRuleRepository {
+	return &FilterRuleRepository{db: db}
+}
+
+const filterRuleColumns = `id, source_id, name, enabled, condition, hit_count, last_hit_at, created_at, updated_at`
+
+// List returns rules, global ones first. With sourceID > 0 only that source's rules are returned,
+// with globalOnly only the global ones.
+func (fr *FilterRuleRepository) List(ctx context.Context, sourceID int64, globalOnly, enabledOnly bool) ([]*models.FilterRule, error) {
+	query := `SELECT ` + filterRuleColumns + ` FROM filter_rules WHERE 1=1`
+	args := []interface{}{}
+	if sourceID > 0 {
+		args = append(args, sourceID)
+		query += " AND source_id = $" + strconv.Itoa(len(args))
+	} else if globalOnly {
+		query += " AND source_id IS NULL"
+	}
+	if enabledOnly {
+		query += " AND enabled"
+	}
+	query += " ORDER BY source_id NULLS FIRST, id"
+
+	rows, err := fr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*models.FilterRule{}
+	for rows.Next() {
+		rule, err := scanFilterRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Create inserts a rule
+func (fr *FilterRuleRepository) Create(ctx context.Context, rule *models.FilterRule) (*models.FilterRule, error) {
+	condition, err := json.Marshal(rule.Condition)
+	if err != nil {
+		return nil, err
+	}
+	return scanFilterRule(fr.db.QueryRowContext(ctx,
+		`INSERT INTO filter_rules (source_id, name, enabled, condition)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING `+filterRuleColumns,
+		rule.SourceID, rule.Name, rule.Enabled, string(condition),
+	))
+}
+
+// Update replaces a rule's scope, name, state and condition, returning nil when it doesn't exist
+func (fr *FilterRuleRepository) Update(ctx context.Context, id int64, rule *models.FilterRule) (*models.FilterRule, error) {
+	condition, err := json.Marshal(rule.Condition)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := scanFilterRule(fr.db.QueryRowContext(ctx,
+		`UPDATE filter_rules
+		 SET source_id = $1, name = $2, enabled = $3, condition = $4, updated_at = NOW()
+		 WHERE id = $5
+		 RETURNING `+filterRuleColumns,
+		rule.SourceID, rule.Name, rule.Enabled, string(condition), id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return updated, err
+}
+
+// Delete removes a rule, reporting whether it existed. Its skips stay, without the rule id.
+func (fr *FilterRuleRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := fr.db.ExecContext(ctx, `DELETE FROM filter_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RecordSkip logs an item skipped by a rule and bumps the rule's hit counter
+func (fr *FilterRuleRepository) RecordSkip(ctx context.Context, rule *models.FilterRule, sourceID int64, title, url string) error {
+	_, err := fr.db.ExecContext(ctx,
+		`WITH hit AS (
+		     UPDATE filter_rules SET hit_count = hit_count + 1, last_hit_at = NOW() WHERE id = $1
+		 )
+		 INSERT INTO filter_skips (rule_id, rule_name, source_id, title, url)
+		 SELECT id, $2, $3, $4, $5 FROM filter_rules WHERE id = $1`,
+		rule.ID, rule.Name, sourceID, title, url,
+	)
+	return err
+}
+
+// ListSkips returns the most recent skips, newest first, optionally of one source and/or rule
+func (fr *FilterRuleRepository) ListSkips(ctx context.Context, sourceID, ruleID int64, limit int) ([]models.FilterSkip, error) {
+	query := `SELECT id, rule_id, rule_name, source_id, title, url, skipped_at FROM filter_skips WHERE 1=1`
+	args := []interface{}{}
+	if sourceID > 0 {
+		args = append(args, sourceID)
+		query += " AND source_id = $" + strconv.Itoa(len(args))
+	}
+	if ruleID > 0 {
+		args = append(args, ruleID)
+		query += " AND rule_id = $" + strconv.Itoa(len(args))
+	}
+	args = append(args, limit)
+	query += " ORDER BY skipped_at DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := fr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skips := []models.FilterSkip{}
+	for rows.Next() {
+		var skip models.FilterSkip
+		var ruleID sql.NullInt64
+		if err := rows.Scan(&skip.ID, &ruleID, &skip.RuleName, &skip.SourceID, &skip.Title, &skip.URL, &skip.SkippedAt); err != nil {
+			return nil, err
+		}
+		if ruleID.Valid {
+			skip.RuleID = &ruleID.Int64
+		}
+		skips = append(skips, skip)
+	}
+	return skips, rows.Err()
+}
+
+// DeleteSkipsBefore removes skips older than cutoff and returns how many were deleted
+func (fr *FilterRuleRepository) DeleteSkipsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := fr.db.ExecContext(ctx, "DELETE FROM filter_skips WHERE skipped_at < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanFilterRule scans a row selected with filterRuleColumns
+func scanFilterRule(row rowScanner) (*models.FilterRule, error) {
+	rule := &models.FilterRule{}
+	var sourceID sql.NullInt64
+	var lastHitAt sql.NullTime
+	var condition []byte
+
+	err := row.Scan(&rule.ID, &sourceID, &rule.Name, &rule.Enabled, &condition, &rule.HitCount, &lastHitAt,
+		&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if sourceID.Valid {
+		rule.SourceID = &sourceID.Int64
+	}
+	if lastHitAt.Valid {
+		rule.LastHitAt = &lastHitAt.Time
+	}
+	if err := json.Unmarshal(condition, &rule.Condition); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/lib/pq"
+)
+
+// ProxiedImageRepository handles proxied_images database operations
+type ProxiedImageRepository struct {
+	db *sql.DB
+}
+
+// NewProxiedImageRepository creates a new proxied image repository
+func NewProxiedImageRepository(db *sql.DB) *ProxiedImageRepository {
+	return &ProxiedImageRepository{db: db}
+}
+
+// Register stores the images in one statement; hashes already registered are left as they are
+func (pr *ProxiedImageRepository) Register(ctx context.Context, images []models.ProxiedImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+	hashes := make([]string, len(images))
+	urls := make([]string, len(images))
+	pageURLs := make([]string, len(images))
+	for i, img := range images {
+		hashes[i], urls[i], pageURLs[i] = img.Hash, img.URL, img.PageURL
+	}
+
+	_, err := pr.db.ExecContext(ctx,
+		`INSERT INTO proxied_images (hash, url, page_url)
+		 SELECT * FROM unnest($1::text[], $2::text[], $3::text[])
+		 ON CONFLICT (hash) DO NOTHING`,
+		pq.Array(hashes), pq.Array(urls), pq.Array(pageURLs),
+	)
+	return err
+}
+
+// GetByHash returns the image registered under hash, or nil
+func (pr *ProxiedImageRepository) GetByHash(ctx context.Context, hash string) (*models.ProxiedImage, error) {
+	img := &models.ProxiedImage{}
+	err := pr.db.QueryRowContext(ctx,
+		`SELECT hash, url, page_url, created_at FROM proxied_images WHERE hash = $1`,
+		hash,
+	).Scan(&img.Hash, &img.URL, &img.PageURL, &img.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return img, nil
+}
@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrFolderNameTaken is returned when a folder would get the same name as a sibling
+	ErrFolderNameTaken = errors.New("a folder with this name already exists here")
+	// ErrFolderParentNotFound is returned when the requested parent folder does not exist
+	ErrFolderParentNotFound = errors.New("parent folder not found")
+	// ErrFolderNotFound is returned when sources are assigned to a folder that does not exist
+	ErrFolderNotFound = errors.New("folder not found")
+	// ErrFolderCycle is returned when a folder would be moved into itself or one of its subfolders
+	ErrFolderCycle = errors.New("a folder cannot be moved into itself or one of its subfolders")
+)
+
+// SourceFolderRepository handles source_folders and the tags stored on sources
+type SourceFolderRepository struct {
+	db *sql.DB
+}
+
+// NewSourceFolderRepository creates a new source folder repository
+func NewSourceFolderRepository(db *sql.DB) *SourceFolderRepository {
+	return &SourceFolderRepository{db: db}
+}
+
+// List returns every folder in tree order, see models.OrderFolderTree
+func (fr *SourceFolderRepository) List(ctx context.Context) ([]models.SourceFolder, error) {
+	rows, err := fr.db.QueryContext(ctx,
+		`SELECT f.id, f.parent_id, f.name, f.position, COUNT(s.id), f.created_at, f.updated_at
+		 FROM source_folders f
+		 LEFT JOIN sources s ON s.folder_id = f.id
+		 GROUP BY f.id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.SourceFolder{}
+	for rows.Next() {
+		var f models.SourceFolder
+		var parentID sql.NullInt64
+		if err := rows.Scan(&f.ID, &parentID, &f.Name, &f.Position, &f.SourceCount, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			f.ParentID = &parentID.Int64
+		}
+		folders = append(folders, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return models.OrderFolderTree(folders), nil
+}
+
+// Create inserts a folder
+func (fr *SourceFolderRepository) Create(ctx context.Context, req *models.SourceFolderRequest) (*models.SourceFolder, error) {
+	folder := &models.SourceFolder{Name: req.Name, ParentID: folderIDOrNil(req.ParentID), Position: req.Position}
+	err := fr.db.QueryRowContext(ctx,
+		`INSERT INTO source_folders (parent_id, name, position)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, created_at, updated_at`,
+		folder.ParentID, folder.Name, folder.Position,
+	).Scan(&folder.ID, &folder.CreatedAt, &folder.UpdatedAt)
+	if err != nil {
+		return nil, folderWriteError(err)
+	}
+	return folder, nil
+}
+
+// EnsurePath returns the folder at the end of a path of names from the top level down, creating
+// the missing folders. Names match case-insensitively.
+func (fr *SourceFolderRepository) EnsurePath(ctx context.Context, names []string) (int64, error) {
+	var parentID *int64
+	for _, name := range names {
+		var id int64
+		err := fr.db.QueryRowContext(ctx,
+			`INSERT INTO source_folders (parent_id, name)
+			 VALUES ($1, $2)
+			 ON CONFLICT ((COALESCE(parent_id, 0)), (lower(name))) DO UPDATE SET name = source_folders.name
+			 RETURNING id`,
+			parentID, name,
+		).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+		parentID = &id
+	}
+	if parentID == nil {
+		return 0, errors.New("empty folder path")
+	}
+	return *parentID, nil
+}
+
+// Update renames, moves or reorders a folder, returning nil when it doesn't exist. Moves into
+// another folder lock source_folders against each other, so two concurrent moves can't each
+// pass the cycle check and together make a loop.
+func (fr *SourceFolderRepository) Update(ctx context.Context, id int64, req *models.SourceFolderRequest) (*models.SourceFolder, error) {
+	tx, err := fr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	parentID := folderIDOrNil(req.ParentID)
+	if parentID != nil {
+		if _, err := tx.ExecContext(ctx, "LOCK TABLE source_folders IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return nil, err
+		}
+		var cycle bool
+		err := tx.QueryRowContext(ctx,
+			fmt.Sprintf("SELECT $2 IN ("+folderSubtreeSQL+")", 1), id, *parentID,
+		).Scan(&cycle)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, ErrFolderCycle
+		}
+	}
+
+	folder := &models.SourceFolder{ID: id, Name: req.Name, ParentID: parentID, Position: req.Position}
+	err = tx.QueryRowContext(ctx,
+		`UPDATE source_folders SET parent_id = $2, name = $3, position = $4, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING created_at, updated_at`,
+		id, folder.ParentID, folder.Name, folder.Position,
+	).Scan(&folder.CreatedAt, &folder.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, folderWriteError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// Delete removes a folder; its sources and subfolders move up to its parent (top level for a
+// top-level folder). It returns sql.ErrNoRows when the folder doesn't exist.
+func (fr *SourceFolderRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := fr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	if err := tx.QueryRowContext(ctx,
+		"SELECT parent_id FROM source_folders WHERE id = $1 FOR UPDATE", id,
+	).Scan(&parentID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE sources SET folder_id = $2, updated_at = NOW() WHERE folder_id = $1", id, parentID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE source_folders SET parent_id = $2, updated_at = NOW() WHERE parent_id = $1", id, parentID,
+	); err != nil {
+		return folderWriteError(err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM source_folders WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListTags returns every tag in use with the number of sources carrying it, by tag
+func (fr *SourceFolderRepository) ListTags(ctx context.Context) ([]models.SourceTag, error) {
+	rows, err := fr.db.QueryContext(ctx,
+		`SELECT tag, COUNT(*) FROM sources, unnest(tags) AS tag
+		 GROUP BY tag ORDER BY tag`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.SourceTag{}
+	for rows.Next() {
+		var t models.SourceTag
+		if err := rows.Scan(&t.Tag, &t.SourceCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// RenameTag replaces a tag on every source carrying it, merging it into newTag when a source
+// already has both. It returns how many sources changed.
+func (fr *SourceFolderRepository) RenameTag(ctx context.Context, oldTag, newTag string) (int64, error) {
+	result, err := fr.db.ExecContext(ctx,
+		`UPDATE sources SET
+		   tags = ARRAY(
+		     SELECT t FROM unnest(array_replace(tags, $1, $2)) WITH ORDINALITY AS u(t, n)
+		     GROUP BY t ORDER BY MIN(n)
+		   ),
+		   updated_at = NOW()
+		 WHERE $1 = ANY(tags)`,
+		oldTag, newTag,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteTag removes a tag from every source carrying it and returns how many sources changed
+func (fr *SourceFolderRepository) DeleteTag(ctx context.Context, tag string) (int64, error) {
+	result, err := fr.db.ExecContext(ctx,
+		"UPDATE sources SET tags = array_remove(tags, $1), updated_at = NOW() WHERE $1 = ANY(tags)", tag)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// sourceFolderError maps a violation of sources.folder_id's foreign key to ErrFolderNotFound
+func sourceFolderError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" && strings.Contains(pqErr.Constraint, "folder_id") {
+		return ErrFolderNotFound
+	}
+	return err
+}
+
+// folderWriteError maps constraint violations on source_folders to their errors
+func folderWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505":
+			return ErrFolderNameTaken
+		case pqErr.Code == "23503" && strings.Contains(pqErr.Constraint, "parent_id"):
+			return ErrFolderParentNotFound
+		}
+	}
+	return err
+}
@@ -3,14 +3,21 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/junkfilter/backend-go/models"
+	"github.com/lib/pq"
 )
 
+// ErrFetchFenced is returned by fetch bookkeeping writes carrying a fencing token older than
+// the one already stored for the source: another replica has taken over its shard.
+var ErrFetchFenced = errors.New("fetch result rejected: a newer lease holder owns this source")
+
 type SourceRepository struct {
 	db *sql.DB
 }
@@ -28,6 +35,13 @@ func (sr *SourceRepository) Create(ctx context.Context, req *models.CreateSource
 		Priority:             req.Priority,
 		FetchIntervalSeconds: req.FetchIntervalSeconds,
 		Enabled:              true,
+		FetchFullText:        req.FetchFullText,
+		AdapterConfigJSON:    rawJSONOrNil(req.AdapterConfig),
+		AllowedLanguages:     req.AllowedLanguages,
+		StreamPayload:        req.StreamPayload,
+		FolderID:             folderIDOrNil(req.FolderID),
+		Tags:                 nonNil(req.Tags),
+		HTTPOptionsJSON:      httpOptionsOrNil(req.HTTPOptions),
 		CreatedAt:            time.Now(),
 		UpdatedAt:            time.Now(),
 	}
@@ -39,47 +53,67 @@ func (sr *SourceRepository) Create(ctx context.Context, req *models.CreateSource
 		source.FetchIntervalSeconds = 3600
 	}
 
-	// Auto-derive favicon URL from the RSS source domain
-	if parsed, err := url.Parse(req.URL); err == nil && parsed.Host != "" {
+	// Auto-derive favicon URL from the RSS source domain unless discovery already found one
+	if req.FaviconURL != "" {
+		source.FaviconURL = &req.FaviconURL
+	} else if parsed, err := url.Parse(req.URL); err == nil && parsed.Host != "" {
 		faviconURL := fmt.Sprintf("%s://%s/favicon.ico", parsed.Scheme, parsed.Host)
 		source.FaviconURL = &faviconURL
 	}
 
 	err := sr.db.QueryRowContext(ctx,
-		`INSERT INTO sources (platform, url, author_name, priority, fetch_interval_seconds, enabled, favicon_url, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`INSERT INTO sources (platform, url, author_name, priority, fetch_interval_seconds, enabled, favicon_url,
+		                      fetch_full_text, adapter_config, created_at, updated_at, allowed_languages, stream_payload,
+		                      folder_id, tags, http_options)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, $15, $16)
 		 RETURNING id, created_at, updated_at`,
 		source.Platform, source.URL, source.AuthorName, source.Priority,
-		source.FetchIntervalSeconds, source.Enabled, source.FaviconURL, source.CreatedAt, source.UpdatedAt,
+		source.FetchIntervalSeconds, source.Enabled, source.FaviconURL, source.FetchFullText,
+		source.AdapterConfigJSON, source.CreatedAt, source.UpdatedAt, pq.Array(source.AllowedLanguages), source.StreamPayload,
+		source.FolderID, pq.Array(source.Tags), source.HTTPOptionsJSON,
 	).Scan(&source.ID, &source.CreatedAt, &source.UpdatedAt)
 
 	if err != nil {
-		return nil, err
+		return nil, sourceFolderError(err)
 	}
 	return source, nil
 }
 
-// GetByID retrieves a source by ID
-func (sr *SourceRepository) GetByID(ctx context.Context, id int64) (*models.Source, error) {
+// sourceColumns is the column list shared by every query that scans into models.Source
+const sourceColumns = `id, platform, url, author_name, author_id, priority, last_fetch_time,
+	fetch_interval_seconds, enabled, favicon_url, author_filter, etag, last_modified,
+	consecutive_failures, last_error, next_retry_at, auto_disabled_at, fetch_full_text,
+	adapter_config, created_at, updated_at, allowed_languages, COALESCE(stream_payload, ''),
+	folder_id, tags, http_options`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSource scans a row selected with sourceColumns into a Source
+func scanSource(row rowScanner) (*models.Source, error) {
 	source := &models.Source{}
 	var lastFetchTime sql.NullTime
 	var authorID sql.NullString
 	var faviconURL sql.NullString
 	var authorFilterJSON sql.NullString
-
-	err := sr.db.QueryRowContext(ctx,
-		`SELECT id, platform, url, author_name, author_id, priority, last_fetch_time,
-		        fetch_interval_seconds, enabled, favicon_url, author_filter, created_at, updated_at
-		 FROM sources WHERE id = $1`,
-		id,
-	).Scan(&source.ID, &source.Platform, &source.URL, &source.AuthorName, &authorID,
+	var etag sql.NullString
+	var lastModified sql.NullString
+	var lastError sql.NullString
+	var nextRetryAt sql.NullTime
+	var autoDisabledAt sql.NullTime
+	var adapterConfig sql.NullString
+	var folderID sql.NullInt64
+	var httpOptions sql.NullString
+
+	err := row.Scan(&source.ID, &source.Platform, &source.URL, &source.AuthorName, &authorID,
 		&source.Priority, &lastFetchTime, &source.FetchIntervalSeconds, &source.Enabled,
-		&faviconURL, &authorFilterJSON, &source.CreatedAt, &source.UpdatedAt)
-
+		&faviconURL, &authorFilterJSON, &etag, &lastModified,
+		&source.ConsecutiveFailures, &lastError, &nextRetryAt, &autoDisabledAt, &source.FetchFullText,
+		&adapterConfig, &source.CreatedAt, &source.UpdatedAt, pq.Array(&source.AllowedLanguages),
+		&source.StreamPayload, &folderID, pq.Array(&source.Tags), &httpOptions)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
 
@@ -95,26 +129,77 @@ func (sr *SourceRepository) GetByID(ctx context.Context, id int64) (*models.Sour
 		source.FaviconURL = &faviconURL.String
 	}
 
+	if adapterConfig.Valid {
+		source.AdapterConfigJSON = &adapterConfig.String
+	}
+
 	if authorFilterJSON.Valid {
 		source.AuthorFilterJSON = &authorFilterJSON.String
 	}
 
+	source.ETag = etag.String
+	source.LastModified = lastModified.String
+
+	if lastError.Valid {
+		source.LastError = &lastError.String
+	}
+
+	if nextRetryAt.Valid {
+		source.NextRetryAt = &nextRetryAt.Time
+	}
+
+	if autoDisabledAt.Valid {
+		source.AutoDisabledAt = &autoDisabledAt.Time
+	}
+
+	if folderID.Valid {
+		source.FolderID = &folderID.Int64
+	}
+
+	if httpOptions.Valid {
+		source.HTTPOptionsJSON = &httpOptions.String
+	}
+
+	return source, nil
+}
+
+// GetByID retrieves a source by ID
+func (sr *SourceRepository) GetByID(ctx context.Context, id int64) (*models.Source, error) {
+	source, err := scanSource(sr.db.QueryRowContext(ctx,
+		`SELECT `+sourceColumns+` FROM sources WHERE id = $1`,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
 	return source, nil
 }
 
 // GetAll retrieves all enabled sources
 func (sr *SourceRepository) GetAll(ctx context.Context, enabledOnly bool) ([]*models.Source, error) {
-	query := `SELECT id, platform, url, author_name, author_id, priority, last_fetch_time,
-	                fetch_interval_seconds, enabled, favicon_url, author_filter, created_at, updated_at
-	          FROM sources`
+	return sr.List(ctx, enabledOnly, models.SourceScope{})
+}
+
+// List retrieves the sources in scope, optionally only the enabled ones
+func (sr *SourceRepository) List(ctx context.Context, enabledOnly bool, scope models.SourceScope) ([]*models.Source, error) {
+	query := `SELECT ` + sourceColumns + ` FROM sources WHERE TRUE`
 
 	if enabledOnly {
-		query += " WHERE enabled = TRUE"
+		query += " AND enabled = TRUE"
+	}
+
+	scopeCond, args := SourceScopeCondition("id", scope, 1)
+	if scopeCond != "" {
+		query += " AND " + scopeCond
 	}
 
 	query += " ORDER BY priority DESC, created_at DESC"
 
-	rows, err := sr.db.QueryContext(ctx, query)
+	rows, err := sr.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -122,35 +207,10 @@ func (sr *SourceRepository) GetAll(ctx context.Context, enabledOnly bool) ([]*mo
 
 	var sources []*models.Source
 	for rows.Next() {
-		source := &models.Source{}
-		var lastFetchTime sql.NullTime
-		var authorID sql.NullString
-		var faviconURL sql.NullString
-		var authorFilterJSON sql.NullString
-
-		err := rows.Scan(&source.ID, &source.Platform, &source.URL, &source.AuthorName, &authorID,
-			&source.Priority, &lastFetchTime, &source.FetchIntervalSeconds, &source.Enabled,
-			&faviconURL, &authorFilterJSON, &source.CreatedAt, &source.UpdatedAt)
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if lastFetchTime.Valid {
-			source.LastFetchTime = &lastFetchTime.Time
-		}
-
-		if authorID.Valid {
-			source.AuthorID = &authorID.String
-		}
-
-		if faviconURL.Valid {
-			source.FaviconURL = &faviconURL.String
-		}
-
-		if authorFilterJSON.Valid {
-			source.AuthorFilterJSON = &authorFilterJSON.String
-		}
-
 		sources = append(sources, source)
 	}
 
@@ -173,17 +233,54 @@ func (sr *SourceRepository) Update(ctx context.Context, id int64, req *models.Up
 	if req.FetchIntervalSeconds > 0 {
 		source.FetchIntervalSeconds = req.FetchIntervalSeconds
 	}
+	if req.FetchFullText != nil {
+		source.FetchFullText = *req.FetchFullText
+	}
+	if len(req.AdapterConfig) > 0 {
+		source.AdapterConfigJSON = rawJSONOrNil(req.AdapterConfig)
+	}
+	if req.AllowedLanguages != nil {
+		source.AllowedLanguages = *req.AllowedLanguages
+	}
+	if req.StreamPayload != nil {
+		source.StreamPayload = *req.StreamPayload
+	}
+	if req.FolderID != nil {
+		source.FolderID = folderIDOrNil(req.FolderID)
+	}
+	if req.Tags != nil {
+		source.Tags = nonNil(*req.Tags)
+	}
+	if req.HTTPOptions != nil {
+		source.HTTPOptionsJSON = httpOptionsOrNil(req.HTTPOptions)
+	}
+	// Re-enabling a source (typically one that was auto-disabled) gives it a clean slate
+	reenabled := req.Enabled && !source.Enabled
 	source.Enabled = req.Enabled
 	source.UpdatedAt = time.Now()
 
 	_, err = sr.db.ExecContext(ctx,
-		`UPDATE sources SET author_name = $1, priority = $2, fetch_interval_seconds = $3, enabled = $4, updated_at = $5
-		 WHERE id = $6`,
-		source.AuthorName, source.Priority, source.FetchIntervalSeconds, source.Enabled, source.UpdatedAt, id,
+		`UPDATE sources SET author_name = $1, priority = $2, fetch_interval_seconds = $3, enabled = $4,
+		        fetch_full_text = $5, adapter_config = $6, updated_at = $7, allowed_languages = $8,
+		        stream_payload = NULLIF($9, ''), folder_id = $10, tags = $11, http_options = $12
+		 WHERE id = $13`,
+		source.AuthorName, source.Priority, source.FetchIntervalSeconds, source.Enabled,
+		source.FetchFullText, source.AdapterConfigJSON, source.UpdatedAt, pq.Array(source.AllowedLanguages),
+		source.StreamPayload, source.FolderID, pq.Array(nonNil(source.Tags)), source.HTTPOptionsJSON, id,
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, sourceFolderError(err)
+	}
+
+	if reenabled {
+		if err := sr.ResetFetchHealth(ctx, id); err != nil {
+			return nil, err
+		}
+		source.ConsecutiveFailures = 0
+		source.LastError = nil
+		source.NextRetryAt = nil
+		source.AutoDisabledAt = nil
 	}
 
 	return source, nil
@@ -217,6 +314,78 @@ func (sr *SourceRepository) UpdateLastFetchTime(ctx context.Context, id int64, l
 	return err
 }
 
+// RecordFetchSuccess marks a poll as successful: updates last_fetch_time and clears the failure state.
+// fenceToken is the fetching replica's lease token (0 when fetches are not coordinated);
+// see fencedExec.
+func (sr *SourceRepository) RecordFetchSuccess(ctx context.Context, id int64, fetchedAt time.Time, fenceToken int64) error {
+	return sr.fencedExec(ctx, fenceToken,
+		`UPDATE sources SET last_fetch_time = $1, consecutive_failures = 0, last_error = NULL,
+		        next_retry_at = NULL, updated_at = $2, fetch_token = GREATEST(fetch_token, $4)
+		 WHERE id = $3 AND ($4 = 0 OR fetch_token <= $4)`,
+		fetchedAt, time.Now(), id, fenceToken,
+	)
+}
+
+// RecordFetchFailure increments the failure counter, stores the error and the backoff deadline.
+// When maxFailures > 0 and the counter reaches it, the source is disabled in the same statement.
+// Returns the new failure count.
+func (sr *SourceRepository) RecordFetchFailure(ctx context.Context, id int64, lastError string, nextRetryAt time.Time, maxFailures int, fenceToken int64) (int, error) {
+	var failures int
+	err := sr.db.QueryRowContext(ctx,
+		`UPDATE sources SET
+		   consecutive_failures = consecutive_failures + 1,
+		   last_error = $1,
+		   next_retry_at = $2,
+		   enabled = CASE WHEN $3 > 0 AND consecutive_failures + 1 >= $3 THEN FALSE ELSE enabled END,
+		   auto_disabled_at = CASE WHEN $3 > 0 AND consecutive_failures + 1 >= $3 AND enabled THEN NOW() ELSE auto_disabled_at END,
+		   updated_at = NOW(),
+		   fetch_token = GREATEST(fetch_token, $5)
+		 WHERE id = $4 AND ($5 = 0 OR fetch_token <= $5)
+		 RETURNING consecutive_failures`,
+		lastError, nextRetryAt, maxFailures, id, fenceToken,
+	).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) && fenceToken != 0 {
+		return 0, ErrFetchFenced
+	}
+	return failures, err
+}
+
+// ResetFetchHealth clears failure counters, backoff and the auto-disable marker
+func (sr *SourceRepository) ResetFetchHealth(ctx context.Context, id int64) error {
+	_, err := sr.db.ExecContext(ctx,
+		`UPDATE sources SET consecutive_failures = 0, last_error = NULL, next_retry_at = NULL,
+		        auto_disabled_at = NULL
+		 WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+// UpdateFeedValidators stores the HTTP cache validators returned by the last successful fetch,
+// so the next poll can send If-None-Match / If-Modified-Since
+func (sr *SourceRepository) UpdateFeedValidators(ctx context.Context, id int64, etag, lastModified string, fenceToken int64) error {
+	return sr.fencedExec(ctx, fenceToken,
+		`UPDATE sources SET etag = $1, last_modified = $2, fetch_token = GREATEST(fetch_token, $4)
+		 WHERE id = $3 AND ($4 = 0 OR fetch_token <= $4)`,
+		nullIfEmpty(etag), nullIfEmpty(lastModified), id, fenceToken,
+	)
+}
+
+// fencedExec runs a fetch bookkeeping UPDATE guarded by "fetch_token <= fenceToken".
+// No affected row with a non-zero token means a newer lease holder already wrote: ErrFetchFenced.
+func (sr *SourceRepository) fencedExec(ctx context.Context, fenceToken int64, query string, args ...interface{}) error {
+	result, err := sr.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if fenceToken != 0 {
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return ErrFetchFenced
+		}
+	}
+	return nil
+}
+
 // UpdateAuthorFilter updates the author_filter for a source
 func (sr *SourceRepository) UpdateAuthorFilter(ctx context.Context, id int64, filterJSON string) error {
 	_, err := sr.db.ExecContext(ctx,
@@ -248,27 +417,36 @@ func (sr *SourceRepository) GetDistinctAuthors(ctx context.Context, sourceID int
 	return authors, rows.Err()
 }
 
-// Search searches for sources by query (author_name or url) and optional platform filter
-func (sr *SourceRepository) Search(ctx context.Context, query, platform string) ([]models.Source, error) {
+// Search searches for sources by query (author_name, url or an exact tag) within scope, with an
+// optional platform filter. limit <= 0 returns every match.
+func (sr *SourceRepository) Search(ctx context.Context, query, platform string, scope models.SourceScope, limit int) ([]models.Source, error) {
 	var sources []models.Source
 
 	// Build query with fuzzy search on author_name and url
 	sqlQuery := `
-		SELECT id, platform, url, author_name, author_id, priority,
-		       fetch_interval_seconds, enabled, last_fetch_time, favicon_url,
-		       author_filter, created_at, updated_at
+		SELECT ` + sourceColumns + `
 		FROM sources
-		WHERE (author_name ILIKE $1 OR url ILIKE $1)
+		WHERE (author_name ILIKE $1 OR url ILIKE $1 OR lower($2) = ANY(tags))
 	`
-	args := []interface{}{"%" + query + "%"}
+	args := []interface{}{"%" + query + "%", strings.TrimSpace(query)}
 
 	// Add platform filter if specified
 	if platform != "" {
-		sqlQuery += " AND platform = $2"
 		args = append(args, platform)
+		sqlQuery += fmt.Sprintf(" AND platform = $%d", len(args))
+	}
+
+	scopeCond, scopeArgs := SourceScopeCondition("id", scope, len(args)+1)
+	if scopeCond != "" {
+		sqlQuery += " AND " + scopeCond
+		args = append(args, scopeArgs...)
 	}
 
 	sqlQuery += " ORDER BY priority DESC, created_at DESC"
+	if limit > 0 {
+		args = append(args, limit)
+		sqlQuery += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
 
 	rows, err := sr.db.QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
@@ -277,45 +455,11 @@ func (sr *SourceRepository) Search(ctx context.Context, query, platform string)
 	defer rows.Close()
 
 	for rows.Next() {
-		var source models.Source
-		var lastFetchTime sql.NullTime
-		var authorID sql.NullString
-		var faviconURL sql.NullString
-		var authorFilterJSON sql.NullString
-
-		err := rows.Scan(
-			&source.ID,
-			&source.Platform,
-			&source.URL,
-			&source.AuthorName,
-			&authorID,
-			&source.Priority,
-			&source.FetchIntervalSeconds,
-			&source.Enabled,
-			&lastFetchTime,
-			&faviconURL,
-			&authorFilterJSON,
-			&source.CreatedAt,
-			&source.UpdatedAt,
-		)
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if lastFetchTime.Valid {
-			source.LastFetchTime = &lastFetchTime.Time
-		}
-		if authorID.Valid {
-			source.AuthorID = &authorID.String
-		}
-		if faviconURL.Valid {
-			source.FaviconURL = &faviconURL.String
-		}
-		if authorFilterJSON.Valid {
-			source.AuthorFilterJSON = &authorFilterJSON.String
-		}
-
-		sources = append(sources, source)
+		sources = append(sources, *source)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -324,3 +468,65 @@ func (sr *SourceRepository) Search(ctx context.Context, query, platform string)
 
 	return sources, nil
 }
+
+// BulkAssign moves the sources to a folder and adds/removes tags in one statement, returning how
+// many sources were updated. A nil FolderID leaves folders unchanged, 0 unfiles the sources.
+// Tags must already be normalized.
+func (sr *SourceRepository) BulkAssign(ctx context.Context, req *models.BulkSourceRequest) (int64, error) {
+	result, err := sr.db.ExecContext(ctx,
+		`UPDATE sources SET
+		   folder_id = CASE WHEN $2 THEN $3 ELSE folder_id END,
+		   tags = ARRAY(
+		     SELECT t FROM unnest(tags || $4::text[]) WITH ORDINALITY AS u(t, n)
+		     WHERE NOT t = ANY($5::text[])
+		     GROUP BY t ORDER BY MIN(n)
+		   ),
+		   updated_at = NOW()
+		 WHERE id = ANY($1)`,
+		pq.Array(req.SourceIDs), req.FolderID != nil, folderIDOrNil(req.FolderID),
+		pq.Array(nonNil(req.AddTags)), pq.Array(nonNil(req.RemoveTags)),
+	)
+	if err != nil {
+		return 0, sourceFolderError(err)
+	}
+	return result.RowsAffected()
+}
+
+// folderIDOrNil maps the "no folder" folder ID 0 to nil
+func folderIDOrNil(id *int64) *int64 {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return id
+}
+
+// httpOptionsOrNil stores a source's HTTP options, whose secrets the caller has sealed; options
+// that change nothing become SQL NULL
+func httpOptionsOrNil(opts *models.SourceHTTPOptions) *string {
+	if opts.IsZero() {
+		return nil
+	}
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	raw := string(data)
+	return &raw
+}
+
+// nullIfEmpty maps "" to SQL NULL so optional text columns stay NULL instead of ''
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// rawJSONOrNil stores an optional JSON document; empty input and JSON null become SQL NULL
+func rawJSONOrNil(raw []byte) *string {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil
+	}
+	return &trimmed
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+// folderSubtreeSQL selects the IDs of folder $n and every folder below it. UNION rather than
+// UNION ALL ends the recursion even if the folders somehow form a loop.
+const folderSubtreeSQL = `WITH RECURSIVE subtree AS (
+		SELECT id FROM source_folders WHERE id = $%[1]d
+		UNION
+		SELECT f.id FROM source_folders f JOIN subtree ON f.parent_id = subtree.id
+	) SELECT id FROM subtree`
+
+// SourceScopeCondition returns an SQL condition that holds when column, a source ID, belongs to a
+// source in scope, with its arguments numbered from argIndex. A folder scope includes the
+// folder's subfolders. The condition is "" (and there are no arguments) for the zero scope.
+func SourceScopeCondition(column string, scope models.SourceScope, argIndex int) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if scope.FolderID != 0 {
+		conds = append(conds, fmt.Sprintf("folder_id IN ("+folderSubtreeSQL+")", argIndex+len(args)))
+		args = append(args, scope.FolderID)
+	}
+	if scope.Tag != "" {
+		conds = append(conds, fmt.Sprintf("$%d = ANY(tags)", argIndex+len(args)))
+		args = append(args, scope.Tag)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%s IN (SELECT id FROM sources WHERE %s)", column, strings.Join(conds, " AND ")), args
+}
@@ -0,0 +1,225 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+// SourceStatsRepository handles the source_daily_stats rollups
+type SourceStatsRepository struct {
+	db *sql.DB
+}
+
+// NewSourceStatsRepository creates a new source stats repository
+func NewSourceStatsRepository(db *sql.DB) *SourceStatsRepository {
+	return &SourceStatsRepository{db: db}
+}
+
+// ErrStatsRefreshBusy is returned by Refresh while another replica refreshes the rollups
+var ErrStatsRefreshBusy = errors.New("source stats are being refreshed by another process")
+
+// sourceStatsLockKey is the transaction-level advisory lock serializing Refresh across replicas
+const sourceStatsLockKey int64 = 0x6a66737461747300 // "jfstats"
+
+// sourceStatsColumns is the column list shared by the source_daily_stats SELECTs
+const sourceStatsColumns = `source_id, day, ingested, evaluated, interesting, bookmarked, skipped,
+	innovation_sum, depth_sum, high_scores, last_high_score_at, duplicates, too_short, filtered, updated_at`
+
+// Refresh recomputes the rollups of every day from since's day on, in one transaction, and
+// returns how many rows it wrote. Days without activity are left without a row. Every fetcher
+// replica refreshes, so refreshes are serialized with an advisory lock: while one runs, the
+// others fail with ErrStatsRefreshBusy instead of racing it into unique violations.
+func (sr *SourceStatsRepository) Refresh(ctx context.Context, since time.Time) (int64, error) {
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", sourceStatsLockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, ErrStatsRefreshBusy
+	}
+
+	day := since.Format("2006-01-02")
+	if _, err := tx.ExecContext(ctx, "DELETE FROM source_daily_stats WHERE day >= $1::date", day); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO source_daily_stats (source_id, day, ingested, evaluated, interesting, bookmarked, skipped,
+		                                 innovation_sum, depth_sum, high_scores, last_high_score_at,
+		                                 duplicates, too_short, filtered, updated_at)
+		 SELECT source_id, day, SUM(ingested), SUM(evaluated), SUM(interesting), SUM(bookmarked), SUM(skipped),
+		        SUM(innovation_sum), SUM(depth_sum), SUM(high_scores), MAX(last_high_score_at),
+		        SUM(duplicates), SUM(too_short), SUM(filtered), NOW()
+		 FROM (
+		   SELECT c.source_id, c.ingested_at::date AS day,
+		          COUNT(*) FILTER (WHERE c.status <> 'DUPLICATE') AS ingested,
+		          COUNT(e.id) AS evaluated,
+		          COUNT(*) FILTER (WHERE upper(e.decision) = 'INTERESTING') AS interesting,
+		          COUNT(*) FILTER (WHERE upper(e.decision) = 'BOOKMARK') AS bookmarked,
+		          COUNT(*) FILTER (WHERE upper(e.decision) = 'SKIP') AS skipped,
+		          COALESCE(SUM(e.innovation_score), 0) AS innovation_sum,
+		          COALESCE(SUM(e.depth_score), 0) AS depth_sum,
+		          COUNT(*) FILTER (WHERE e.innovation_score >= $2 AND e.depth_score >= $3) AS high_scores,
+		          MAX(c.ingested_at) FILTER (WHERE e.innovation_score >= $2 AND e.depth_score >= $3) AS last_high_score_at,
+		          0 AS duplicates, 0 AS too_short, 0 AS filtered
+		   FROM content c
+		   LEFT JOIN evaluation e ON e.content_id = c.id
+		   WHERE c.source_id IS NOT NULL AND c.ingested_at >= $1::date
+		   GROUP BY 1, 2
+		   UNION ALL
+		   SELECT r.source_id, r.started_at::date,
+		          0, 0, 0, 0, 0, 0, 0, 0, NULL,
+		          SUM(r.distinct_duplicate + r.items_near_duplicate),
+		          SUM(r.distinct_too_short),
+		          SUM(r.distinct_filtered)
+		   FROM fetch_runs r
+		   WHERE r.source_id IS NOT NULL AND r.started_at >= $1::date
+		   GROUP BY 1, 2
+		 ) d
+		 WHERE source_id IN (SELECT id FROM sources)
+		 GROUP BY source_id, day`,
+		day, models.HighScoreMinInnovation, models.HighScoreMinDepth,
+	)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rows, tx.Commit()
+}
+
+// LatestDay returns the most recent rolled-up day, or nil before the first refresh
+func (sr *SourceStatsRepository) LatestDay(ctx context.Context) (*time.Time, error) {
+	var day sql.NullTime
+	if err := sr.db.QueryRowContext(ctx, "SELECT MAX(day) FROM source_daily_stats").Scan(&day); err != nil {
+		return nil, err
+	}
+	if !day.Valid {
+		return nil, nil
+	}
+	return &day.Time, nil
+}
+
+// ListDaily returns a source's rollups from since's day on, oldest first
+func (sr *SourceStatsRepository) ListDaily(ctx context.Context, sourceID int64, since time.Time) ([]models.SourceDailyStats, error) {
+	rows, err := sr.db.QueryContext(ctx,
+		`SELECT `+sourceStatsColumns+`
+		 FROM source_daily_stats
+		 WHERE source_id = $1 AND day >= $2::date
+		 ORDER BY day`,
+		sourceID, since.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	daily := []models.SourceDailyStats{}
+	for rows.Next() {
+		var d models.SourceDailyStats
+		var lastHighScoreAt sql.NullTime
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&d.SourceID, &d.Day, &d.Ingested, &d.Evaluated, &d.Interesting, &d.Bookmarked,
+			&d.Skipped, &d.InnovationSum, &d.DepthSum, &d.HighScores, &lastHighScoreAt,
+			&d.Duplicates, &d.TooShort, &d.Filtered, &updatedAt); err != nil {
+			return nil, err
+		}
+		if lastHighScoreAt.Valid {
+			d.LastHighScoreAt = &lastHighScoreAt.Time
+		}
+		d.UpdatedAt = updatedAt.Time
+		daily = append(daily, d)
+	}
+	return daily, rows.Err()
+}
+
+// LastHighScoreAt returns when the source last ingested a high scorer, or nil if it never did
+func (sr *SourceStatsRepository) LastHighScoreAt(ctx context.Context, sourceID int64) (*time.Time, error) {
+	var at sql.NullTime
+	err := sr.db.QueryRowContext(ctx,
+		"SELECT MAX(last_high_score_at) FROM source_daily_stats WHERE source_id = $1", sourceID,
+	).Scan(&at)
+	if err != nil || !at.Valid {
+		return nil, err
+	}
+	return &at.Time, nil
+}
+
+// leaderboardOrder maps the leaderboard sort keys to their SQL expressions over the rollups
+var leaderboardOrder = map[string]string{
+	models.LeaderboardByPassRate:   "(SUM(d.evaluated) - SUM(d.skipped))::float / NULLIF(SUM(d.evaluated), 0)",
+	models.LeaderboardByAvgScore:   "(SUM(d.innovation_sum) + SUM(d.depth_sum))::float / NULLIF(SUM(d.evaluated), 0)",
+	models.LeaderboardByHighScores: "SUM(d.high_scores)",
+	models.LeaderboardByVolume:     "SUM(d.ingested)",
+}
+
+// Leaderboard ranks the sources in scope by their rollups since q.Since
+func (sr *SourceStatsRepository) Leaderboard(ctx context.Context, q *models.LeaderboardQuery) ([]models.SourceLeaderboardEntry, error) {
+	order, ok := leaderboardOrder[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard sort %q", q.Sort)
+	}
+	direction := "DESC"
+	if q.Ascending {
+		direction = "ASC"
+	}
+
+	args := []interface{}{q.Since.Format("2006-01-02"), q.MinEvaluated, q.Limit}
+	scopeCond, scopeArgs := SourceScopeCondition("s.id", q.Scope, len(args)+1)
+	if scopeCond != "" {
+		scopeCond = "AND " + scopeCond
+		args = append(args, scopeArgs...)
+	}
+
+	rows, err := sr.db.QueryContext(ctx,
+		`SELECT s.id, COALESCE(s.author_name, ''), s.url, s.enabled,
+		        (SELECT MAX(a.last_high_score_at) FROM source_daily_stats a WHERE a.source_id = s.id),
+		        SUM(d.ingested), SUM(d.evaluated), SUM(d.interesting), SUM(d.bookmarked), SUM(d.skipped),
+		        SUM(d.innovation_sum), SUM(d.depth_sum), SUM(d.high_scores),
+		        SUM(d.duplicates), SUM(d.too_short), SUM(d.filtered)
+		 FROM source_daily_stats d
+		 JOIN sources s ON s.id = d.source_id
+		 WHERE d.day >= $1::date `+scopeCond+`
+		 GROUP BY s.id
+		 HAVING SUM(d.evaluated) >= $2
+		 ORDER BY `+order+` `+direction+` NULLS LAST, s.id
+		 LIMIT $3`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.SourceLeaderboardEntry{}
+	for rows.Next() {
+		var e models.SourceLeaderboardEntry
+		var c models.SourceStatsCounts
+		var lastHighScoreAt sql.NullTime
+		if err := rows.Scan(&e.SourceID, &e.Name, &e.URL, &e.Enabled, &lastHighScoreAt,
+			&c.Ingested, &c.Evaluated, &c.Interesting, &c.Bookmarked, &c.Skipped,
+			&c.InnovationSum, &c.DepthSum, &c.HighScores,
+			&c.Duplicates, &c.TooShort, &c.Filtered); err != nil {
+			return nil, err
+		}
+		if lastHighScoreAt.Valid {
+			e.LastHighScoreAt = &lastHighScoreAt.Time
+		}
+		e.SourceStatsSummary = c.Summarize()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
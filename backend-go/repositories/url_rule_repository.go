@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+	"github.com/lib/pq"
+)
+
+// ErrURLRuleExists is returned when saving a rule for a domain that already has one
+var ErrURLRuleExists = errors.New("a rule for this domain already exists")
+
+// URLRuleRepository handles url_rules database operations
+type URLRuleRepository struct {
+	db *sql.DB
+}
+
+// NewURLRuleRepository creates a new URL rule repository
+func NewURLRuleRepository(db *sql.DB) *URLRuleRepository {
+	return &URLRuleRepository{db: db}
+}
+
+const urlRuleColumns = `id, domain, keep_params, drop_params, keep_fragment, redirect_param,
+	resolve_canonical, created_at, updated_at`
+
+// List returns all rules ordered by domain
+func (ur *URLRuleRepository) List(ctx context.Context) ([]*models.URLRule, error) {
+	rows, err := ur.db.QueryContext(ctx, `SELECT `+urlRuleColumns+` FROM url_rules ORDER BY domain`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*models.URLRule{}
+	for rows.Next() {
+		rule, err := scanURLRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Create inserts a rule; a second rule for the same domain fails on the UNIQUE constraint
+func (ur *URLRuleRepository) Create(ctx context.Context, rule *utils.URLRule) (*models.URLRule, error) {
+	created, err := scanURLRule(ur.db.QueryRowContext(ctx,
+		`INSERT INTO url_rules (domain, keep_params, drop_params, keep_fragment, redirect_param, resolve_canonical)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+urlRuleColumns,
+		rule.Domain, pq.StringArray(nonNil(rule.KeepParams)), pq.StringArray(nonNil(rule.DropParams)),
+		rule.KeepFragment, nullIfEmpty(rule.RedirectParam), rule.ResolveCanonical,
+	))
+	return created, uniqueDomainError(err)
+}
+
+// Update replaces a rule, returning nil when it doesn't exist
+func (ur *URLRuleRepository) Update(ctx context.Context, id int64, rule *utils.URLRule) (*models.URLRule, error) {
+	updated, err := scanURLRule(ur.db.QueryRowContext(ctx,
+		`UPDATE url_rules
+		 SET domain = $1, keep_params = $2, drop_params = $3, keep_fragment = $4, redirect_param = $5,
+		     resolve_canonical = $6, updated_at = NOW()
+		 WHERE id = $7
+		 RETURNING `+urlRuleColumns,
+		rule.Domain, pq.StringArray(nonNil(rule.KeepParams)), pq.StringArray(nonNil(rule.DropParams)),
+		rule.KeepFragment, nullIfEmpty(rule.RedirectParam), rule.ResolveCanonical, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return updated, uniqueDomainError(err)
+}
+
+// Delete removes a rule, reporting whether it existed
+func (ur *URLRuleRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := ur.db.ExecContext(ctx, `DELETE FROM url_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// scanURLRule scans a row selected with urlRuleColumns
+func scanURLRule(row rowScanner) (*models.URLRule, error) {
+	rule := &models.URLRule{}
+	var keepParams, dropParams pq.StringArray
+	var redirectParam sql.NullString
+
+	err := row.Scan(&rule.ID, &rule.Domain, &keepParams, &dropParams, &rule.KeepFragment, &redirectParam,
+		&rule.ResolveCanonical, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.KeepParams = []string(keepParams)
+	rule.DropParams = []string(dropParams)
+	rule.RedirectParam = redirectParam.String
+	return rule, nil
+}
+
+// uniqueDomainError maps a violation of the domain UNIQUE constraint to ErrURLRuleExists
+func uniqueDomainError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrURLRuleExists
+	}
+	return err
+}
+
+// nonNil stores a missing list as an empty array, matching the NOT NULL DEFAULT '{}' columns
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+// WebSubRepository handles websub_subscriptions database operations
+type WebSubRepository struct {
+	db *sql.DB
+}
+
+// NewWebSubRepository creates a new WebSub subscription repository
+func NewWebSubRepository(db *sql.DB) *WebSubRepository {
+	return &WebSubRepository{db: db}
+}
+
+const webSubColumns = `source_id, hub_url, topic_url, secret, state, lease_seconds, expires_at,
+	last_push_at, last_error, created_at, updated_at`
+
+// Get returns the subscription of a source, or nil when there is none
+func (wr *WebSubRepository) Get(ctx context.Context, sourceID int64) (*models.WebSubSubscription, error) {
+	sub, err := scanWebSubSubscription(wr.db.QueryRowContext(ctx,
+		`SELECT `+webSubColumns+` FROM websub_subscriptions WHERE source_id = $1`, sourceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sub, err
+}
+
+// SavePending records a subscription request that is waiting for hub verification.
+// An existing row for the source is replaced, including its secret; renewing an active
+// subscription for the same hub and topic keeps it active until the hub verifies again.
+func (wr *WebSubRepository) SavePending(ctx context.Context, sub *models.WebSubSubscription) error {
+	_, err := wr.db.ExecContext(ctx,
+		`INSERT INTO websub_subscriptions (source_id, hub_url, topic_url, secret, state, lease_seconds, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		 ON CONFLICT (source_id) DO UPDATE SET
+		     state = CASE WHEN websub_subscriptions.state = 'active'
+		                   AND websub_subscriptions.hub_url = EXCLUDED.hub_url
+		                   AND websub_subscriptions.topic_url = EXCLUDED.topic_url
+		                  THEN websub_subscriptions.state ELSE EXCLUDED.state END,
+		     hub_url = EXCLUDED.hub_url, topic_url = EXCLUDED.topic_url, secret = EXCLUDED.secret,
+		     lease_seconds = EXCLUDED.lease_seconds, last_error = NULL, updated_at = NOW()`,
+		sub.SourceID, sub.HubURL, sub.TopicURL, sub.Secret, models.WebSubStatePending, sub.LeaseSeconds,
+	)
+	return err
+}
+
+// MarkActive stores the lease granted by the hub's verification request
+func (wr *WebSubRepository) MarkActive(ctx context.Context, sourceID int64, leaseSeconds int, expiresAt time.Time) error {
+	_, err := wr.db.ExecContext(ctx,
+		`UPDATE websub_subscriptions
+		 SET state = $1, lease_seconds = $2, expires_at = $3, last_error = NULL, updated_at = NOW()
+		 WHERE source_id = $4`,
+		models.WebSubStateActive, leaseSeconds, expiresAt, sourceID,
+	)
+	return err
+}
+
+// MarkDenied records that the hub refused (or stopped honoring) the subscription
+func (wr *WebSubRepository) MarkDenied(ctx context.Context, sourceID int64, reason string) error {
+	_, err := wr.db.ExecContext(ctx,
+		`UPDATE websub_subscriptions SET state = $1, last_error = $2, updated_at = NOW() WHERE source_id = $3`,
+		models.WebSubStateDenied, nullIfEmpty(reason), sourceID,
+	)
+	return err
+}
+
+// RecordError stores the error of a failed subscription request without changing state
+func (wr *WebSubRepository) RecordError(ctx context.Context, sourceID int64, lastError string) error {
+	_, err := wr.db.ExecContext(ctx,
+		`UPDATE websub_subscriptions SET last_error = $1, updated_at = NOW() WHERE source_id = $2`,
+		lastError, sourceID,
+	)
+	return err
+}
+
+// RecordPush stamps the time of the latest content distribution
+func (wr *WebSubRepository) RecordPush(ctx context.Context, sourceID int64, at time.Time) error {
+	_, err := wr.db.ExecContext(ctx,
+		`UPDATE websub_subscriptions SET last_push_at = $1 WHERE source_id = $2`, at, sourceID)
+	return err
+}
+
+// ListExpiring returns active subscriptions whose lease ends before the given time
+func (wr *WebSubRepository) ListExpiring(ctx context.Context, before time.Time) ([]*models.WebSubSubscription, error) {
+	rows, err := wr.db.QueryContext(ctx,
+		`SELECT `+webSubColumns+` FROM websub_subscriptions
+		 WHERE state = $1 AND expires_at < $2
+		 ORDER BY expires_at`,
+		models.WebSubStateActive, before,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*models.WebSubSubscription
+	for rows.Next() {
+		sub, err := scanWebSubSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// ActiveSourceIDs returns the sources with an unexpired, verified subscription
+func (wr *WebSubRepository) ActiveSourceIDs(ctx context.Context, now time.Time) (map[int64]bool, error) {
+	rows, err := wr.db.QueryContext(ctx,
+		`SELECT source_id FROM websub_subscriptions WHERE state = $1 AND expires_at > $2`,
+		models.WebSubStateActive, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// scanWebSubSubscription scans a row selected with webSubColumns
+func scanWebSubSubscription(row rowScanner) (*models.WebSubSubscription, error) {
+	sub := &models.WebSubSubscription{}
+	var expiresAt, lastPushAt sql.NullTime
+	var lastError sql.NullString
+
+	err := row.Scan(&sub.SourceID, &sub.HubURL, &sub.TopicURL, &sub.Secret, &sub.State, &sub.LeaseSeconds,
+		&expiresAt, &lastPushAt, &lastError, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		sub.ExpiresAt = &expiresAt.Time
+	}
+	if lastPushAt.Valid {
+		sub.LastPushAt = &lastPushAt.Time
+	}
+	if lastError.Valid {
+		sub.LastError = &lastError.String
+	}
+	return sub, nil
+}
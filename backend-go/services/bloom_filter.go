@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spaolacci/murmur3"
+)
+
+// BloomConfig sizes the windowed Bloom filter in front of the Redis dedup keys
+type BloomConfig struct {
+	Capacity     int           // URLs expected within one window
+	FPR          float64       // target false positive rate across the whole window
+	Window       time.Duration // how long a URL is remembered; matches the dedup:url key TTL
+	SlotDuration time.Duration // the window is made of slots this long, dropped whole as they age out
+	SnapshotPath string        // file the filter is saved to besides Redis; empty keeps it in Redis only
+	// Replicated is set when several replicas ingest. Each filter then only holds the URLs its
+	// own replica saw, so the Redis snapshot, which all of them share, is neither saved nor
+	// restored: a replica without a disk snapshot rebuilds from the content table instead.
+	Replicated bool
+}
+
+// DefaultBloomConfig remembers a million URLs for 7 days at a 0.1% false positive rate
+var DefaultBloomConfig = BloomConfig{
+	Capacity:     1000000,
+	FPR:          0.001,
+	Window:       dedupTTL,
+	SlotDuration: 24 * time.Hour,
+}
+
+// withDefaults fills zero fields from DefaultBloomConfig
+func (c BloomConfig) withDefaults() BloomConfig {
+	if c.Capacity <= 0 {
+		c.Capacity = DefaultBloomConfig.Capacity
+	}
+	if c.FPR <= 0 || c.FPR >= 1 {
+		c.FPR = DefaultBloomConfig.FPR
+	}
+	if c.Window <= 0 {
+		c.Window = DefaultBloomConfig.Window
+	}
+	if c.SlotDuration <= 0 || c.SlotDuration > c.Window {
+		c.SlotDuration = DefaultBloomConfig.SlotDuration
+		if c.SlotDuration > c.Window {
+			c.SlotDuration = c.Window
+		}
+	}
+	return c
+}
+
+// BloomFilter is a fixed-size Bloom filter with k hash functions derived from one 128-bit
+// murmur3 hash (Kirsch–Mitzenmacher double hashing). It is not safe for concurrent use.
+type BloomFilter struct {
+	bits  []uint64
+	m     uint64 // number of bits
+	k     uint32 // number of hash functions
+	count uint64 // items added
+}
+
+// NewBloomFilter sizes a filter for capacity items at the given false positive rate:
+// m = -n·ln(p)/ln(2)² bits and k = m/n·ln(2) hash functions
+func NewBloomFilter(capacity int, fpr float64) *BloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpr <= 0 || fpr >= 1 {
+		fpr = DefaultBloomConfig.FPR
+	}
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpr) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &BloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// Add adds an item to the bloom filter
+func (bf *BloomFilter) Add(item string) {
+	h1, h2 := murmur3.Sum128([]byte(item))
+	for i := uint64(0); i < uint64(bf.k); i++ {
+		pos := (h1 + i*h2) % bf.m
+		bf.bits[pos/64] |= 1 << (pos % 64)
+	}
+	bf.count++
+}
+
+// Contains checks if an item might exist in the bloom filter
+func (bf *BloomFilter) Contains(item string) bool {
+	h1, h2 := murmur3.Sum128([]byte(item))
+	for i := uint64(0); i < uint64(bf.k); i++ {
+		pos := (h1 + i*h2) % bf.m
+		if bf.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// EstimatedFPR is the expected false positive rate at the current fill: (1 - e^(-kn/m))^k
+func (bf *BloomFilter) EstimatedFPR() float64 {
+	return math.Pow(1-math.Exp(-float64(bf.k)*float64(bf.count)/float64(bf.m)), float64(bf.k))
+}
+
+// bloomSlot is the part of the window starting at start
+type bloomSlot struct {
+	start  time.Time
+	filter *BloomFilter
+}
+
+// WindowedBloomFilter remembers items for a sliding window. The window is split into slots of
+// SlotDuration, each its own BloomFilter; an item lands in the slot of the time it was seen and
+// is forgotten when that slot ages out of the window. Each slot gets an equal share of the
+// capacity and of the false positive budget, so a lookup across all slots meets the target FPR.
+type WindowedBloomFilter struct {
+	mu           sync.RWMutex
+	cfg          BloomConfig
+	slotCapacity int
+	slotFPR      float64
+	slots        []*bloomSlot // oldest first
+	now          func() time.Time
+}
+
+// NewWindowedBloomFilter creates an empty filter; zero config fields fall back to the defaults
+func NewWindowedBloomFilter(cfg BloomConfig) *WindowedBloomFilter {
+	cfg = cfg.withDefaults()
+	slots := int((cfg.Window + cfg.SlotDuration - 1) / cfg.SlotDuration)
+	return &WindowedBloomFilter{
+		cfg:          cfg,
+		slotCapacity: (cfg.Capacity + slots - 1) / slots,
+		// The window may straddle one more, partial slot
+		slotFPR: cfg.FPR / float64(slots+1),
+		now:     time.Now,
+	}
+}
+
+// Add remembers an item as seen now
+func (w *WindowedBloomFilter) Add(item string) {
+	w.AddAt(item, w.now())
+}
+
+// AddAt remembers an item as seen at a given time; items older than the window are ignored
+func (w *WindowedBloomFilter) AddAt(item string, at time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.expireLocked(now)
+	if !at.After(now.Add(-w.cfg.Window)) {
+		return
+	}
+	if at.After(now) {
+		at = now
+	}
+	w.slotLocked(at).filter.Add(item)
+}
+
+// Contains reports whether an item may have been seen within the window
+func (w *WindowedBloomFilter) Contains(item string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	cutoff := w.now().Add(-w.cfg.Window)
+	for _, slot := range w.slots {
+		if slot.start.Add(w.cfg.SlotDuration).After(cutoff) && slot.filter.Contains(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expire drops the slots that ended before the window and returns how many were dropped
+func (w *WindowedBloomFilter) Expire() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.expireLocked(w.now())
+}
+
+func (w *WindowedBloomFilter) expireLocked(now time.Time) int {
+	cutoff := now.Add(-w.cfg.Window)
+	dropped := 0
+	for dropped < len(w.slots) && !w.slots[dropped].start.Add(w.cfg.SlotDuration).After(cutoff) {
+		dropped++
+	}
+	w.slots = w.slots[dropped:]
+	return dropped
+}
+
+// slotLocked returns the slot covering at, creating it if needed
+func (w *WindowedBloomFilter) slotLocked(at time.Time) *bloomSlot {
+	start := at.Truncate(w.cfg.SlotDuration)
+	i := sort.Search(len(w.slots), func(i int) bool { return !w.slots[i].start.Before(start) })
+	if i < len(w.slots) && w.slots[i].start.Equal(start) {
+		return w.slots[i]
+	}
+	slot := &bloomSlot{start: start, filter: NewBloomFilter(w.slotCapacity, w.slotFPR)}
+	w.slots = append(w.slots, nil)
+	copy(w.slots[i+1:], w.slots[i:])
+	w.slots[i] = slot
+	return slot
+}
+
+// BloomStats describes the state of the windowed filter
+type BloomStats struct {
+	Slots        int     `json:"slots"`
+	Items        uint64  `json:"items"`
+	Bits         uint64  `json:"bits"`
+	Hashes       uint32  `json:"hashes"`
+	Capacity     int     `json:"capacity"`
+	TargetFPR    float64 `json:"target_fpr"`
+	EstimatedFPR float64 `json:"estimated_fpr"` // at the current fill, across all slots
+	Window       string  `json:"window"`
+}
+
+// Stats reports the size and fill of the filter
+func (w *WindowedBloomFilter) Stats() BloomStats {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	stats := BloomStats{
+		Slots:     len(w.slots),
+		Capacity:  w.cfg.Capacity,
+		TargetFPR: w.cfg.FPR,
+		Window:    w.cfg.Window.String(),
+	}
+	pass := 1.0 // probability that no slot reports a false positive
+	for _, slot := range w.slots {
+		stats.Items += slot.filter.count
+		stats.Bits += slot.filter.m
+		stats.Hashes = slot.filter.k
+		pass *= 1 - slot.filter.EstimatedFPR()
+	}
+	stats.EstimatedFPR = 1 - pass
+	return stats
+}
+
+// bloomSnapshot is the gob encoding of a WindowedBloomFilter
+type bloomSnapshot struct {
+	Capacity     int
+	FPR          float64
+	Window       time.Duration
+	SlotDuration time.Duration
+	Slots        []bloomSlotSnapshot
+}
+
+type bloomSlotSnapshot struct {
+	Start time.Time
+	M     uint64
+	K     uint32
+	Count uint64
+	Bits  []uint64
+}
+
+// errBloomSnapshotMismatch means a snapshot was taken with a different sizing and can't be reused
+var errBloomSnapshotMismatch = errors.New("bloom snapshot was taken with a different configuration")
+
+// MarshalBinary snapshots the filter
+func (w *WindowedBloomFilter) MarshalBinary() ([]byte, error) {
+	w.mu.RLock()
+	snap := bloomSnapshot{
+		Capacity:     w.cfg.Capacity,
+		FPR:          w.cfg.FPR,
+		Window:       w.cfg.Window,
+		SlotDuration: w.cfg.SlotDuration,
+		Slots:        make([]bloomSlotSnapshot, len(w.slots)),
+	}
+	for i, slot := range w.slots {
+		snap.Slots[i] = bloomSlotSnapshot{
+			Start: slot.start,
+			M:     slot.filter.m,
+			K:     slot.filter.k,
+			Count: slot.filter.count,
+			Bits:  slot.filter.bits,
+		}
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&snap)
+	w.mu.RUnlock()
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary restores a snapshot taken with the same configuration, dropping expired slots
+func (w *WindowedBloomFilter) UnmarshalBinary(data []byte) error {
+	var snap bloomSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snap); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if snap.Capacity != w.cfg.Capacity || snap.FPR != w.cfg.FPR ||
+		snap.Window != w.cfg.Window || snap.SlotDuration != w.cfg.SlotDuration {
+		return errBloomSnapshotMismatch
+	}
+	slots := make([]*bloomSlot, 0, len(snap.Slots))
+	for _, s := range snap.Slots {
+		if s.M == 0 || uint64(len(s.Bits)) != (s.M+63)/64 {
+			return errors.New("corrupt bloom snapshot")
+		}
+		slots = append(slots, &bloomSlot{
+			start:  s.Start,
+			filter: &BloomFilter{bits: s.Bits, m: s.M, k: s.K, count: s.Count},
+		})
+	}
+	w.slots = slots
+	w.expireLocked(w.now())
+	return nil
+}
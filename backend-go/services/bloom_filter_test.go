@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewBloomFilterSizing(t *testing.T) {
+	// 1M items at 1%: m ≈ 9.59M bits, k ≈ 7
+	bf := NewBloomFilter(1000000, 0.01)
+	if bf.m < 9500000 || bf.m > 9700000 {
+		t.Errorf("m = %d, want about 9.59M bits", bf.m)
+	}
+	if bf.k != 7 {
+		t.Errorf("k = %d, want 7", bf.k)
+	}
+}
+
+func TestBloomFilterFalsePositiveRate(t *testing.T) {
+	const n = 10000
+	bf := NewBloomFilter(n, 0.01)
+	for i := 0; i < n; i++ {
+		bf.Add(fmt.Sprintf("https://example.com/post/%d", i))
+	}
+	for i := 0; i < n; i++ {
+		if !bf.Contains(fmt.Sprintf("https://example.com/post/%d", i)) {
+			t.Fatalf("added item %d reported missing", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if bf.Contains(fmt.Sprintf("https://example.org/other/%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 0.02 {
+		t.Errorf("false positive rate %.4f, want about 0.01", rate)
+	}
+}
+
+func TestWindowedBloomFilterExpiresSlots(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	w := NewWindowedBloomFilter(BloomConfig{Capacity: 1000, FPR: 0.01, Window: 72 * time.Hour, SlotDuration: 24 * time.Hour})
+	w.now = func() time.Time { return now }
+
+	w.AddAt("old", now.Add(-60*time.Hour))
+	w.Add("new")
+	w.AddAt("too-old", now.Add(-80*time.Hour))
+	if !w.Contains("old") || !w.Contains("new") {
+		t.Fatal("items within the window should be found")
+	}
+	if w.Contains("too-old") {
+		t.Error("an item older than the window should not be added")
+	}
+
+	// 2024-02-27 slot (holding "old") ends at 2024-02-28 00:00, outside a window starting 2024-02-28 12:00
+	now = now.Add(36 * time.Hour)
+	if w.Contains("old") {
+		t.Error("item should be forgotten once its slot leaves the window")
+	}
+	if !w.Contains("new") {
+		t.Error("recent item should still be found")
+	}
+	if dropped := w.Expire(); dropped != 1 {
+		t.Errorf("Expire dropped %d slots, want 1", dropped)
+	}
+}
+
+func TestWindowedBloomFilterSnapshotRoundTrip(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := BloomConfig{Capacity: 1000, FPR: 0.01, Window: 72 * time.Hour, SlotDuration: 24 * time.Hour}
+	w := NewWindowedBloomFilter(cfg)
+	w.now = func() time.Time { return now }
+	w.Add("https://example.com/a")
+	w.AddAt("https://example.com/b", now.Add(-30*time.Hour))
+
+	data, err := w.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewWindowedBloomFilter(cfg)
+	restored.now = w.now
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Contains("https://example.com/a") || !restored.Contains("https://example.com/b") {
+		t.Error("restored filter lost items")
+	}
+	if got := restored.Stats(); got.Slots != 2 || got.Items != 2 {
+		t.Errorf("restored stats = %+v, want 2 slots with 2 items", got)
+	}
+
+	cfg.FPR = 0.001
+	if err := NewWindowedBloomFilter(cfg).UnmarshalBinary(data); err != errBloomSnapshotMismatch {
+		t.Errorf("snapshot with another FPR: err = %v, want mismatch", err)
+	}
+}
+
+func TestDatabaseDuplicateJoinsBloomFilter(t *testing.T) {
+	// A URL another replica ingested: this filter never saw it, so only the database caught it
+	ds := NewDedupService(nil, nil)
+	url := "https://example.com/posts/1"
+	if ds.bloomFilter.Contains(url) {
+		t.Fatal("fresh filter should not contain the URL")
+	}
+	ds.RecordDatabaseDuplicate(url)
+	if !ds.bloomFilter.Contains(url) {
+		t.Error("a database duplicate should go into the bloom filter so the next poll stops at Redis")
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// defaultRevisionMinChange is the share of changed text that makes an edit significant
+const defaultRevisionMinChange = 0.2
+
+// RevisionPolicy decides what happens when the feed serves different text for an ingested URL
+type RevisionPolicy struct {
+	Requeue        bool    // send significant edits back to the evaluator
+	MinChangeRatio float64 // share of changed text that counts as significant; a new title always does
+}
+
+// SetRevisionTracking enables recording edits of already-ingested items
+func (rs *RSSService) SetRevisionTracking(repo *repositories.ContentRevisionRepository, policy RevisionPolicy) {
+	if policy.MinChangeRatio <= 0 {
+		policy.MinChangeRatio = defaultRevisionMinChange
+	}
+	rs.revisionRepo = repo
+	rs.revisionPolicy = policy
+}
+
+// checkRevision looks for edits of an item whose URL was already ingested and records a new
+// revision when its text changed. Unchanged items cost one Redis GET: the body hash is kept in
+// the URL's dedup key, and the database is only consulted when that hash differs or expired.
+func (rs *RSSService) checkRevision(ctx context.Context, source *models.Source, item *utils.FeedItem, bodyHash string) bool {
+	if rs.revisionRepo == nil || item.URL == "" {
+		return false
+	}
+	if seen, err := rs.dedupService.SeenHash(ctx, item.URL); err == nil && seen == bodyHash {
+		return false
+	}
+
+	current, err := rs.revisionRepo.Current(ctx, item.URL)
+	if err != nil {
+		log.Printf("[Revision] Failed to load %s: %v", item.URL, err)
+		return false
+	}
+	if current == nil {
+		return false // a duplicate by content hash, not by URL
+	}
+	if current.BodyHash == "" {
+		// Ingested before body hashes were stored
+		current.BodyHash = utils.GenerateBodyHash(current.Title, current.CleanContent)
+		if err := rs.revisionRepo.UpdateBodyHash(ctx, current.ContentID, current.BodyHash); err != nil {
+			log.Printf("[Revision] Failed to backfill body hash of content %d: %v", current.ContentID, err)
+		}
+	}
+	if current.BodyHash == bodyHash {
+		// Refresh the dedup key, which may still hold a value from before body hashes
+		rs.dedupService.MarkAsSeen(ctx, item.URL, bodyHash)
+		return false
+	}
+
+	next, significant := nextRevision(current, item.Title, item.Content, bodyHash, rs.revisionPolicy)
+	next.FullMarkdown = item.FullContent
+	requeue := significant && rs.revisionPolicy.Requeue
+	recorded, err := rs.revisionRepo.Record(ctx, current, next, requeue)
+	if err != nil {
+		log.Printf("[Revision] Failed to record revision of content %d: %v", current.ContentID, err)
+		return false
+	}
+	if err := rs.dedupService.MarkAsSeen(ctx, item.URL, bodyHash); err != nil {
+		log.Printf("Warning: Failed to mark URL as seen: %v", err)
+	}
+	if !recorded {
+		return false // another worker recorded this edit first
+	}
+	log.Printf("[Revision] Content %d changed (revision %d, %.0f%% of text, requeued=%v): %s",
+		current.ContentID, next.Revision, next.ChangeRatio*100, requeue, item.Title)
+
+	if requeue {
+		content, err := rs.contentRepo.GetByID(ctx, current.ContentID)
+		if err != nil || content == nil {
+			log.Printf("[Revision] Failed to reload content %d for evaluation: %v", current.ContentID, err)
+			return true
+		}
+		if err := rs.contentService.PublishToStream(ctx, content, source.EffectiveStreamPayload()); err != nil {
+			// Left PENDING; the evaluator picks it up when it requeues pending content
+			log.Printf("Error publishing revised content to stream: %v", err)
+		}
+	}
+	return true
+}
+
+// nextRevision builds the revision that follows current and decides whether the edit is
+// significant: a changed title, or at least policy.MinChangeRatio of the text rewritten
+func nextRevision(current *models.ContentRevision, title, content, bodyHash string, policy RevisionPolicy) (*models.ContentRevision, bool) {
+	ratio := utils.ChangeRatio(utils.DiffText(current.CleanContent, content))
+	next := &models.ContentRevision{
+		ContentID:    current.ContentID,
+		Revision:     current.Revision + 1,
+		Title:        title,
+		CleanContent: content,
+		BodyHash:     bodyHash,
+		ChangeRatio:  ratio,
+	}
+	return next, title != current.Title || ratio >= policy.MinChangeRatio
+}
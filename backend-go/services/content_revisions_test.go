@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+func TestNextRevisionSignificance(t *testing.T) {
+	current := &models.ContentRevision{
+		ContentID:    7,
+		Revision:     2,
+		Title:        "Release notes",
+		CleanContent: "One. Two. Three. Four. Five. Six. Seven. Eight. Nine. Ten.",
+	}
+	policy := RevisionPolicy{MinChangeRatio: 0.2}
+
+	// A typo fix in one sentence out of ten
+	next, significant := nextRevision(current, current.Title,
+		"One. Two. Three. Four. Five. Six. Seven. Eight. Nine. Ten!", "h1", policy)
+	if next.Revision != 3 || next.ContentID != 7 || next.BodyHash != "h1" {
+		t.Errorf("next revision = %+v", next)
+	}
+	if significant {
+		t.Errorf("small edit (ratio %.2f) should not be significant", next.ChangeRatio)
+	}
+
+	// Most of the text rewritten
+	next, significant = nextRevision(current, current.Title, "Completely different text. Nothing stays.", "h2", policy)
+	if !significant {
+		t.Errorf("rewrite (ratio %.2f) should be significant", next.ChangeRatio)
+	}
+
+	// A new title is significant on its own
+	if _, significant = nextRevision(current, "Release notes (updated)", current.CleanContent, "h3", policy); !significant {
+		t.Error("title change should be significant")
+	}
+}
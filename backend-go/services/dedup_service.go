@@ -2,91 +2,217 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/spaolacci/murmur3"
 	"github.com/junkfilter/backend-go/repositories"
 	"github.com/junkfilter/backend-go/utils"
 )
 
-// BloomFilter simple in-memory bloom filter implementation
-type BloomFilter struct {
-	bits []byte
-	size uint64
-}
-
-// NewBloomFilter creates a new bloom filter with 7-day window
-func NewBloomFilter(expectedItems int) *BloomFilter {
-	// Estimate bit size: -1/(ln(2)^2) * n * ln(p)
-	// For 1 million items and 0.1% error rate
-	size := uint64(expectedItems * 1000 / 100) // ~10M bits
-	return &BloomFilter{
-		bits: make([]byte, size/8+1),
-		size: size,
-	}
-}
+// dedupTTL is how long a URL or content hash is remembered
+const dedupTTL = 7 * 24 * time.Hour
 
-// Add adds an item to the bloom filter
-func (bf *BloomFilter) Add(item string) {
-	h1 := murmur3.Sum32([]byte(item))
-	h2 := murmur3.Sum32([]byte(item + "2"))
-
-	pos1 := uint64(h1) % bf.size
-	pos2 := uint64(h2) % bf.size
-
-	bf.bits[pos1/8] |= 1 << (pos1 % 8)
-	bf.bits[pos2/8] |= 1 << (pos2 % 8)
-}
+// dedupMaintenanceInterval is how often expired bloom slots are dropped, the filter is
+// snapshotted and the layer counters are flushed
+const dedupMaintenanceInterval = 10 * time.Minute
 
-// Contains checks if an item might exist in the bloom filter
-func (bf *BloomFilter) Contains(item string) bool {
-	h1 := murmur3.Sum32([]byte(item))
-	h2 := murmur3.Sum32([]byte(item + "2"))
-
-	pos1 := uint64(h1) % bf.size
-	pos2 := uint64(h2) % bf.size
+const (
+	bloomSnapshotKey = "dedup:bloom:snapshot"
+	dedupStatsKey    = "dedup:stats"
+	dedupScanBatch   = 1000
+)
 
-	return (bf.bits[pos1/8]&(1<<(pos1%8)) != 0) && (bf.bits[pos2/8]&(1<<(pos2%8)) != 0)
+// dedupCounters counts how each dedup layer answered since the last flush to Redis
+type dedupCounters struct {
+	l1Negative atomic.Int64 // bloom filter: definitely new, Redis not queried
+	l2Hit      atomic.Int64 // bloom positive confirmed by the Redis key
+	l2Miss     atomic.Int64 // bloom false positive
+	l3Hit      atomic.Int64 // duplicate only caught by the database UNIQUE constraint
+	hashHit    atomic.Int64 // new URL carrying an already-seen content hash
 }
 
 // DedupService handles three-layer deduplication
 type DedupService struct {
-	bloomFilter *BloomFilter
+	bloomFilter *WindowedBloomFilter
+	bloomConfig BloomConfig
 	redis       *redis.Client
 	contentRepo *repositories.ContentRepository
+	counters    dedupCounters
+	loaded      atomic.Bool // the bloom filter was initialized; false in an API-only process
 }
 
 // NewDedupService creates a new dedup service
 func NewDedupService(redis *redis.Client, contentRepo *repositories.ContentRepository) *DedupService {
 	return &DedupService{
-		bloomFilter: NewBloomFilter(1000000),
+		bloomFilter: NewWindowedBloomFilter(DefaultBloomConfig),
+		bloomConfig: DefaultBloomConfig.withDefaults(),
 		redis:       redis,
 		contentRepo: contentRepo,
 	}
 }
 
-// InitializeBloomFilter loads existing URLs from DB into bloom filter
+// Configure resizes the bloom filter; call it before InitializeBloomFilter, as it starts empty
+func (ds *DedupService) Configure(cfg BloomConfig) {
+	ds.bloomConfig = cfg.withDefaults()
+	ds.bloomFilter = NewWindowedBloomFilter(ds.bloomConfig)
+}
+
+// InitializeBloomFilter restores the bloom filter from the disk snapshot, else the Redis
+// snapshot (unless replicated), else rebuilds it from the content table, falling back to
+// scanning the dedup:url keys
 func (ds *DedupService) InitializeBloomFilter(ctx context.Context) error {
-	// Load last 7 days of content
-	// This is a simplified version - in production, you'd query all URLs from last 7 days
-	// For now, we'll just load URLs from Redis keys
+	defer ds.loaded.Store(true)
 
-	keys, err := ds.redis.Keys(ctx, "dedup:url:*").Result()
-	if err != nil && err != redis.Nil {
+	if path := ds.bloomConfig.SnapshotPath; path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if err = ds.bloomFilter.UnmarshalBinary(data); err == nil {
+				log.Printf("✓ Bloom filter restored from %s (%d items)", path, ds.bloomFilter.Stats().Items)
+				return nil
+			}
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Warning: Ignoring bloom snapshot %s: %v", path, err)
+		}
+	}
+
+	if !ds.bloomConfig.Replicated {
+		data, err := ds.redis.Get(ctx, bloomSnapshotKey).Bytes()
+		if err == nil {
+			if err = ds.bloomFilter.UnmarshalBinary(data); err == nil {
+				log.Printf("✓ Bloom filter restored from Redis (%d items)", ds.bloomFilter.Stats().Items)
+				return nil
+			}
+		}
+		if err != redis.Nil {
+			log.Printf("Warning: Ignoring Redis bloom snapshot: %v", err)
+		}
+	}
+
+	source := "content table"
+	if err := ds.rebuildFromContent(ctx); err != nil {
+		log.Printf("Warning: Failed to rebuild bloom filter from content: %v", err)
+		source = "dedup:url keys"
+		ds.bloomFilter = NewWindowedBloomFilter(ds.bloomConfig)
+		if err := ds.rebuildFromRedis(ctx); err != nil {
+			return err
+		}
+	}
+	log.Printf("✓ Bloom filter rebuilt from %s (%d items)", source, ds.bloomFilter.Stats().Items)
+	return ds.SaveSnapshot(ctx)
+}
+
+// rebuildFromContent adds every URL ingested within the window, each in the slot of its ingestion time
+func (ds *DedupService) rebuildFromContent(ctx context.Context) error {
+	if ds.contentRepo == nil {
+		return errors.New("no content repository")
+	}
+	since := time.Now().Add(-ds.bloomConfig.Window)
+	return ds.contentRepo.EachURLSince(ctx, since, func(url string, ingestedAt time.Time) error {
+		ds.bloomFilter.AddAt(url, ingestedAt)
+		return nil
+	})
+}
+
+// rebuildFromRedis adds the URL of every dedup:url key, walking them with SCAN so Redis is
+// never blocked. A key's remaining TTL tells when it was set, which picks the URL's slot.
+func (ds *DedupService) rebuildFromRedis(ctx context.Context) error {
+	const prefix = "dedup:url:"
+	var cursor uint64
+	for {
+		keys, next, err := ds.redis.Scan(ctx, cursor, prefix+"*", dedupScanBatch).Result()
+		if err != nil {
+			return err
+		}
+
+		pipe := ds.redis.Pipeline()
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		if len(keys) > 0 {
+			if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		for i, key := range keys {
+			seenAt := now
+			if ttl := ttls[i].Val(); ttl > 0 && ttl < dedupTTL {
+				seenAt = now.Add(ttl - dedupTTL)
+			}
+			ds.bloomFilter.AddAt(key[len(prefix):], seenAt)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// SaveSnapshot writes the bloom filter to Redis, unless replicated, and, when configured, to disk
+func (ds *DedupService) SaveSnapshot(ctx context.Context) error {
+	data, err := ds.bloomFilter.MarshalBinary()
+	if err != nil {
 		return err
 	}
 
-	for _, key := range keys {
-		// Extract URL from key format "dedup:url:{hash}"
-		if len(key) > 11 {
-			urlHash := key[11:]
-			ds.bloomFilter.Add(urlHash)
+	var errs []error
+	if !ds.bloomConfig.Replicated {
+		if err := ds.redis.Set(ctx, bloomSnapshotKey, data, ds.bloomConfig.Window).Err(); err != nil {
+			errs = append(errs, fmt.Errorf("redis: %w", err))
 		}
 	}
+	if path := ds.bloomConfig.SnapshotPath; path != "" {
+		if err := writeFileAtomic(path, data); err != nil {
+			errs = append(errs, fmt.Errorf("disk: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	return nil
+// writeFileAtomic replaces path with data through a temporary file, so a crash mid-write
+// leaves the previous snapshot intact
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Maintain drops expired bloom slots, saves a snapshot and flushes the layer counters to Redis
+func (ds *DedupService) Maintain(ctx context.Context) {
+	if dropped := ds.bloomFilter.Expire(); dropped > 0 {
+		log.Printf("[Dedup] Dropped %d expired bloom filter slot(s)", dropped)
+	}
+	if err := ds.SaveSnapshot(ctx); err != nil {
+		log.Printf("Warning: Failed to save bloom filter snapshot: %v", err)
+	}
+	if err := ds.flushStats(ctx); err != nil {
+		log.Printf("Warning: Failed to flush dedup stats: %v", err)
+	}
 }
 
 // IsDuplicate checks if URL is duplicate using three-layer dedup.
@@ -103,9 +229,13 @@ func (ds *DedupService) IsDuplicate(ctx context.Context, url, contentHash string
 		}
 
 		if exists > 0 {
+			ds.counters.l2Hit.Add(1)
 			return true, nil // Confirmed duplicate
 		}
 		// L1 false positive: Bloom Filter said "seen" but Redis says "not seen" → treat as new
+		ds.counters.l2Miss.Add(1)
+	} else {
+		ds.counters.l1Negative.Add(1)
 	}
 
 	// L3: Database UNIQUE constraint on original_url catches concurrent inserts
@@ -113,6 +243,28 @@ func (ds *DedupService) IsDuplicate(ctx context.Context, url, contentHash string
 	return false, nil
 }
 
+// RecordDatabaseDuplicate counts a duplicate of url that passed L1+L2 and was rejected by the
+// database. The URL goes into the bloom filter, which misses URLs another replica ingested or
+// that were restored from an older snapshot, so the next poll stops at Redis.
+func (ds *DedupService) RecordDatabaseDuplicate(url string) {
+	ds.counters.l3Hit.Add(1)
+	ds.bloomFilter.Add(url)
+}
+
+// SeenHash returns the hash stored by MarkAsSeen for a URL, or "" if the URL is not in Redis.
+// A URL found in Redis is added to the bloom filter, in case this replica's filter lacks it.
+func (ds *DedupService) SeenHash(ctx context.Context, url string) (string, error) {
+	hash, err := ds.redis.Get(ctx, fmt.Sprintf("dedup:url:%s", url)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	ds.bloomFilter.Add(url)
+	return hash, nil
+}
+
 // MarkAsSeen marks a URL/hash as seen
 func (ds *DedupService) MarkAsSeen(ctx context.Context, url, contentHash string) error {
 	// Add to bloom filter
@@ -120,7 +272,40 @@ func (ds *DedupService) MarkAsSeen(ctx context.Context, url, contentHash string)
 
 	// Add to Redis with 7-day TTL
 	redisKey := fmt.Sprintf("dedup:url:%s", url)
-	return ds.redis.Set(ctx, redisKey, contentHash, 7*24*time.Hour).Err()
+	return ds.redis.Set(ctx, redisKey, contentHash, dedupTTL).Err()
+}
+
+// FirstOutcome reports whether the source's item at url gets the outcome kind for the first time
+// within dedupTTL. Feeds list the same items on every poll; this lets the daily stats count items
+// rather than polls.
+func (ds *DedupService) FirstOutcome(ctx context.Context, sourceID int64, kind, url string) (bool, error) {
+	redisKey := fmt.Sprintf("dedup:outcome:%d:%s:%s", sourceID, kind, url)
+	return ds.redis.SetNX(ctx, redisKey, 1, dedupTTL).Result()
+}
+
+// PageSeen is what a poll recorded about an item whose article page it downloaded
+type PageSeen struct {
+	FeedHash string // body hash of the item's feed title and excerpt
+	URL      string // the URL the item was stored under, after following rel=canonical
+}
+
+// SeenPage returns what MarkPageSeen recorded for an item's canonicalized feed link, nil if nothing
+func (ds *DedupService) SeenPage(ctx context.Context, feedURL string) (*PageSeen, error) {
+	value, err := ds.redis.Get(ctx, fmt.Sprintf("dedup:page:%s", feedURL)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	feedHash, storedURL, _ := strings.Cut(value, " ")
+	return &PageSeen{FeedHash: feedHash, URL: storedURL}, nil
+}
+
+// MarkPageSeen remembers the feed text an item's page was downloaded for and where it led
+func (ds *DedupService) MarkPageSeen(ctx context.Context, feedURL string, seen PageSeen) error {
+	redisKey := fmt.Sprintf("dedup:page:%s", feedURL)
+	return ds.redis.Set(ctx, redisKey, seen.FeedHash+" "+seen.URL, dedupTTL).Err()
 }
 
 // CheckContentHash checks if content hash exists
@@ -136,7 +321,7 @@ func (ds *DedupService) CheckContentHash(ctx context.Context, hash string) (bool
 // MarkHashAsSeen marks a content hash as seen
 func (ds *DedupService) MarkHashAsSeen(ctx context.Context, hash string) error {
 	redisKey := fmt.Sprintf("dedup:hash:%s", hash)
-	return ds.redis.Set(ctx, redisKey, "1", 7*24*time.Hour).Err()
+	return ds.redis.Set(ctx, redisKey, "1", dedupTTL).Err()
 }
 
 // ValidateContent generates hash and checks for duplicates
@@ -159,6 +344,96 @@ func (ds *DedupService) ValidateContent(ctx context.Context, url, title, content
 	if err != nil {
 		return contentHash, false, err
 	}
+	if isHashDup {
+		ds.counters.hashHit.Add(1)
+	}
 
 	return contentHash, isHashDup, nil
 }
+
+// DedupStats reports how often each dedup layer settled a lookup, accumulated across replicas
+type DedupStats struct {
+	Lookups       int64 `json:"lookups"`           // URLs checked against the bloom filter
+	L1Negatives   int64 `json:"l1_negatives"`      // settled by the bloom filter alone
+	L2Hits        int64 `json:"l2_hits"`           // bloom positives confirmed by Redis
+	L2Misses      int64 `json:"l2_misses"`         // bloom false positives
+	L3Hits        int64 `json:"l3_hits"`           // duplicates only caught by the database
+	ContentHashes int64 `json:"content_hash_hits"` // new URLs with an already-seen content hash
+
+	L1Rate              float64 `json:"l1_rate"`                   // share of lookups settled without Redis
+	L2Rate              float64 `json:"l2_rate"`                   // share of lookups found duplicate in Redis
+	L3Rate              float64 `json:"l3_rate"`                   // share of lookups only caught by the database
+	BloomFalsePositives float64 `json:"bloom_false_positive_rate"` // observed, among lookups of new URLs
+
+	Bloom *BloomStats `json:"bloom,omitempty"` // this replica's filter; absent when it doesn't fetch
+}
+
+// flushStats adds the counters gathered since the last flush to the shared Redis hash
+func (ds *DedupService) flushStats(ctx context.Context) error {
+	fields := map[string]*atomic.Int64{
+		"l1_negative": &ds.counters.l1Negative,
+		"l2_hit":      &ds.counters.l2Hit,
+		"l2_miss":     &ds.counters.l2Miss,
+		"l3_hit":      &ds.counters.l3Hit,
+		"hash_hit":    &ds.counters.hashHit,
+	}
+	deltas := make(map[string]int64, len(fields))
+	pipe := ds.redis.Pipeline()
+	for field, counter := range fields {
+		if delta := counter.Swap(0); delta != 0 {
+			deltas[field] = delta
+			pipe.HIncrBy(ctx, dedupStatsKey, field, delta)
+		}
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Put the deltas back for the next flush
+		for field, delta := range deltas {
+			fields[field].Add(delta)
+		}
+		return err
+	}
+	return nil
+}
+
+// Stats flushes this replica's counters and returns the totals of all replicas
+func (ds *DedupService) Stats(ctx context.Context) (*DedupStats, error) {
+	if err := ds.flushStats(ctx); err != nil {
+		return nil, err
+	}
+	totals, err := ds.redis.HGetAll(ctx, dedupStatsKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	count := func(field string) int64 {
+		n, _ := strconv.ParseInt(totals[field], 10, 64)
+		return n
+	}
+
+	stats := &DedupStats{
+		L1Negatives:   count("l1_negative"),
+		L2Hits:        count("l2_hit"),
+		L2Misses:      count("l2_miss"),
+		L3Hits:        count("l3_hit"),
+		ContentHashes: count("hash_hit"),
+	}
+	if ds.loaded.Load() {
+		bloom := ds.bloomFilter.Stats()
+		stats.Bloom = &bloom
+	}
+	stats.Lookups = stats.L1Negatives + stats.L2Hits + stats.L2Misses
+	stats.L1Rate = ratio(stats.L1Negatives, stats.Lookups)
+	stats.L2Rate = ratio(stats.L2Hits, stats.Lookups)
+	stats.L3Rate = ratio(stats.L3Hits, stats.Lookups)
+	stats.BloomFalsePositives = ratio(stats.L2Misses, stats.L1Negatives+stats.L2Misses)
+	return stats, nil
+}
+
+func ratio(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
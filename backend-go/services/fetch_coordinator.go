@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis keys of the fetch coordination protocol
+const (
+	fetchShardKeyPrefix    = "fetch:shard:"    // fetch:shard:{n} = "{instance}|{token}", expires with the lease
+	fetchInstanceKeyPrefix = "fetch:instance:" // fetch:instance:{id} = heartbeat, expires with the lease TTL
+	fetchTokenKey          = "fetch:lease_token"
+)
+
+// Defaults for FetchCoordinatorConfig
+const (
+	defaultFetchShards   = 16
+	defaultFetchLeaseTTL = 30 * time.Second
+)
+
+// claimShardScript takes a free shard and stamps it with a fresh fencing token.
+// The token counter is global, so a newer lease always carries a larger token than any older one.
+var claimShardScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then return 0 end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. '|' .. token, 'PX', ARGV[2])
+return token`)
+
+// renewShardScript extends a lease only while it still holds our value
+var renewShardScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return 0`)
+
+// releaseShardScript deletes a lease only while it still holds our value
+var releaseShardScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`)
+
+// FetchCoordinatorConfig configures multi-replica fetch coordination
+type FetchCoordinatorConfig struct {
+	InstanceID string        // defaults to hostname-pid
+	Shards     int           // sources are split into this many shards by id
+	LeaseTTL   time.Duration // a dead replica's shards are taken over after at most this long
+}
+
+// ShardLease describes who holds a shard, as reported by the admin endpoint
+type ShardLease struct {
+	Shard     int    `json:"shard"`
+	Owner     string `json:"owner,omitempty"`
+	Token     int64  `json:"token,omitempty"`
+	TTLMillis int64  `json:"ttl_ms,omitempty"`
+}
+
+// ownedShard is a lease held by this instance
+type ownedShard struct {
+	token     int64
+	expiresAt time.Time // local, conservative view of the Redis expiry
+}
+
+// FetchCoordinator lets several backend replicas share the sources. Sources are split into
+// shards by id and each shard is leased in Redis to one replica, which renews it every TTL/3.
+// Replicas claim an equal share of the shards and hand surplus shards back when a new replica
+// appears; when a replica dies its leases expire and the others claim them.
+// Every lease carries a fencing token that is stored with the fetch results, so a replica that
+// lost its lease cannot overwrite the results of the new owner.
+type FetchCoordinator struct {
+	redis      *redis.Client
+	instanceID string
+	shards     int
+	ttl        time.Duration
+
+	mu    sync.RWMutex
+	owned map[int]ownedShard
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewFetchCoordinator creates a coordinator; zero config fields fall back to the defaults
+func NewFetchCoordinator(rdb *redis.Client, cfg FetchCoordinatorConfig) *FetchCoordinator {
+	if cfg.InstanceID == "" {
+		host, _ := os.Hostname()
+		cfg.InstanceID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if cfg.Shards <= 0 {
+		cfg.Shards = defaultFetchShards
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = defaultFetchLeaseTTL
+	}
+	return &FetchCoordinator{
+		redis:      rdb,
+		instanceID: cfg.InstanceID,
+		shards:     cfg.Shards,
+		ttl:        cfg.LeaseTTL,
+		owned:      make(map[int]ownedShard),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start claims an initial share of the shards and keeps the leases renewed
+func (fc *FetchCoordinator) Start(ctx context.Context) {
+	fc.rebalance(ctx)
+
+	fc.wg.Add(1)
+	go func() {
+		defer fc.wg.Done()
+		ticker := time.NewTicker(fc.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-fc.stopChan:
+				return
+			case <-ticker.C:
+				fc.rebalance(ctx)
+			}
+		}
+	}()
+	log.Printf("✓ Fetch coordination enabled: instance %s, %d shards, lease TTL %v", fc.instanceID, fc.shards, fc.ttl)
+}
+
+// Stop ends the renewal loop and releases every lease so other replicas take over at once
+func (fc *FetchCoordinator) Stop(ctx context.Context) {
+	close(fc.stopChan)
+	fc.wg.Wait()
+
+	fc.mu.Lock()
+	owned := fc.owned
+	fc.owned = make(map[int]ownedShard)
+	fc.mu.Unlock()
+
+	for shard, lease := range owned {
+		fc.release(ctx, shard, lease.token)
+	}
+	fc.redis.Del(ctx, fetchInstanceKeyPrefix+fc.instanceID)
+}
+
+// InstanceID identifies this replica in leases and the admin endpoint
+func (fc *FetchCoordinator) InstanceID() string {
+	return fc.instanceID
+}
+
+// ShardOf maps a source to its shard
+func (fc *FetchCoordinator) ShardOf(sourceID int64) int {
+	shard := int(sourceID % int64(fc.shards))
+	if shard < 0 {
+		shard += fc.shards
+	}
+	return shard
+}
+
+// Lease returns the fencing token when this replica currently holds the source's shard
+func (fc *FetchCoordinator) Lease(sourceID int64) (int64, bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	lease, ok := fc.owned[fc.ShardOf(sourceID)]
+	if !ok || !time.Now().Before(lease.expiresAt) {
+		return 0, false
+	}
+	return lease.token, true
+}
+
+// OwnedShards returns the shards this replica holds, sorted
+func (fc *FetchCoordinator) OwnedShards() []int {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	shards := make([]int, 0, len(fc.owned))
+	for shard := range fc.owned {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	return shards
+}
+
+// rebalance heartbeats, renews held leases, then claims or hands back shards so every live
+// replica holds about shards/replicas of them
+func (fc *FetchCoordinator) rebalance(ctx context.Context) {
+	if err := fc.redis.Set(ctx, fetchInstanceKeyPrefix+fc.instanceID, time.Now().Unix(), fc.ttl).Err(); err != nil {
+		log.Printf("[FetchLease] Heartbeat failed: %v", err)
+	}
+
+	fc.renewAll(ctx)
+
+	instances, err := fc.liveInstances(ctx)
+	if err != nil {
+		log.Printf("[FetchLease] Failed to list instances: %v", err)
+		return
+	}
+	if len(instances) == 0 {
+		instances = []string{fc.instanceID}
+	}
+	fair := (fc.shards + len(instances) - 1) / len(instances)
+
+	owned := fc.OwnedShards()
+	if len(owned) > fair {
+		// Hand back the highest shards; the newcomer picks them up on its next pass
+		for _, shard := range owned[fair:] {
+			fc.mu.Lock()
+			lease := fc.owned[shard]
+			delete(fc.owned, shard)
+			fc.mu.Unlock()
+			fc.release(ctx, shard, lease.token)
+			log.Printf("[FetchLease] Released shard %d for rebalancing (%d instances)", shard, len(instances))
+		}
+		return
+	}
+
+	for shard := 0; shard < fc.shards && len(owned) < fair; shard++ {
+		fc.mu.RLock()
+		_, held := fc.owned[shard]
+		fc.mu.RUnlock()
+		if held {
+			continue
+		}
+		if fc.claim(ctx, shard) {
+			owned = append(owned, shard)
+		}
+	}
+}
+
+func (fc *FetchCoordinator) claim(ctx context.Context, shard int) bool {
+	start := time.Now()
+	token, err := claimShardScript.Run(ctx, fc.redis,
+		[]string{fetchShardKeyPrefix + strconv.Itoa(shard), fetchTokenKey},
+		fc.instanceID, fc.ttl.Milliseconds(),
+	).Int64()
+	if err != nil {
+		log.Printf("[FetchLease] Failed to claim shard %d: %v", shard, err)
+		return false
+	}
+	if token == 0 {
+		return false
+	}
+
+	fc.mu.Lock()
+	fc.owned[shard] = ownedShard{token: token, expiresAt: start.Add(fc.ttl)}
+	fc.mu.Unlock()
+	log.Printf("[FetchLease] Claimed shard %d (token %d)", shard, token)
+	return true
+}
+
+func (fc *FetchCoordinator) renewAll(ctx context.Context) {
+	fc.mu.RLock()
+	owned := make(map[int]ownedShard, len(fc.owned))
+	for shard, lease := range fc.owned {
+		owned[shard] = lease
+	}
+	fc.mu.RUnlock()
+
+	for shard, lease := range owned {
+		start := time.Now()
+		renewed, err := renewShardScript.Run(ctx, fc.redis,
+			[]string{fetchShardKeyPrefix + strconv.Itoa(shard)},
+			fc.leaseValue(lease.token), fc.ttl.Milliseconds(),
+		).Int64()
+		if err != nil {
+			// Keep the local lease until it expires; Redis may be back before then
+			log.Printf("[FetchLease] Failed to renew shard %d: %v", shard, err)
+			continue
+		}
+
+		fc.mu.Lock()
+		if renewed == 1 {
+			fc.owned[shard] = ownedShard{token: lease.token, expiresAt: start.Add(fc.ttl)}
+		} else {
+			delete(fc.owned, shard)
+			log.Printf("[FetchLease] Lost shard %d (token %d) to another instance", shard, lease.token)
+		}
+		fc.mu.Unlock()
+	}
+}
+
+func (fc *FetchCoordinator) release(ctx context.Context, shard int, token int64) {
+	if err := releaseShardScript.Run(ctx, fc.redis,
+		[]string{fetchShardKeyPrefix + strconv.Itoa(shard)}, fc.leaseValue(token),
+	).Err(); err != nil && err != redis.Nil {
+		log.Printf("[FetchLease] Failed to release shard %d: %v", shard, err)
+	}
+}
+
+func (fc *FetchCoordinator) leaseValue(token int64) string {
+	return fc.instanceID + "|" + strconv.FormatInt(token, 10)
+}
+
+// liveInstances lists the replicas with an unexpired heartbeat
+func (fc *FetchCoordinator) liveInstances(ctx context.Context) ([]string, error) {
+	var instances []string
+	iter := fc.redis.Scan(ctx, 0, fetchInstanceKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		instances = append(instances, strings.TrimPrefix(iter.Val(), fetchInstanceKeyPrefix))
+	}
+	sort.Strings(instances)
+	return instances, iter.Err()
+}
+
+// Leases reads the current owner of every shard from Redis, plus the live replicas
+func (fc *FetchCoordinator) Leases(ctx context.Context) ([]ShardLease, []string, error) {
+	instances, err := fc.liveInstances(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipe := fc.redis.Pipeline()
+	values := make([]*redis.StringCmd, fc.shards)
+	ttls := make([]*redis.DurationCmd, fc.shards)
+	for shard := 0; shard < fc.shards; shard++ {
+		key := fetchShardKeyPrefix + strconv.Itoa(shard)
+		values[shard] = pipe.Get(ctx, key)
+		ttls[shard] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, nil, err
+	}
+
+	leases := make([]ShardLease, fc.shards)
+	for shard := range leases {
+		leases[shard].Shard = shard
+		value, err := values[shard].Result()
+		if err != nil {
+			continue // unowned
+		}
+		owner, token := parseLeaseValue(value)
+		leases[shard].Owner = owner
+		leases[shard].Token = token
+		if ttl, err := ttls[shard].Result(); err == nil && ttl > 0 {
+			leases[shard].TTLMillis = ttl.Milliseconds()
+		}
+	}
+	return leases, instances, nil
+}
+
+// parseLeaseValue splits "{instance}|{token}"; instance ids may themselves contain '|'
+func parseLeaseValue(value string) (string, int64) {
+	i := strings.LastIndexByte(value, '|')
+	if i < 0 {
+		return value, 0
+	}
+	token, _ := strconv.ParseInt(value[i+1:], 10, 64)
+	return value[:i], token
+}
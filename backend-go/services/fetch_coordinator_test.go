@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+func TestFetchCoordinatorShardOf(t *testing.T) {
+	fc := NewFetchCoordinator(nil, FetchCoordinatorConfig{InstanceID: "a", Shards: 4})
+	for id, want := range map[int64]int{0: 0, 1: 1, 4: 0, 7: 3, 1001: 1} {
+		if got := fc.ShardOf(id); got != want {
+			t.Errorf("ShardOf(%d) = %d, want %d", id, got, want)
+		}
+	}
+}
+
+func TestFetchCoordinatorLeaseExpires(t *testing.T) {
+	fc := NewFetchCoordinator(nil, FetchCoordinatorConfig{InstanceID: "a", Shards: 4})
+	fc.owned[1] = ownedShard{token: 42, expiresAt: time.Now().Add(time.Minute)}
+	fc.owned[2] = ownedShard{token: 43, expiresAt: time.Now().Add(-time.Second)}
+
+	if token, ok := fc.Lease(5); !ok || token != 42 {
+		t.Errorf("Lease(5) = %d, %v; want token 42 of shard 1", token, ok)
+	}
+	// A lease that could not be renewed in time must not be used, even before Redis confirms the loss
+	if _, ok := fc.Lease(6); ok {
+		t.Error("Lease(6) should fail once the local lease view has expired")
+	}
+	if _, ok := fc.Lease(3); ok {
+		t.Error("Lease(3) should fail for a shard that is not held")
+	}
+}
+
+func TestParseLeaseValue(t *testing.T) {
+	owner, token := parseLeaseValue("web-1|pod|17")
+	if owner != "web-1|pod" || token != 17 {
+		t.Errorf("parseLeaseValue = %q, %d", owner, token)
+	}
+}
+
+func TestClaimScheduledFetchPostponesForeignShards(t *testing.T) {
+	fc := NewFetchCoordinator(nil, FetchCoordinatorConfig{InstanceID: "a", Shards: 2, LeaseTTL: 30 * time.Second})
+	rs := &RSSService{scheduler: newTestScheduler(), coordinator: fc, defaultInterval: time.Hour}
+
+	rs.scheduler.Schedule(&models.Source{ID: 1}, time.Hour, true)
+	source, _ := rs.scheduler.Next(context.Background())
+
+	if _, ok := rs.claimScheduledFetch(context.Background(), source); ok {
+		t.Fatal("source in a shard leased to another replica should not be fetched")
+	}
+	entry := rs.scheduler.entries[1]
+	if entry == nil || time.Until(entry.dueAt) < 5*time.Second {
+		t.Fatalf("source should be postponed until the next rebalance, got %+v", entry)
+	}
+}
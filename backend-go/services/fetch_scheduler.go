@@ -0,0 +1,261 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+// maxFetchJitter caps the random delay added to a source's due time
+const maxFetchJitter = 2 * time.Minute
+
+// scheduleResyncInterval is how often the schedule is reconciled with the sources table,
+// picking up rows changed outside the API (AI tasks, direct SQL)
+const scheduleResyncInterval = 5 * time.Minute
+
+// scheduledFetch is one source waiting in the FetchScheduler
+type scheduledFetch struct {
+	source *models.Source
+	base   time.Time // due time before jitter; zero for a source that was never fetched
+	dueAt  time.Time
+	ready  bool // in the ready heap rather than the waiting heap
+	index  int
+}
+
+// fetchHeap is a container/heap of scheduled fetches ordered by less
+type fetchHeap struct {
+	entries []*scheduledFetch
+	less    func(a, b *scheduledFetch) bool
+}
+
+func (h *fetchHeap) Len() int           { return len(h.entries) }
+func (h *fetchHeap) Less(i, j int) bool { return h.less(h.entries[i], h.entries[j]) }
+func (h *fetchHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.entries[i].index = i
+	h.entries[j].index = j
+}
+func (h *fetchHeap) Push(x interface{}) {
+	entry := x.(*scheduledFetch)
+	entry.index = len(h.entries)
+	h.entries = append(h.entries, entry)
+}
+func (h *fetchHeap) Pop() interface{} {
+	n := len(h.entries)
+	entry := h.entries[n-1]
+	h.entries[n-1] = nil
+	h.entries = h.entries[:n-1]
+	entry.index = -1
+	return entry
+}
+
+// FetchScheduler hands out sources when they fall due. Sources wait in a heap keyed on
+// their next due time; once due they move to a ready heap ordered by priority (higher first),
+// so when every worker is busy the most important sources are fetched first.
+// A source handed out by Next is in flight until Done and is not scheduled meanwhile.
+type FetchScheduler struct {
+	mu       sync.Mutex
+	entries  map[int64]*scheduledFetch
+	waiting  *fetchHeap
+	ready    *fetchHeap
+	inFlight map[int64]bool
+	wake     chan struct{} // closed and replaced whenever the schedule changes
+	jitter   func(interval time.Duration) time.Duration
+}
+
+// NewFetchScheduler creates an empty scheduler
+func NewFetchScheduler() *FetchScheduler {
+	return &FetchScheduler{
+		entries: make(map[int64]*scheduledFetch),
+		waiting: &fetchHeap{less: func(a, b *scheduledFetch) bool {
+			return a.dueAt.Before(b.dueAt)
+		}},
+		ready: &fetchHeap{less: func(a, b *scheduledFetch) bool {
+			if a.source.Priority != b.source.Priority {
+				return a.source.Priority > b.source.Priority
+			}
+			return a.dueAt.Before(b.dueAt)
+		}},
+		inFlight: make(map[int64]bool),
+		wake:     make(chan struct{}),
+		jitter:   randomFetchJitter,
+	}
+}
+
+// randomFetchJitter spreads sources over up to 10% of their interval, capped at maxFetchJitter
+func randomFetchJitter(interval time.Duration) time.Duration {
+	limit := interval / 10
+	if limit > maxFetchJitter {
+		limit = maxFetchJitter
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(limit)))
+}
+
+// fetchDueBase is when a source is next due before jitter: one interval after the last fetch,
+// or the end of its failure backoff if that is later. Never-fetched sources are due at once.
+func fetchDueBase(source *models.Source, interval time.Duration) time.Time {
+	var base time.Time
+	if source.LastFetchTime != nil {
+		base = source.LastFetchTime.Add(interval)
+	}
+	if source.NextRetryAt != nil && source.NextRetryAt.After(base) {
+		base = *source.NextRetryAt
+	}
+	return base
+}
+
+// Schedule adds or updates a source. An entry whose base due time is unchanged keeps its
+// jittered due time, so periodic resyncs don't shuffle the schedule. With immediate set the
+// source is due now without jitter. Sources currently in flight are left alone.
+func (fs *FetchScheduler) Schedule(source *models.Source, interval time.Duration, immediate bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.inFlight[source.ID] {
+		return
+	}
+
+	base := fetchDueBase(source, interval)
+	if entry, ok := fs.entries[source.ID]; ok {
+		fs.removeLocked(entry)
+		if entry.base.Equal(base) && !immediate {
+			entry.source = source
+			fs.pushLocked(entry)
+			return
+		}
+	}
+
+	now := time.Now()
+	dueAt := base
+	if immediate || dueAt.Before(now) {
+		dueAt = now
+	}
+	if !immediate {
+		dueAt = dueAt.Add(fs.jitter(interval))
+	}
+	fs.pushLocked(&scheduledFetch{source: source, base: base, dueAt: dueAt})
+}
+
+// Postpone puts a source back with its due time pushed delay into the future. Its base due
+// time is kept, so a resync doesn't pull the source forward again.
+func (fs *FetchScheduler) Postpone(source *models.Source, interval, delay time.Duration) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.inFlight[source.ID] {
+		return
+	}
+	if entry, ok := fs.entries[source.ID]; ok {
+		fs.removeLocked(entry)
+	}
+	fs.pushLocked(&scheduledFetch{
+		source: source,
+		base:   fetchDueBase(source, interval),
+		dueAt:  time.Now().Add(delay),
+	})
+}
+
+// Remove drops a source from the schedule
+func (fs *FetchScheduler) Remove(sourceID int64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if entry, ok := fs.entries[sourceID]; ok {
+		fs.removeLocked(entry)
+		fs.signalLocked()
+	}
+}
+
+// Retain drops every scheduled source not in keep
+func (fs *FetchScheduler) Retain(keep map[int64]bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for id, entry := range fs.entries {
+		if !keep[id] {
+			fs.removeLocked(entry)
+		}
+	}
+	fs.signalLocked()
+}
+
+// Next blocks until a source is due and returns the highest-priority one, marking it in flight.
+// It returns false once ctx is done.
+func (fs *FetchScheduler) Next(ctx context.Context) (*models.Source, bool) {
+	for {
+		fs.mu.Lock()
+		now := time.Now()
+		for fs.waiting.Len() > 0 && !fs.waiting.entries[0].dueAt.After(now) {
+			entry := heap.Pop(fs.waiting).(*scheduledFetch)
+			entry.ready = true
+			heap.Push(fs.ready, entry)
+		}
+		if fs.ready.Len() > 0 {
+			entry := heap.Pop(fs.ready).(*scheduledFetch)
+			delete(fs.entries, entry.source.ID)
+			fs.inFlight[entry.source.ID] = true
+			fs.mu.Unlock()
+			return entry.source, true
+		}
+
+		wait := scheduleResyncInterval
+		if fs.waiting.Len() > 0 {
+			wait = fs.waiting.entries[0].dueAt.Sub(now)
+		}
+		wake := fs.wake
+		fs.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-wake:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, false
+		}
+	}
+}
+
+// Done marks a source handed out by Next as finished, so it can be scheduled again
+func (fs *FetchScheduler) Done(sourceID int64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.inFlight, sourceID)
+}
+
+// Len returns the number of scheduled sources, not counting those in flight
+func (fs *FetchScheduler) Len() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return len(fs.entries)
+}
+
+func (fs *FetchScheduler) pushLocked(entry *scheduledFetch) {
+	entry.ready = false
+	heap.Push(fs.waiting, entry)
+	fs.entries[entry.source.ID] = entry
+	fs.signalLocked()
+}
+
+func (fs *FetchScheduler) removeLocked(entry *scheduledFetch) {
+	if entry.ready {
+		heap.Remove(fs.ready, entry.index)
+	} else {
+		heap.Remove(fs.waiting, entry.index)
+	}
+	delete(fs.entries, entry.source.ID)
+}
+
+// signalLocked wakes every goroutine blocked in Next
+func (fs *FetchScheduler) signalLocked() {
+	close(fs.wake)
+	fs.wake = make(chan struct{})
+}
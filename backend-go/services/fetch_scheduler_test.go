@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+func newTestScheduler() *FetchScheduler {
+	fs := NewFetchScheduler()
+	fs.jitter = func(time.Duration) time.Duration { return 0 }
+	return fs
+}
+
+func nextWithin(t *testing.T, fs *FetchScheduler, d time.Duration) *models.Source {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	source, ok := fs.Next(ctx)
+	if !ok {
+		return nil
+	}
+	return source
+}
+
+func TestFetchSchedulerPriorityWhenSaturated(t *testing.T) {
+	fs := newTestScheduler()
+	past := time.Now().Add(-2 * time.Hour)
+
+	// All three are overdue; the backlog drains by priority, not by due time
+	fs.Schedule(&models.Source{ID: 1, Priority: 3, LastFetchTime: &past}, time.Hour, false)
+	fs.Schedule(&models.Source{ID: 2, Priority: 9, LastFetchTime: &past}, time.Hour, false)
+	fs.Schedule(&models.Source{ID: 3, Priority: 5, LastFetchTime: &past}, 30*time.Minute, false)
+
+	var order []int64
+	for i := 0; i < 3; i++ {
+		source := nextWithin(t, fs, time.Second)
+		if source == nil {
+			t.Fatalf("Next() returned nothing after %v", order)
+		}
+		order = append(order, source.ID)
+	}
+	if order[0] != 2 || order[1] != 3 || order[2] != 1 {
+		t.Errorf("fetch order = %v, want [2 3 1]", order)
+	}
+}
+
+func TestFetchSchedulerWaitsForDueTime(t *testing.T) {
+	fs := newTestScheduler()
+	recent := time.Now()
+	fs.Schedule(&models.Source{ID: 1, Priority: 10, LastFetchTime: &recent}, time.Hour, false)
+
+	if source := nextWithin(t, fs, 30*time.Millisecond); source != nil {
+		t.Fatalf("Next() = source %d, want nothing before it is due", source.ID)
+	}
+
+	// Rescheduling wakes a blocked Next; a new source is due immediately
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fs.Schedule(&models.Source{ID: 2, Priority: 1}, time.Hour, true)
+	}()
+	source := nextWithin(t, fs, time.Second)
+	if source == nil || source.ID != 2 {
+		t.Fatalf("Next() = %v, want the newly scheduled source 2", source)
+	}
+}
+
+func TestFetchSchedulerInFlightAndRemove(t *testing.T) {
+	fs := newTestScheduler()
+	fs.Schedule(&models.Source{ID: 1}, time.Hour, true)
+
+	source := nextWithin(t, fs, time.Second)
+	if source == nil {
+		t.Fatal("Next() returned nothing")
+	}
+
+	// Updates while the source is being fetched are ignored; the worker reschedules it after Done
+	fs.Schedule(&models.Source{ID: 1}, time.Hour, true)
+	if fs.Len() != 0 {
+		t.Errorf("Len() = %d while in flight, want 0", fs.Len())
+	}
+	fs.Done(1)
+	fs.Schedule(&models.Source{ID: 1}, time.Hour, true)
+	fs.Schedule(&models.Source{ID: 2}, time.Hour, true)
+	fs.Remove(1)
+	fs.Retain(map[int64]bool{1: true})
+	if fs.Len() != 0 {
+		t.Errorf("Len() = %d after Remove and Retain, want 0", fs.Len())
+	}
+}
+
+func TestFetchSchedulerKeepsJitterAcrossResyncs(t *testing.T) {
+	fs := NewFetchScheduler()
+	calls := 0
+	fs.jitter = func(time.Duration) time.Duration {
+		calls++
+		return time.Duration(calls) * time.Minute
+	}
+
+	last := time.Now()
+	source := &models.Source{ID: 1, LastFetchTime: &last}
+	fs.Schedule(source, time.Hour, false)
+	first := fs.entries[1].dueAt
+	fs.Schedule(&models.Source{ID: 1, Priority: 7, LastFetchTime: &last}, time.Hour, false)
+
+	entry := fs.entries[1]
+	if !entry.dueAt.Equal(first) || entry.source.Priority != 7 {
+		t.Errorf("unchanged base should keep due time %v, got %v (priority %d)", first, entry.dueAt, entry.source.Priority)
+	}
+
+	// A shorter interval moves the base and is re-jittered
+	fs.Schedule(&models.Source{ID: 1, LastFetchTime: &last}, 30*time.Minute, false)
+	if want := last.Add(30*time.Minute + 2*time.Minute); !fs.entries[1].dueAt.Equal(want) {
+		t.Errorf("dueAt = %v, want %v", fs.entries[1].dueAt, want)
+	}
+}
+
+func TestFetchDueBase(t *testing.T) {
+	last := time.Now().Add(-10 * time.Minute)
+	retry := time.Now().Add(time.Hour)
+
+	if base := fetchDueBase(&models.Source{}, time.Hour); !base.IsZero() {
+		t.Errorf("never-fetched source base = %v, want zero", base)
+	}
+	if base := fetchDueBase(&models.Source{LastFetchTime: &last}, time.Hour); !base.Equal(last.Add(time.Hour)) {
+		t.Errorf("base = %v, want last fetch + interval", base)
+	}
+	if base := fetchDueBase(&models.Source{LastFetchTime: &last, NextRetryAt: &retry}, time.Minute); !base.Equal(retry) {
+		t.Errorf("backoff should win: base = %v, want %v", base, retry)
+	}
+}
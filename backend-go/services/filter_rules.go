@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// filterRulesChannel tells every process to reload the filter rules after an API change
+const filterRulesChannel = "filter-rules:changed"
+
+// filterSkipRetention is how long filter_skips rows are kept before pruning
+const filterSkipRetention = 30 * 24 * time.Hour
+
+// filterSkipSeenTTL keeps an item skipped on every poll from being logged more than once a week
+const filterSkipSeenTTL = 7 * 24 * time.Hour
+
+// maxFilterConditionDepth bounds nesting of condition groups
+const maxFilterConditionDepth = 8
+
+// compiledCondition is a FilterCondition ready to evaluate: regexes compiled, values lowercased,
+// lengths and ages parsed
+type compiledCondition struct {
+	match    string
+	children []*compiledCondition
+	field    string
+	operator string
+	values   []string
+	regex    *regexp.Regexp
+	length   int
+	age      time.Duration
+	negate   bool
+}
+
+// ValidateFilterCondition reports the first problem that keeps a condition tree from compiling
+func ValidateFilterCondition(cond models.FilterCondition) error {
+	_, err := compileFilterCondition(cond, 0)
+	return err
+}
+
+func compileFilterCondition(cond models.FilterCondition, depth int) (*compiledCondition, error) {
+	if depth >= maxFilterConditionDepth {
+		return nil, fmt.Errorf("conditions are nested deeper than %d levels", maxFilterConditionDepth)
+	}
+	c := &compiledCondition{negate: cond.Negate}
+
+	if len(cond.Conditions) > 0 || cond.Match != "" {
+		c.match = strings.ToLower(cond.Match)
+		if c.match == "" {
+			c.match = "all"
+		}
+		if c.match != "all" && c.match != "any" {
+			return nil, fmt.Errorf("match must be \"all\" or \"any\", got %q", cond.Match)
+		}
+		if len(cond.Conditions) == 0 {
+			return nil, fmt.Errorf("a %q group needs conditions", c.match)
+		}
+		for _, child := range cond.Conditions {
+			compiled, err := compileFilterCondition(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			c.children = append(c.children, compiled)
+		}
+		return c, nil
+	}
+
+	c.field = strings.ToLower(cond.Field)
+	c.operator = strings.ToLower(cond.Operator)
+	needsField := c.operator != models.FilterOpLanguage && c.operator != models.FilterOpOlderThan
+	switch c.field {
+	case models.FilterFieldTitle, models.FilterFieldContent, models.FilterFieldAuthor,
+		models.FilterFieldURL, models.FilterFieldCategory:
+	case "":
+		if needsField {
+			return nil, fmt.Errorf("operator %q needs a field", cond.Operator)
+		}
+	default:
+		return nil, fmt.Errorf("unknown field %q", cond.Field)
+	}
+
+	values := cond.Values
+	if cond.Value != "" {
+		values = append([]string{cond.Value}, values...)
+	}
+	switch c.operator {
+	case models.FilterOpContains, models.FilterOpEquals, models.FilterOpLanguage:
+		for _, v := range values {
+			if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+				c.values = append(c.values, v)
+			}
+		}
+		if len(c.values) == 0 {
+			return nil, fmt.Errorf("operator %q needs values", cond.Operator)
+		}
+	case models.FilterOpRegex:
+		re, err := regexp.Compile(cond.Value)
+		if err != nil || cond.Value == "" {
+			return nil, fmt.Errorf("invalid regex %q: %v", cond.Value, err)
+		}
+		c.regex = re
+	case models.FilterOpMinLength, models.FilterOpMaxLength:
+		n, err := strconv.Atoi(strings.TrimSpace(cond.Value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("operator %q needs a rune count, got %q", cond.Operator, cond.Value)
+		}
+		c.length = n
+	case models.FilterOpOlderThan:
+		age, err := parseFilterAge(cond.Value)
+		if err != nil {
+			return nil, err
+		}
+		c.age = age
+	default:
+		return nil, fmt.Errorf("unknown operator %q", cond.Operator)
+	}
+	return c, nil
+}
+
+// parseFilterAge accepts Go durations ("72h") and whole days ("7d")
+func parseFilterAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("older_than needs a duration such as \"72h\" or \"7d\", got %q", value)
+}
+
+// filterSubject is the item being filtered; unless SanitizeFeedItem already detected its
+// language, that is detected on first use
+type filterSubject struct {
+	item     *utils.FeedItem
+	now      time.Time
+	language *string
+}
+
+func (s *filterSubject) fieldValues(field string) []string {
+	switch field {
+	case models.FilterFieldTitle:
+		return []string{s.item.Title}
+	case models.FilterFieldContent:
+		return []string{s.item.Content}
+	case models.FilterFieldAuthor:
+		return []string{s.item.Author}
+	case models.FilterFieldURL:
+		return []string{s.item.URL}
+	case models.FilterFieldCategory:
+		return s.item.Categories
+	}
+	return nil
+}
+
+func (s *filterSubject) detectedLanguage() string {
+	if s.language == nil {
+		lang := s.item.Language
+		if lang == "" {
+			lang = utils.DetectLanguage(s.item.Title + "\n" + s.item.Content)
+		}
+		s.language = &lang
+	}
+	return *s.language
+}
+
+// matches evaluates the condition against an item. A language test on text whose language
+// can't be detected never matches, negated or not, so short items aren't skipped as "not English".
+func (c *compiledCondition) matches(s *filterSubject) bool {
+	if c.operator == models.FilterOpLanguage && s.detectedLanguage() == "" {
+		return false
+	}
+	return c.evaluate(s) != c.negate
+}
+
+func (c *compiledCondition) evaluate(s *filterSubject) bool {
+	if c.match != "" {
+		for _, child := range c.children {
+			if child.matches(s) == (c.match == "any") {
+				return c.match == "any"
+			}
+		}
+		return c.match == "all"
+	}
+
+	switch c.operator {
+	case models.FilterOpLanguage:
+		return containsString(c.values, s.detectedLanguage())
+	case models.FilterOpOlderThan:
+		return s.item.PublishedAt != nil && s.now.Sub(*s.item.PublishedAt) > c.age
+	}
+
+	for _, value := range s.fieldValues(c.field) {
+		if c.matchesValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *compiledCondition) matchesValue(value string) bool {
+	switch c.operator {
+	case models.FilterOpContains:
+		lower := strings.ToLower(value)
+		for _, v := range c.values {
+			if strings.Contains(lower, v) {
+				return true
+			}
+		}
+	case models.FilterOpEquals:
+		return containsString(c.values, strings.ToLower(strings.TrimSpace(value)))
+	case models.FilterOpRegex:
+		return c.regex.MatchString(value)
+	case models.FilterOpMinLength:
+		return len([]rune(value)) >= c.length
+	case models.FilterOpMaxLength:
+		return len([]rune(value)) <= c.length
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// activeFilterRule is an enabled rule with its compiled condition
+type activeFilterRule struct {
+	rule      *models.FilterRule
+	condition *compiledCondition
+}
+
+// FilterEngine holds the enabled filter rules, compiled, for matching feed items before dedup
+type FilterEngine struct {
+	repo     *repositories.FilterRuleRepository
+	mu       sync.RWMutex
+	global   []activeFilterRule
+	bySource map[int64][]activeFilterRule
+}
+
+// NewFilterEngine creates an engine; rules are loaded by Reload
+func NewFilterEngine(repo *repositories.FilterRuleRepository) *FilterEngine {
+	return &FilterEngine{repo: repo, bySource: map[int64][]activeFilterRule{}}
+}
+
+// Reload replaces the rules with the enabled ones in the database
+func (fe *FilterEngine) Reload(ctx context.Context) error {
+	rules, err := fe.repo.List(ctx, 0, false, true)
+	if err != nil {
+		return err
+	}
+	fe.Set(rules)
+	return nil
+}
+
+// Set replaces the rules. A rule that no longer compiles is logged and left out rather than
+// failing the others.
+func (fe *FilterEngine) Set(rules []*models.FilterRule) {
+	var global []activeFilterRule
+	bySource := make(map[int64][]activeFilterRule)
+	for _, rule := range rules {
+		condition, err := compileFilterCondition(rule.Condition, 0)
+		if err != nil {
+			log.Printf("Warning: Ignoring filter rule %d (%s): %v", rule.ID, rule.Name, err)
+			continue
+		}
+		active := activeFilterRule{rule: rule, condition: condition}
+		if rule.SourceID == nil {
+			global = append(global, active)
+		} else {
+			bySource[*rule.SourceID] = append(bySource[*rule.SourceID], active)
+		}
+	}
+
+	fe.mu.Lock()
+	fe.global = global
+	fe.bySource = bySource
+	fe.mu.Unlock()
+}
+
+// Match returns the first rule that skips the item: the source's own rules first, then the
+// global ones. A nil engine matches nothing.
+func (fe *FilterEngine) Match(sourceID int64, item *utils.FeedItem, now time.Time) *models.FilterRule {
+	if fe == nil {
+		return nil
+	}
+	fe.mu.RLock()
+	defer fe.mu.RUnlock()
+
+	subject := &filterSubject{item: item, now: now}
+	for _, rules := range [][]activeFilterRule{fe.bySource[sourceID], fe.global} {
+		for _, active := range rules {
+			if active.condition.matches(subject) {
+				return active.rule
+			}
+		}
+	}
+	return nil
+}
+
+// SetFilterRules enables pre-dedup filter rules
+func (rs *RSSService) SetFilterRules(engine *FilterEngine, repo *repositories.FilterRuleRepository) {
+	rs.filters = engine
+	rs.filterRuleRepo = repo
+}
+
+// ReloadFilterRules loads the enabled filter rules into the engine
+func (rs *RSSService) ReloadFilterRules(ctx context.Context) error {
+	if rs.filters == nil {
+		return nil
+	}
+	return rs.filters.Reload(ctx)
+}
+
+// FilterRulesChanged reloads the rules here and asks the other processes to do the same
+func (rs *RSSService) FilterRulesChanged(ctx context.Context) {
+	if err := rs.ReloadFilterRules(ctx); err != nil {
+		log.Printf("Warning: Failed to reload filter rules: %v", err)
+	}
+	if rs.redis != nil {
+		if err := rs.redis.Publish(ctx, filterRulesChannel, "").Err(); err != nil {
+			log.Printf("Warning: Failed to announce filter rule change: %v", err)
+		}
+	}
+}
+
+// recordFilterSkip logs a skipped item with the rule that matched. Feeds keep serving the same
+// items, so an item is logged once per rule and URL within filterSkipSeenTTL.
+func (rs *RSSService) recordFilterSkip(ctx context.Context, source *models.Source, item *utils.FeedItem, rule *models.FilterRule) {
+	if rs.filterRuleRepo == nil {
+		return
+	}
+	if rs.redis != nil && item.URL != "" {
+		key := fmt.Sprintf("filtered:%d:%s", rule.ID, item.URL)
+		first, err := rs.redis.SetNX(ctx, key, "1", filterSkipSeenTTL).Result()
+		if err == nil && !first {
+			return
+		}
+	}
+
+	log.Printf("[Filter] Rule %q skipped: %s", rule.Name, item.Title)
+	if err := rs.filterRuleRepo.RecordSkip(ctx, rule, source.ID, item.Title, item.URL); err != nil {
+		log.Printf("Warning: Failed to record filter skip: %v", err)
+	}
+}
+
+// pruneFilterSkips drops skip records older than filterSkipRetention
+func (rs *RSSService) pruneFilterSkips(ctx context.Context) {
+	if rs.filterRuleRepo == nil {
+		return
+	}
+	deleted, err := rs.filterRuleRepo.DeleteSkipsBefore(ctx, time.Now().Add(-filterSkipRetention))
+	if err != nil {
+		log.Printf("Failed to prune filter skips: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d filter skips older than %v", deleted, filterSkipRetention)
+	}
+}
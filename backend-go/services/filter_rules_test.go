@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+func TestValidateFilterCondition(t *testing.T) {
+	invalid := []models.FilterCondition{
+		{Field: "body", Operator: models.FilterOpContains, Values: []string{"x"}},
+		{Field: models.FilterFieldTitle, Operator: "startswith", Value: "x"},
+		{Field: models.FilterFieldTitle, Operator: models.FilterOpContains},
+		{Field: models.FilterFieldTitle, Operator: models.FilterOpRegex, Value: "("},
+		{Field: models.FilterFieldContent, Operator: models.FilterOpMaxLength, Value: "short"},
+		{Operator: models.FilterOpOlderThan, Value: "a week"},
+		{Operator: models.FilterOpContains, Values: []string{"x"}},
+		{Match: "xor", Conditions: []models.FilterCondition{{Operator: models.FilterOpLanguage, Value: "en"}}},
+		{Match: "any"},
+	}
+	for _, cond := range invalid {
+		if err := ValidateFilterCondition(cond); err == nil {
+			t.Errorf("condition %+v should be invalid", cond)
+		}
+	}
+
+	valid := models.FilterCondition{Match: "any", Conditions: []models.FilterCondition{
+		{Operator: models.FilterOpOlderThan, Value: "7d"},
+		{Field: models.FilterFieldURL, Operator: models.FilterOpRegex, Value: `(?i)/sponsored/`},
+	}}
+	if err := ValidateFilterCondition(valid); err != nil {
+		t.Errorf("valid condition rejected: %v", err)
+	}
+}
+
+func TestFilterEngineMatch(t *testing.T) {
+	sourceID := int64(3)
+	engine := NewFilterEngine(nil)
+	engine.Set([]*models.FilterRule{
+		{ID: 1, Name: "sponsored", Condition: models.FilterCondition{
+			Field: models.FilterFieldTitle, Operator: models.FilterOpContains, Values: []string{"Sponsored", "广告"},
+		}},
+		{ID: 2, Name: "short deals", SourceID: &sourceID, Condition: models.FilterCondition{
+			Match: "all",
+			Conditions: []models.FilterCondition{
+				{Field: models.FilterFieldCategory, Operator: models.FilterOpEquals, Value: "deals"},
+				{Field: models.FilterFieldContent, Operator: models.FilterOpMaxLength, Value: "500"},
+			},
+		}},
+		{ID: 3, Name: "english or chinese only", SourceID: &sourceID, Condition: models.FilterCondition{
+			Operator: models.FilterOpLanguage, Values: []string{"en", "zh"}, Negate: true,
+		}},
+		{ID: 4, Name: "stale", Condition: models.FilterCondition{Operator: models.FilterOpOlderThan, Value: "30d"}},
+	})
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	old := now.Add(-60 * 24 * time.Hour)
+	english := "This is a long enough article body about the design of the new storage engine and its trade-offs."
+	cases := []struct {
+		name     string
+		sourceID int64
+		item     *utils.FeedItem
+		want     int64 // matched rule id, 0 for none
+	}{
+		{"global keyword", 9, &utils.FeedItem{Title: "SPONSORED: buy now", Content: english, PublishedAt: &recent}, 1},
+		{"chinese keyword", 9, &utils.FeedItem{Title: "【广告】新品上市", Content: english, PublishedAt: &recent}, 1},
+		{"all group matches", 3, &utils.FeedItem{Title: "Deal", Content: english, Categories: []string{"Deals"}, PublishedAt: &recent}, 2},
+		{"all group needs every condition", 9, &utils.FeedItem{Title: "Deal", Content: english, Categories: []string{"Deals"}, PublishedAt: &recent}, 0},
+		{"negated language", 3, &utils.FeedItem{Title: "Bericht", Content: "Das ist nicht der Artikel, den wir auf der Seite mit dem Bild sehen wollen, und das ist auch gut so.", PublishedAt: &recent}, 3},
+		{"undetected language never matches", 3, &utils.FeedItem{Title: "OK", Content: "12345", PublishedAt: &recent}, 0},
+		{"age", 9, &utils.FeedItem{Title: "Old news", Content: english, PublishedAt: &old}, 4},
+		{"clean item", 3, &utils.FeedItem{Title: "Storage engines", Content: english, PublishedAt: &recent}, 0},
+	}
+	for _, tc := range cases {
+		rule := engine.Match(tc.sourceID, tc.item, now)
+		var got int64
+		if rule != nil {
+			got = rule.ID
+		}
+		if got != tc.want {
+			t.Errorf("%s: matched rule %d, want %d", tc.name, got, tc.want)
+		}
+	}
+
+	var nilEngine *FilterEngine
+	if nilEngine.Match(3, cases[0].item, now) != nil {
+		t.Error("a nil engine should match nothing")
+	}
+}
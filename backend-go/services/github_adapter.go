@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// GitHubConfig is the adapter_config of a "github" source
+type GitHubConfig struct {
+	Kind   string `json:"kind"`   // "releases" (default), "tags" or "commits"
+	Branch string `json:"branch"` // commits only; empty means the default branch
+}
+
+// GitHubAdapter follows a repository's releases, tags or commits through GitHub's Atom feeds.
+// Source.URL is the repository page (https://github.com/owner/repo) or a feed URL ending in .atom.
+// URLs on other hosts, such as RSSHub's /github/trending routes, are fetched as plain feeds
+// unless the source has an adapter_config, which marks a GitHub Enterprise repository.
+type GitHubAdapter struct {
+	parser *utils.RSSParser
+}
+
+// ValidateConfig implements adapterConfigValidator
+func (a *GitHubAdapter) ValidateConfig(raw []byte) error {
+	var cfg GitHubConfig
+	if err := decodeAdapterConfig(raw, &cfg); err != nil {
+		return err
+	}
+	switch cfg.Kind {
+	case "", "releases", "tags", "commits":
+		return nil
+	}
+	return fmt.Errorf("github kind must be releases, tags or commits, got %q", cfg.Kind)
+}
+
+// Fetch implements SourceAdapter
+func (a *GitHubAdapter) Fetch(ctx context.Context, source *models.Source) (*utils.FetchResult, error) {
+	if !isGitHubRepoSource(source) {
+		return (&RSSAdapter{parser: a.parser}).Fetch(ctx, source)
+	}
+
+	var cfg GitHubConfig
+	if err := decodeAdapterConfig(sourceAdapterConfig(source), &cfg); err != nil {
+		return nil, err
+	}
+
+	feedURL, repo, err := githubFeedURL(source.URL, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.parser.FetchFeed(ctx, feedURL, utils.FeedValidators{
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Release and tag entries are titled with the bare tag ("v1.2.0"); name the repository
+	if repo != "" && cfg.Kind != "commits" {
+		for _, item := range result.Items {
+			item.Title = repo + " " + item.Title
+		}
+	}
+	return result, nil
+}
+
+// isGitHubRepoSource reports whether the source points at a repository: its URL is on
+// github.com or it has an adapter_config
+func isGitHubRepoSource(source *models.Source) bool {
+	if strings.TrimSpace(string(sourceAdapterConfig(source))) != "" {
+		return true
+	}
+	parsed, err := url.Parse(strings.TrimSpace(source.URL))
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	return host == "github.com"
+}
+
+// githubFeedURL builds the Atom feed URL for a repository URL and returns the "owner/repo" name.
+// The feed is resolved on the repository URL's own host, so GitHub Enterprise works too.
+func githubFeedURL(repoURL string, cfg GitHubConfig) (string, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(repoURL))
+	if err != nil || parsed.Host == "" {
+		return "", "", fmt.Errorf("invalid GitHub URL %q", repoURL)
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] == "" || segments[1] == "" {
+		return "", "", fmt.Errorf("GitHub URL %q must point to a repository (owner/repo)", repoURL)
+	}
+	repo := segments[0] + "/" + strings.TrimSuffix(segments[1], ".git")
+
+	if strings.HasSuffix(parsed.Path, ".atom") {
+		return parsed.String(), repo, nil
+	}
+
+	var feedPath string
+	switch cfg.Kind {
+	case "commits":
+		if cfg.Branch != "" {
+			feedPath = "/commits/" + cfg.Branch + ".atom"
+		} else {
+			feedPath = "/commits.atom"
+		}
+	case "tags":
+		feedPath = "/tags.atom"
+	default:
+		feedPath = "/releases.atom"
+	}
+
+	feed := url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: "/" + repo + feedPath}
+	return feed.String(), repo, nil
+}
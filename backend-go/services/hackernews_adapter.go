@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// hackerNewsAPIBase is the official Firebase API, used when Source.URL is a news.ycombinator.com page
+const hackerNewsAPIBase = "https://hacker-news.firebaseio.com/v0/"
+
+// hackerNewsItemBase links to the discussion page of stories that have no external URL
+const hackerNewsItemBase = "https://news.ycombinator.com/item?id="
+
+// Defaults for HackerNewsConfig
+const (
+	defaultHackerNewsLimit = 30
+	maxHackerNewsLimit     = 200
+	hackerNewsItemWorkers  = 8
+)
+
+// hackerNewsLists are the story lists served by the API
+var hackerNewsLists = map[string]bool{
+	"topstories": true, "newstories": true, "beststories": true,
+	"askstories": true, "showstories": true, "jobstories": true,
+}
+
+// hackerNewsPageLists maps news.ycombinator.com pages to API lists
+var hackerNewsPageLists = map[string]string{
+	"":       "topstories",
+	"news":   "topstories",
+	"newest": "newstories",
+	"best":   "beststories",
+	"ask":    "askstories",
+	"show":   "showstories",
+	"jobs":   "jobstories",
+}
+
+// HackerNewsConfig is the adapter_config of a "hackernews" source
+type HackerNewsConfig struct {
+	List     string `json:"list"`      // e.g. "topstories"; defaults to the list implied by the URL
+	Limit    int    `json:"limit"`     // stories fetched per poll, default 30
+	MinScore int    `json:"min_score"` // skip stories below this score
+}
+
+// hackerNewsItem is the subset of the Firebase item object the adapter uses
+type hackerNewsItem struct {
+	ID          int64  `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by"`
+	Time        int64  `json:"time"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Text        string `json:"text"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+	Deleted     bool   `json:"deleted"`
+	Dead        bool   `json:"dead"`
+}
+
+// HackerNewsAdapter reads a story list from the Hacker News Firebase API and fetches each story.
+// Source.URL is a news.ycombinator.com page (/, /newest, /ask, ...), an API list URL such as
+// https://hacker-news.firebaseio.com/v0/topstories.json, or an API base ending in "/".
+type HackerNewsAdapter struct {
+	parser *utils.RSSParser
+}
+
+// ValidateConfig implements adapterConfigValidator
+func (a *HackerNewsAdapter) ValidateConfig(raw []byte) error {
+	var cfg HackerNewsConfig
+	if err := decodeAdapterConfig(raw, &cfg); err != nil {
+		return err
+	}
+	if cfg.List != "" && !hackerNewsLists[cfg.List] {
+		return fmt.Errorf("unknown hackernews list %q", cfg.List)
+	}
+	if cfg.Limit < 0 || cfg.Limit > maxHackerNewsLimit {
+		return fmt.Errorf("hackernews limit must be between 0 and %d", maxHackerNewsLimit)
+	}
+	return nil
+}
+
+// Fetch implements SourceAdapter
+func (a *HackerNewsAdapter) Fetch(ctx context.Context, source *models.Source) (*utils.FetchResult, error) {
+	var cfg HackerNewsConfig
+	if err := decodeAdapterConfig(sourceAdapterConfig(source), &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Limit <= 0 || cfg.Limit > maxHackerNewsLimit {
+		cfg.Limit = defaultHackerNewsLimit
+	}
+
+	listURL, itemBase, err := hackerNewsEndpoints(source.URL, cfg.List)
+	if err != nil {
+		return nil, err
+	}
+
+	client := a.parser.HTTPClient()
+	var ids []int64
+	result, err := fetchJSON(ctx, client, listURL, utils.FeedValidators{
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+	}, &ids)
+	if err != nil || result.NotModified {
+		return result, err
+	}
+	if len(ids) > cfg.Limit {
+		ids = ids[:cfg.Limit]
+	}
+
+	// Fetch stories with a small worker pool, keeping the list's ranking order
+	stories := make([]*hackerNewsItem, len(ids))
+	sizes := make([]int64, len(ids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < hackerNewsItemWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var story hackerNewsItem
+				itemResult, err := fetchJSON(ctx, client, fmt.Sprintf("%s%d.json", itemBase, ids[i]), utils.FeedValidators{}, &story)
+				if itemResult != nil {
+					sizes[i] = itemResult.Bytes
+				}
+				if err == nil && story.ID != 0 {
+					stories[i] = &story
+				}
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, story := range stories {
+		result.Bytes += sizes[i]
+		if story == nil || story.Deleted || story.Dead || story.Title == "" || story.Score < cfg.MinScore {
+			continue
+		}
+		result.Items = append(result.Items, story.toFeedItem())
+	}
+	return result, nil
+}
+
+// toFeedItem maps a story; Ask HN / job text becomes the content, link posts keep their target URL
+func (s *hackerNewsItem) toFeedItem() *utils.FeedItem {
+	link := s.URL
+	if link == "" {
+		link = fmt.Sprintf("%s%d", hackerNewsItemBase, s.ID)
+	}
+	published := time.Unix(s.Time, 0)
+	return &utils.FeedItem{
+		Title:       s.Title,
+		Description: fmt.Sprintf("%d points, %d comments: %s%d", s.Score, s.Descendants, hackerNewsItemBase, s.ID),
+		URL:         link,
+		Author:      s.By,
+		PublishedAt: &published,
+		Content:     s.Text,
+	}
+}
+
+// hackerNewsEndpoints resolves the list URL and the item URL prefix for a source URL
+func hackerNewsEndpoints(sourceURL, list string) (string, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(sourceURL))
+	if err != nil || parsed.Host == "" {
+		return "", "", fmt.Errorf("invalid Hacker News URL %q", sourceURL)
+	}
+
+	switch {
+	case strings.HasSuffix(parsed.Host, "news.ycombinator.com"):
+		if list == "" {
+			page, ok := hackerNewsPageLists[strings.Trim(parsed.Path, "/")]
+			if !ok {
+				return "", "", fmt.Errorf("no Hacker News story list for %q", sourceURL)
+			}
+			list = page
+		}
+		return hackerNewsAPIBase + list + ".json", hackerNewsAPIBase + "item/", nil
+
+	case strings.HasSuffix(parsed.Path, ".json"):
+		base, err := parsed.Parse("./")
+		if err != nil {
+			return "", "", err
+		}
+		return parsed.String(), base.String() + "item/", nil
+
+	default:
+		if list == "" {
+			list = "topstories"
+		}
+		base := parsed.String()
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		return base + list + ".json", base + "item/", nil
+	}
+}
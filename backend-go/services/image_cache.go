@@ -0,0 +1,194 @@
+package services
+
+import (
+	"container/list"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// imageExtensions maps the cached image types to file extensions; the extension is how the type
+// is recovered when the cache directory is scanned after a restart
+var imageExtensions = map[string]string{
+	"image/jpeg":   ".jpg",
+	"image/png":    ".png",
+	"image/gif":    ".gif",
+	"image/webp":   ".webp",
+	"image/avif":   ".avif",
+	"image/bmp":    ".bmp",
+	"image/x-icon": ".ico",
+}
+
+// imageCacheEntry is one cached file
+type imageCacheEntry struct {
+	key         string
+	path        string
+	contentType string
+	size        int64
+}
+
+// ImageCache keeps images in a local directory up to maxBytes, evicting the least recently used.
+// Access times are written to the files' mtime, so the LRU order survives restarts.
+type ImageCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	lru     *list.List // of *imageCacheEntry, most recently used first
+	entries map[string]*list.Element
+	size    int64
+}
+
+// NewImageCache opens the cache in dir, indexing the files already there
+func NewImageCache(dir string, maxBytes int64) (*ImageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	ic := &ImageCache{dir: dir, maxBytes: maxBytes, lru: list.New(), entries: make(map[string]*list.Element)}
+	if err := ic.load(); err != nil {
+		return nil, err
+	}
+	return ic, nil
+}
+
+// load indexes the cached files, oldest access last, and trims the cache to maxBytes
+func (ic *ImageCache) load() error {
+	type cachedFile struct {
+		entry   *imageCacheEntry
+		modTime time.Time
+	}
+	var files []cachedFile
+	err := filepath.WalkDir(ic.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		if strings.Contains(name, ".tmp") {
+			os.Remove(path) // left behind by an interrupted write
+			return nil
+		}
+		ext := filepath.Ext(name)
+		contentType := ""
+		for ct, e := range imageExtensions {
+			if e == ext {
+				contentType = ct
+			}
+		}
+		info, err := d.Info()
+		if contentType == "" || err != nil {
+			return nil
+		}
+		files = append(files, cachedFile{
+			entry:   &imageCacheEntry{key: strings.TrimSuffix(name, ext), path: path, contentType: contentType, size: info.Size()},
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	for _, f := range files {
+		ic.entries[f.entry.key] = ic.lru.PushBack(f.entry)
+		ic.size += f.entry.size
+	}
+	if evicted := ic.evictLocked(); evicted > 0 {
+		log.Printf("[Images] Evicted %d cached image(s) over the %d MB limit", evicted, ic.maxBytes>>20)
+	}
+	return nil
+}
+
+// Get returns a cached image and marks it as recently used
+func (ic *ImageCache) Get(key string) ([]byte, string, bool) {
+	ic.mu.Lock()
+	elem, ok := ic.entries[key]
+	if !ok {
+		ic.mu.Unlock()
+		return nil, "", false
+	}
+	ic.lru.MoveToFront(elem)
+	entry := elem.Value.(*imageCacheEntry)
+	ic.mu.Unlock()
+
+	data, err := os.ReadFile(entry.path)
+	if err != nil {
+		// Removed behind our back; forget it so the image is fetched again
+		ic.mu.Lock()
+		if current, ok := ic.entries[key]; ok && current == elem {
+			ic.removeLocked(elem)
+		}
+		ic.mu.Unlock()
+		return nil, "", false
+	}
+	now := time.Now()
+	os.Chtimes(entry.path, now, now)
+	return data, entry.contentType, true
+}
+
+// Put stores an image under key. Images of a type without a known extension, or larger than the
+// whole cache, are not stored.
+func (ic *ImageCache) Put(key, contentType string, data []byte) error {
+	ext, ok := imageExtensions[contentType]
+	if !ok || int64(len(data)) > ic.maxBytes {
+		return nil
+	}
+	// Files are spread over subdirectories by key prefix to keep directories small
+	path := filepath.Join(ic.dir, key[:2], key+ext)
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if elem, ok := ic.entries[key]; ok {
+		old := elem.Value.(*imageCacheEntry)
+		ic.lru.Remove(elem)
+		delete(ic.entries, key)
+		ic.size -= old.size
+		if old.path != path {
+			os.Remove(old.path)
+		}
+	}
+	entry := &imageCacheEntry{key: key, path: path, contentType: contentType, size: int64(len(data))}
+	ic.entries[key] = ic.lru.PushFront(entry)
+	ic.size += entry.size
+	ic.evictLocked()
+	return nil
+}
+
+// Usage returns the number of cached files and their total size
+func (ic *ImageCache) Usage() (int, int64) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	return ic.lru.Len(), ic.size
+}
+
+// evictLocked removes least recently used files until the cache fits maxBytes
+func (ic *ImageCache) evictLocked() int {
+	evicted := 0
+	for ic.size > ic.maxBytes {
+		oldest := ic.lru.Back()
+		if oldest == nil {
+			break
+		}
+		ic.removeLocked(oldest)
+		evicted++
+	}
+	return evicted
+}
+
+func (ic *ImageCache) removeLocked(elem *list.Element) {
+	entry := elem.Value.(*imageCacheEntry)
+	ic.lru.Remove(elem)
+	delete(ic.entries, entry.key)
+	ic.size -= entry.size
+	os.Remove(entry.path)
+}
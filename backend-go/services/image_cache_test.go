@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+)
+
+func TestImageCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewImageCache(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := bytes.Repeat([]byte{1}, 100)
+
+	cache.Put("aa01", "image/png", img)
+	cache.Put("bb02", "image/jpeg", img)
+	if _, _, ok := cache.Get("aa01"); !ok { // aa01 is now the most recently used
+		t.Fatal("cached image missing")
+	}
+	cache.Put("cc03", "image/png", img)
+
+	if _, _, ok := cache.Get("bb02"); ok {
+		t.Error("least recently used image should have been evicted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bb", "bb02.jpg")); !os.IsNotExist(err) {
+		t.Error("evicted file should be removed from disk")
+	}
+	if data, contentType, ok := cache.Get("aa01"); !ok || contentType != "image/png" || len(data) != 100 {
+		t.Errorf("Get(aa01) = %d bytes, %q, %v", len(data), contentType, ok)
+	}
+	if files, size := cache.Usage(); files != 2 || size != 200 {
+		t.Errorf("Usage = %d files, %d bytes; want 2, 200", files, size)
+	}
+}
+
+func TestImageCacheReloadKeepsAccessOrder(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewImageCache(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := bytes.Repeat([]byte{1}, 100)
+	cache.Put("aa01", "image/png", img)
+	cache.Put("bb02", "image/png", img)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "aa", "aa01.png"), old, old)
+
+	// Reopened with room for one image, the one accessed last survives
+	reopened, err := NewImageCache(dir, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := reopened.Get("aa01"); ok {
+		t.Error("older image should be evicted on load")
+	}
+	if _, contentType, ok := reopened.Get("bb02"); !ok || contentType != "image/png" {
+		t.Error("newer image should survive the reload")
+	}
+}
+
+func TestRewriteContentProxiesImages(t *testing.T) {
+	ip := &ImageProxy{}
+	page := "https://blog.example.cn/posts/1"
+	absolute := "https://img.example.cn/a.jpg"
+	relative := "https://blog.example.cn/images/b.png"
+	ip.registered.Store(ImageHash(absolute), true)
+	ip.registered.Store(ImageHash(relative), true)
+
+	resp := &models.ContentResponse{
+		OriginalURL: page,
+		ImageURLs:   models.StringArray{"data:image/gif;base64,R0lGOD", absolute, "/images/b.png"},
+	}
+	empty := &models.ContentResponse{OriginalURL: page}
+	ip.RewriteContent(context.Background(), resp, empty)
+
+	want := []string{"data:image/gif;base64,R0lGOD", ImageProxyPath(ImageHash(absolute)), ImageProxyPath(ImageHash(relative))}
+	for i := range want {
+		if resp.ImageURLs[i] != want[i] {
+			t.Errorf("ImageURLs[%d] = %q, want %q", i, resp.ImageURLs[i], want[i])
+		}
+	}
+	if resp.ThumbnailURL != want[1]+"?size=thumb" {
+		t.Errorf("ThumbnailURL = %q", resp.ThumbnailURL)
+	}
+	if empty.ThumbnailURL != "" || len(empty.ImageURLs) != 0 {
+		t.Errorf("content without images changed: %+v", empty)
+	}
+
+	var disabled *ImageProxy
+	disabled.RewriteContent(context.Background(), resp) // must not panic
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// imageFetchTimeout bounds one upstream image download
+const imageFetchTimeout = 30 * time.Second
+
+// imageFailureTTL keeps a failing image from being requested upstream on every page view
+const imageFailureTTL = 10 * time.Minute
+
+// thumbnailSuffix marks the cache key of a thumbnail
+const thumbnailSuffix = "_thumb"
+
+// maxThumbnailDecodes bounds the thumbnails decoded at once: each may hold a large decoded
+// canvas in memory
+const maxThumbnailDecodes = 2
+
+// ErrImageNotFound is returned for hashes that no content image was registered under
+var ErrImageNotFound = errors.New("image not found")
+
+// errImageUnavailable is returned while a recent upstream failure is remembered
+var errImageUnavailable = errors.New("image recently failed to download")
+
+// ImageProxyConfig sizes the image cache and thumbnails
+type ImageProxyConfig struct {
+	CacheDir       string
+	MaxCacheBytes  int64
+	MaxImageBytes  int64 // larger images are not proxied
+	ThumbnailWidth int
+}
+
+// DefaultImageProxyConfig is used for zero fields
+var DefaultImageProxyConfig = ImageProxyConfig{
+	CacheDir:       "data/images",
+	MaxCacheBytes:  1 << 30,
+	MaxImageBytes:  10 << 20,
+	ThumbnailWidth: 320,
+}
+
+func (c ImageProxyConfig) withDefaults() ImageProxyConfig {
+	if c.CacheDir == "" {
+		c.CacheDir = DefaultImageProxyConfig.CacheDir
+	}
+	if c.MaxCacheBytes <= 0 {
+		c.MaxCacheBytes = DefaultImageProxyConfig.MaxCacheBytes
+	}
+	if c.MaxImageBytes <= 0 {
+		c.MaxImageBytes = DefaultImageProxyConfig.MaxImageBytes
+	}
+	if c.ThumbnailWidth <= 0 {
+		c.ThumbnailWidth = DefaultImageProxyConfig.ThumbnailWidth
+	}
+	return c
+}
+
+// imageFetcher downloads an image; RSSService does it through the RSS proxy
+type imageFetcher interface {
+	FetchImage(ctx context.Context, imageURL, referer string, maxBytes int64) ([]byte, string, error)
+}
+
+// imageFetch is a download or thumbnail shared by concurrent requests for the same image
+type imageFetch struct {
+	done        chan struct{}
+	data        []byte
+	contentType string
+	err         error
+}
+
+// ImageProxy serves content images from a local cache as /api/images/:hash, so readers don't
+// hotlink referer-protected CDNs or reveal their IPs to them
+type ImageProxy struct {
+	repo    *repositories.ProxiedImageRepository
+	fetcher imageFetcher
+	cache   *ImageCache
+	cfg     ImageProxyConfig
+
+	registered sync.Map // hashes known to be in proxied_images
+
+	mu       sync.Mutex
+	inflight map[string]*imageFetch // by cache key
+	failures map[string]time.Time   // hash -> until when upstream isn't retried
+
+	decodes chan struct{} // one slot per thumbnail being decoded
+}
+
+// NewImageProxy opens the image cache and creates the proxy
+func NewImageProxy(repo *repositories.ProxiedImageRepository, fetcher imageFetcher, cfg ImageProxyConfig) (*ImageProxy, error) {
+	cfg = cfg.withDefaults()
+	cache, err := NewImageCache(cfg.CacheDir, cfg.MaxCacheBytes)
+	if err != nil {
+		return nil, err
+	}
+	files, size := cache.Usage()
+	log.Printf("✓ Image cache opened: %d file(s), %d MB in %s", files, size>>20, cfg.CacheDir)
+
+	return &ImageProxy{
+		repo:     repo,
+		fetcher:  fetcher,
+		cache:    cache,
+		cfg:      cfg,
+		inflight: make(map[string]*imageFetch),
+		failures: make(map[string]time.Time),
+		decodes:  make(chan struct{}, maxThumbnailDecodes),
+	}, nil
+}
+
+// ImageHash is the hash an image URL is served under: the first 128 bits of its SHA-256, in hex
+func ImageHash(imageURL string) string {
+	sum := sha256.Sum256([]byte(imageURL))
+	return hex.EncodeToString(sum[:16])
+}
+
+// imageProxyPrefix is where the API serves proxied images
+const imageProxyPrefix = "/api/images/"
+
+// ImageProxyPath is the API path serving the image with the given hash
+func ImageProxyPath(hash string) string {
+	return imageProxyPrefix + hash
+}
+
+// RewriteContent replaces the responses' image URLs with their proxied paths and sets the
+// thumbnail of each first image, registering new images in one statement. Relative URLs are
+// resolved against the article URL; URLs that aren't http(s), such as data: URIs, are left alone.
+// If the images can't be registered the original URLs are kept. A nil proxy rewrites nothing.
+func (ip *ImageProxy) RewriteContent(ctx context.Context, responses ...*models.ContentResponse) {
+	if ip == nil {
+		return
+	}
+
+	rewritten := make([]models.StringArray, len(responses))
+	var unregistered []models.ProxiedImage
+	seen := make(map[string]bool)
+	for i, resp := range responses {
+		if len(resp.ImageURLs) == 0 {
+			continue
+		}
+		page, _ := url.Parse(resp.OriginalURL)
+		rewritten[i] = make(models.StringArray, len(resp.ImageURLs))
+		for j, raw := range resp.ImageURLs {
+			rewritten[i][j] = raw
+			u, err := url.Parse(raw)
+			if err != nil {
+				continue
+			}
+			if page != nil {
+				u = page.ResolveReference(u)
+			}
+			if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				continue
+			}
+			imageURL := u.String()
+			hash := ImageHash(imageURL)
+			if _, ok := ip.registered.Load(hash); !ok && !seen[hash] {
+				seen[hash] = true
+				unregistered = append(unregistered, models.ProxiedImage{Hash: hash, URL: imageURL, PageURL: resp.OriginalURL})
+			}
+			rewritten[i][j] = ImageProxyPath(hash)
+		}
+	}
+
+	if err := ip.repo.Register(ctx, unregistered); err != nil {
+		log.Printf("Warning: Failed to register %d content image(s): %v", len(unregistered), err)
+		return
+	}
+	for _, img := range unregistered {
+		ip.registered.Store(img.Hash, true)
+	}
+
+	for i, resp := range responses {
+		if rewritten[i] == nil {
+			continue
+		}
+		resp.ImageURLs = rewritten[i]
+		for _, u := range rewritten[i] {
+			if strings.HasPrefix(u, imageProxyPrefix) {
+				resp.ThumbnailURL = u + "?size=thumb"
+				break
+			}
+		}
+	}
+}
+
+// Image returns the image registered under hash, from the cache or else downloaded and cached.
+// With thumbnail set it returns a copy scaled to the configured width; images that are already
+// narrow, or in formats that can't be scaled, are returned as they are.
+func (ip *ImageProxy) Image(ctx context.Context, hash string, thumbnail bool) ([]byte, string, error) {
+	if !thumbnail {
+		return ip.original(ctx, hash)
+	}
+
+	if data, contentType, ok := ip.cache.Get(hash + thumbnailSuffix); ok {
+		return data, contentType, nil
+	}
+	return ip.shared(ctx, hash+thumbnailSuffix, func(ctx context.Context) ([]byte, string, error) {
+		return ip.thumbnail(ctx, hash)
+	})
+}
+
+// thumbnail scales the original image down and caches the result, decoding at most
+// maxThumbnailDecodes images at once
+func (ip *ImageProxy) thumbnail(ctx context.Context, hash string) ([]byte, string, error) {
+	data, contentType, err := ip.original(ctx, hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	select {
+	case ip.decodes <- struct{}{}:
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+	thumb, thumbType, err := utils.MakeThumbnail(data, ip.cfg.ThumbnailWidth)
+	<-ip.decodes
+	if err != nil || thumb == nil {
+		return data, contentType, nil
+	}
+	if err := ip.cache.Put(hash+thumbnailSuffix, thumbType, thumb); err != nil {
+		log.Printf("Warning: Failed to cache thumbnail %s: %v", hash, err)
+	}
+	return thumb, thumbType, nil
+}
+
+// original returns the full image, downloading it at most once however many requests ask for it
+func (ip *ImageProxy) original(ctx context.Context, hash string) ([]byte, string, error) {
+	if data, contentType, ok := ip.cache.Get(hash); ok {
+		return data, contentType, nil
+	}
+
+	ip.mu.Lock()
+	if until, failed := ip.failures[hash]; failed {
+		if time.Now().Before(until) {
+			ip.mu.Unlock()
+			return nil, "", errImageUnavailable
+		}
+		delete(ip.failures, hash)
+	}
+	ip.mu.Unlock()
+
+	return ip.shared(ctx, hash, func(ctx context.Context) ([]byte, string, error) {
+		return ip.download(ctx, hash)
+	})
+}
+
+// shared runs produce for key once however many requests ask for it at the same time; the
+// others wait for its result
+func (ip *ImageProxy) shared(ctx context.Context, key string, produce func(context.Context) ([]byte, string, error)) ([]byte, string, error) {
+	ip.mu.Lock()
+	fetch, running := ip.inflight[key]
+	if !running {
+		fetch = &imageFetch{done: make(chan struct{})}
+		ip.inflight[key] = fetch
+	}
+	ip.mu.Unlock()
+
+	if !running {
+		// Detached from the first requester: the others wait on the same work
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), imageFetchTimeout)
+		fetch.data, fetch.contentType, fetch.err = produce(fetchCtx)
+		cancel()
+
+		ip.mu.Lock()
+		delete(ip.inflight, key)
+		ip.mu.Unlock()
+		close(fetch.done)
+	}
+
+	select {
+	case <-fetch.done:
+		return fetch.data, fetch.contentType, fetch.err
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+}
+
+// rememberFailureLocked stops hash from being fetched upstream for imageFailureTTL, dropping
+// failures that have already expired so the map doesn't grow with every broken image
+func (ip *ImageProxy) rememberFailureLocked(hash string) {
+	now := time.Now()
+	for h, until := range ip.failures {
+		if now.After(until) {
+			delete(ip.failures, h)
+		}
+	}
+	ip.failures[hash] = now.Add(imageFailureTTL)
+}
+
+// download fetches a registered image upstream and caches it
+func (ip *ImageProxy) download(ctx context.Context, hash string) ([]byte, string, error) {
+	img, err := ip.repo.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, "", err
+	}
+	if img == nil {
+		return nil, "", ErrImageNotFound
+	}
+
+	data, contentType, err := ip.fetcher.FetchImage(ctx, img.URL, imageReferer(img.PageURL), ip.cfg.MaxImageBytes)
+	if err != nil {
+		log.Printf("[Images] Failed to fetch %s: %v", img.URL, err)
+		if isUpstreamFailure(ctx, err) {
+			ip.mu.Lock()
+			ip.rememberFailureLocked(hash)
+			ip.mu.Unlock()
+		}
+		return nil, "", err
+	}
+	if err := ip.cache.Put(hash, contentType, data); err != nil {
+		log.Printf("Warning: Failed to cache image %s: %v", hash, err)
+	}
+	return data, contentType, nil
+}
+
+// isUpstreamFailure reports whether err says something about the image itself. Running out of
+// time, including while waiting for a per-host slot under load, does not: the next view may well
+// succeed, so it isn't remembered.
+func isUpstreamFailure(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled)
+}
+
+// imageReferer is the home page of the article's site: hotlink protection checks the referring
+// host, and the article path itself is nobody else's business
+func imageReferer(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
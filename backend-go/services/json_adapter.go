@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// JSONConfig is the adapter_config of a "json" source: JSONPath expressions that locate the
+// item list in the response and, relative to each item, its fields. Example:
+//
+//	{"items": "$.data.posts[*]", "title": "$.title", "url": "$.links.html",
+//	 "content": "$.body_html", "author": "$.author.name", "published_at": "$.created_at"}
+type JSONConfig struct {
+	Items       string `json:"items"` // required
+	Title       string `json:"title"` // required
+	URL         string `json:"url"`   // required; relative values resolve against Source.URL
+	Content     string `json:"content"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+	PublishedAt string `json:"published_at"` // RFC 3339-style string or Unix seconds/milliseconds
+	Image       string `json:"image"`        // a string or an array of strings
+}
+
+// jsonMapping is a JSONConfig with its expressions compiled
+type jsonMapping struct {
+	items, title, url, content, description, author, publishedAt, image *utils.JSONPath
+}
+
+// JSONAdapter ingests arbitrary JSON APIs using the JSONPath mappings in adapter_config
+type JSONAdapter struct {
+	parser *utils.RSSParser
+}
+
+// ValidateConfig implements adapterConfigValidator
+func (a *JSONAdapter) ValidateConfig(raw []byte) error {
+	_, err := compileJSONMapping(raw)
+	return err
+}
+
+// Fetch implements SourceAdapter
+func (a *JSONAdapter) Fetch(ctx context.Context, source *models.Source) (*utils.FetchResult, error) {
+	mapping, err := compileJSONMapping(sourceAdapterConfig(source))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	result, err := fetchJSON(ctx, a.parser.HTTPClient(), source.URL, utils.FeedValidators{
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+	}, &doc)
+	if err != nil || result.NotModified {
+		return result, err
+	}
+
+	base, _ := url.Parse(source.URL)
+	for _, node := range jsonItemNodes(mapping.items.Find(doc)) {
+		if item := mapping.toFeedItem(node, base); item != nil {
+			result.Items = append(result.Items, item)
+		}
+	}
+	return result, nil
+}
+
+// compileJSONMapping parses and compiles the mapping; items, title and url are required
+func compileJSONMapping(raw []byte) (*jsonMapping, error) {
+	var cfg JSONConfig
+	if err := decodeAdapterConfig(raw, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Items == "" || cfg.Title == "" || cfg.URL == "" {
+		return nil, fmt.Errorf("json adapter_config requires items, title and url mappings")
+	}
+
+	mapping := &jsonMapping{}
+	fields := []struct {
+		expr string
+		dst  **utils.JSONPath
+	}{
+		{cfg.Items, &mapping.items},
+		{cfg.Title, &mapping.title},
+		{cfg.URL, &mapping.url},
+		{cfg.Content, &mapping.content},
+		{cfg.Description, &mapping.description},
+		{cfg.Author, &mapping.author},
+		{cfg.PublishedAt, &mapping.publishedAt},
+		{cfg.Image, &mapping.image},
+	}
+	for _, field := range fields {
+		if field.expr == "" {
+			continue
+		}
+		path, err := utils.ParseJSONPath(field.expr)
+		if err != nil {
+			return nil, err
+		}
+		*field.dst = path
+	}
+	return mapping, nil
+}
+
+// jsonItemNodes flattens the items selection: "$.data" selecting one array yields its elements,
+// the same as "$.data[*]"
+func jsonItemNodes(matches []interface{}) []interface{} {
+	if len(matches) == 1 {
+		if list, ok := matches[0].([]interface{}); ok {
+			return list
+		}
+	}
+	return matches
+}
+
+// toFeedItem maps one item node; items without a title or URL are skipped
+func (m *jsonMapping) toFeedItem(node interface{}, base *url.URL) *utils.FeedItem {
+	item := &utils.FeedItem{
+		Title: strings.TrimSpace(m.title.FindString(node)),
+		URL:   strings.TrimSpace(m.url.FindString(node)),
+	}
+	if item.Title == "" || item.URL == "" {
+		return nil
+	}
+	if base != nil {
+		if resolved, err := base.Parse(item.URL); err == nil {
+			item.URL = resolved.String()
+		}
+	}
+
+	if m.content != nil {
+		item.Content = m.content.FindString(node)
+	}
+	if m.description != nil {
+		item.Description = m.description.FindString(node)
+	}
+	if m.author != nil {
+		item.Author = m.author.FindString(node)
+	}
+	if m.publishedAt != nil {
+		if t, ok := parseAPITime(m.publishedAt.FindString(node)); ok {
+			item.PublishedAt = &t
+		}
+	}
+	if item.PublishedAt == nil {
+		now := time.Now()
+		item.PublishedAt = &now
+	}
+	if m.image != nil {
+		for _, v := range jsonItemNodes(m.image.Find(node)) {
+			if s, ok := v.(string); ok && s != "" {
+				item.ImageURLs = append(item.ImageURLs, s)
+			}
+		}
+	}
+	return item
+}
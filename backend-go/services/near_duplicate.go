@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// Bounds of the near-duplicate Hamming threshold. Each unit of distance costs one more band,
+// and narrower bands match more unrelated items, so the index stops being selective beyond 16.
+const (
+	defaultNearDuplicateDistance = 3
+	maxNearDuplicateDistance     = 16
+)
+
+// nearDuplicateTTL matches the dedup:url window: copies are usually reposted within days
+const nearDuplicateTTL = 7 * 24 * time.Hour
+
+// NearDuplicateIndex finds earlier items whose SimHash is within a Hamming distance of a new one.
+// Fingerprints are split into maxDistance+1 bands; two fingerprints within maxDistance bits of
+// each other agree exactly on at least one band (pigeonhole), so only items sharing a band value
+// are compared. Each band value is a Redis set key of "id:fingerprint" members.
+type NearDuplicateIndex struct {
+	redis       *redis.Client
+	maxDistance int
+	bands       int
+}
+
+// NewNearDuplicateIndex creates an index; maxDistance is clamped to [0, 16] and defaults to 3 when negative
+func NewNearDuplicateIndex(rdb *redis.Client, maxDistance int) *NearDuplicateIndex {
+	if maxDistance < 0 {
+		maxDistance = defaultNearDuplicateDistance
+	}
+	if maxDistance > maxNearDuplicateDistance {
+		maxDistance = maxNearDuplicateDistance
+	}
+	return &NearDuplicateIndex{redis: rdb, maxDistance: maxDistance, bands: maxDistance + 1}
+}
+
+// MaxDistance is the Hamming distance up to which items count as near-duplicates
+func (ni *NearDuplicateIndex) MaxDistance() int {
+	return ni.maxDistance
+}
+
+// Find returns the closest indexed item within the threshold, preferring the oldest on ties
+func (ni *NearDuplicateIndex) Find(ctx context.Context, fingerprint uint64) (int64, int, bool, error) {
+	keys := ni.bandKeys(fingerprint)
+	pipe := ni.redis.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.SMembers(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, false, err
+	}
+
+	var bestID int64
+	bestDistance := ni.maxDistance + 1
+	for _, cmd := range cmds {
+		for _, member := range cmd.Val() {
+			id, candidate, ok := parseNearDuplicateMember(member)
+			if !ok {
+				continue
+			}
+			distance := utils.HammingDistance(fingerprint, candidate)
+			if distance < bestDistance || (distance == bestDistance && id < bestID) {
+				bestID, bestDistance = id, distance
+			}
+		}
+	}
+	if bestDistance > ni.maxDistance {
+		return 0, 0, false, nil
+	}
+	return bestID, bestDistance, true, nil
+}
+
+// Add indexes a canonical item under each of its band values
+func (ni *NearDuplicateIndex) Add(ctx context.Context, contentID int64, fingerprint uint64) error {
+	member := fmt.Sprintf("%d:%x", contentID, fingerprint)
+	pipe := ni.redis.Pipeline()
+	for _, key := range ni.bandKeys(fingerprint) {
+		pipe.SAdd(ctx, key, member)
+		pipe.Expire(ctx, key, nearDuplicateTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// bandKeys returns the Redis key of each band of a fingerprint. The band count is part of the
+// key, so changing the threshold starts a fresh index instead of mixing band layouts.
+func (ni *NearDuplicateIndex) bandKeys(fingerprint uint64) []string {
+	keys := make([]string, ni.bands)
+	offset := 0
+	for i := 0; i < ni.bands; i++ {
+		width := 64 / ni.bands
+		if i < 64%ni.bands {
+			width++
+		}
+		band := (fingerprint >> uint(offset)) & (1<<uint(width) - 1)
+		keys[i] = fmt.Sprintf("simhash:%d:%d:%x", ni.bands, i, band)
+		offset += width
+	}
+	return keys
+}
+
+func parseNearDuplicateMember(member string) (int64, uint64, bool) {
+	idPart, fpPart, ok := strings.Cut(member, ":")
+	if !ok {
+		return 0, 0, false
+	}
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	fingerprint, err := strconv.ParseUint(fpPart, 16, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return id, fingerprint, true
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestNearDuplicateBandsShareAKeyWithinThreshold(t *testing.T) {
+	index := NewNearDuplicateIndex(nil, 3)
+	base := uint64(0x9e3779b97f4a7c15)
+
+	// Any fingerprint at most 3 bits away agrees with base on at least one band
+	for _, flips := range [][]uint{{0}, {0, 17, 63}, {5, 21, 37}, {15, 16, 47}} {
+		other := base
+		for _, bit := range flips {
+			other ^= 1 << bit
+		}
+		shared := false
+		baseKeys := index.bandKeys(base)
+		for i, key := range index.bandKeys(other) {
+			if key == baseKeys[i] {
+				shared = true
+			}
+		}
+		if !shared {
+			t.Errorf("flipping bits %v left no shared band", flips)
+		}
+	}
+}
+
+func TestNewNearDuplicateIndexClampsDistance(t *testing.T) {
+	if d := NewNearDuplicateIndex(nil, -1).MaxDistance(); d != defaultNearDuplicateDistance {
+		t.Errorf("negative distance = %d, want default", d)
+	}
+	if d := NewNearDuplicateIndex(nil, 40).MaxDistance(); d != maxNearDuplicateDistance {
+		t.Errorf("distance 40 = %d, want clamped to %d", d, maxNearDuplicateDistance)
+	}
+	if keys := NewNearDuplicateIndex(nil, 0).bandKeys(42); len(keys) != 1 || keys[0] != "simhash:1:0:2a" {
+		t.Errorf("distance 0 should use the whole fingerprint as one band, got %v", keys)
+	}
+}
+
+func TestParseNearDuplicateMember(t *testing.T) {
+	id, fp, ok := parseNearDuplicateMember("123:ff00")
+	if !ok || id != 123 || fp != 0xff00 {
+		t.Errorf("parse = %d, %x, %v", id, fp, ok)
+	}
+	if _, _, ok := parseNearDuplicateMember("garbage"); ok {
+		t.Error("malformed member should not parse")
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// maxRedditLimit is the largest page size the listing API accepts
+const maxRedditLimit = 100
+
+// RedditConfig is the adapter_config of a "reddit" source
+type RedditConfig struct {
+	Limit        int  `json:"limit"`         // posts per poll, Reddit's default (25) when 0
+	SkipStickied bool `json:"skip_stickied"` // drop pinned moderator posts
+	IncludeNSFW  bool `json:"include_nsfw"`  // over_18 posts are skipped unless set
+}
+
+// redditListing is the subset of a Reddit listing the adapter uses
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Kind string     `json:"kind"`
+			Data redditPost `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditPost struct {
+	Title        string  `json:"title"`
+	URL          string  `json:"url"`
+	Permalink    string  `json:"permalink"`
+	Author       string  `json:"author"`
+	CreatedUTC   float64 `json:"created_utc"`
+	IsSelf       bool    `json:"is_self"`
+	SelftextHTML *string `json:"selftext_html"`
+	Thumbnail    string  `json:"thumbnail"`
+	Stickied     bool    `json:"stickied"`
+	Over18       bool    `json:"over_18"`
+	Score        int     `json:"score"`
+	NumComments  int     `json:"num_comments"`
+}
+
+// RedditAdapter reads a subreddit, user or multireddit listing through Reddit's .json endpoints.
+// Source.URL is the listing page, e.g. https://www.reddit.com/r/golang/ or .../r/golang/top/?t=day;
+// ".json" is appended to the path when missing.
+type RedditAdapter struct {
+	parser *utils.RSSParser
+}
+
+// ValidateConfig implements adapterConfigValidator
+func (a *RedditAdapter) ValidateConfig(raw []byte) error {
+	var cfg RedditConfig
+	if err := decodeAdapterConfig(raw, &cfg); err != nil {
+		return err
+	}
+	if cfg.Limit < 0 || cfg.Limit > maxRedditLimit {
+		return fmt.Errorf("reddit limit must be between 0 and %d", maxRedditLimit)
+	}
+	return nil
+}
+
+// Fetch implements SourceAdapter
+func (a *RedditAdapter) Fetch(ctx context.Context, source *models.Source) (*utils.FetchResult, error) {
+	var cfg RedditConfig
+	if err := decodeAdapterConfig(sourceAdapterConfig(source), &cfg); err != nil {
+		return nil, err
+	}
+
+	listingURL, err := redditListingURL(source.URL, cfg.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var listing redditListing
+	result, err := fetchJSON(ctx, a.parser.HTTPClient(), listingURL.String(), utils.FeedValidators{
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+	}, &listing)
+	if err != nil || result.NotModified {
+		return result, err
+	}
+
+	for _, child := range listing.Data.Children {
+		post := child.Data
+		if child.Kind != "t3" || post.Title == "" {
+			continue
+		}
+		if (cfg.SkipStickied && post.Stickied) || (post.Over18 && !cfg.IncludeNSFW) {
+			continue
+		}
+		result.Items = append(result.Items, post.toFeedItem(listingURL))
+	}
+	return result, nil
+}
+
+// toFeedItem maps a post: self posts link to their comments page, link posts to their target
+func (p *redditPost) toFeedItem(listingURL *url.URL) *utils.FeedItem {
+	commentsURL := p.Permalink
+	if resolved, err := listingURL.Parse(p.Permalink); err == nil {
+		commentsURL = resolved.String()
+	}
+
+	link := p.URL
+	if p.IsSelf || link == "" {
+		link = commentsURL
+	}
+
+	published := time.Unix(int64(p.CreatedUTC), 0)
+	item := &utils.FeedItem{
+		Title:       p.Title,
+		Description: fmt.Sprintf("%d points, %d comments: %s", p.Score, p.NumComments, commentsURL),
+		URL:         link,
+		Author:      p.Author,
+		PublishedAt: &published,
+	}
+	if p.SelftextHTML != nil {
+		item.Content = *p.SelftextHTML
+	}
+	// thumbnail is "self", "default", "nsfw" or "" when there is no preview image
+	if strings.HasPrefix(p.Thumbnail, "http") {
+		item.ImageURLs = []string{p.Thumbnail}
+	}
+	return item
+}
+
+// redditListingURL appends .json to the listing path and asks for unescaped HTML (raw_json=1)
+func redditListingURL(sourceURL string, limit int) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(sourceURL))
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Reddit URL %q", sourceURL)
+	}
+
+	if !strings.HasSuffix(parsed.Path, ".json") {
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/") + ".json"
+		parsed.RawPath = ""
+	}
+
+	query := parsed.Query()
+	query.Set("raw_json", "1")
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed, nil
+}
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,18 +15,69 @@ import (
 	"github.com/junkfilter/backend-go/utils"
 )
 
+// Backoff bounds for failing sources: the delay doubles per consecutive failure,
+// starting at backoffBase and capped at backoffMax
+const (
+	backoffBase = 5 * time.Minute
+	backoffMax  = 24 * time.Hour
+)
+
+// minContentRunes is the shortest cleaned body worth sending to evaluation
+const minContentRunes = 200
+
+// minShowNotesRunes replaces minContentRunes for podcast episodes and videos: they are evaluated
+// on their show notes, which are often only a few sentences
+const minShowNotesRunes = 40
+
+// fetchRunRetention is how long fetch_runs rows are kept before pruning
+const fetchRunRetention = 30 * 24 * time.Hour
+
+// itemOutcome is what processItem did with a feed item, tallied into the fetch run record
+type itemOutcome int
+
+const (
+	itemIngested itemOutcome = iota
+	itemDuplicate
+	itemRevised
+	itemNearDuplicate
+	itemTooShort
+	itemAuthorFiltered
+	itemRuleFiltered
+	itemLanguageFiltered
+	itemErrored
+)
+
+// rescheduleChannel is the Redis pub/sub channel carrying the ids of sources to reschedule
+const rescheduleChannel = "fetch:reschedule"
+
 // RSSService handles RSS fetching and processing
 type RSSService struct {
 	parser          *utils.RSSParser
+	adapters        *AdapterRegistry
+	websub          *WebSubService    // nil when WebSub is disabled
+	coordinator     *FetchCoordinator // nil when this is the only replica
 	sourceRepo      *repositories.SourceRepository
 	contentRepo     *repositories.ContentRepository
+	fetchRunRepo    *repositories.FetchRunRepository
 	dedupService    *DedupService
+	revisionRepo    *repositories.ContentRevisionRepository // nil disables revision tracking
+	revisionPolicy  RevisionPolicy
+	nearDuplicates  *NearDuplicateIndex             // nil disables near-duplicate detection
+	urlRuleRepo     *repositories.URLRuleRepository // nil keeps the default canonicalization only
+	filters         *FilterEngine                   // nil disables filter rules
+	filterRuleRepo  *repositories.FilterRuleRepository
+	statsRepo       *repositories.SourceStatsRepository // nil disables the daily source rollups
+	secrets         *utils.SecretBox                    // nil when no secret key is configured
+	legacyFallback  bool                                // see SetLegacyURLFallback
 	contentService  *ContentService
 	redis           *redis.Client
 	workerCount     int
 	fetchTimeout    time.Duration
 	maxRetries      int
-	ticker          *time.Ticker
+	maxFailures     int
+	scheduler       *FetchScheduler
+	defaultInterval time.Duration // used for sources without fetch_interval_seconds
+	cancelFetches   context.CancelFunc
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 }
@@ -32,188 +86,725 @@ type RSSService struct {
 func NewRSSService(
 	sourceRepo *repositories.SourceRepository,
 	contentRepo *repositories.ContentRepository,
+	fetchRunRepo *repositories.FetchRunRepository,
 	redis *redis.Client,
 	contentService *ContentService,
 	workerCount int,
 	fetchTimeout time.Duration,
 	maxRetries int,
+	maxFailures int,
 	proxyURL string,
 ) *RSSService {
+	parser := utils.NewRSSParser(proxyURL)
 	return &RSSService{
-		parser:         utils.NewRSSParser(proxyURL),
+		parser:         parser,
+		adapters:       NewAdapterRegistry(parser),
 		sourceRepo:     sourceRepo,
 		contentRepo:    contentRepo,
+		fetchRunRepo:   fetchRunRepo,
 		dedupService:   NewDedupService(redis, contentRepo),
 		contentService: contentService,
 		redis:          redis,
 		workerCount:    workerCount,
 		fetchTimeout:   fetchTimeout,
 		maxRetries:     maxRetries,
+		maxFailures:    maxFailures,
+		scheduler:      NewFetchScheduler(),
 		stopChan:       make(chan struct{}),
 	}
 }
 
 // Start begins the RSS fetching service.
-// The ticker polls every 30s, but each source is only fetched when its own
-// fetch_interval_seconds has elapsed — one ticker serves all sources with different intervals.
+// Sources are kept in a FetchScheduler keyed on their next due time; workers take the
+// highest-priority due source as soon as they are free. interval is the fetch interval
+// of sources that don't set fetch_interval_seconds.
 func (rs *RSSService) Start(ctx context.Context, interval time.Duration) error {
-	// Initialize bloom filter
-	if err := rs.dedupService.InitializeBloomFilter(ctx); err != nil {
-		log.Printf("Warning: Failed to initialize bloom filter: %v", err)
-	}
-
-	// 使用更小的轮询间隔（30秒），以支持更频繁的更新需求
-	// 这样即使用户设置 30 分钟更新，也能及时响应
-	minInterval := 30 * time.Second
-	if interval < minInterval {
-		minInterval = interval
-	}
-
-	rs.ticker = time.NewTicker(minInterval)
+	rs.defaultInterval = interval
+	ctx, rs.cancelFetches = context.WithCancel(ctx)
 	rs.wg.Add(1)
 	go rs.run(ctx)
-	log.Printf("✓ RSS Service started with poll interval: %v (configured: %v)", minInterval, interval)
+	log.Printf("✓ RSS Service started with %d workers (default interval: %v)", rs.workerCount, interval)
 	return nil
 }
 
-// Stop stops the RSS fetching service
-func (rs *RSSService) Stop() {
+// Stop stops handing out sources and waits for in-flight fetches to finish. If ctx expires
+// first, their HTTP requests are cancelled and the remaining items skipped; an item already
+// being stored is still stored and published, so no content row is left without its stream message.
+func (rs *RSSService) Stop(ctx context.Context) error {
 	close(rs.stopChan)
-	if rs.ticker != nil {
-		rs.ticker.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		rs.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	log.Printf("[Shutdown] Fetch drain deadline reached, cancelling in-flight fetches")
+	if rs.cancelFetches != nil {
+		rs.cancelFetches()
 	}
-	rs.wg.Wait()
+	<-done
+	return ctx.Err()
 }
 
 func (rs *RSSService) run(ctx context.Context) {
 	defer rs.wg.Done()
 
-	// Fetch immediately on start
-	rs.fetchAllSources(ctx)
+	// Initialize bloom filter
+	if err := rs.dedupService.InitializeBloomFilter(ctx); err != nil {
+		log.Printf("Warning: Failed to initialize bloom filter: %v", err)
+	}
+
+	rs.pruneFetchRuns(ctx)
+	rs.pruneFilterSkips(ctx)
+	rs.resyncSchedule(ctx)
+
+	// Sources, URL rules and filter rules changed through the API, possibly in another process (cmd/api)
+	var announced <-chan *redis.Message
+	if rs.redis != nil {
+		pubsub := rs.redis.Subscribe(ctx, rescheduleChannel, urlRulesChannel, filterRulesChannel)
+		defer pubsub.Close()
+		announced = pubsub.Channel()
+	}
+
+	// Workers block in scheduler.Next until a source is due; closing stopChan releases them
+	nextCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var workers sync.WaitGroup
+	workers.Add(rs.workerCount)
+	for i := 0; i < rs.workerCount; i++ {
+		go func() {
+			defer workers.Done()
+			for {
+				source, ok := rs.scheduler.Next(nextCtx)
+				if !ok {
+					return
+				}
+				fence, ok := rs.claimScheduledFetch(ctx, source)
+				if !ok {
+					continue
+				}
+				rs.fetchSource(ctx, source, models.FetchTriggerScheduled, fence)
+				rs.scheduler.Done(source.ID)
+				rs.reschedule(ctx, source.ID, false)
+			}
+		}()
+	}
+
+	resyncTicker := time.NewTicker(scheduleResyncInterval)
+	defer resyncTicker.Stop()
+	pruneTicker := time.NewTicker(24 * time.Hour)
+	defer pruneTicker.Stop()
+	dedupTicker := time.NewTicker(dedupMaintenanceInterval)
+	defer dedupTicker.Stop()
+
+	// Cancelled with the workers on stop, which rolls back a refresh in progress
+	var stats sync.WaitGroup
+	stats.Add(1)
+	go func() {
+		defer stats.Done()
+		rs.runSourceStats(nextCtx)
+	}()
 
 	for {
 		select {
 		case <-rs.stopChan:
+			cancel()
+			workers.Wait()
+			stats.Wait()
+			// Saves the URLs seen since the last snapshot so the next start needn't rebuild
+			rs.dedupService.Maintain(context.WithoutCancel(ctx))
 			return
-		case <-rs.ticker.C:
-			rs.fetchAllSources(ctx)
+		case <-resyncTicker.C:
+			rs.resyncSchedule(ctx)
+			rs.reloadRules(ctx, urlRulesChannel)
+			rs.reloadRules(ctx, filterRulesChannel)
+		case msg, ok := <-announced:
+			if !ok {
+				announced = nil
+				continue
+			}
+			if rs.reloadRules(ctx, msg.Channel) {
+				continue
+			}
+			sourceID, err := strconv.ParseInt(msg.Payload, 10, 64)
+			if err != nil {
+				log.Printf("Ignoring malformed reschedule message %q", msg.Payload)
+				continue
+			}
+			rs.reschedule(ctx, sourceID, true)
+		case <-pruneTicker.C:
+			rs.pruneFetchRuns(ctx)
+			rs.pruneFilterSkips(ctx)
+		case <-dedupTicker.C:
+			rs.dedupService.Maintain(ctx)
+		}
+	}
+}
+
+// reloadRules reloads the rules a message on channel announces changed, reporting false for
+// channels that don't announce rules
+func (rs *RSSService) reloadRules(ctx context.Context, channel string) bool {
+	switch channel {
+	case urlRulesChannel:
+		if err := rs.ReloadURLRules(ctx); err != nil {
+			log.Printf("Warning: Failed to reload URL rules: %v", err)
 		}
+	case filterRulesChannel:
+		if err := rs.ReloadFilterRules(ctx); err != nil {
+			log.Printf("Warning: Failed to reload filter rules: %v", err)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// claimScheduledFetch decides whether this replica fetches a due source. Without coordination
+// it always does (fence token 0). Otherwise the source's shard must be leased to this replica,
+// and the source is reloaded to make sure another replica didn't fetch it just before the shard
+// changed hands. Sources it passes on are put back in the scheduler.
+func (rs *RSSService) claimScheduledFetch(ctx context.Context, source *models.Source) (int64, bool) {
+	if rs.coordinator == nil {
+		return 0, true
+	}
+
+	fence, owned := rs.coordinator.Lease(source.ID)
+	if !owned {
+		// Check again after the next lease rebalance, in case this replica takes the shard over
+		rs.scheduler.Done(source.ID)
+		rs.scheduler.Postpone(source, rs.pollInterval(source, false), rs.coordinator.ttl/3)
+		return 0, false
+	}
+
+	fresh, err := rs.sourceRepo.GetByID(ctx, source.ID)
+	if err != nil || fresh == nil || !fresh.Enabled ||
+		fetchDueBase(fresh, rs.pollInterval(fresh, false)).After(time.Now()) {
+		rs.scheduler.Done(source.ID)
+		rs.reschedule(ctx, source.ID, false)
+		return 0, false
 	}
+	*source = *fresh
+	return fence, true
 }
 
-func (rs *RSSService) fetchAllSources(ctx context.Context) {
+// resyncSchedule reconciles the scheduler with the enabled sources in the database
+func (rs *RSSService) resyncSchedule(ctx context.Context) {
 	sources, err := rs.sourceRepo.GetAll(ctx, true)
 	if err != nil {
 		log.Printf("Error fetching sources: %v", err)
 		return
 	}
 
-	// Check which sources need fetching based on last_fetch_time and interval
-	toFetch := rs.filterSourcesToFetch(sources)
-
-	if len(toFetch) == 0 {
-		return
+	// Sources the hub pushes for are only polled at the WebSub fallback interval
+	var pushed map[int64]bool
+	if rs.websub != nil {
+		if pushed, err = rs.websub.ActiveSourceIDs(ctx); err != nil {
+			log.Printf("Error loading WebSub subscriptions: %v", err)
+		}
 	}
 
-	// Worker pool pattern - use a local WaitGroup to avoid race with rs.wg
-	sourceChan := make(chan *models.Source, len(toFetch))
-	var localWg sync.WaitGroup
-	localWg.Add(rs.workerCount)
+	keep := make(map[int64]bool, len(sources))
+	for _, source := range sources {
+		keep[source.ID] = true
+		rs.scheduler.Schedule(source, rs.pollInterval(source, pushed[source.ID]), false)
+	}
+	rs.scheduler.Retain(keep)
+}
 
-	for i := 0; i < rs.workerCount; i++ {
-		go func() {
-			defer localWg.Done()
-			for source := range sourceChan {
-				rs.fetchSource(ctx, source)
-			}
-		}()
+// Reschedule reloads a source after it was created, updated or deleted through the API,
+// so the scheduler picks up the change without waiting for the next resync.
+// A source that was never fetched is due immediately.
+// The id is published on rescheduleChannel so every fetching process picks it up, including
+// those started by cmd/fetcher; without Redis only the local scheduler is updated.
+func (rs *RSSService) Reschedule(ctx context.Context, sourceID int64) {
+	if rs.redis != nil {
+		err := rs.redis.Publish(ctx, rescheduleChannel, strconv.FormatInt(sourceID, 10)).Err()
+		if err == nil {
+			return
+		}
+		log.Printf("Error publishing reschedule of source %d: %v", sourceID, err)
 	}
+	rs.reschedule(ctx, sourceID, true)
+}
 
-	for _, source := range toFetch {
-		sourceChan <- source
+func (rs *RSSService) reschedule(ctx context.Context, sourceID int64, immediateIfNew bool) {
+	source, err := rs.sourceRepo.GetByID(ctx, sourceID)
+	if err != nil {
+		log.Printf("Error reloading source %d for scheduling: %v", sourceID, err)
+		return
+	}
+	if source == nil || !source.Enabled {
+		rs.scheduler.Remove(sourceID)
+		return
 	}
-	close(sourceChan)
 
-	localWg.Wait()
+	pushed := false
+	if rs.websub != nil {
+		pushed = rs.websub.IsPushed(ctx, sourceID)
+	}
+	immediate := immediateIfNew && source.LastFetchTime == nil && source.NextRetryAt == nil
+	rs.scheduler.Schedule(source, rs.pollInterval(source, pushed), immediate)
 }
 
-func (rs *RSSService) filterSourcesToFetch(sources []*models.Source) []*models.Source {
-	var toFetch []*models.Source
-	now := time.Now()
-
-	for _, source := range sources {
-		shouldFetch := source.LastFetchTime == nil || // Never fetched
-			now.Sub(*source.LastFetchTime) > time.Duration(source.FetchIntervalSeconds)*time.Second
+// pollInterval is how often a source is polled: its own interval (or the default),
+// stretched to the WebSub fallback interval while a hub pushes its updates
+func (rs *RSSService) pollInterval(source *models.Source, pushed bool) time.Duration {
+	interval := time.Duration(source.FetchIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = rs.defaultInterval
+	}
+	if pushed && interval < rs.websub.FallbackInterval() {
+		interval = rs.websub.FallbackInterval()
+	}
+	return interval
+}
 
-		if shouldFetch {
-			toFetch = append(toFetch, source)
-		}
+// fetchSource fetches and processes one source. fence is the lease token of the source's shard
+// (0 without coordination); results are discarded once a newer lease holder has written.
+func (rs *RSSService) fetchSource(ctx context.Context, source *models.Source, trigger string, fence int64) {
+	run := &models.FetchRun{
+		SourceID:  source.ID,
+		Trigger:   trigger,
+		StartedAt: time.Now(),
 	}
+	// Bookkeeping writes must land even when ctx is cancelled by a shutdown
+	dbCtx := context.WithoutCancel(ctx)
+	defer rs.saveFetchRun(dbCtx, run)
 
-	return toFetch
-}
+	ctx, err := rs.sourceHTTPContext(ctx, source)
+	if err != nil {
+		run.Status = models.FetchStatusFailed
+		errMsg := err.Error()
+		run.Error = &errMsg
+		log.Printf("Failed to fetch %s: %v", source.URL, err)
+		rs.recordFailure(dbCtx, source, err, fence)
+		return
+	}
 
-func (rs *RSSService) fetchSource(ctx context.Context, source *models.Source) {
-	// Create a timeout context for this fetch
-	fetchCtx, cancel := context.WithTimeout(ctx, rs.fetchTimeout)
-	defer cancel()
+	adapter := rs.adapters.ForPlatform(source.Platform)
 
 	var lastErr error
 	for attempt := 1; attempt <= rs.maxRetries; attempt++ {
 		if attempt > 1 {
 			backoff := time.Duration(attempt-1) * 2 * time.Second
-			time.Sleep(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				lastErr = ctx.Err()
+				break
+			}
+		}
+		run.Attempts = attempt
+
+		// Each attempt gets the full timeout; waiting for a host slot counts against it
+		fetchCtx, cancel := context.WithTimeout(ctx, rs.fetchTimeout)
+		result, err := adapter.Fetch(fetchCtx, source)
+		cancel()
+		if result != nil {
+			run.HTTPStatus = result.StatusCode
+			run.Bytes = result.Bytes
 		}
-		items, err := rs.parser.ParseFeed(source.URL)
 		if err != nil {
 			lastErr = err
 			log.Printf("Attempt %d: Failed to fetch %s: %v", attempt, source.URL, err)
+			// The server asked us to come back later; retrying within seconds would only be refused again
+			var retryAfter *utils.RetryAfterError
+			if errors.As(err, &retryAfter) || ctx.Err() != nil {
+				break
+			}
 			continue
 		}
 
+		// Update last fetch time and clear failure state — a 304 still counts as a successful poll
+		if err := rs.sourceRepo.RecordFetchSuccess(dbCtx, source.ID, time.Now(), fence); err != nil {
+			if errors.Is(err, repositories.ErrFetchFenced) {
+				run.Status = models.FetchStatusFailed
+				errMsg := err.Error()
+				run.Error = &errMsg
+				log.Printf("[FetchLease] Discarding fetch of %s: lease token %d superseded", source.URL, fence)
+				return
+			}
+			log.Printf("Failed to update last_fetch_time for source %d: %v", source.ID, err)
+		}
+
+		if result.NotModified {
+			run.Status = models.FetchStatusNotModified
+			log.Printf("Not modified: %s (304)", source.URL)
+			return
+		}
+
 		// Process items
-		for _, item := range items {
-			rs.processItem(fetchCtx, source, item)
+		run.Status = models.FetchStatusSuccess
+		rs.processItems(ctx, source, result.Items, run)
+		if ctx.Err() != nil {
+			// Interrupted by shutdown: keep the old validators so the next poll sees the skipped items again
+			log.Printf("Fetch of %s interrupted after %d new items", source.URL, run.ItemsNew)
+			return
 		}
 
-		// Update last fetch time
-		if err := rs.sourceRepo.UpdateLastFetchTime(ctx, source.ID, time.Now()); err != nil {
-			log.Printf("Failed to update last_fetch_time for source %d: %v", source.ID, err)
+		// Feeds that advertise a hub get a push subscription; polling then falls back to a long interval
+		if rs.websub != nil && result.WebSub.Hub != "" {
+			rs.websub.EnsureSubscription(ctx, source, result.WebSub)
+		}
+
+		if result.Validators.ETag != source.ETag || result.Validators.LastModified != source.LastModified {
+			if err := rs.sourceRepo.UpdateFeedValidators(dbCtx, source.ID, result.Validators.ETag, result.Validators.LastModified, fence); err != nil {
+				log.Printf("Failed to update feed validators for source %d: %v", source.ID, err)
+			}
+		}
+
+		log.Printf("Successfully fetched %s (%d items, %d new)", source.URL, len(result.Items), run.ItemsNew)
+		return
+	}
+
+	run.Status = models.FetchStatusFailed
+	if lastErr != nil {
+		errMsg := lastErr.Error()
+		run.Error = &errMsg
+	}
+	if ctx.Err() != nil {
+		// A shutdown is not the source's fault; don't count it towards backoff
+		log.Printf("Fetch of %s cancelled: %v", source.URL, ctx.Err())
+		return
+	}
+	log.Printf("Failed to fetch %s after %d attempts: %v", source.URL, run.Attempts, lastErr)
+	rs.recordFailure(dbCtx, source, lastErr, fence)
+}
+
+// processItems runs every item through processItem and tallies the outcomes into run.
+// It stops between items once ctx is cancelled.
+func (rs *RSSService) processItems(ctx context.Context, source *models.Source, items []*utils.FeedItem, run *models.FetchRun) {
+	run.ItemsSeen = len(items)
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		outcome := rs.processItem(ctx, source, item)
+		rs.countDistinct(ctx, source, item, outcome, run)
+		switch outcome {
+		case itemIngested:
+			run.ItemsNew++
+		case itemDuplicate:
+			run.ItemsDuplicate++
+		case itemRevised:
+			run.ItemsRevised++
+		case itemNearDuplicate:
+			run.ItemsNearDuplicate++
+		case itemTooShort:
+			run.ItemsTooShort++
+		case itemAuthorFiltered:
+			run.ItemsAuthorFiltered++
+		case itemRuleFiltered:
+			run.ItemsRuleFiltered++
+		case itemLanguageFiltered:
+			run.ItemsLanguageFiltered++
+		case itemErrored:
+			run.ItemsErrored++
+		}
+	}
+}
+
+// countDistinct adds a skipped item to run's distinct counters the first time the source lists
+// it with that outcome. Items the source stores are marked as well, so listing them again on
+// later polls doesn't count as a duplicate.
+func (rs *RSSService) countDistinct(ctx context.Context, source *models.Source, item *utils.FeedItem, outcome itemOutcome, run *models.FetchRun) {
+	if rs.dedupService == nil {
+		return
+	}
+	var kind string
+	var counter *int
+	switch outcome {
+	case itemIngested, itemNearDuplicate, itemRevised, itemDuplicate:
+		kind, counter = "duplicate", &run.DistinctDuplicate
+	case itemTooShort:
+		kind, counter = "too_short", &run.DistinctTooShort
+	case itemAuthorFiltered, itemRuleFiltered, itemLanguageFiltered:
+		kind, counter = "filtered", &run.DistinctFiltered
+	default:
+		return
+	}
+	first, err := rs.dedupService.FirstOutcome(context.WithoutCancel(ctx), source.ID, kind, item.URL)
+	if err != nil {
+		log.Printf("Warning: Failed to record outcome of %s: %v", item.URL, err)
+		return
+	}
+	if first && (kind != "duplicate" || outcome == itemDuplicate) {
+		*counter++
+	}
+}
+
+// StartPushIngestion readies a process that ingests WebSub pushes without running the fetch
+// loop (cmd/api): it loads the bloom filter and follows the rule changes announced by other
+// processes, as run does. The fetcher owns the bloom snapshots, so this filter is rebuilt from
+// the content table and never saved. Stop ends it.
+func (rs *RSSService) StartPushIngestion(ctx context.Context) {
+	cfg := rs.dedupService.bloomConfig
+	cfg.SnapshotPath, cfg.Replicated = "", true
+	rs.dedupService.Configure(cfg)
+
+	rs.wg.Add(1)
+	go rs.runPushIngestion(ctx)
+	log.Println("✓ Push ingestion started")
+}
+
+func (rs *RSSService) runPushIngestion(ctx context.Context) {
+	defer rs.wg.Done()
+
+	if err := rs.dedupService.InitializeBloomFilter(ctx); err != nil {
+		log.Printf("Warning: Failed to initialize bloom filter: %v", err)
+	}
+
+	var announced <-chan *redis.Message
+	if rs.redis != nil {
+		pubsub := rs.redis.Subscribe(ctx, urlRulesChannel, filterRulesChannel)
+		defer pubsub.Close()
+		announced = pubsub.Channel()
+	}
+
+	resyncTicker := time.NewTicker(scheduleResyncInterval)
+	defer resyncTicker.Stop()
+	dedupTicker := time.NewTicker(dedupMaintenanceInterval)
+	defer dedupTicker.Stop()
+
+	for {
+		select {
+		case <-rs.stopChan:
+			return
+		case <-resyncTicker.C:
+			rs.reloadRules(ctx, urlRulesChannel)
+			rs.reloadRules(ctx, filterRulesChannel)
+		case msg, ok := <-announced:
+			if !ok {
+				announced = nil
+				continue
+			}
+			rs.reloadRules(ctx, msg.Channel)
+		case <-dedupTicker.C:
+			rs.dedupService.Maintain(ctx)
 		}
+	}
+}
+
+// IngestPushedFeed processes a feed document delivered by a WebSub hub exactly like a poll,
+// recording it as a fetch run with trigger "push"
+func (rs *RSSService) IngestPushedFeed(ctx context.Context, source *models.Source, body []byte) {
+	run := &models.FetchRun{
+		SourceID:  source.ID,
+		Trigger:   models.FetchTriggerPush,
+		StartedAt: time.Now(),
+		Attempts:  1,
+		Bytes:     int64(len(body)),
+	}
+	defer rs.saveFetchRun(ctx, run)
 
-		log.Printf("Successfully fetched %s (%d items)", source.URL, len(items))
+	items, err := utils.ParseFeedBody(bytes.NewReader(body))
+	if err != nil {
+		errMsg := err.Error()
+		run.Status = models.FetchStatusFailed
+		run.Error = &errMsg
+		log.Printf("[WebSub] Failed to parse push for %s: %v", source.URL, err)
 		return
 	}
 
-	log.Printf("Failed to fetch %s after %d attempts: %v", source.URL, rs.maxRetries, lastErr)
+	run.Status = models.FetchStatusSuccess
+	rs.processItems(ctx, source, items, run)
+	log.Printf("[WebSub] Push for %s (%d items, %d new)", source.URL, len(items), run.ItemsNew)
+}
+
+// SetNearDuplicates enables SimHash near-duplicate detection across URLs
+func (rs *RSSService) SetNearDuplicates(index *NearDuplicateIndex) {
+	rs.nearDuplicates = index
+}
+
+// ConfigureDedup sizes the URL bloom filter; call it before Start
+func (rs *RSSService) ConfigureDedup(cfg BloomConfig) {
+	rs.dedupService.Configure(cfg)
+}
+
+// Dedup returns the deduplication service, whose stats the admin API reports
+func (rs *RSSService) Dedup() *DedupService {
+	return rs.dedupService
 }
 
-func (rs *RSSService) processItem(ctx context.Context, source *models.Source, item *utils.FeedItem) {
+// SetCoordinator enables multi-replica coordination: only sources whose shard is leased
+// to this replica are fetched by the scheduler
+func (rs *RSSService) SetCoordinator(coordinator *FetchCoordinator) {
+	rs.coordinator = coordinator
+}
+
+// SetWebSub enables push subscriptions for feeds that advertise a hub
+func (rs *RSSService) SetWebSub(websub *WebSubService) {
+	rs.websub = websub
+}
+
+// saveFetchRun stamps the finish time and persists the run; failures are logged, never fatal
+func (rs *RSSService) saveFetchRun(ctx context.Context, run *models.FetchRun) {
+	if rs.fetchRunRepo == nil {
+		return
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	if run.Status == "" {
+		run.Status = models.FetchStatusFailed
+	}
+	if err := rs.fetchRunRepo.Create(ctx, run); err != nil {
+		log.Printf("Failed to save fetch run for source %d: %v", run.SourceID, err)
+	}
+}
+
+// pruneFetchRuns deletes fetch_runs older than fetchRunRetention
+func (rs *RSSService) pruneFetchRuns(ctx context.Context) {
+	if rs.fetchRunRepo == nil {
+		return
+	}
+	deleted, err := rs.fetchRunRepo.DeleteBefore(ctx, time.Now().Add(-fetchRunRetention))
+	if err != nil {
+		log.Printf("Failed to prune fetch runs: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d fetch runs older than %v", deleted, fetchRunRetention)
+	}
+}
+
+// recordFailure persists the failure and schedules the next retry with exponential backoff.
+// Sources that reach maxFailures consecutive failures are disabled.
+func (rs *RSSService) recordFailure(ctx context.Context, source *models.Source, fetchErr error, fence int64) {
+	errMsg := "unknown error"
+	if fetchErr != nil {
+		errMsg = fetchErr.Error()
+	}
+	errMsg = utils.TruncateRunes(errMsg, 500)
+
+	delay := backoffDelay(source.ConsecutiveFailures + 1)
+	var retryAfter *utils.RetryAfterError
+	if errors.As(fetchErr, &retryAfter) && retryAfter.RetryAfter > delay {
+		delay = retryAfter.RetryAfter
+	}
+	nextRetryAt := time.Now().Add(delay)
+	failures, err := rs.sourceRepo.RecordFetchFailure(ctx, source.ID, errMsg, nextRetryAt, rs.maxFailures, fence)
+	if err != nil {
+		log.Printf("Failed to record fetch failure for source %d: %v", source.ID, err)
+		return
+	}
+
+	if rs.maxFailures > 0 && failures == rs.maxFailures {
+		log.Printf("[AutoDisable] Source %d (%s) disabled after %d consecutive failures", source.ID, source.URL, failures)
+		return
+	}
+	log.Printf("[Backoff] Source %d failed %d time(s) in a row, next retry at %s", source.ID, failures, nextRetryAt.Format(time.RFC3339))
+}
+
+// backoffDelay returns backoffBase * 2^(failures-1), capped at backoffMax
+func backoffDelay(failures int) time.Duration {
+	if failures < 1 {
+		failures = 1
+	}
+	delay := backoffBase
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if delay >= backoffMax {
+			return backoffMax
+		}
+	}
+	return delay
+}
+
+func (rs *RSSService) processItem(ctx context.Context, source *models.Source, item *utils.FeedItem) itemOutcome {
+	articleURL := item.URL // keep the raw link: canonicalizing drops tracking parameters and redirectors
 	item = utils.SanitizeFeedItem(item)
 
+	// Excerpt-only feeds: replace the excerpt with the extracted article body before the length check.
+	// The article page is also fetched when the domain's URL rule follows rel=canonical.
+	fullText := source.FetchFullText && len([]rune(item.Content)) < minContentRunes
+	if articleURL == "" || !(fullText || utils.ResolvesCanonical(item.URL)) {
+		return rs.ingestItem(ctx, source, item)
+	}
+
+	// Each page download is a request to the publisher, and the feed keeps listing old items:
+	// only items that pass the filters on their excerpt and are new, or whose feed text
+	// changed since the last poll, get their page downloaded
+	if outcome, filtered := rs.filterItem(ctx, source, item); filtered {
+		return outcome
+	}
+	feedURL := item.URL
+	feedHash := utils.GenerateBodyHash(item.Title, item.Content)
+	seen, err := rs.dedupService.SeenPage(ctx, feedURL)
+	if err != nil {
+		log.Printf("Warning: Failed to look up page of %s: %v", feedURL, err)
+	}
+	record := false
+	switch {
+	case seen != nil && seen.FeedHash == feedHash:
+		return itemDuplicate
+	case seen != nil && !fullText && seen.URL != "":
+		// Edited feed text of a known item: the canonical URL found last time still holds
+		item.URL = seen.URL
+		record = true
+	default:
+		record = rs.enrichFromPage(ctx, item, articleURL, fullText)
+	}
+
+	outcome := rs.ingestItem(ctx, source, item)
+	// A failed download is retried on the next poll
+	if record && outcome != itemErrored {
+		if err := rs.dedupService.MarkPageSeen(context.WithoutCancel(ctx), feedURL, PageSeen{FeedHash: feedHash, URL: item.URL}); err != nil {
+			log.Printf("Warning: Failed to mark page of %s as seen: %v", feedURL, err)
+		}
+	}
+	return outcome
+}
+
+// ingestItem checks, stores and publishes a sanitized item whose page, if any, was downloaded
+func (rs *RSSService) ingestItem(ctx context.Context, source *models.Source, item *utils.FeedItem) itemOutcome {
+	// From here the item is checked, stored and published as a unit: a shutdown must not
+	// leave a content row behind without its stream message
+	ctx = context.WithoutCancel(ctx)
+
 	// Short-content filter before dedup — skip RSS excerpts with no real body,
 	// saving Redis and DB round-trips for content we'd discard anyway
-	if len([]rune(item.Content)) < 200 {
+	minRunes := minContentRunes
+	if utils.HasMedia(item.Enclosures) {
+		minRunes = minShowNotesRunes
+	}
+	if len([]rune(item.Content)) < minRunes {
 		log.Printf("[Skip] Content too short (%d runes), skipping: %s", len([]rune(item.Content)), item.Title)
-		return
+		return itemTooShort
 	}
 
-	// Author filter before dedup — same reason: skip early before any I/O
-	if source.ShouldFilterAuthor(item.Author) {
-		return
+	if outcome, filtered := rs.filterItem(ctx, source, item); filtered {
+		return outcome
 	}
 
-	// Check for duplicates
+	// Check for duplicates; an already-ingested URL may still carry edited text
+	bodyHash := utils.GenerateBodyHash(item.Title, item.Content)
 	contentHash, isDuplicate, err := rs.dedupService.ValidateContent(
 		ctx, item.URL, item.Title, item.Content,
 	)
 	if err != nil {
 		log.Printf("Error validating content: %v", err)
-		return
+		return itemErrored
 	}
 
 	if isDuplicate {
-		return // Skip duplicate
+		if rs.checkRevision(ctx, source, item, bodyHash) {
+			return itemRevised
+		}
+		return itemDuplicate
+	}
+	if rs.isLegacyDuplicate(ctx, item, bodyHash) {
+		return itemDuplicate
 	}
 
 	// Create content record
@@ -223,6 +814,19 @@ func (rs *RSSService) processItem(ctx context.Context, source *models.Source, it
 		authorName = source.AuthorName
 	}
 
+	// Near-duplicate check: a copy of an earlier item under another URL is stored and linked
+	// to that item, but not evaluated again
+	fingerprint, fingerprinted := utils.SimHash(item.Content)
+	var canonicalID *int64
+	if fingerprinted && rs.nearDuplicates != nil {
+		if id, distance, found, err := rs.nearDuplicates.Find(ctx, fingerprint); err != nil {
+			log.Printf("Warning: Near-duplicate lookup failed: %v", err)
+		} else if found {
+			log.Printf("[NearDup] %s is within %d bits of content %d", item.URL, distance, id)
+			canonicalID = &id
+		}
+	}
+
 	req := &models.CreateContentRequest{
 		SourceID:     source.ID,
 		Platform:     source.Platform,
@@ -231,31 +835,117 @@ func (rs *RSSService) processItem(ctx context.Context, source *models.Source, it
 		OriginalURL:  item.URL,
 		ContentHash:  contentHash,
 		CleanContent: item.Content,
+		FullMarkdown: item.FullContent,
 		ImageURLs:    item.ImageURLs,
 		PublishedAt:  item.PublishedAt,
+		BodyHash:     bodyHash,
+		CanonicalID:  canonicalID,
+		Language:     item.Language,
+		Enclosures:   item.Enclosures,
+	}
+	if fingerprinted {
+		req.SimHash = &fingerprint
 	}
 
 	content, err := rs.contentRepo.Create(ctx, req)
 	if err != nil {
-		// Might be a duplicate from concurrent insert (L3 constraint)
+		// Might be a duplicate from concurrent insert (L3 constraint), or an item whose
+		// Redis dedup key expired — which may have been edited since
 		log.Printf("Note: Could not create content (may be duplicate): %v", err)
-		return
+		if rs.checkRevision(ctx, source, item, bodyHash) {
+			return itemRevised
+		}
+		rs.dedupService.RecordDatabaseDuplicate(item.URL)
+		return itemDuplicate
 	}
 
-	// Mark as seen
-	if err := rs.dedupService.MarkAsSeen(ctx, item.URL, contentHash); err != nil {
+	// Mark as seen; the stored body hash lets later polls spot edits without a DB lookup
+	if err := rs.dedupService.MarkAsSeen(ctx, item.URL, bodyHash); err != nil {
 		log.Printf("Warning: Failed to mark URL as seen: %v", err)
 	}
 
+	if canonicalID != nil {
+		return itemNearDuplicate
+	}
+	// Only originals are indexed, so every copy links straight to the first item
+	if fingerprinted && rs.nearDuplicates != nil {
+		if err := rs.nearDuplicates.Add(ctx, content.ID, fingerprint); err != nil {
+			log.Printf("Warning: Failed to index SimHash of content %d: %v", content.ID, err)
+		}
+	}
+
 	// Publish to Stream
-	if err := rs.contentService.PublishToStream(ctx, content); err != nil {
+	if err := rs.contentService.PublishToStream(ctx, content, source.EffectiveStreamPayload()); err != nil {
 		log.Printf("Error publishing to stream: %v", err)
 		// Keep status as PENDING so it can be retried
 		rs.contentRepo.UpdateStatus(ctx, content.ID, "PENDING")
-		return
+		return itemIngested
 	}
 
 	log.Printf("Ingested: %s (ID: %d)", item.Title, content.ID)
+	return itemIngested
+}
+
+// filterItem applies the source's author filter and language policy, then the filter rules.
+// They run before dedup: skipped items cost no Redis or DB lookups and are never evaluated.
+func (rs *RSSService) filterItem(ctx context.Context, source *models.Source, item *utils.FeedItem) (itemOutcome, bool) {
+	if source.ShouldFilterAuthor(item.Author) {
+		return itemAuthorFiltered, true
+	}
+
+	// Language policy: sources that mix in languages we don't read keep only the allowed ones
+	if !source.AllowsLanguage(item.Language) {
+		return itemLanguageFiltered, true
+	}
+
+	// Filter rules: source-specific, then global
+	if rule := rs.filters.Match(source.ID, item, time.Now()); rule != nil {
+		rs.recordFilterSkip(ctx, source, item, rule)
+		return itemRuleFiltered, true
+	}
+	return 0, false
+}
+
+// enrichFromPage downloads the article page and, when its domain's URL rule asks for it, moves
+// the item to the page's rel=canonical URL. With fullText it also extracts the main content and,
+// when that is longer than the feed excerpt, runs it through the CleanContent Markdown pipeline
+// in place of the excerpt. Errors are logged and leave the item untouched; it reports whether the
+// page was downloaded.
+func (rs *RSSService) enrichFromPage(ctx context.Context, item *utils.FeedItem, articleURL string, fullText bool) bool {
+	fetchCtx, cancel := context.WithTimeout(ctx, rs.fetchTimeout)
+	defer cancel()
+
+	pageHTML, err := rs.parser.FetchArticleHTML(fetchCtx, articleURL)
+	if err != nil {
+		log.Printf("[FullText] Failed to download %s: %v", articleURL, err)
+		return false
+	}
+
+	if utils.ResolvesCanonical(item.URL) {
+		resolveCanonicalURL(item, pageHTML, articleURL)
+	}
+	if !fullText {
+		return true
+	}
+
+	mainHTML, err := utils.ExtractMainContent(pageHTML, articleURL)
+	if err != nil {
+		log.Printf("[FullText] No main content extracted from %s: %v", articleURL, err)
+		return true
+	}
+
+	articleText := utils.CleanContent(mainHTML)
+	if len([]rune(articleText)) <= len([]rune(item.Body())) {
+		return true
+	}
+
+	item.SetBody(articleText)
+	item.Language = utils.DetectLanguage(item.Title + "\n" + item.Content)
+	if len(item.ImageURLs) == 0 {
+		item.ImageURLs = utils.ExtractImageURLs(mainHTML)
+	}
+	log.Printf("[FullText] Extracted %d runes from %s", len([]rune(articleText)), articleURL)
+	return true
 }
 
 // SetProxyURL updates the RSS proxy at runtime
@@ -263,11 +953,43 @@ func (rs *RSSService) SetProxyURL(proxyURL string) {
 	rs.parser.SetProxyURL(proxyURL)
 }
 
+// ConfigureFetching sets the User-Agent and the per-host limits shared by every fetch worker
+func (rs *RSSService) ConfigureFetching(userAgent string, defaults utils.HostLimit, overrides map[string]utils.HostLimit) {
+	rs.parser.SetUserAgent(userAgent)
+	rs.parser.SetHostLimits(defaults, overrides)
+}
+
+// SetImageHostLimit changes the per-host limit of image proxy downloads
+func (rs *RSSService) SetImageHostLimit(limit utils.HostLimit) {
+	rs.parser.SetImageHostLimit(limit)
+}
+
+// SetMaxFeedBytes bounds the size of a feed body; larger feeds fail to fetch
+func (rs *RSSService) SetMaxFeedBytes(n int64) {
+	rs.parser.SetMaxFeedBytes(n)
+}
+
 // GetProxyURL returns the current proxy URL
 func (rs *RSSService) GetProxyURL() string {
 	return rs.parser.GetProxyURL()
 }
 
+// Adapters returns the registry mapping Source.Platform to ingestion adapters
+func (rs *RSSService) Adapters() *AdapterRegistry {
+	return rs.adapters
+}
+
+// FetchImage downloads a content image through the fetcher's HTTP client (and proxy)
+func (rs *RSSService) FetchImage(ctx context.Context, imageURL, referer string, maxBytes int64) ([]byte, string, error) {
+	return rs.parser.FetchImage(ctx, imageURL, referer, maxBytes)
+}
+
+// DiscoverFeeds inspects a URL through the fetcher's HTTP client (and proxy) and
+// returns the feeds it is or advertises
+func (rs *RSSService) DiscoverFeeds(ctx context.Context, pageURL string) (*utils.FeedDiscovery, error) {
+	return rs.parser.DiscoverFeeds(ctx, pageURL)
+}
+
 // FetchSourceOnDemand fetches a specific source immediately
 func (rs *RSSService) FetchSourceOnDemand(ctx context.Context, sourceID int64) error {
 	source, err := rs.sourceRepo.GetByID(ctx, sourceID)
@@ -279,6 +1001,13 @@ func (rs *RSSService) FetchSourceOnDemand(ctx context.Context, sourceID int64) e
 		return nil
 	}
 
-	rs.fetchSource(ctx, source)
+	// A manual fetch runs on whichever replica received the request; it is fenced only if this
+	// replica happens to hold the shard
+	var fence int64
+	if rs.coordinator != nil {
+		fence, _ = rs.coordinator.Lease(sourceID)
+	}
+	rs.fetchSource(ctx, source, models.FetchTriggerManual, fence)
+	rs.Reschedule(ctx, sourceID)
 	return nil
 }
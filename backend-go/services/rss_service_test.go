@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+func TestRSSServiceStopCancelsFetchesAfterDeadline(t *testing.T) {
+	rs := &RSSService{stopChan: make(chan struct{})}
+	fetchCtx, cancel := context.WithCancel(context.Background())
+	rs.cancelFetches = cancel
+
+	// A fetch that only ends when its context is cancelled
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+		<-fetchCtx.Done()
+	}()
+
+	ctx, cancelStop := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelStop()
+	if err := rs.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop() error = %v, want deadline exceeded", err)
+	}
+	if fetchCtx.Err() == nil {
+		t.Error("in-flight fetches should be cancelled once the drain deadline passes")
+	}
+}
+
+func TestRSSServiceStopWaitsForFetches(t *testing.T) {
+	rs := &RSSService{stopChan: make(chan struct{})}
+	_, cancel := context.WithCancel(context.Background())
+	rs.cancelFetches = cancel
+
+	finished := false
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+	}()
+
+	if err := rs.Stop(context.Background()); err != nil || !finished {
+		t.Fatalf("Stop() = %v, finished = %v; want a clean drain", err, finished)
+	}
+}
+
+func TestProcessItemsStopsWhenCancelled(t *testing.T) {
+	rs := &RSSService{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	run := &models.FetchRun{}
+	items := []*utils.FeedItem{{Title: "a"}, {Title: "b"}}
+	rs.processItems(ctx, &models.Source{ID: 1}, items, run)
+
+	if run.ItemsSeen != 2 || run.ItemsNew+run.ItemsTooShort+run.ItemsDuplicate+run.ItemsErrored != 0 {
+		t.Errorf("cancelled run processed items: %+v", run)
+	}
+}
+
+func TestProcessItemsAppliesLanguagePolicy(t *testing.T) {
+	rs := &RSSService{}
+	source := &models.Source{ID: 1, AllowedLanguages: []string{"zh"}}
+	items := []*utils.FeedItem{{
+		Title:   "Notes on the new scheduler",
+		Content: "This is a long article about how the new scheduler works and why it was needed. It covers the design of the run queue, the fairness between tasks and the benchmarks that were used to compare it with the old implementation in detail.",
+	}}
+
+	run := &models.FetchRun{}
+	rs.processItems(context.Background(), source, items, run)
+	if run.ItemsLanguageFiltered != 1 {
+		t.Errorf("English item from a Chinese-only source: %+v", run)
+	}
+}
+
+func TestProcessItemsAcceptsShortShowNotes(t *testing.T) {
+	rs := &RSSService{}
+	// The Chinese-only policy stops both items right after the length check, before any I/O
+	source := &models.Source{ID: 1, AllowedLanguages: []string{"zh"}}
+	notes := "We talk about the new scheduler and how it was benchmarked."
+	items := []*utils.FeedItem{
+		{Title: "Episode 12", Content: notes, Enclosures: []utils.Enclosure{{URL: "https://cdn.example.com/ep12.mp3", Type: "audio/mpeg"}}},
+		{Title: "Episode 12 notes", Content: notes},
+	}
+
+	run := &models.FetchRun{}
+	rs.processItems(context.Background(), source, items, run)
+	if run.ItemsLanguageFiltered != 1 || run.ItemsTooShort != 1 {
+		t.Errorf("episode should pass the length check and the article should not: %+v", run)
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// maxAdapterBodyBytes bounds a single API response read by an adapter
+const maxAdapterBodyBytes = 10 << 20
+
+// SourceAdapter fetches one source and converts what it serves into FeedItems.
+// The result carries HTTP status, bytes and cache validators exactly like an RSS fetch,
+// so fetch runs, conditional GET and failure backoff work the same for every platform.
+type SourceAdapter interface {
+	Fetch(ctx context.Context, source *models.Source) (*utils.FetchResult, error)
+}
+
+// adapterConfigValidator is implemented by adapters whose adapter_config can be checked
+// when a source is created or updated rather than on its first fetch
+type adapterConfigValidator interface {
+	ValidateConfig(raw []byte) error
+}
+
+// AdapterRegistry maps Source.Platform to the adapter that fetches it.
+// Platforms without a registered adapter fall back to the RSS adapter.
+type AdapterRegistry struct {
+	mu       sync.RWMutex
+	adapters map[string]SourceAdapter
+	fallback SourceAdapter
+}
+
+// NewAdapterRegistry creates a registry with the built-in adapters. All of them share
+// parser's HTTP client, so runtime proxy changes apply to every platform.
+func NewAdapterRegistry(parser *utils.RSSParser) *AdapterRegistry {
+	rss := &RSSAdapter{parser: parser}
+	registry := &AdapterRegistry{
+		adapters: make(map[string]SourceAdapter),
+		fallback: rss,
+	}
+	registry.Register(models.PlatformRSS, rss)
+	registry.Register(models.PlatformGitHub, &GitHubAdapter{parser: parser})
+	registry.Register(models.PlatformHackerNews, &HackerNewsAdapter{parser: parser})
+	registry.Register(models.PlatformReddit, &RedditAdapter{parser: parser})
+	registry.Register(models.PlatformJSON, &JSONAdapter{parser: parser})
+	return registry
+}
+
+// Register adds or replaces the adapter for platform (case-insensitive)
+func (ar *AdapterRegistry) Register(platform string, adapter SourceAdapter) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.adapters[strings.ToLower(platform)] = adapter
+}
+
+// ForPlatform returns the adapter for platform, or the RSS adapter when none is registered
+func (ar *AdapterRegistry) ForPlatform(platform string) SourceAdapter {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	if adapter, ok := ar.adapters[strings.ToLower(platform)]; ok {
+		return adapter
+	}
+	return ar.fallback
+}
+
+// IsFeedPlatform reports whether platform is fetched as an RSS/Atom/JSON Feed,
+// i.e. whether its URL should go through feed autodiscovery
+func (ar *AdapterRegistry) IsFeedPlatform(platform string) bool {
+	_, ok := ar.ForPlatform(platform).(*RSSAdapter)
+	return ok
+}
+
+// ValidateConfig checks adapter_config for platform; adapters without settings accept anything
+func (ar *AdapterRegistry) ValidateConfig(platform string, raw []byte) error {
+	validator, ok := ar.ForPlatform(platform).(adapterConfigValidator)
+	if !ok {
+		return nil
+	}
+	return validator.ValidateConfig(raw)
+}
+
+// RSSAdapter fetches RSS, Atom and JSON Feed documents through gofeed with conditional GET
+type RSSAdapter struct {
+	parser *utils.RSSParser
+}
+
+// Fetch implements SourceAdapter
+func (a *RSSAdapter) Fetch(ctx context.Context, source *models.Source) (*utils.FetchResult, error) {
+	return a.parser.FetchFeed(ctx, source.URL, utils.FeedValidators{
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+	})
+}
+
+// decodeAdapterConfig unmarshals the source's adapter_config into cfg; a missing config leaves cfg untouched
+func decodeAdapterConfig(raw []byte, cfg interface{}) error {
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return fmt.Errorf("invalid adapter_config: %w", err)
+	}
+	return nil
+}
+
+// sourceAdapterConfig returns the source's raw adapter_config
+func sourceAdapterConfig(source *models.Source) []byte {
+	if source.AdapterConfigJSON == nil {
+		return nil
+	}
+	return []byte(*source.AdapterConfigJSON)
+}
+
+// fetchJSON GETs apiURL and decodes the body into out. Validators are sent as
+// If-None-Match / If-Modified-Since; on 304 the result has NotModified set and out is untouched.
+// Like FetchFeed, a result with StatusCode and Bytes accompanies errors once the server answered.
+func fetchJSON(ctx context.Context, client *http.Client, apiURL string, validators utils.FeedValidators, out interface{}) (*utils.FetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &utils.FetchResult{NotModified: true, Validators: validators, StatusCode: resp.StatusCode}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &utils.FetchResult{StatusCode: resp.StatusCode}, utils.HTTPStatusError(resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAdapterBodyBytes))
+	result := &utils.FetchResult{
+		StatusCode: resp.StatusCode,
+		Bytes:      int64(len(body)),
+		Validators: utils.FeedValidators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return result, fmt.Errorf("decode %s: %w", apiURL, err)
+	}
+	return result, nil
+}
+
+// adapterTimeLayouts are tried in order when a JSON API returns a timestamp as a string
+var adapterTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006-01-02",
+}
+
+// parseAPITime accepts RFC 3339-style strings and Unix timestamps in seconds or milliseconds
+func parseAPITime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(int64(n)), true
+		}
+		return time.Unix(int64(n), 0), true
+	}
+	for _, layout := range adapterTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
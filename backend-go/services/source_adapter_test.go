@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// newStubParser returns a parser without per-host limits: every stub server is 127.0.0.1
+func newStubParser() *utils.RSSParser {
+	rp := utils.NewRSSParser()
+	rp.SetHostLimits(utils.HostLimit{}, nil)
+	return rp
+}
+
+func newStubSource(platform, url, config string) *models.Source {
+	source := &models.Source{ID: 1, Platform: platform, URL: url}
+	if config != "" {
+		source.AdapterConfigJSON = &config
+	}
+	return source
+}
+
+func TestAdapterRegistryForPlatform(t *testing.T) {
+	registry := NewAdapterRegistry(newStubParser())
+
+	cases := map[string]interface{}{
+		"":           &RSSAdapter{},
+		"blog":       &RSSAdapter{},
+		"GitHub":     &GitHubAdapter{},
+		"hackernews": &HackerNewsAdapter{},
+		"reddit":     &RedditAdapter{},
+		"json":       &JSONAdapter{},
+	}
+	for platform, want := range cases {
+		got := registry.ForPlatform(platform)
+		if fmt.Sprintf("%T", got) != fmt.Sprintf("%T", want) {
+			t.Errorf("ForPlatform(%q) = %T, want %T", platform, got, want)
+		}
+	}
+
+	if !registry.IsFeedPlatform("blog") || registry.IsFeedPlatform("reddit") {
+		t.Error("IsFeedPlatform should be true only for feed platforms")
+	}
+	if err := registry.ValidateConfig("json", []byte(`{"items": "$.items"}`)); err == nil {
+		t.Error("json config without title/url mappings should be rejected")
+	}
+	if err := registry.ValidateConfig("github", []byte(`{"kind": "issues"}`)); err == nil {
+		t.Error("unknown github kind should be rejected")
+	}
+	if err := registry.ValidateConfig("blog", []byte(`{"anything": 1}`)); err != nil {
+		t.Errorf("RSS sources accept any config, got %v", err)
+	}
+}
+
+func TestGitHubAdapterReleases(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Header().Set("ETag", `"rel-1"`)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Release notes from widget</title>
+  <entry>
+    <id>tag:github.com,2008:Repository/1/v1.2.0</id>
+    <updated>2024-05-01T10:00:00Z</updated>
+    <link rel="alternate" type="text/html" href="https://github.com/acme/widget/releases/tag/v1.2.0"/>
+    <title>v1.2.0</title>
+    <content type="html">&lt;p&gt;Bug fixes&lt;/p&gt;</content>
+    <author><name>octocat</name></author>
+  </entry>
+</feed>`)
+	}))
+	defer server.Close()
+
+	adapter := &GitHubAdapter{parser: newStubParser()}
+	// The stub isn't on github.com: the adapter_config marks it as a GitHub Enterprise repository
+	result, err := adapter.Fetch(context.Background(), newStubSource("github", server.URL+"/acme/widget/", `{"kind": "releases"}`))
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if requested != "/acme/widget/releases.atom" {
+		t.Errorf("requested %q, want /acme/widget/releases.atom", requested)
+	}
+	if len(result.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(result.Items))
+	}
+	item := result.Items[0]
+	if item.Title != "acme/widget v1.2.0" || item.Author != "octocat" {
+		t.Errorf("unexpected item: title=%q author=%q", item.Title, item.Author)
+	}
+	if result.Validators.ETag != `"rel-1"` {
+		t.Errorf("ETag = %q, want \"rel-1\"", result.Validators.ETag)
+	}
+}
+
+func TestGitHubAdapterRSSHubRoute(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Trending</title>
+  <item><title>acme/widget</title><link>https://github.com/acme/widget</link><description>A widget</description></item>
+</channel></rss>`)
+	}))
+	defer server.Close()
+
+	adapter := &GitHubAdapter{parser: newStubParser()}
+	result, err := adapter.Fetch(context.Background(), newStubSource("github", server.URL+"/github/trending/daily", ""))
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if requested != "/github/trending/daily" {
+		t.Errorf("requested %q, want the RSSHub route itself", requested)
+	}
+	if len(result.Items) != 1 || result.Items[0].Title != "acme/widget" {
+		t.Errorf("items should be taken from the feed as they are, got %+v", result.Items)
+	}
+}
+
+func TestGitHubFeedURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		cfg  GitHubConfig
+		want string
+	}{
+		{"https://github.com/acme/widget", GitHubConfig{}, "https://github.com/acme/widget/releases.atom"},
+		{"https://github.com/acme/widget.git", GitHubConfig{Kind: "tags"}, "https://github.com/acme/widget/tags.atom"},
+		{"https://github.com/acme/widget/tree/dev", GitHubConfig{Kind: "commits", Branch: "dev"}, "https://github.com/acme/widget/commits/dev.atom"},
+		{"https://github.com/acme/widget/commits/main.atom", GitHubConfig{Kind: "releases"}, "https://github.com/acme/widget/commits/main.atom"},
+	}
+	for _, tt := range tests {
+		got, _, err := githubFeedURL(tt.url, tt.cfg)
+		if err != nil || got != tt.want {
+			t.Errorf("githubFeedURL(%q) = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	if _, _, err := githubFeedURL("https://github.com/acme", GitHubConfig{}); err == nil {
+		t.Error("expected an error for a URL without a repository")
+	}
+}
+
+func TestHackerNewsAdapter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v0/newstories.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[101, 102, 103, 104]`)
+	})
+	mux.HandleFunc("/v0/item/101.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 101, "type": "story", "by": "pg", "time": 1700000000, "title": "Show HN: A thing",
+			"url": "https://example.com/thing", "score": 50, "descendants": 7}`)
+	})
+	mux.HandleFunc("/v0/item/102.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 102, "type": "story", "by": "dang", "time": 1700000100, "title": "Ask HN: Why?",
+			"text": "<p>Genuinely curious</p>", "score": 12}`)
+	})
+	mux.HandleFunc("/v0/item/103.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 103, "deleted": true}`)
+	})
+	mux.HandleFunc("/v0/item/104.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 104, "type": "story", "title": "Low score", "score": 1}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	adapter := &HackerNewsAdapter{parser: newStubParser()}
+	source := newStubSource("hackernews", server.URL+"/v0/newstories.json", `{"min_score": 10}`)
+	result, err := adapter.Fetch(context.Background(), source)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if len(result.Items) != 2 {
+		t.Fatalf("got %d items, want 2 (deleted and low-score stories skipped)", len(result.Items))
+	}
+	if result.Items[0].URL != "https://example.com/thing" || result.Items[0].Author != "pg" {
+		t.Errorf("unexpected link story: %+v", result.Items[0])
+	}
+	if result.Items[1].URL != "https://news.ycombinator.com/item?id=102" || !strings.Contains(result.Items[1].Content, "curious") {
+		t.Errorf("unexpected Ask HN story: %+v", result.Items[1])
+	}
+	if result.Items[0].PublishedAt == nil || result.Items[0].PublishedAt.Unix() != 1700000000 {
+		t.Errorf("PublishedAt = %v, want unix 1700000000", result.Items[0].PublishedAt)
+	}
+}
+
+func TestHackerNewsEndpoints(t *testing.T) {
+	list, item, err := hackerNewsEndpoints("https://news.ycombinator.com/ask", "")
+	if err != nil || list != hackerNewsAPIBase+"askstories.json" || item != hackerNewsAPIBase+"item/" {
+		t.Errorf("got %q, %q, %v", list, item, err)
+	}
+	list, _, err = hackerNewsEndpoints("http://127.0.0.1:8080/v0", "beststories")
+	if err != nil || list != "http://127.0.0.1:8080/v0/beststories.json" {
+		t.Errorf("got %q, %v", list, err)
+	}
+}
+
+func TestRedditAdapter(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/r/golang.json" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"kind": "Listing", "data": {"children": [
+			{"kind": "t3", "data": {"title": "Go 1.23 released", "url": "https://go.dev/blog/go1.23",
+				"permalink": "/r/golang/comments/abc/go_123_released/", "author": "gopher",
+				"created_utc": 1700000000.0, "is_self": false, "selftext_html": null,
+				"thumbnail": "https://b.thumbs.example.com/x.jpg", "score": 300, "num_comments": 40}},
+			{"kind": "t3", "data": {"title": "Weekly thread", "url": "https://www.reddit.com/r/golang/comments/def/",
+				"permalink": "/r/golang/comments/def/weekly/", "author": "AutoModerator", "created_utc": 1700000050,
+				"is_self": true, "selftext_html": "<div class=\"md\"><p>Ask anything</p></div>",
+				"thumbnail": "self", "stickied": true}},
+			{"kind": "t3", "data": {"title": "NSFW", "url": "https://example.com", "permalink": "/r/golang/comments/ghi/",
+				"over_18": true}}
+		]}}`)
+	}))
+	defer server.Close()
+
+	adapter := &RedditAdapter{parser: newStubParser()}
+	result, err := adapter.Fetch(context.Background(), newStubSource("reddit", server.URL+"/r/golang/", `{"limit": 50}`))
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if !strings.Contains(query, "raw_json=1") || !strings.Contains(query, "limit=50") {
+		t.Errorf("query = %q, want raw_json=1 and limit=50", query)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("got %d items, want 2 (NSFW skipped)", len(result.Items))
+	}
+
+	link := result.Items[0]
+	if link.URL != "https://go.dev/blog/go1.23" || len(link.ImageURLs) != 1 {
+		t.Errorf("unexpected link post: %+v", link)
+	}
+	self := result.Items[1]
+	if self.URL != server.URL+"/r/golang/comments/def/weekly/" || !strings.Contains(self.Content, "Ask anything") {
+		t.Errorf("unexpected self post: %+v", self)
+	}
+}
+
+func TestJSONAdapter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v2"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		fmt.Fprint(w, `{"data": {"posts": [
+			{"headline": "Relative link", "path": "/posts/1", "body": "<p>Hello <img src=\"https://cdn.example.com/a.png\"></p>",
+			 "writer": {"name": "Ann"}, "ts": 1700000000000},
+			{"headline": "Absolute link", "path": "https://other.example.com/2", "ts": "2024-01-02T03:04:05Z",
+			 "images": ["https://cdn.example.com/b.png"]},
+			{"headline": "", "path": "/posts/3"}
+		]}}`)
+	}))
+	defer server.Close()
+
+	config := `{"items": "$.data.posts", "title": "$.headline", "url": "$.path", "content": "$.body",
+		"author": "$.writer.name", "published_at": "$.ts", "image": "$.images"}`
+	adapter := &JSONAdapter{parser: newStubParser()}
+	source := newStubSource("json", server.URL+"/api/posts", config)
+
+	result, err := adapter.Fetch(context.Background(), source)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("got %d items, want 2 (untitled item skipped)", len(result.Items))
+	}
+
+	first, second := result.Items[0], result.Items[1]
+	if first.URL != server.URL+"/posts/1" || first.Author != "Ann" || first.PublishedAt.UnixMilli() != 1700000000000 {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+	if second.URL != "https://other.example.com/2" || second.PublishedAt.Year() != 2024 || len(second.ImageURLs) != 1 {
+		t.Errorf("unexpected second item: %+v", second)
+	}
+
+	source.ETag = result.Validators.ETag
+	result, err = adapter.Fetch(context.Background(), source)
+	if err != nil || !result.NotModified {
+		t.Errorf("second fetch: NotModified = %v, err = %v; want a 304", result != nil && result.NotModified, err)
+	}
+}
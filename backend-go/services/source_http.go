@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// SetSecretBox enables per-source credentials: the box seals them on save and opens them at fetch time
+func (rs *RSSService) SetSecretBox(box *utils.SecretBox) {
+	rs.secrets = box
+}
+
+// Secrets returns the box sealing per-source credentials, nil when no secret key is configured
+func (rs *RSSService) Secrets() *utils.SecretBox {
+	return rs.secrets
+}
+
+// sourceHTTPContext attaches the source's proxy, TLS settings and decrypted credentials to ctx,
+// so every request made on its behalf picks them up
+func (rs *RSSService) sourceHTTPContext(ctx context.Context, source *models.Source) (context.Context, error) {
+	opts := source.GetHTTPOptions()
+	if opts == nil {
+		return ctx, nil
+	}
+	httpOpts, err := opts.Open(rs.secrets.Open, source.URL)
+	if err != nil {
+		return ctx, fmt.Errorf("http options: %w", err)
+	}
+	return utils.WithHTTPOptions(ctx, httpOpts), nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/junkfilter/backend-go/repositories"
+)
+
+// statsRollupInterval is how often the daily source rollups are refreshed
+const statsRollupInterval = 15 * time.Minute
+
+// statsRollupWindow is how many days back each refresh recomputes: items are usually evaluated
+// within minutes, but a stalled evaluator can take a while to catch up. It must stay below
+// fetchRunRetention, or refreshed days would lose their fetch counters.
+const statsRollupWindow = 3
+
+// SetSourceStats enables the daily per-source rollups behind the stats endpoints
+func (rs *RSSService) SetSourceStats(repo *repositories.SourceStatsRepository) {
+	rs.statsRepo = repo
+}
+
+// runSourceStats refreshes the rollups now and then every statsRollupInterval until ctx is
+// cancelled. It runs beside the scheduling loop, which must keep serving reschedules and
+// shutdown while the first refresh backfills.
+func (rs *RSSService) runSourceStats(ctx context.Context) {
+	if rs.statsRepo == nil {
+		return
+	}
+	ticker := time.NewTicker(statsRollupInterval)
+	defer ticker.Stop()
+	for {
+		rs.refreshSourceStats(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshSourceStats recomputes the recent rollups, or every day when none were computed yet
+func (rs *RSSService) refreshSourceStats(ctx context.Context) {
+	if rs.statsRepo == nil {
+		return
+	}
+	latest, err := rs.statsRepo.LatestDay(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to refresh source stats: %v", err)
+		}
+		return
+	}
+	since := time.Time{}
+	if latest != nil {
+		since = rollupWindowStart(*latest, time.Now())
+	}
+
+	start := time.Now()
+	rows, err := rs.statsRepo.Refresh(ctx, since)
+	if errors.Is(err, repositories.ErrStatsRefreshBusy) || ctx.Err() != nil {
+		return // another replica refreshes this round; a shutdown rolls the refresh back
+	}
+	if err != nil {
+		log.Printf("Failed to refresh source stats: %v", err)
+		return
+	}
+	if latest == nil {
+		log.Printf("Backfilled %d daily source stats rows in %v", rows, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// rollupWindowStart is the first day a refresh recomputes: statsRollupWindow days before today,
+// or earlier when the latest rollup is older than that (the fetcher was stopped for a while)
+func rollupWindowStart(latest, now time.Time) time.Time {
+	since := now.AddDate(0, 0, -statsRollupWindow)
+	if latest.Before(since) {
+		return latest
+	}
+	return since
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollupWindowStart(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	if got := rollupWindowStart(now, now); !got.Equal(now.AddDate(0, 0, -statsRollupWindow)) {
+		t.Errorf("up-to-date rollups: window starts %v", got)
+	}
+	stale := now.AddDate(0, 0, -10)
+	if got := rollupWindowStart(stale, now); !got.Equal(stale) {
+		t.Errorf("stale rollups: window starts %v, want %v", got, stale)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// urlRulesChannel tells every process to reload the URL canonicalization rules after an API change
+const urlRulesChannel = "url-rules:changed"
+
+// SetURLRules enables per-domain URL canonicalization rules from the url_rules table
+func (rs *RSSService) SetURLRules(repo *repositories.URLRuleRepository) {
+	rs.urlRuleRepo = repo
+}
+
+// ReloadURLRules loads the rules into the canonicalizer used by utils.NormalizeURL
+func (rs *RSSService) ReloadURLRules(ctx context.Context) error {
+	if rs.urlRuleRepo == nil {
+		return nil
+	}
+	stored, err := rs.urlRuleRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	rules := make([]utils.URLRule, len(stored))
+	for i, rule := range stored {
+		rules[i] = rule.URLRule
+	}
+	utils.SetURLRules(rules)
+	return nil
+}
+
+// URLRulesChanged reloads the rules here and asks the other processes to do the same
+func (rs *RSSService) URLRulesChanged(ctx context.Context) {
+	if err := rs.ReloadURLRules(ctx); err != nil {
+		log.Printf("Warning: Failed to reload URL rules: %v", err)
+	}
+	if rs.redis != nil {
+		if err := rs.redis.Publish(ctx, urlRulesChannel, "").Err(); err != nil {
+			log.Printf("Warning: Failed to announce URL rule change: %v", err)
+		}
+	}
+}
+
+// SetLegacyURLFallback makes ingestion treat an item as a duplicate of the row stored under its
+// URL with the query string stripped, as the canonicalization before url_rules stored it, when
+// that row holds the same article. Turn it off once the feeds no longer list items ingested
+// before the upgrade.
+func (rs *RSSService) SetLegacyURLFallback(enabled bool) {
+	rs.legacyFallback = enabled
+}
+
+// isLegacyDuplicate reports whether the item was already ingested under the legacy form of its
+// URL. Hits are marked as seen under the canonical URL, so the next poll stops at Redis.
+func (rs *RSSService) isLegacyDuplicate(ctx context.Context, item *utils.FeedItem, bodyHash string) bool {
+	if !rs.legacyFallback {
+		return false
+	}
+	legacy := utils.LegacyURL(item.URL)
+	if legacy == "" {
+		return false
+	}
+	title, legacyHash, found, err := rs.contentRepo.GetLegacyBody(ctx, legacy)
+	if err != nil {
+		log.Printf("Warning: Legacy URL lookup failed for %s: %v", item.URL, err)
+		return false
+	}
+	if !found || !sameLegacyArticle(item.Title, bodyHash, title, legacyHash) {
+		return false
+	}
+	if err := rs.dedupService.MarkAsSeen(ctx, item.URL, bodyHash); err != nil {
+		log.Printf("Warning: Failed to mark URL as seen: %v", err)
+	}
+	return true
+}
+
+// sameLegacyArticle reports whether a legacy row holds the item rather than another article
+// whose URL differed only in the query (?p=1 and ?p=2 both stripped to the same URL). Rows
+// stored before body hashes were kept are compared by title.
+func sameLegacyArticle(title, bodyHash, legacyTitle, legacyHash string) bool {
+	if legacyHash != "" {
+		return legacyHash == bodyHash
+	}
+	return legacyTitle == title
+}
+
+// resolveCanonicalURL replaces the item's URL with the rel=canonical link of its page
+func resolveCanonicalURL(item *utils.FeedItem, pageHTML, articleURL string) {
+	canonical := utils.ExtractCanonicalURL(pageHTML, articleURL)
+	if canonical == "" {
+		return
+	}
+	if normalized := utils.NormalizeURL(canonical); normalized != item.URL {
+		log.Printf("[Canonical] %s -> %s", item.URL, normalized)
+		item.URL = normalized
+	}
+}
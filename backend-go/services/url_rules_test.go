@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/junkfilter/backend-go/utils"
+)
+
+func TestSameLegacyArticle(t *testing.T) {
+	// Before url_rules, ?p=1 and ?p=2 were both stored as https://blog.example.com/
+	first := &utils.FeedItem{URL: "https://blog.example.com/?p=1", Title: "First post", Content: "Hello"}
+	second := &utils.FeedItem{URL: "https://blog.example.com/?p=2", Title: "Second post", Content: "Again"}
+	if utils.LegacyURL(first.URL) != utils.LegacyURL(second.URL) {
+		t.Fatalf("both items should share the legacy URL, got %q and %q", utils.LegacyURL(first.URL), utils.LegacyURL(second.URL))
+	}
+	legacyHash := utils.GenerateBodyHash(first.Title, first.Content)
+
+	if !sameLegacyArticle(first.Title, utils.GenerateBodyHash(first.Title, first.Content), first.Title, legacyHash) {
+		t.Error("the item stored under the legacy URL should be a duplicate")
+	}
+	if sameLegacyArticle(second.Title, utils.GenerateBodyHash(second.Title, second.Content), first.Title, legacyHash) {
+		t.Error("another article under the same legacy URL should not be a duplicate")
+	}
+
+	// Rows stored before body hashes were kept are compared by title
+	if !sameLegacyArticle(first.Title, "x", first.Title, "") || sameLegacyArticle(second.Title, "x", first.Title, "") {
+		t.Error("legacy rows without a body hash should match by title only")
+	}
+}
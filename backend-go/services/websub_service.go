@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/junkfilter/backend-go/models"
+	"github.com/junkfilter/backend-go/repositories"
+	"github.com/junkfilter/backend-go/utils"
+)
+
+// WebSub timing
+const (
+	defaultWebSubLease       = 10 * 24 * time.Hour // requested lease; hubs may grant less
+	defaultWebSubFallback    = 6 * time.Hour       // polling interval for sources the hub pushes
+	webSubRenewBefore        = time.Hour           // renew leases this long before they expire
+	webSubRenewCheckInterval = 15 * time.Minute
+	webSubRequestRetry       = 15 * time.Minute // don't re-send a request the hub hasn't verified yet
+	webSubDeniedRetry        = 24 * time.Hour
+	webSubPushTimeout        = 2 * time.Minute
+)
+
+// ErrWebSubUnknownSubscription is returned for callbacks of sources with no subscription;
+// the callback answers 410 Gone so the hub drops it
+var ErrWebSubUnknownSubscription = errors.New("no WebSub subscription for this source")
+
+// WebSubConfig configures push subscriptions
+type WebSubConfig struct {
+	CallbackBaseURL  string        // public base URL hubs can reach, e.g. https://junkfilter.example.com
+	LeaseSeconds     int           // requested lease, 0 = 10 days
+	FallbackInterval time.Duration // polling interval for pushed sources, 0 = 6h
+}
+
+// WebSubService subscribes to the hubs feeds advertise (rel="hub"), verifies hub requests,
+// ingests signed content distributions and renews leases before they expire
+type WebSubService struct {
+	repo             *repositories.WebSubRepository
+	sourceRepo       *repositories.SourceRepository
+	rss              *RSSService
+	callbackBase     string
+	leaseSeconds     int
+	fallbackInterval time.Duration
+	stopChan         chan struct{}
+	wg               sync.WaitGroup
+}
+
+// NewWebSubService creates a WebSub service that ingests pushes through rss
+func NewWebSubService(
+	repo *repositories.WebSubRepository,
+	sourceRepo *repositories.SourceRepository,
+	rss *RSSService,
+	cfg WebSubConfig,
+) *WebSubService {
+	ws := &WebSubService{
+		repo:             repo,
+		sourceRepo:       sourceRepo,
+		rss:              rss,
+		callbackBase:     strings.TrimSuffix(cfg.CallbackBaseURL, "/"),
+		leaseSeconds:     cfg.LeaseSeconds,
+		fallbackInterval: cfg.FallbackInterval,
+		stopChan:         make(chan struct{}),
+	}
+	if ws.leaseSeconds <= 0 {
+		ws.leaseSeconds = int(defaultWebSubLease / time.Second)
+	}
+	if ws.fallbackInterval <= 0 {
+		ws.fallbackInterval = defaultWebSubFallback
+	}
+	return ws
+}
+
+// Start runs the lease renewal loop
+func (ws *WebSubService) Start(ctx context.Context) {
+	ws.wg.Add(1)
+	go func() {
+		defer ws.wg.Done()
+		ticker := time.NewTicker(webSubRenewCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ws.stopChan:
+				return
+			case <-ticker.C:
+				ws.renewExpiring(ctx)
+			}
+		}
+	}()
+	log.Printf("✓ WebSub enabled, callback base: %s", ws.callbackBase)
+}
+
+// Stop ends the renewal loop and waits for in-flight pushes to be ingested
+func (ws *WebSubService) Stop() {
+	close(ws.stopChan)
+	ws.wg.Wait()
+}
+
+// FallbackInterval is how often sources with an active subscription are still polled
+func (ws *WebSubService) FallbackInterval() time.Duration {
+	return ws.fallbackInterval
+}
+
+// ActiveSourceIDs returns the sources the hub currently pushes for
+func (ws *WebSubService) ActiveSourceIDs(ctx context.Context) (map[int64]bool, error) {
+	return ws.repo.ActiveSourceIDs(ctx, time.Now())
+}
+
+// IsPushed reports whether a source has a verified, unexpired subscription
+func (ws *WebSubService) IsPushed(ctx context.Context, sourceID int64) bool {
+	sub, err := ws.repo.Get(ctx, sourceID)
+	if err != nil {
+		log.Printf("[WebSub] Failed to load subscription for source %d: %v", sourceID, err)
+		return false
+	}
+	return sub != nil && sub.IsActive(time.Now())
+}
+
+// CallbackURL is the hub.callback registered for a source
+func (ws *WebSubService) CallbackURL(sourceID int64) string {
+	return fmt.Sprintf("%s/websub/callback/%d", ws.callbackBase, sourceID)
+}
+
+// EnsureSubscription subscribes a polled source to the hub its feed advertises, unless an
+// active subscription for the same hub and topic exists or a recent request is still pending.
+// The topic is the feed's rel="self" URL, falling back to the polled URL.
+func (ws *WebSubService) EnsureSubscription(ctx context.Context, source *models.Source, links utils.WebSubLinks) {
+	if links.Hub == "" {
+		return
+	}
+	topic := links.Self
+	if topic == "" {
+		topic = source.URL
+	}
+
+	sub, err := ws.repo.Get(ctx, source.ID)
+	if err != nil {
+		log.Printf("[WebSub] Failed to load subscription of source %d: %v", source.ID, err)
+		return
+	}
+
+	now := time.Now()
+	secret := ""
+	if sub != nil && sub.HubURL == links.Hub && sub.TopicURL == topic {
+		switch {
+		case sub.IsActive(now) && sub.ExpiresAt.Sub(now) > webSubRenewBefore:
+			return
+		case sub.State != models.WebSubStateDenied && now.Sub(sub.UpdatedAt) < webSubRequestRetry:
+			return // a request is already waiting for verification
+		case sub.State == models.WebSubStateDenied && now.Sub(sub.UpdatedAt) < webSubDeniedRetry:
+			return
+		}
+		// Same subscription: keep the secret so pushes in flight still verify
+		secret = sub.Secret
+	}
+
+	if err := ws.subscribe(ctx, source.ID, links.Hub, topic, secret); err != nil {
+		log.Printf("[WebSub] Subscribe request for source %d at %s failed: %v", source.ID, links.Hub, err)
+	}
+}
+
+// subscribe stores the request and sends hub.mode=subscribe. The row is saved first because
+// hubs may verify the intent before answering the subscription request.
+func (ws *WebSubService) subscribe(ctx context.Context, sourceID int64, hub, topic, secret string) error {
+	if secret == "" {
+		var err error
+		if secret, err = newWebSubSecret(); err != nil {
+			return err
+		}
+	}
+
+	sub := &models.WebSubSubscription{
+		SourceID:     sourceID,
+		HubURL:       hub,
+		TopicURL:     topic,
+		Secret:       secret,
+		LeaseSeconds: ws.leaseSeconds,
+	}
+	if err := ws.repo.SavePending(ctx, sub); err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {ws.CallbackURL(sourceID)},
+		"hub.secret":        {secret},
+		"hub.lease_seconds": {strconv.Itoa(ws.leaseSeconds)},
+	}
+	if err := postHubRequest(ctx, ws.rss.parser.HTTPClient(), hub, form); err != nil {
+		if recErr := ws.repo.RecordError(ctx, sourceID, err.Error()); recErr != nil {
+			log.Printf("[WebSub] Failed to record error for source %d: %v", sourceID, recErr)
+		}
+		return err
+	}
+
+	log.Printf("[WebSub] Subscription requested for source %d: topic=%s hub=%s", sourceID, topic, hub)
+	return nil
+}
+
+// postHubRequest sends a subscription request; hubs answer 202 Accepted (or 204) on success
+func postHubRequest(ctx context.Context, client *http.Client, hub string, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub responded %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// VerifyIntent answers a hub's verification GET. It returns the body to echo and whether
+// the request was accepted; rejected requests are answered with 404.
+func (ws *WebSubService) VerifyIntent(ctx context.Context, sourceID int64, query url.Values) (string, bool) {
+	mode := query.Get("hub.mode")
+	topic := query.Get("hub.topic")
+	challenge := query.Get("hub.challenge")
+
+	sub, err := ws.repo.Get(ctx, sourceID)
+	if err != nil {
+		log.Printf("[WebSub] Failed to load subscription of source %d: %v", sourceID, err)
+		return "", false
+	}
+
+	switch mode {
+	case "subscribe":
+		if sub == nil || sub.TopicURL != topic || challenge == "" {
+			return "", false
+		}
+		lease := sub.LeaseSeconds
+		if granted, err := strconv.Atoi(query.Get("hub.lease_seconds")); err == nil && granted > 0 {
+			lease = granted
+		}
+		expiresAt := time.Now().Add(time.Duration(lease) * time.Second)
+		if err := ws.repo.MarkActive(ctx, sourceID, lease, expiresAt); err != nil {
+			log.Printf("[WebSub] Failed to activate subscription of source %d: %v", sourceID, err)
+			return "", false
+		}
+		log.Printf("[WebSub] Subscription verified for source %d, lease %ds", sourceID, lease)
+		ws.rss.Reschedule(ctx, sourceID) // polling drops to the fallback interval
+		return challenge, true
+
+	case "unsubscribe":
+		// Only confirm unsubscribing from sources we no longer track
+		if sub != nil || challenge == "" {
+			return "", false
+		}
+		return challenge, true
+
+	case "denied":
+		if sub == nil || sub.TopicURL != topic {
+			return "", false
+		}
+		reason := query.Get("hub.reason")
+		if err := ws.repo.MarkDenied(ctx, sourceID, reason); err != nil {
+			log.Printf("[WebSub] Failed to record denial for source %d: %v", sourceID, err)
+		}
+		log.Printf("[WebSub] Hub denied subscription of source %d: %s", sourceID, reason)
+		ws.rss.Reschedule(ctx, sourceID)
+		return "", true
+	}
+	return "", false
+}
+
+// Receive accepts a content distribution. Payloads whose X-Hub-Signature does not match the
+// subscription secret are dropped (the hub still gets 2xx, as WebSub recommends); valid ones
+// are ingested in the background so the hub is acknowledged immediately.
+func (ws *WebSubService) Receive(ctx context.Context, sourceID int64, body []byte, signature string) error {
+	sub, err := ws.repo.Get(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	if sub == nil || sub.State == models.WebSubStateDenied {
+		return ErrWebSubUnknownSubscription
+	}
+
+	if !utils.VerifyHubSignature(sub.Secret, body, signature) {
+		log.Printf("[WebSub] Dropped push for source %d: missing or invalid signature", sourceID)
+		return nil
+	}
+
+	ws.wg.Add(1)
+	go func() {
+		defer ws.wg.Done()
+		pushCtx, cancel := context.WithTimeout(context.Background(), webSubPushTimeout)
+		defer cancel()
+		ws.ingest(pushCtx, sourceID, body)
+	}()
+	return nil
+}
+
+// ingest runs a verified payload through the regular item pipeline
+func (ws *WebSubService) ingest(ctx context.Context, sourceID int64, body []byte) {
+	source, err := ws.sourceRepo.GetByID(ctx, sourceID)
+	if err != nil || source == nil {
+		log.Printf("[WebSub] Push for unknown source %d ignored: %v", sourceID, err)
+		return
+	}
+	if !source.Enabled {
+		return
+	}
+
+	if err := ws.repo.RecordPush(ctx, sourceID, time.Now()); err != nil {
+		log.Printf("[WebSub] Failed to record push for source %d: %v", sourceID, err)
+	}
+	ws.rss.IngestPushedFeed(ctx, source, body)
+}
+
+// renewExpiring re-subscribes leases that end within webSubRenewBefore
+func (ws *WebSubService) renewExpiring(ctx context.Context) {
+	subs, err := ws.repo.ListExpiring(ctx, time.Now().Add(webSubRenewBefore))
+	if err != nil {
+		log.Printf("[WebSub] Failed to list expiring subscriptions: %v", err)
+		return
+	}
+	for _, sub := range subs {
+		if time.Since(sub.UpdatedAt) < webSubRequestRetry {
+			continue // renewal already requested, waiting for verification
+		}
+		if err := ws.subscribe(ctx, sub.SourceID, sub.HubURL, sub.TopicURL, sub.Secret); err != nil {
+			log.Printf("[WebSub] Lease renewal for source %d failed: %v", sub.SourceID, err)
+		}
+	}
+}
+
+// newWebSubSecret returns a random hub.secret (the spec caps secrets at 200 bytes)
+func newWebSubSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPostHubRequest(t *testing.T) {
+	var got url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		got = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	form := url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.topic":    {"https://blog.example.com/feed"},
+		"hub.callback": {"https://jf.example.com/websub/callback/7"},
+		"hub.secret":   {"abc"},
+	}
+	if err := postHubRequest(context.Background(), hub.Client(), hub.URL, form); err != nil {
+		t.Fatalf("postHubRequest() error = %v", err)
+	}
+	if got.Get("hub.callback") != "https://jf.example.com/websub/callback/7" || got.Get("hub.mode") != "subscribe" {
+		t.Errorf("hub received %v", got)
+	}
+}
+
+func TestPostHubRequestRejected(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "topic not allowed", http.StatusBadRequest)
+	}))
+	defer hub.Close()
+
+	err := postHubRequest(context.Background(), hub.Client(), hub.URL, url.Values{"hub.mode": {"subscribe"}})
+	if err == nil || !strings.Contains(err.Error(), "topic not allowed") {
+		t.Errorf("expected the hub's error message, got %v", err)
+	}
+}
+
+func TestWebSubCallbackURL(t *testing.T) {
+	ws := NewWebSubService(nil, nil, nil, WebSubConfig{CallbackBaseURL: "https://jf.example.com/"})
+	if got := ws.CallbackURL(42); got != "https://jf.example.com/websub/callback/42" {
+		t.Errorf("CallbackURL() = %q", got)
+	}
+	if ws.FallbackInterval() != defaultWebSubFallback {
+		t.Errorf("FallbackInterval() = %v, want default %v", ws.FallbackInterval(), defaultWebSubFallback)
+	}
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// maxArticleBodyBytes bounds article page downloads
+const maxArticleBodyBytes = 5 << 20
+
+// minExtractedRunes is the least text a node must hold to be accepted as the main content
+const minExtractedRunes = 140
+
+var (
+	// unlikelyNodes never contain the article body
+	unlikelyNodes = "script, style, noscript, iframe, form, nav, header, footer, aside, button, svg, template"
+
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeHint = regexp.MustCompile(`(?i)comment|footer|sidebar|side-bar|nav|menu|share|social|related|promo|sponsor|ad-|advert|banner|popup|subscribe|breadcrumb|meta|tag`)
+)
+
+// FetchArticleHTML downloads an article page through the parser's HTTP client (and proxy)
+func (rp *RSSParser) FetchArticleHTML(ctx context.Context, articleURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, articleURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := rp.HTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", HTTPStatusError(resp)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return "", fmt.Errorf("not an HTML page: %s", ct)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxArticleBodyBytes))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// ExtractMainContent is a readability-style extractor: it scores block containers by the
+// paragraphs they hold (text length, commas, class/id hints) and returns the HTML of the
+// best one, with relative links and image sources resolved against pageURL.
+func ExtractMainContent(pageHTML, pageURL string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return "", err
+	}
+
+	doc.Find(unlikelyNodes).Remove()
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*goquery.Selection
+	addScore := func(sel *goquery.Selection, score float64) {
+		if sel.Length() == 0 || goquery.NodeName(sel) == "body" || goquery.NodeName(sel) == "html" {
+			return
+		}
+		node := sel.Nodes[0]
+		if _, ok := scores[node]; !ok {
+			candidates = append(candidates, sel)
+			scores[node] = classWeight(sel)
+		}
+		scores[node] += score
+	}
+
+	doc.Find("p, pre, blockquote, li, td").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		runes := len([]rune(text))
+		if runes < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")) + math.Min(float64(runes)/100, 3)
+		addScore(p.Parent(), score)
+		addScore(p.Parent().Parent(), score/2)
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for _, n := range candidates {
+		// Penalize link-heavy containers (menus, tag clouds, "related posts")
+		score := scores[n.Nodes[0]] * (1 - linkDensity(n))
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+
+	// <article> is a strong signal when the scorer found nothing convincing
+	if article := doc.Find("article").First(); article.Length() > 0 {
+		if best == nil || len([]rune(strings.TrimSpace(best.Text()))) < minExtractedRunes {
+			best = article
+		}
+	}
+
+	if best == nil || len([]rune(strings.TrimSpace(best.Text()))) < minExtractedRunes {
+		return "", fmt.Errorf("no main content found")
+	}
+
+	if base, err := url.Parse(pageURL); err == nil {
+		resolveRelative(best, "a[href]", "href", base)
+		resolveRelative(best, "img[src]", "src", base)
+	}
+
+	return goquery.OuterHtml(best)
+}
+
+// classWeight rewards containers whose class/id look like article bodies and punishes chrome
+func classWeight(sel *goquery.Selection) float64 {
+	hints := sel.AttrOr("class", "") + " " + sel.AttrOr("id", "")
+	weight := 0.0
+	if goquery.NodeName(sel) == "article" {
+		weight += 10
+	}
+	if positiveHint.MatchString(hints) {
+		weight += 25
+	}
+	if negativeHint.MatchString(hints) {
+		weight -= 25
+	}
+	return weight
+}
+
+// linkDensity is the share of a node's text that sits inside links
+func linkDensity(sel *goquery.Selection) float64 {
+	total := len([]rune(sel.Text()))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linked += len([]rune(a.Text()))
+	})
+	return float64(linked) / float64(total)
+}
+
+// resolveRelative rewrites attr on matching elements to absolute URLs
+func resolveRelative(root *goquery.Selection, selector, attr string, base *url.URL) {
+	root.Find(selector).Each(func(_ int, sel *goquery.Selection) {
+		if v, ok := sel.Attr(attr); ok {
+			if resolved, err := base.Parse(strings.TrimSpace(v)); err == nil {
+				sel.SetAttr(attr, resolved.String())
+			}
+		}
+	})
+}
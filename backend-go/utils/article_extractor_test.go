@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractMainContent(t *testing.T) {
+	page := `<html><head><title>Post</title></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a> <a href="/archive">Archive</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter, follow us on social media, and share this page with friends.</p></div>
+<div class="post-content">
+  <h1>Why excerpts are not enough</h1>
+  <p>Many feeds only publish the first sentence of each article, which leaves the evaluator with almost nothing to judge.</p>
+  <p>Downloading the page and extracting the main block recovers the body, including <a href="/more">relative links</a> and images.</p>
+  <p><img src="/img/chart.png" alt="chart"> The chart above shows how much text we gain, on average, per article.</p>
+</div>
+<footer><p>Copyright notice, privacy policy, terms of service, cookie settings and other legal text.</p></footer>
+</body></html>`
+
+	got, err := ExtractMainContent(page, "https://blog.example.com/posts/1")
+	if err != nil {
+		t.Fatalf("ExtractMainContent() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"Many feeds only publish",
+		`href="https://blog.example.com/more"`,
+		`src="https://blog.example.com/img/chart.png"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("extracted content missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"newsletter", "Copyright", "Archive"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("extracted content should not contain %q:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestExtractMainContentTooShort(t *testing.T) {
+	page := `<html><body><div class="content"><p>Just a teaser sentence for the article.</p></div></body></html>`
+
+	if _, err := ExtractMainContent(page, "https://blog.example.com/posts/2"); err == nil {
+		t.Error("expected an error for a page without a real article body")
+	}
+}
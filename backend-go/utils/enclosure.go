@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// Enclosure is a media file attached to a feed item: a podcast episode, a video, a download
+type Enclosure struct {
+	URL      string `json:"url"`
+	Type     string `json:"type,omitempty"`     // MIME type as declared by the feed
+	Medium   string `json:"medium,omitempty"`   // Media RSS medium (audio, video, image...) when no type is given
+	Length   int64  `json:"length,omitempty"`   // size in bytes, 0 when unknown
+	Duration int    `json:"duration,omitempty"` // playing time in seconds, 0 when unknown
+}
+
+// Kind returns "audio", "video", "image" or "" for other and unknown files
+func (e Enclosure) Kind() string {
+	kind, _, _ := strings.Cut(strings.ToLower(e.Type), "/")
+	if kind == "" {
+		kind = strings.ToLower(e.Medium)
+	}
+	switch kind {
+	case "audio", "video", "image":
+		return kind
+	}
+	return ""
+}
+
+// IsMedia reports whether the enclosure is audio or video
+func (e Enclosure) IsMedia() bool {
+	kind := e.Kind()
+	return kind == "audio" || kind == "video"
+}
+
+// HasMedia reports whether any of the enclosures is audio or video, i.e. whether the item is a
+// podcast episode or a video rather than an article
+func HasMedia(enclosures []Enclosure) bool {
+	for _, e := range enclosures {
+		if e.IsMedia() {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMediaDuration parses an itunes:duration or media:content duration: plain seconds
+// ("3723", "3723.5"), "MM:SS" or "HH:MM:SS". It returns 0 for anything else.
+func ParseMediaDuration(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	total := 0.0
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return int(total)
+}
+
+// feedItemEnclosures collects the enclosures of a gofeed item: RSS <enclosure> and Atom
+// rel="enclosure" links, then Media RSS media:content, bare or grouped in media:group. Files listed
+// by both are kept once. The episode's itunes:duration fills in durations the files don't declare.
+func feedItemEnclosures(item *gofeed.Item) []Enclosure {
+	var enclosures []Enclosure
+	seen := make(map[string]int)
+	add := func(e Enclosure) {
+		e.URL = strings.TrimSpace(e.URL)
+		if e.URL == "" {
+			return
+		}
+		if i, ok := seen[e.URL]; ok {
+			// Media RSS usually repeats the enclosure with more detail
+			existing := &enclosures[i]
+			if existing.Type == "" {
+				existing.Type = e.Type
+			}
+			if existing.Medium == "" {
+				existing.Medium = e.Medium
+			}
+			if existing.Length == 0 {
+				existing.Length = e.Length
+			}
+			if existing.Duration == 0 {
+				existing.Duration = e.Duration
+			}
+			return
+		}
+		seen[e.URL] = len(enclosures)
+		enclosures = append(enclosures, e)
+	}
+
+	for _, enc := range item.Enclosures {
+		if enc == nil {
+			continue
+		}
+		length, _ := strconv.ParseInt(strings.TrimSpace(enc.Length), 10, 64)
+		add(Enclosure{URL: enc.URL, Type: strings.TrimSpace(enc.Type), Length: length})
+	}
+	for _, mc := range mediaContents(item.Extensions) {
+		length, _ := strconv.ParseInt(strings.TrimSpace(mc.Attrs["fileSize"]), 10, 64)
+		add(Enclosure{
+			URL:      mc.Attrs["url"],
+			Type:     strings.TrimSpace(mc.Attrs["type"]),
+			Medium:   strings.TrimSpace(mc.Attrs["medium"]),
+			Length:   length,
+			Duration: ParseMediaDuration(mc.Attrs["duration"]),
+		})
+	}
+
+	if item.ITunesExt != nil {
+		if duration := ParseMediaDuration(item.ITunesExt.Duration); duration > 0 {
+			for i := range enclosures {
+				if enclosures[i].Duration == 0 && enclosures[i].IsMedia() {
+					enclosures[i].Duration = duration
+				}
+			}
+		}
+	}
+	return enclosures
+}
+
+// mediaContents returns the item's media:content elements, including those inside media:group
+func mediaContents(extensions ext.Extensions) []ext.Extension {
+	media := extensions["media"]
+	if media == nil {
+		return nil
+	}
+	contents := append([]ext.Extension(nil), media["content"]...)
+	for _, group := range media["group"] {
+		contents = append(contents, group.Children["content"]...)
+	}
+	return contents
+}
+
+// mediaShowNotes returns the episode description carried outside the item description: the
+// itunes:summary of podcasts, or the media:description of video feeds such as YouTube's
+func mediaShowNotes(item *gofeed.Item) string {
+	if item.ITunesExt != nil {
+		if item.ITunesExt.Summary != "" {
+			return item.ITunesExt.Summary
+		}
+		if item.ITunesExt.Subtitle != "" {
+			return item.ITunesExt.Subtitle
+		}
+	}
+	media := item.Extensions["media"]
+	if media == nil {
+		return ""
+	}
+	for _, d := range media["description"] {
+		if d.Value != "" {
+			return d.Value
+		}
+	}
+	for _, group := range media["group"] {
+		for _, d := range group.Children["description"] {
+			if d.Value != "" {
+				return d.Value
+			}
+		}
+	}
+	return ""
+}
+
+// sanitizeEnclosures resolves relative enclosure URLs against the item link and keeps the
+// enclosures that can be downloaded over http(s)
+func sanitizeEnclosures(enclosures []Enclosure, itemURL string) []Enclosure {
+	base, _ := url.Parse(itemURL)
+	var kept []Enclosure
+	for _, e := range enclosures {
+		u, err := url.Parse(strings.TrimSpace(e.URL))
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		e.URL = u.String()
+		kept = append(kept, e)
+	}
+	return kept
+}
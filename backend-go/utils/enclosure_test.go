@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+const podcastFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+  <title>Systems Talk</title>
+  <item>
+    <title>Episode 12</title>
+    <link>https://example.com/ep12</link>
+    <enclosure url="https://cdn.example.com/ep12.mp3" length="24986239" type="audio/mpeg"/>
+    <media:content url="https://cdn.example.com/ep12.mp3" medium="audio" duration="3720"/>
+    <itunes:duration>1:02:00</itunes:duration>
+    <itunes:summary>We talk about the new scheduler.</itunes:summary>
+  </item>
+  <item>
+    <title>Trailer</title>
+    <link>https://example.com/trailer</link>
+    <description>A short look at the season.</description>
+    <media:group>
+      <media:content url="https://cdn.example.com/trailer.mp4" type="video/mp4" fileSize="1048576"/>
+      <media:description>Ignored: the description is already set.</media:description>
+    </media:group>
+    <itunes:duration>95</itunes:duration>
+  </item>
+</channel>
+</rss>`
+
+func TestFeedToItemsCapturesEnclosures(t *testing.T) {
+	feed, err := gofeed.NewParser().ParseString(podcastFeed)
+	if err != nil {
+		t.Fatalf("ParseString: %v", err)
+	}
+	items := feedToItems(feed)
+	if len(items) != 2 {
+		t.Fatalf("got %d items", len(items))
+	}
+
+	episode := items[0]
+	want := Enclosure{URL: "https://cdn.example.com/ep12.mp3", Type: "audio/mpeg", Medium: "audio", Length: 24986239, Duration: 3720}
+	if len(episode.Enclosures) != 1 || episode.Enclosures[0] != want {
+		t.Errorf("episode enclosures = %+v, want one %+v", episode.Enclosures, want)
+	}
+	if episode.Description != "We talk about the new scheduler." {
+		t.Errorf("show notes not taken from itunes:summary: %q", episode.Description)
+	}
+
+	trailer := items[1]
+	want = Enclosure{URL: "https://cdn.example.com/trailer.mp4", Type: "video/mp4", Length: 1048576, Duration: 95}
+	if len(trailer.Enclosures) != 1 || trailer.Enclosures[0] != want {
+		t.Errorf("trailer enclosures = %+v, want one %+v", trailer.Enclosures, want)
+	}
+	if trailer.Description != "A short look at the season." {
+		t.Errorf("description replaced: %q", trailer.Description)
+	}
+}
+
+func TestParseMediaDuration(t *testing.T) {
+	cases := map[string]int{
+		"3723":    3723,
+		"3723.5":  3723,
+		"62:03":   3723,
+		"1:02:03": 3723,
+		" 00:45 ": 45,
+		"":        0,
+		"1h":      0,
+		"1:2:3:4": 0,
+		"-5":      0,
+	}
+	for in, want := range cases {
+		if got := ParseMediaDuration(in); got != want {
+			t.Errorf("ParseMediaDuration(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestSanitizeFeedItemResolvesEnclosures(t *testing.T) {
+	item := SanitizeFeedItem(&FeedItem{URL: "https://example.com/episodes/12", Enclosures: []Enclosure{
+		{URL: "https://cdn.example.com/ep.mp3", Type: "audio/mpeg"},
+		{URL: "/files/ep.mp3", Type: "audio/mpeg"},
+		{URL: "magnet:?xt=urn:btih:abc", Type: "application/x-bittorrent"},
+	}})
+	if len(item.Enclosures) != 2 || item.Enclosures[0].URL != "https://cdn.example.com/ep.mp3" ||
+		item.Enclosures[1].URL != "https://example.com/files/ep.mp3" {
+		t.Errorf("enclosures = %+v", item.Enclosures)
+	}
+	if !HasMedia(item.Enclosures) {
+		t.Error("audio enclosure not recognized as media")
+	}
+	if HasMedia([]Enclosure{{URL: "https://example.com/cover.jpg", Medium: "image"}}) {
+		t.Error("image counted as media")
+	}
+}
@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// maxDiscoveryBodyBytes bounds how much of a page or candidate feed is read during discovery
+const maxDiscoveryBodyBytes = 2 << 20
+
+// maxCandidateChecks bounds how many <link> candidates are downloaded to find a valid one
+const maxCandidateChecks = 5
+
+// commonFeedPaths are probed when a page advertises no <link rel="alternate"> feeds
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml", "/rss", "/feed.json"}
+
+// feedLinkTypes maps <link type="..."> values to candidate types
+var feedLinkTypes = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+	"application/json":      "json",
+	"application/rdf+xml":   "rss",
+	"text/xml":              "rss",
+	"application/xml":       "rss",
+}
+
+// FeedCandidate is a feed URL found while discovering a page
+type FeedCandidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type"`  // "rss", "atom" or "json"
+	Via   string `json:"via"`   // "direct", "link" or "probe"
+	Valid bool   `json:"valid"` // downloaded and recognized as a feed
+}
+
+// FeedDiscovery is the result of inspecting a URL that may be a feed or a website
+type FeedDiscovery struct {
+	PageURL    string          `json:"page_url"`
+	IsFeed     bool            `json:"is_feed"` // the URL itself is a feed
+	SiteTitle  string          `json:"site_title,omitempty"`
+	FaviconURL string          `json:"favicon_url,omitempty"`
+	Candidates []FeedCandidate `json:"candidates"`
+	Best       *FeedCandidate  `json:"best,omitempty"`
+}
+
+// DiscoverFeeds inspects pageURL. If it is already a feed it is returned as the only candidate;
+// if it is an HTML page, <link rel="alternate"> feeds are collected (falling back to probing
+// common paths such as /feed and /rss.xml), and the first candidate that parses becomes Best.
+// The page title and favicon are returned so the caller can fill author_name / favicon_url.
+func (rp *RSSParser) DiscoverFeeds(ctx context.Context, pageURL string) (*FeedDiscovery, error) {
+	client := rp.HTTPClient()
+
+	body, finalURL, err := discoveryGet(ctx, client, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	discovery := &FeedDiscovery{PageURL: finalURL.String(), Candidates: []FeedCandidate{}}
+
+	if feedType := detectFeed(body); feedType != "" {
+		candidate := FeedCandidate{URL: pageURL, Type: feedType, Via: "direct", Valid: true}
+		if feed, err := gofeed.NewParser().Parse(bytes.NewReader(body)); err == nil {
+			candidate.Title = strings.TrimSpace(feed.Title)
+			discovery.SiteTitle = candidate.Title
+			if feed.Image != nil && feed.Image.URL != "" {
+				discovery.FaviconURL = feed.Image.URL
+			}
+		}
+		discovery.IsFeed = true
+		discovery.Candidates = append(discovery.Candidates, candidate)
+		discovery.Best = &discovery.Candidates[0]
+		return discovery, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("not a feed and not parseable as HTML: %w", err)
+	}
+
+	base := finalURL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := finalURL.Parse(href); err == nil {
+			base = u
+		}
+	}
+
+	discovery.SiteTitle = pageTitle(doc)
+	discovery.FaviconURL = pageFavicon(doc, base)
+
+	seen := make(map[string]bool)
+	doc.Find("link[rel]").Each(func(_ int, sel *goquery.Selection) {
+		if !hasRelToken(sel.AttrOr("rel", ""), "alternate") {
+			return
+		}
+		linkType := strings.ToLower(strings.TrimSpace(strings.Split(sel.AttrOr("type", ""), ";")[0]))
+		candidateType, ok := feedLinkTypes[linkType]
+		if !ok {
+			return
+		}
+		href, ok := sel.Attr("href")
+		if !ok || strings.TrimSpace(href) == "" {
+			return
+		}
+		resolved, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || seen[resolved.String()] {
+			return
+		}
+		seen[resolved.String()] = true
+		discovery.Candidates = append(discovery.Candidates, FeedCandidate{
+			URL:   resolved.String(),
+			Title: strings.TrimSpace(sel.AttrOr("title", "")),
+			Type:  candidateType,
+			Via:   "link",
+		})
+	})
+
+	// Validate advertised candidates in document order — sites list their main feed first
+	for i := range discovery.Candidates {
+		if i >= maxCandidateChecks {
+			break
+		}
+		if feedType, ok := probeFeed(ctx, client, discovery.Candidates[i].URL); ok {
+			discovery.Candidates[i].Valid = true
+			discovery.Candidates[i].Type = feedType
+			if discovery.Best == nil {
+				discovery.Best = &discovery.Candidates[i]
+			}
+		}
+	}
+
+	if len(discovery.Candidates) == 0 {
+		for _, path := range commonFeedPaths {
+			probeURL, err := finalURL.Parse(path)
+			if err != nil || seen[probeURL.String()] {
+				continue
+			}
+			seen[probeURL.String()] = true
+			if feedType, ok := probeFeed(ctx, client, probeURL.String()); ok {
+				discovery.Candidates = append(discovery.Candidates, FeedCandidate{
+					URL:   probeURL.String(),
+					Type:  feedType,
+					Via:   "probe",
+					Valid: true,
+				})
+			}
+		}
+		if len(discovery.Candidates) > 0 {
+			discovery.Best = &discovery.Candidates[0]
+		}
+	}
+
+	return discovery, nil
+}
+
+// discoveryGet downloads up to maxDiscoveryBodyBytes and returns the body and the post-redirect URL
+func discoveryGet(ctx context.Context, client *http.Client, rawURL string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "text/html, application/rss+xml, application/atom+xml, application/feed+json, */*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, HTTPStatusError(resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBodyBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Request.URL, nil
+}
+
+// probeFeed downloads a candidate and reports its feed type when it is one
+func probeFeed(ctx context.Context, client *http.Client, rawURL string) (string, bool) {
+	body, _, err := discoveryGet(ctx, client, rawURL)
+	if err != nil {
+		return "", false
+	}
+	feedType := detectFeed(body)
+	return feedType, feedType != ""
+}
+
+// detectFeed returns "rss", "atom" or "json" when body is a feed, otherwise ""
+func detectFeed(body []byte) string {
+	switch gofeed.DetectFeedType(bytes.NewReader(body)) {
+	case gofeed.FeedTypeRSS:
+		return "rss"
+	case gofeed.FeedTypeAtom:
+		return "atom"
+	case gofeed.FeedTypeJSON:
+		return "json"
+	}
+	return ""
+}
+
+// pageTitle prefers og:site_name over <title>, which often carries the article headline
+func pageTitle(doc *goquery.Document) string {
+	if name, ok := doc.Find(`meta[property="og:site_name"]`).First().Attr("content"); ok && strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	return strings.TrimSpace(doc.Find("title").First().Text())
+}
+
+// pageFavicon returns the first declared icon, falling back to /favicon.ico on the page host
+func pageFavicon(doc *goquery.Document, base *url.URL) string {
+	var icon string
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		rel := sel.AttrOr("rel", "")
+		if !hasRelToken(rel, "icon") && !hasRelToken(rel, "apple-touch-icon") {
+			return true
+		}
+		if resolved, err := base.Parse(strings.TrimSpace(sel.AttrOr("href", ""))); err == nil {
+			icon = resolved.String()
+			return false
+		}
+		return true
+	})
+	if icon != "" {
+		return icon
+	}
+	return fmt.Sprintf("%s://%s/favicon.ico", base.Scheme, base.Host)
+}
+
+// hasRelToken reports whether a space-separated rel attribute contains token
+func hasRelToken(rel, token string) bool {
+	for _, t := range strings.Fields(strings.ToLower(rel)) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Stub Blog</title><link>http://stub/</link>
+<item><title>Hello</title><link>http://stub/hello</link><description>hi</description></item>
+</channel></rss>`
+
+func newDiscoveryStub() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Home | Stub</title>
+<meta property="og:site_name" content="Stub Blog">
+<link rel="shortcut icon" href="/static/icon.png">
+<link rel="alternate" type="application/atom+xml" href="/broken.atom" title="Broken">
+<link rel="alternate" type="application/rss+xml" href="/posts/index.xml" title="Posts">
+</head><body>hi</body></html>`)
+	})
+	mux.HandleFunc("/posts/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testRSS)
+	})
+	mux.HandleFunc("/bare", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Bare</title></head></html>`)
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testRSS)
+	})
+	return httptest.NewServer(mux)
+}
+
+// newStubParser returns a parser without per-host limits: every stub server is 127.0.0.1
+func newStubParser() *RSSParser {
+	rp := NewRSSParser()
+	rp.SetHostLimits(HostLimit{}, nil)
+	return rp
+}
+
+func TestDiscoverFeedsFromLinkTags(t *testing.T) {
+	srv := newDiscoveryStub()
+	defer srv.Close()
+
+	d, err := newStubParser().DiscoverFeeds(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("DiscoverFeeds failed: %v", err)
+	}
+
+	if d.IsFeed {
+		t.Error("Expected HTML page not to be reported as a feed")
+	}
+	if len(d.Candidates) != 2 {
+		t.Fatalf("Expected 2 link candidates, got %+v", d.Candidates)
+	}
+	if d.Best == nil || d.Best.URL != srv.URL+"/posts/index.xml" {
+		t.Fatalf("Expected the first valid candidate as best, got %+v", d.Best)
+	}
+	if d.Candidates[0].Valid {
+		t.Error("Expected 404 candidate to be invalid")
+	}
+	if d.SiteTitle != "Stub Blog" {
+		t.Errorf("Expected og:site_name as title, got %q", d.SiteTitle)
+	}
+	if d.FaviconURL != srv.URL+"/static/icon.png" {
+		t.Errorf("Unexpected favicon %q", d.FaviconURL)
+	}
+}
+
+func TestDiscoverFeedsProbesCommonPaths(t *testing.T) {
+	srv := newDiscoveryStub()
+	defer srv.Close()
+
+	d, err := newStubParser().DiscoverFeeds(context.Background(), srv.URL+"/bare")
+	if err != nil {
+		t.Fatalf("DiscoverFeeds failed: %v", err)
+	}
+
+	if d.Best == nil || d.Best.URL != srv.URL+"/feed.xml" || d.Best.Via != "probe" {
+		t.Fatalf("Expected probed /feed.xml, got %+v", d.Best)
+	}
+	if d.FaviconURL == "" {
+		t.Error("Expected /favicon.ico fallback")
+	}
+}
+
+func TestDiscoverFeedsDirectFeed(t *testing.T) {
+	srv := newDiscoveryStub()
+	defer srv.Close()
+
+	d, err := newStubParser().DiscoverFeeds(context.Background(), srv.URL+"/posts/index.xml")
+	if err != nil {
+		t.Fatalf("DiscoverFeeds failed: %v", err)
+	}
+
+	if !d.IsFeed || d.Best == nil || d.Best.Type != "rss" || d.SiteTitle != "Stub Blog" {
+		t.Fatalf("Expected direct RSS feed, got %+v", d)
+	}
+}
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// DefaultUserAgent is sent when no user agent is configured
+const DefaultUserAgent = "JunkFilter/1.0 (+https://github.com/xiaoyu-ops/Junk-Filter)"
+
+// maxRetryAfter caps how long a single Retry-After header can hold back a host
+const maxRetryAfter = 24 * time.Hour
+
+// HostLimit bounds the requests made to one host. Zero values mean unlimited.
+type HostLimit struct {
+	Concurrency       int     `yaml:"concurrency" json:"concurrency"`                 // requests in flight at once
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"` // request starts per second
+}
+
+// DefaultHostLimit is polite for ordinary websites: two connections, one request per second
+var DefaultHostLimit = HostLimit{Concurrency: 2, RequestsPerSecond: 1}
+
+// RetryAfterError is a 429/503 response that asked the client to wait before trying again
+type RetryAfterError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("http error: %d %s, retry after %s",
+		e.StatusCode, http.StatusText(e.StatusCode), e.RetryAfter.Round(time.Second))
+}
+
+// HTTPStatusError builds the error for a non-2xx response: a *RetryAfterError when a 429/503
+// carries a usable Retry-After header, otherwise a gofeed.HTTPError
+func HTTPStatusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return &RetryAfterError{StatusCode: resp.StatusCode, RetryAfter: delay}
+		}
+	}
+	return gofeed.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// ParseRetryAfter reads a Retry-After value in delay-seconds or HTTP-date form, capped at 24h
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = at.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+	} else {
+		return 0, false
+	}
+
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
+
+// HostLimiter enforces per-host concurrency and request rate across every goroutine
+// sharing it, and holds hosts back after they answer with Retry-After
+type HostLimiter struct {
+	mu        sync.Mutex
+	defaults  HostLimit
+	overrides map[string]HostLimit
+	hosts     map[string]*hostState
+}
+
+type hostState struct {
+	limit        HostLimit
+	slots        chan struct{} // nil when concurrency is unlimited
+	nextStart    time.Time     // earliest start of the next request (rate limit)
+	blockedUntil time.Time     // set from Retry-After
+}
+
+// NewHostLimiter creates a limiter. overrides are keyed by host name (without port);
+// a key like ".example.com" also matches its subdomains.
+func NewHostLimiter(defaults HostLimit, overrides map[string]HostLimit) *HostLimiter {
+	hl := &HostLimiter{}
+	hl.Configure(defaults, overrides)
+	return hl
+}
+
+// Configure replaces the limits. Requests already waiting keep their old slots.
+func (hl *HostLimiter) Configure(defaults HostLimit, overrides map[string]HostLimit) {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	hl.defaults = defaults
+	hl.overrides = make(map[string]HostLimit, len(overrides))
+	for host, limit := range overrides {
+		hl.overrides[strings.ToLower(host)] = limit
+	}
+	hl.hosts = make(map[string]*hostState)
+}
+
+// limitFor returns the override for host, an override for a parent domain, or the defaults
+func (hl *HostLimiter) limitFor(host string) HostLimit {
+	if limit, ok := hl.overrides[host]; ok {
+		return limit
+	}
+	for suffix, limit := range hl.overrides {
+		if strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return limit
+		}
+	}
+	return hl.defaults
+}
+
+func (hl *HostLimiter) state(host string) *hostState {
+	if st, ok := hl.hosts[host]; ok {
+		return st
+	}
+	st := &hostState{limit: hl.limitFor(host)}
+	if st.limit.Concurrency > 0 {
+		st.slots = make(chan struct{}, st.limit.Concurrency)
+	}
+	hl.hosts[host] = st
+	return st
+}
+
+// Acquire waits for a free slot and the host's next rate-limited start time.
+// It fails fast with a *RetryAfterError when the host is held back past ctx's deadline.
+// The returned release must be called once the response has been consumed.
+func (hl *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	host = strings.ToLower(hostWithoutPort(host))
+
+	hl.mu.Lock()
+	st := hl.state(host)
+	hl.mu.Unlock()
+
+	if st.slots != nil {
+		select {
+		case st.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if st.slots != nil {
+				<-st.slots
+			}
+		})
+	}
+
+	hl.mu.Lock()
+	now := time.Now()
+	start := now
+	if st.nextStart.After(start) {
+		start = st.nextStart
+	}
+	blocked := st.blockedUntil.After(start)
+	if blocked {
+		start = st.blockedUntil
+	}
+	if deadline, ok := ctx.Deadline(); ok && blocked && start.After(deadline) {
+		hl.mu.Unlock()
+		release()
+		return nil, &RetryAfterError{StatusCode: http.StatusTooManyRequests, RetryAfter: start.Sub(now)}
+	}
+	if st.limit.RequestsPerSecond > 0 {
+		st.nextStart = start.Add(time.Duration(float64(time.Second) / st.limit.RequestsPerSecond))
+	}
+	hl.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// BlockUntil holds back new requests to host until the given time
+func (hl *HostLimiter) BlockUntil(host string, until time.Time) {
+	host = strings.ToLower(hostWithoutPort(host))
+
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+	st := hl.state(host)
+	if until.After(st.blockedUntil) {
+		st.blockedUntil = until
+	}
+}
+
+func hostWithoutPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i > 0 && !strings.Contains(host[i:], "]") {
+		return host[:i]
+	}
+	return host
+}
+
+// politeTransport applies the host limiter and user agent to every request made by the parser's
+// client — feeds, source adapters, discovery, article pages and WebSub hub requests alike — and
+// the HTTPOptions of the source the request is made for
+type politeTransport struct {
+	base    http.RoundTripper
+	limiter *HostLimiter
+	parser  *RSSParser
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if opts := HTTPOptionsFrom(req.Context()); opts != nil {
+		req = opts.apply(req)
+		if opts.needsTransport() {
+			base = t.parser.transportFor(opts)
+		}
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.parser.UserAgent())
+	}
+
+	release, err := t.limiter.Acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			t.limiter.BlockUntil(req.URL.Host, time.Now().Add(delay))
+		}
+	}
+
+	// Keep the slot until the caller has read the body
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees the host slot when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 May 2024 11:00:00 GMT", 0, true},
+		{"999999", maxRetryAfter, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHostLimiterConcurrency(t *testing.T) {
+	hl := NewHostLimiter(HostLimit{Concurrency: 1}, nil)
+
+	release, err := hl.Acquire(context.Background(), "example.com:443")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := hl.Acquire(ctx, "EXAMPLE.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second Acquire() error = %v, want deadline exceeded while the slot is held", err)
+	}
+
+	// Other hosts are unaffected
+	other, err := hl.Acquire(context.Background(), "other.example")
+	if err != nil {
+		t.Fatalf("Acquire(other) error = %v", err)
+	}
+	other()
+
+	release()
+	release() // releasing twice must not free a second slot
+	again, err := hl.Acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	again()
+}
+
+func TestHostLimiterRate(t *testing.T) {
+	hl := NewHostLimiter(HostLimit{}, map[string]HostLimit{
+		".example.com": {RequestsPerSecond: 20},
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := hl.Acquire(context.Background(), "feeds.example.com")
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests at 20/s took %v, want at least 100ms", elapsed)
+	}
+
+	// Hosts without an override use the (unlimited) defaults
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		release, _ := hl.Acquire(context.Background(), "unlimited.test")
+		release()
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("unlimited host took %v", elapsed)
+	}
+}
+
+func TestHostLimiterBlockUntil(t *testing.T) {
+	hl := NewHostLimiter(HostLimit{}, nil)
+	hl.BlockUntil("slow.test", time.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := hl.Acquire(ctx, "slow.test")
+	var retryAfter *RetryAfterError
+	if !errors.As(err, &retryAfter) || retryAfter.RetryAfter < 59*time.Minute {
+		t.Fatalf("Acquire() error = %v, want a RetryAfterError of about an hour", err)
+	}
+}
+
+func TestPoliteTransport(t *testing.T) {
+	var mu sync.Mutex
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents = append(agents, r.UserAgent())
+		mu.Unlock()
+		if r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<p>ok</p>"))
+	}))
+	defer server.Close()
+
+	rp := newStubParser()
+	rp.SetUserAgent("TestBot/2.0")
+
+	if _, err := rp.FetchArticleHTML(context.Background(), server.URL+"/page"); err != nil {
+		t.Fatalf("FetchArticleHTML() error = %v", err)
+	}
+	_, err := rp.FetchArticleHTML(context.Background(), server.URL+"/busy")
+	var retryAfter *RetryAfterError
+	if !errors.As(err, &retryAfter) || retryAfter.RetryAfter != time.Hour {
+		t.Fatalf("FetchArticleHTML(/busy) error = %v, want RetryAfterError of 1h", err)
+	}
+
+	// The 429 holds the whole host back: the next request fails without reaching the server
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := rp.FetchArticleHTML(ctx, server.URL+"/page"); !errors.As(err, &retryAfter) {
+		t.Fatalf("request to a blocked host error = %v, want RetryAfterError", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(agents) != 2 {
+		t.Fatalf("server saw %d requests, want 2", len(agents))
+	}
+	for _, ua := range agents {
+		if ua != "TestBot/2.0" {
+			t.Errorf("User-Agent = %q, want TestBot/2.0", ua)
+		}
+	}
+}
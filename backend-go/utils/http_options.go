@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPOptions are a source's own request settings. They travel on the request context, so
+// every fetch made on the source's behalf (feed, adapter API calls, article pages, redirects)
+// goes through its proxy and TLS settings, while the credentials are only ever sent to the
+// source's own site.
+type HTTPOptions struct {
+	ProxyURL           *url.URL // nil falls back to the global proxy; http, https or socks5
+	InsecureSkipVerify bool
+	CredentialHost     string // the headers, cookies and auth go to this host and its subdomains only
+	Headers            map[string]string
+	Cookies            map[string]string
+	Username           string // basic auth, together with Password
+	Password           string
+	BearerToken        string
+}
+
+type httpOptionsKey struct{}
+
+// WithHTTPOptions returns a context whose requests use opts
+func WithHTTPOptions(ctx context.Context, opts *HTTPOptions) context.Context {
+	return context.WithValue(ctx, httpOptionsKey{}, opts)
+}
+
+// HTTPOptionsFrom returns the options attached by WithHTTPOptions, nil when there are none
+func HTTPOptionsFrom(ctx context.Context) *HTTPOptions {
+	opts, _ := ctx.Value(httpOptionsKey{}).(*HTTPOptions)
+	return opts
+}
+
+// CredentialHostOf is the host a source's credentials are scoped to: its URL's host without
+// "www.", so API subdomains (api.github.com for github.com) are covered too
+func CredentialHostOf(sourceURL string) string {
+	parsed, err := url.Parse(sourceURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// sendsCredentials reports whether a request to u may carry the credentials
+func (o *HTTPOptions) sendsCredentials(u *url.URL) bool {
+	if o.CredentialHost == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == o.CredentialHost || strings.HasSuffix(host, "."+o.CredentialHost)
+}
+
+// hasCredentials reports whether there is anything to add to requests
+func (o *HTTPOptions) hasCredentials() bool {
+	return len(o.Headers) > 0 || len(o.Cookies) > 0 || o.Username != "" || o.BearerToken != ""
+}
+
+// needsTransport reports whether requests need a transport other than the global one
+func (o *HTTPOptions) needsTransport() bool {
+	return o.ProxyURL != nil || o.InsecureSkipVerify
+}
+
+// apply returns req with the headers, cookies and auth added, or req itself when it goes to
+// another host
+func (o *HTTPOptions) apply(req *http.Request) *http.Request {
+	if !o.hasCredentials() || !o.sendsCredentials(req.URL) {
+		return req
+	}
+	req = req.Clone(req.Context())
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range o.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	switch {
+	case o.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+o.BearerToken)
+	case o.Username != "":
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+	return req
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPOptionsCredentialsStayOnSourceHost(t *testing.T) {
+	var elsewhere http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elsewhere = r.Header.Clone()
+	}))
+	defer other.Close()
+	// Same listener address, different host name: credentials are scoped by host, not port
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	var atSource http.Header
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atSource = r.Header.Clone()
+		http.Redirect(w, r, otherURL+"/moved", http.StatusFound)
+	}))
+	defer source.Close()
+
+	rp := NewRSSParser()
+	rp.SetHostLimits(HostLimit{}, nil)
+	ctx := WithHTTPOptions(context.Background(), &HTTPOptions{
+		CredentialHost: CredentialHostOf(source.URL),
+		Headers:        map[string]string{"X-Api-Key": "k1"},
+		Cookies:        map[string]string{"session": "s1"},
+		BearerToken:    "t1",
+	})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, source.URL+"/feed", nil)
+	resp, err := rp.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if atSource.Get("X-Api-Key") != "k1" || atSource.Get("Authorization") != "Bearer t1" || atSource.Get("Cookie") != "session=s1" {
+		t.Errorf("source host got headers %v, want the key, token and cookie", atSource)
+	}
+	if atSource.Get("User-Agent") != DefaultUserAgent {
+		t.Errorf("User-Agent = %q, want the default", atSource.Get("User-Agent"))
+	}
+	if elsewhere == nil {
+		t.Fatal("redirect target was not requested")
+	}
+	for _, name := range []string{"X-Api-Key", "Authorization", "Cookie"} {
+		if elsewhere.Get(name) != "" {
+			t.Errorf("redirect target got %s = %q, want no credentials", name, elsewhere.Get(name))
+		}
+	}
+}
+
+func TestHTTPOptionsSendsCredentials(t *testing.T) {
+	opts := &HTTPOptions{CredentialHost: CredentialHostOf("https://www.Example.com/feed")}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/a", true},
+		{"https://www.example.com:8443/a", true},
+		{"https://api.example.com/a", true},
+		{"https://badexample.com/a", false},
+		{"https://example.com.evil.io/a", false},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		if got := opts.sendsCredentials(req.URL); got != tt.want {
+			t.Errorf("sendsCredentials(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPOptionsInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rp := NewRSSParser()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	if _, err := rp.HTTPClient().Do(req); err == nil {
+		t.Fatal("self-signed certificate accepted without insecure_skip_verify")
+	}
+
+	ctx := WithHTTPOptions(context.Background(), &HTTPOptions{InsecureSkipVerify: true})
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := rp.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() with insecure_skip_verify error = %v", err)
+	}
+	resp.Body.Close()
+}
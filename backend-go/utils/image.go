@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers GIF decoding for thumbnails
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxThumbnailPixels bounds the images decoded for thumbnails, so a small file declaring a
+// huge canvas can't exhaust memory: decoded as RGBA, 16M pixels take 64 MB
+const maxThumbnailPixels = 16 << 20
+
+// thumbnailJPEGQuality is the quality of opaque thumbnails
+const thumbnailJPEGQuality = 80
+
+// ErrImageTooLarge is returned for images over the download limit
+var ErrImageTooLarge = errors.New("image exceeds the size limit")
+
+// ErrImageAddressNotAllowed is returned for image URLs that resolve to loopback, private or
+// link-local addresses. Image URLs come from feed content and are fetched server-side, so they
+// must not reach the host itself or its internal network.
+var ErrImageAddressNotAllowed = errors.New("image host resolves to a non-public address")
+
+// DefaultImageHostLimit bounds image downloads per host. Articles embed many images from the same
+// CDN and a reader waits for them, so the limit is looser than DefaultHostLimit for feeds.
+var DefaultImageHostLimit = HostLimit{Concurrency: 6, RequestsPerSecond: 10}
+
+// maxImageRedirects matches the default of net/http
+const maxImageRedirects = 10
+
+// FetchImage downloads an image through the RSS proxy, with the image client's own per-host
+// limits. referer is sent when set: hotlink-protected CDNs usually serve requests that come from
+// their own site.
+func (rp *RSSParser) FetchImage(ctx context.Context, imageURL, referer string, maxBytes int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, "", fmt.Errorf("unsupported image URL scheme %q", req.URL.Scheme)
+	}
+	if err := checkPublicHost(ctx, req.URL.Hostname()); err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "image/avif,image/webp,image/*,*/*;q=0.8")
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+
+	rp.mu.Lock()
+	client := rp.images
+	rp.mu.Unlock()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", HTTPStatusError(resp)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, "", ErrImageTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", ErrImageTooLarge
+	}
+
+	contentType := ImageContentType(data, resp.Header.Get("Content-Type"))
+	if contentType == "" {
+		return nil, "", fmt.Errorf("not an image: %s", resp.Header.Get("Content-Type"))
+	}
+	return data, contentType, nil
+}
+
+// newImageClient builds the client FetchImage uses. Without a proxy every connection is checked
+// when it is dialed, which also covers redirects and DNS answers that change after FetchImage
+// looked the host up. Through a proxy the proxy resolves names, so hosts are checked up front
+// and again on each redirect.
+func (rp *RSSParser) newImageClient(proxy *url.URL) *http.Client {
+	transport := &http.Transport{Proxy: nil}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	} else {
+		dialer := &net.Dialer{Timeout: 10 * time.Second, Control: refuseNonPublicDial}
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{
+		Transport: &politeTransport{base: transport, limiter: rp.imageLimiter, parser: rp},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxImageRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxImageRedirects)
+			}
+			return checkPublicHost(req.Context(), req.URL.Hostname())
+		},
+	}
+}
+
+// checkPublicHost resolves host and fails if any of its addresses is not public
+func checkPublicHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return ErrImageAddressNotAllowed
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return ErrImageAddressNotAllowed
+		}
+	}
+	return nil
+}
+
+// refuseNonPublicDial is a net.Dialer Control hook that refuses connections to non-public addresses
+func refuseNonPublicDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return ErrImageAddressNotAllowed
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), private in practice
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip))
+}
+
+// ImageContentType returns the raster image type of data, sniffed from its bytes, or the declared
+// type for image formats the sniffer doesn't know (AVIF). It returns "" for anything else,
+// including SVG, which can carry scripts.
+func ImageContentType(data []byte, declared string) string {
+	sniffed := http.DetectContentType(data)
+	if strings.HasPrefix(sniffed, "image/") {
+		if sniffed == "image/svg+xml" {
+			return ""
+		}
+		return sniffed
+	}
+	declared, _, _ = strings.Cut(declared, ";")
+	declared = strings.ToLower(strings.TrimSpace(declared))
+	if sniffed == "application/octet-stream" && strings.HasPrefix(declared, "image/") && declared != "image/svg+xml" {
+		return declared
+	}
+	return ""
+}
+
+// MakeThumbnail scales a JPEG, PNG or GIF (its first frame) down to width pixels wide, keeping
+// the aspect ratio. Opaque images become JPEG, others PNG. It returns nil data when the image is
+// no wider than width, and an error for formats it can't decode; either way the original image
+// can serve as its own thumbnail.
+func MakeThumbnail(data []byte, width int) ([]byte, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width <= width || cfg.Height == 0 {
+		return nil, "", nil
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, "", fmt.Errorf("image too large to thumbnail: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	height := cfg.Height * width / cfg.Width
+	if height < 1 {
+		height = 1
+	}
+	thumb := downscale(src, width, height)
+
+	var buf bytes.Buffer
+	if thumb.Opaque() {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&buf, thumb)
+	return buf.Bytes(), "image/png", err
+}
+
+// downscale resizes src to width x height by averaging the source pixels each target pixel covers
+func downscale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+	sw, sh := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := dy*sh/height, (dy+1)*sh/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := dx*sw/width, (dx+1)*sw/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for y := y0; y < y1; y++ {
+				row := rgba.Pix[y*rgba.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(b / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMakeThumbnail(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			src.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+
+	thumb, contentType, err := MakeThumbnail(buf.Bytes(), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/jpeg" {
+		t.Errorf("opaque thumbnail type = %q, want image/jpeg", contentType)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil || cfg.Width != 200 || cfg.Height != 100 {
+		t.Errorf("thumbnail is %dx%d (%v), want 200x100", cfg.Width, cfg.Height, err)
+	}
+
+	if thumb, _, err := MakeThumbnail(buf.Bytes(), 1000); thumb != nil || err != nil {
+		t.Error("an image narrower than the thumbnail width should be used as is")
+	}
+}
+
+func TestImageContentType(t *testing.T) {
+	pngHeader := []byte("\x89PNG\r\n\x1a\n0000")
+	cases := []struct {
+		data     []byte
+		declared string
+		want     string
+	}{
+		{pngHeader, "text/html", "image/png"},
+		{[]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), "image/svg+xml", ""},
+		{[]byte{0, 0, 0, 0x1c, 'f', 't', 'y', 'p', 'a', 'v', 'i', 'f'}, "image/avif; charset=binary", "image/avif"},
+		{[]byte("<html><body>Forbidden</body></html>"), "image/jpeg", ""},
+	}
+	for _, tc := range cases {
+		if got := ImageContentType(tc.data, tc.declared); got != tc.want {
+			t.Errorf("ImageContentType(%q, %q) = %q, want %q", tc.data, tc.declared, got, tc.want)
+		}
+	}
+}
+
+func TestFetchImageRefusesNonPublicHosts(t *testing.T) {
+	requested := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n0000"))
+	}))
+	defer srv.Close()
+
+	rp := NewRSSParser()
+	for _, imageURL := range []string{srv.URL + "/a.png", "http://localhost/a.png", "http://169.254.169.254/latest/meta-data"} {
+		if _, _, err := rp.FetchImage(context.Background(), imageURL, "", 1<<20); !errors.Is(err, ErrImageAddressNotAllowed) {
+			t.Errorf("FetchImage(%s) error = %v, want ErrImageAddressNotAllowed", imageURL, err)
+		}
+	}
+	if requested {
+		t.Error("a loopback image was requested")
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1":     true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"100.64.0.1":       false,
+		"169.254.169.254":  false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	}
+	for addr, want := range cases {
+		if got := isPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
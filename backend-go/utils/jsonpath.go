@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled path over documents decoded with encoding/json.
+// The supported subset covers field mappings for JSON APIs:
+// $ (root, optional), .name, ['name'], [n] (negative counts from the end), [*] and .*
+type JSONPath struct {
+	expr  string
+	steps []jsonPathStep
+}
+
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ParseJSONPath compiles expr. A bare "title" is shorthand for "$.title".
+func ParseJSONPath(expr string) (*JSONPath, error) {
+	rest := strings.TrimSpace(expr)
+	if rest == "" {
+		return nil, fmt.Errorf("empty JSONPath")
+	}
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else if rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+
+	path := &JSONPath{expr: expr}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty field name", expr)
+			}
+			if name == "*" {
+				path.steps = append(path.steps, jsonPathStep{wildcard: true})
+			} else {
+				path.steps = append(path.steps, jsonPathStep{key: name})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: unclosed bracket", expr)
+			}
+			step, err := parseBracket(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: %w", expr, err)
+			}
+			path.steps = append(path.steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return path, nil
+}
+
+// parseBracket parses the inside of [...]: *, an index, or a quoted key
+func parseBracket(inner string) (jsonPathStep, error) {
+	if inner == "*" {
+		return jsonPathStep{wildcard: true}, nil
+	}
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		return jsonPathStep{key: inner[1 : len(inner)-1]}, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("unsupported selector [%s]", inner)
+	}
+	return jsonPathStep{index: index, isIndex: true}, nil
+}
+
+// String returns the expression the path was compiled from
+func (p *JSONPath) String() string {
+	return p.expr
+}
+
+// Find returns every value the path selects in doc, in document order.
+// Object wildcards follow Go map iteration, so their order is unspecified.
+func (p *JSONPath) Find(doc interface{}) []interface{} {
+	current := []interface{}{doc}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, node := range current {
+			switch v := node.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, child := range v {
+						next = append(next, child)
+					}
+				} else if !step.isIndex {
+					if child, ok := v[step.key]; ok {
+						next = append(next, child)
+					}
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, v...)
+				} else if step.isIndex {
+					i := step.index
+					if i < 0 {
+						i += len(v)
+					}
+					if i >= 0 && i < len(v) {
+						next = append(next, v[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// FindString returns the first non-null scalar the path selects, formatted as a string
+func (p *JSONPath) FindString(doc interface{}) string {
+	for _, v := range p.Find(doc) {
+		switch value := v.(type) {
+		case string:
+			return value
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(value)
+		}
+	}
+	return ""
+}
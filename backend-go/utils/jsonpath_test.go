@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPathFind(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"data": {"posts": [
+			{"title": "First", "meta": {"id": 1, "tags": ["a", "b"]}},
+			{"title": "Second", "meta": {"id": 2, "tags": []}, "draft": true}
+		]},
+		"weird key": "yes"
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []interface{}
+	}{
+		{"$.data.posts[*].title", []interface{}{"First", "Second"}},
+		{"data.posts[0].title", []interface{}{"First"}},
+		{"$.data.posts[-1].meta.id", []interface{}{float64(2)}},
+		{"$['weird key']", []interface{}{"yes"}},
+		{`$.data["posts"][0].meta.tags[*]`, []interface{}{"a", "b"}},
+		{"$.data.posts[5].title", nil},
+		{"$.missing.field", nil},
+	}
+	for _, tt := range tests {
+		path, err := ParseJSONPath(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseJSONPath(%q) error = %v", tt.expr, err)
+		}
+		if got := path.Find(doc); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+
+	id, _ := ParseJSONPath("$.data.posts[0].meta.id")
+	if got := id.FindString(doc); got != "1" {
+		t.Errorf("FindString(number) = %q, want \"1\"", got)
+	}
+	draft, _ := ParseJSONPath("$.data.posts[1].draft")
+	if got := draft.FindString(doc); got != "true" {
+		t.Errorf("FindString(bool) = %q, want \"true\"", got)
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{"", "$.", "$.a[", "$.a[foo]", "$..a"} {
+		if _, err := ParseJSONPath(expr); err == nil {
+			t.Errorf("ParseJSONPath(%q) expected an error", expr)
+		}
+	}
+}
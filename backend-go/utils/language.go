@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// minLanguageLetters is the fewest letters a text needs before its language is guessed
+const minLanguageLetters = 20
+
+// latinStopwords are frequent function words that tell Latin-script languages apart
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "this", "are", "was", "on"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "eine", "zu", "den", "auf", "sich", "auch"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "pour", "que", "dans", "pas", "du", "sur", "qui"},
+	"es": {"el", "la", "los", "las", "y", "que", "es", "por", "una", "para", "con", "del", "se", "como"},
+}
+
+// DetectableLanguages are the codes DetectLanguage can return
+var DetectableLanguages = []string{"zh", "ja", "ko", "en", "de", "fr", "es", "ru", "ar"}
+
+// DetectLanguage guesses the ISO 639-1 code of text from its scripts: kana means Japanese,
+// Hangul Korean, other Han characters Chinese; Latin text is told apart by stopwords.
+// It returns "" when the text is too short or the language isn't recognized.
+func DetectLanguage(text string) string {
+	var han, kana, hangul, cyrillic, arabic, latin, letters int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			continue
+		}
+		letters++
+	}
+	if letters < minLanguageLetters {
+		return ""
+	}
+
+	// A CJK character carries about as much as a short Latin word, so CJK scripts win early
+	cjk := han + kana + hangul
+	switch {
+	case cjk*4 >= letters/2:
+		if kana*10 >= cjk {
+			return "ja"
+		}
+		if hangul > han {
+			return "ko"
+		}
+		return "zh"
+	case cyrillic*2 > letters:
+		return "ru"
+	case arabic*2 > letters:
+		return "ar"
+	case latin*2 > letters:
+		return latinLanguage(text)
+	}
+	return ""
+}
+
+// latinLanguage picks the Latin-script language whose stopwords occur most often
+func latinLanguage(text string) string {
+	counts := make(map[string]int, len(latinStopwords))
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for lang, stopwords := range latinStopwords {
+			for _, stopword := range stopwords {
+				if word == stopword {
+					counts[lang]++
+				}
+			}
+		}
+	}
+
+	best, bestCount := "", 0
+	for _, lang := range []string{"en", "de", "fr", "es"} {
+		if counts[lang] > bestCount {
+			best, bestCount = lang, counts[lang]
+		}
+	}
+	return best
+}
+
+// NormalizeLanguages lowercases and deduplicates language codes, reducing regional tags such as
+// "zh-CN" to "zh". Codes DetectLanguage never returns are rejected, since a policy or filter
+// listing them could never match.
+func NormalizeLanguages(codes []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if i := strings.IndexAny(code, "-_"); i >= 0 {
+			code = code[:i]
+		}
+		if code == "" || seen[code] {
+			continue
+		}
+		if !containsFold(DetectableLanguages, code) {
+			return nil, fmt.Errorf("unsupported language %q, expected one of %s", code, strings.Join(DetectableLanguages, ", "))
+		}
+		seen[code] = true
+		normalized = append(normalized, code)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,47 @@
+package utils
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	cases := []struct {
+		text, want string
+	}{
+		{"The quick brown fox jumps over the lazy dog and then it runs into the forest.", "en"},
+		{"这是一篇关于分布式系统设计的文章，讨论了一致性与可用性之间的权衡。", "zh"},
+		{"Go 1.22 发布：range over func 实验特性与 net/http 路由增强", "zh"},
+		{"これは分散システムの設計についての記事です。一貫性と可用性のトレードオフを説明します。", "ja"},
+		{"이 글은 분산 시스템 설계에 관한 글입니다. 일관성과 가용성의 균형을 다룹니다.", "ko"},
+		{"Die Entwickler haben eine neue Version veröffentlicht, die auch mit der alten API kompatibel ist.", "de"},
+		{"Les développeurs ont publié une nouvelle version qui est compatible avec la précédente.", "fr"},
+		{"Это статья о проектировании распределённых систем и их компромиссах.", "ru"},
+		{"OK 123", ""},
+	}
+	for _, tc := range cases {
+		if got := DetectLanguage(tc.text); got != tc.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestSanitizeFeedItemDetectsLanguage(t *testing.T) {
+	item := SanitizeFeedItem(&FeedItem{
+		Title:   "Rust 异步运行时对比",
+		Content: "<p>本文比较了 Tokio 与 async-std 两个异步运行时在调度和性能上的差异。</p>",
+	})
+	if item.Language != "zh" {
+		t.Errorf("Language = %q, want zh", item.Language)
+	}
+}
+
+func TestNormalizeLanguages(t *testing.T) {
+	got, err := NormalizeLanguages([]string{" ZH-cn", "en", "zh_TW", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "zh" || got[1] != "en" {
+		t.Errorf("NormalizeLanguages = %v, want [zh en]", got)
+	}
+	if _, err := NormalizeLanguages([]string{"it"}); err == nil {
+		t.Error("a language DetectLanguage never returns should be rejected")
+	}
+}
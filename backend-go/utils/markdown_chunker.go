@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"strings"
+)
+
+// minSectionExcerptRunes is the least text a section gets in an excerpt; with more sections
+// than the budget allows at this size, the later ones are left out
+const minSectionExcerptRunes = 120
+
+// MarkdownSection is a heading and the text under it, up to the next heading of any level
+type MarkdownSection struct {
+	Heading string // heading path such as "Design > Storage", "" for the text before the first heading
+	Text    string // the section's Markdown, heading line included
+}
+
+// MarkdownChunk is a piece of an article no longer than the chunk size, taken from one section
+type MarkdownChunk struct {
+	Heading string `json:"heading,omitempty"` // heading path of the section the chunk comes from
+	Text    string `json:"text"`
+}
+
+// SplitSections splits Markdown at its ATX headings (# to ######). Lines inside fenced code
+// blocks are never taken for headings. Blank sections are dropped.
+func SplitSections(markdown string) []MarkdownSection {
+	var sections []MarkdownSection
+	var path []string // heading text per level, path[0] is level 1
+	var current strings.Builder
+	heading := ""
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			sections = append(sections, MarkdownSection{Heading: heading, Text: text})
+		}
+		current.Reset()
+	}
+
+	fence := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		} else if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+		} else if level, title := atxHeading(trimmed); level > 0 {
+			flush()
+			for len(path) < level {
+				path = append(path, "")
+			}
+			path = append(path[:level-1], title)
+			heading = joinHeadingPath(path)
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	flush()
+	return sections
+}
+
+// atxHeading returns the level and text of an ATX heading line, or 0 for other lines
+func atxHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return 0, ""
+	}
+	title := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+	if title == "" {
+		return 0, ""
+	}
+	return level, title
+}
+
+// joinHeadingPath joins the non-empty headings of a path with " > "
+func joinHeadingPath(path []string) string {
+	var parts []string
+	for _, p := range path {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// ChunkMarkdown splits Markdown into chunks of at most maxRunes runes that never span two
+// sections. A long section is split between paragraphs; a paragraph longer than maxRunes is cut
+// at line or sentence ends where it can be.
+func ChunkMarkdown(markdown string, maxRunes int) []MarkdownChunk {
+	if maxRunes <= 0 {
+		return nil
+	}
+	var chunks []MarkdownChunk
+	for _, section := range SplitSections(markdown) {
+		var current []rune
+		emit := func() {
+			if text := strings.TrimSpace(string(current)); text != "" {
+				chunks = append(chunks, MarkdownChunk{Heading: section.Heading, Text: text})
+			}
+			current = current[:0]
+		}
+		for _, paragraph := range splitParagraphs(section.Text) {
+			p := []rune(paragraph)
+			if len(current) > 0 && len(current)+2+len(p) > maxRunes {
+				emit()
+			}
+			for len(p) > maxRunes {
+				cut := []rune(cutAtBoundary(string(p), maxRunes))
+				current = append(current, cut...)
+				emit()
+				p = []rune(strings.TrimSpace(string(p[len(cut):])))
+			}
+			if len(p) == 0 {
+				continue
+			}
+			if len(current) > 0 {
+				current = append(current, '\n', '\n')
+			}
+			current = append(current, p...)
+		}
+		emit()
+	}
+	return chunks
+}
+
+// splitParagraphs splits text at blank lines, keeping fenced code blocks whole
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	var current strings.Builder
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		} else if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+		} else if trimmed == "" {
+			if p := strings.TrimSpace(current.String()); p != "" {
+				paragraphs = append(paragraphs, p)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	if p := strings.TrimSpace(current.String()); p != "" {
+		paragraphs = append(paragraphs, p)
+	}
+	return paragraphs
+}
+
+// cutAtBoundary returns the longest prefix of text within maxRunes runes that ends at a
+// paragraph, line or sentence end, falling back to a plain cut when none is in the second half
+func cutAtBoundary(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	head := string(runes[:maxRunes])
+	half := len(string(runes[:maxRunes/2]))
+	for _, sep := range []string{"\n\n", "\n", "。", "！", "？", ". ", "! ", "? "} {
+		if i := strings.LastIndex(head, sep); i >= half {
+			return strings.TrimSpace(head[:i+len(sep)])
+		}
+	}
+	return head
+}
+
+// TruncateRunes cuts s to at most n runes without splitting a character
+func TruncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// EvaluationExcerpt shortens an article to at most maxRunes runes for evaluation without
+// losing its structure: every section keeps its heading and opening paragraphs, with the budget
+// shared evenly and the room short sections don't use going to the longer ones. Cut sections
+// end in "…". Text that fits is returned unchanged.
+func EvaluationExcerpt(markdown string, maxRunes int) string {
+	markdown = strings.TrimSpace(markdown)
+	if len([]rune(markdown)) <= maxRunes {
+		return markdown
+	}
+	sections := SplitSections(markdown)
+	// Each section after the first costs a "\n\n" separator
+	count := len(sections)
+	if most := (maxRunes + 2) / (minSectionExcerptRunes + 2); count > most {
+		count = most
+	}
+	if count <= 1 {
+		return cutWithEllipsis(markdown, maxRunes)
+	}
+	sections = sections[:count]
+
+	// Water-filling: sections shorter than the even share keep all their text and the rest of
+	// the budget is shared again among the longer ones
+	budget := maxRunes - 2*(count-1)
+	allocation := make([]int, count)
+	open := make([]int, count)
+	for i := range open {
+		open[i] = i
+	}
+	for len(open) > 0 {
+		share := budget / len(open)
+		var longer []int
+		for _, i := range open {
+			if n := len([]rune(sections[i].Text)); n <= share {
+				allocation[i] = n
+				budget -= n
+			} else {
+				longer = append(longer, i)
+			}
+		}
+		if len(longer) == len(open) {
+			for _, i := range longer {
+				allocation[i] = share
+			}
+			break
+		}
+		open = longer
+	}
+
+	parts := make([]string, 0, count)
+	for i, section := range sections {
+		if part := cutWithEllipsis(section.Text, allocation[i]); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// cutWithEllipsis cuts text to maxRunes runes at a boundary, marking the cut with "…"
+func cutWithEllipsis(text string, maxRunes int) string {
+	if len([]rune(text)) <= maxRunes {
+		return text
+	}
+	if maxRunes < 2 {
+		return ""
+	}
+	return cutAtBoundary(text, maxRunes-1) + "…"
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+const chunkerArticle = `Intro paragraph about the release.
+
+# Design
+
+Why it was built.
+
+## Storage
+
+` + "```sh\n# not a heading\nmake build\n```" + `
+
+Rows are kept in pages.
+
+## Replication
+
+Followers copy the log.
+`
+
+func TestSplitSections(t *testing.T) {
+	sections := SplitSections(chunkerArticle)
+	want := []string{"", "Design", "Design > Storage", "Design > Replication"}
+	if len(sections) != len(want) {
+		t.Fatalf("got %d sections: %+v", len(sections), sections)
+	}
+	for i, s := range sections {
+		if s.Heading != want[i] {
+			t.Errorf("section %d heading = %q, want %q", i, s.Heading, want[i])
+		}
+	}
+	if !strings.Contains(sections[2].Text, "# not a heading") {
+		t.Errorf("fenced code split or lost: %q", sections[2].Text)
+	}
+	if !strings.HasPrefix(sections[3].Text, "## Replication") {
+		t.Errorf("heading line not kept with its section: %q", sections[3].Text)
+	}
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	long := "# Notes\n\n" + strings.Repeat("First point. ", 40) + "\n\n" + strings.Repeat("Second point. ", 40)
+	chunks := ChunkMarkdown(long, 300)
+	if len(chunks) < 3 {
+		t.Fatalf("expected the section to be split, got %d chunk(s)", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := len([]rune(c.Text)); n > 300 {
+			t.Errorf("chunk %d has %d runes", i, n)
+		}
+		if c.Heading != "Notes" {
+			t.Errorf("chunk %d heading = %q", i, c.Heading)
+		}
+	}
+
+	chunks = ChunkMarkdown(chunkerArticle, 1000)
+	if len(chunks) != 4 {
+		t.Errorf("short sections should stay one chunk each, got %d", len(chunks))
+	}
+}
+
+func TestEvaluationExcerpt(t *testing.T) {
+	if got := EvaluationExcerpt(chunkerArticle, 5000); got != strings.TrimSpace(chunkerArticle) {
+		t.Errorf("article that fits was changed: %q", got)
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat("Intro sentence here. ", 100))
+	for _, h := range []string{"Method", "Results", "Limits"} {
+		b.WriteString("\n\n## " + h + "\n\n" + strings.Repeat(h+" detail sentence. ", 100))
+	}
+	b.WriteString("\n\n## Thanks\n\nShort.")
+	excerpt := EvaluationExcerpt(b.String(), 1500)
+
+	if n := len([]rune(excerpt)); n > 1500 {
+		t.Errorf("excerpt has %d runes", n)
+	}
+	for _, h := range []string{"## Method", "## Results", "## Limits", "## Thanks\n\nShort."} {
+		if !strings.Contains(excerpt, h) {
+			t.Errorf("excerpt lost %q", h)
+		}
+	}
+	if !strings.Contains(excerpt, "…") {
+		t.Error("cut sections not marked")
+	}
+}
+
+// testdata/evaluation_excerpt.json is shared with backend-python's tests, so the consumer's
+// excerpts of "reference" messages are cut exactly like the ones published here
+func TestEvaluationExcerptFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/evaluation_excerpt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []struct {
+		Name     string `json:"name"`
+		Markdown string `json:"markdown"`
+		MaxRunes int    `json:"max_runes"`
+		Excerpt  string `json:"excerpt"`
+	}
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		if got := EvaluationExcerpt(c.Markdown, c.MaxRunes); got != c.Excerpt {
+			t.Errorf("%s: got %q, want %q", c.Name, got, c.Excerpt)
+		}
+	}
+}
+
+func TestSanitizeFeedItemKeepsFullContent(t *testing.T) {
+	html := "<p>" + strings.Repeat("长文内容。", 1000) + "</p>"
+	item := SanitizeFeedItem(&FeedItem{Content: html})
+	if n := len([]rune(item.Content)); n != ContentExcerptRunes {
+		t.Errorf("excerpt has %d runes, want %d", n, ContentExcerptRunes)
+	}
+	if n := len([]rune(item.FullContent)); n != 5000 {
+		t.Errorf("full content has %d runes, want 5000", n)
+	}
+	if item.Body() != item.FullContent {
+		t.Error("Body should return the full content")
+	}
+
+	item = SanitizeFeedItem(&FeedItem{Content: "<p>short</p>"})
+	if item.FullContent != "" || item.Body() != "short" {
+		t.Errorf("short item: content %q, full %q", item.Content, item.FullContent)
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// opmlDocument mirrors the OPML 2.0 structure used by feed readers for subscription lists
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []opmlOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// OPMLFeed is one subscription from (or for) an OPML file
+type OPMLFeed struct {
+	Title    string
+	XMLURL   string
+	HTMLURL  string
+	Category string // folder path, nested outlines joined with "/"
+}
+
+// ParseOPML reads an OPML document and flattens it into feeds.
+// Nested outline folders become the feed's Category ("Tech/Go"); when a feed sits
+// at the top level, its own category attribute is used instead.
+func ParseOPML(r io.Reader) ([]OPMLFeed, error) {
+	var doc opmlDocument
+	decoder := xml.NewDecoder(r)
+	// Many exporters declare non-UTF-8 charsets while actually emitting UTF-8
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OPML: %w", err)
+	}
+
+	var feeds []OPMLFeed
+	var walk func(outlines []opmlOutline, path []string)
+	walk = func(outlines []opmlOutline, path []string) {
+		for _, o := range outlines {
+			title := strings.TrimSpace(o.Title)
+			if title == "" {
+				title = strings.TrimSpace(o.Text)
+			}
+
+			if o.XMLURL != "" {
+				category := strings.Join(path, "/")
+				if category == "" && o.Category != "" {
+					// category="/Tech/Go,/Other" — take the first path
+					category = strings.Trim(strings.Split(o.Category, ",")[0], "/ ")
+				}
+				feeds = append(feeds, OPMLFeed{
+					Title:    title,
+					XMLURL:   strings.TrimSpace(o.XMLURL),
+					HTMLURL:  strings.TrimSpace(o.HTMLURL),
+					Category: category,
+				})
+			}
+
+			if len(o.Outlines) > 0 {
+				next := path
+				if o.XMLURL == "" && title != "" {
+					next = append(append([]string{}, path...), title)
+				}
+				walk(o.Outlines, next)
+			}
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+
+	return feeds, nil
+}
+
+// BuildOPML renders feeds as an OPML 2.0 document.
+// Feeds are grouped into one folder outline per Category, in first-seen order;
+// feeds without a category are written at the top level.
+func BuildOPML(title string, feeds []OPMLFeed) ([]byte, error) {
+	doc := opmlDocument{Version: "2.0"}
+	doc.Head.Title = title
+	doc.Head.DateCreated = time.Now().UTC().Format(time.RFC1123Z)
+
+	folders := make(map[string]int)
+	for _, f := range feeds {
+		outline := opmlOutline{
+			Text:    f.Title,
+			Title:   f.Title,
+			Type:    "rss",
+			XMLURL:  f.XMLURL,
+			HTMLURL: f.HTMLURL,
+		}
+
+		if f.Category == "" {
+			doc.Body.Outlines = append(doc.Body.Outlines, outline)
+			continue
+		}
+
+		idx, ok := folders[f.Category]
+		if !ok {
+			doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{Text: f.Category, Title: f.Category})
+			idx = len(doc.Body.Outlines) - 1
+			folders[f.Category] = idx
+		}
+		doc.Body.Outlines[idx].Outlines = append(doc.Body.Outlines[idx].Outlines, outline)
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
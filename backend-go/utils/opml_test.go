@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseOPMLNestedCategories(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<opml version="2.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Tech">
+      <outline text="Go">
+        <outline text="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog"/>
+      </outline>
+      <outline title="HN" text="ignored" type="rss" xmlUrl="https://news.ycombinator.com/rss"/>
+    </outline>
+    <outline text="Loose" type="rss" xmlUrl=" https://example.com/feed " category="/News/World,/Other"/>
+  </body>
+</opml>`
+
+	feeds, err := ParseOPML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("ParseOPML failed: %v", err)
+	}
+
+	want := []OPMLFeed{
+		{Title: "Go Blog", XMLURL: "https://go.dev/blog/feed.atom", HTMLURL: "https://go.dev/blog", Category: "Tech/Go"},
+		{Title: "HN", XMLURL: "https://news.ycombinator.com/rss", Category: "Tech"},
+		{Title: "Loose", XMLURL: "https://example.com/feed", Category: "News/World"},
+	}
+	if len(feeds) != len(want) {
+		t.Fatalf("Expected %d feeds, got %d: %+v", len(want), len(feeds), feeds)
+	}
+	for i := range want {
+		if feeds[i] != want[i] {
+			t.Errorf("feed %d: expected %+v, got %+v", i, want[i], feeds[i])
+		}
+	}
+}
+
+func TestParseOPMLInvalid(t *testing.T) {
+	if _, err := ParseOPML(strings.NewReader("not xml")); err == nil {
+		t.Error("Expected error for invalid OPML")
+	}
+}
+
+func TestBuildOPMLRoundTrip(t *testing.T) {
+	in := []OPMLFeed{
+		{Title: "A", XMLURL: "https://a.example/feed", Category: "blog"},
+		{Title: "B", XMLURL: "https://b.example/feed"},
+		{Title: "C", XMLURL: "https://c.example/feed", Category: "blog"},
+	}
+
+	data, err := BuildOPML("Export", in)
+	if err != nil {
+		t.Fatalf("BuildOPML failed: %v", err)
+	}
+
+	out, err := ParseOPML(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseOPML of built document failed: %v", err)
+	}
+
+	// Folder outlines come first in first-seen order, so A and C are grouped together
+	if len(out) != 3 || out[0].Title != "A" || out[1].Title != "C" || out[2].Title != "B" {
+		t.Fatalf("Unexpected round trip result: %+v", out)
+	}
+	if out[1].Category != "blog" || out[2].Category != "" {
+		t.Errorf("Categories not preserved: %+v", out)
+	}
+}
@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/tls"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -15,49 +19,40 @@ import (
 	"github.com/mmcdole/gofeed"
 )
 
-// RSSParser wraps gofeed parser
+// RSSParser wraps gofeed parser. Its HTTP client is shared by everything that fetches on
+// behalf of sources, so the per-host limits and User-Agent apply to all of it.
 type RSSParser struct {
-	parser   *gofeed.Parser
-	proxyURL string
-	mu       sync.Mutex
+	parser    *gofeed.Parser
+	proxyURL  string
+	userAgent string
+	limiter   *HostLimiter
+	maxFeed   int64 // bytes of a feed body read before the fetch fails
+	mu        sync.Mutex
+	// transports serves sources with their own proxy or TLS settings, keyed by both
+	transports map[string]*http.Transport
+	// images downloads content images for the image proxy, with limits of its own so readers
+	// opening articles never wait behind (or hold up) feed polling
+	images       *http.Client
+	imageLimiter *HostLimiter
 }
 
 // NewRSSParser creates a new RSS parser, optionally with HTTP proxy
 func NewRSSParser(proxyURL ...string) *RSSParser {
-	fp := gofeed.NewParser()
-
-	var transport *http.Transport
-	if len(proxyURL) > 0 && proxyURL[0] != "" {
-		proxy, err := url.Parse(proxyURL[0])
-		if err == nil {
-			transport = &http.Transport{
-				Proxy: http.ProxyURL(proxy),
-			}
-		}
-	}
-
-	if transport == nil {
-		// Explicitly nil proxy — without this, Go inherits HTTP_PROXY env var,
-		// which can silently route all RSS traffic through a container-level proxy
-		transport = &http.Transport{
-			Proxy: nil,
-		}
-	}
-
-	fp.Client = &http.Client{
-		Transport: transport,
-		Timeout:   30 * time.Second,
-	}
-
 	pURL := ""
 	if len(proxyURL) > 0 {
 		pURL = proxyURL[0]
 	}
 
-	return &RSSParser{
-		parser:   fp,
-		proxyURL: pURL,
+	rp := &RSSParser{
+		parser:    gofeed.NewParser(),
+		userAgent: DefaultUserAgent,
+		limiter:   NewHostLimiter(DefaultHostLimit, nil),
+		maxFeed:   DefaultMaxFeedBytes,
+
+		imageLimiter: NewHostLimiter(DefaultImageHostLimit, nil),
 	}
+	rp.SetProxyURL(pURL)
+	return rp
 }
 
 // SetProxyURL updates the proxy at runtime without restarting
@@ -66,11 +61,17 @@ func (rp *RSSParser) SetProxyURL(proxyURL string) {
 	defer rp.mu.Unlock()
 
 	rp.proxyURL = proxyURL
+	// Sources without a proxy of their own fall back to the global one
+	for _, t := range rp.transports {
+		t.CloseIdleConnections()
+	}
+	rp.transports = make(map[string]*http.Transport)
 
 	var transport *http.Transport
+	var proxy *url.URL
 	if proxyURL != "" {
-		proxy, err := url.Parse(proxyURL)
-		if err == nil {
+		if parsed, err := url.Parse(proxyURL); err == nil {
+			proxy = parsed
 			transport = &http.Transport{
 				Proxy: http.ProxyURL(proxy),
 			}
@@ -78,15 +79,89 @@ func (rp *RSSParser) SetProxyURL(proxyURL string) {
 	}
 
 	if transport == nil {
+		// Explicitly nil proxy — without this, Go inherits HTTP_PROXY env var,
+		// which can silently route all RSS traffic through a container-level proxy
 		transport = &http.Transport{
 			Proxy: nil,
 		}
 	}
 
 	rp.parser.Client = &http.Client{
-		Transport: transport,
+		Transport: &politeTransport{base: transport, limiter: rp.limiter, parser: rp},
 		Timeout:   30 * time.Second,
 	}
+	if rp.images != nil {
+		rp.images.CloseIdleConnections()
+	}
+	rp.images = rp.newImageClient(proxy)
+}
+
+// transportFor returns the transport for a source's proxy and TLS settings. Transports are
+// cached per distinct settings so the source's connections are pooled across fetches.
+func (rp *RSSParser) transportFor(opts *HTTPOptions) *http.Transport {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	proxy := opts.ProxyURL
+	if proxy == nil && rp.proxyURL != "" {
+		proxy, _ = url.Parse(rp.proxyURL)
+	}
+	key := fmt.Sprintf("%t|", opts.InsecureSkipVerify)
+	if proxy != nil {
+		key += proxy.String()
+	}
+	if t, ok := rp.transports[key]; ok {
+		return t
+	}
+
+	t := &http.Transport{Proxy: nil}
+	if proxy != nil {
+		// net/http speaks SOCKS5 itself for socks5:// proxy URLs
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	if opts.InsecureSkipVerify {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	rp.transports[key] = t
+	return t
+}
+
+// SetUserAgent changes the User-Agent sent with every request; "" restores DefaultUserAgent
+func (rp *RSSParser) SetUserAgent(userAgent string) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	rp.userAgent = userAgent
+}
+
+// UserAgent returns the User-Agent sent with every request
+func (rp *RSSParser) UserAgent() string {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.userAgent
+}
+
+// SetMaxFeedBytes bounds the feed bodies FetchFeed reads; 0 or less restores DefaultMaxFeedBytes
+func (rp *RSSParser) SetMaxFeedBytes(n int64) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if n <= 0 {
+		n = DefaultMaxFeedBytes
+	}
+	rp.maxFeed = n
+}
+
+// SetHostLimits replaces the per-host concurrency and rate limits
+func (rp *RSSParser) SetHostLimits(defaults HostLimit, overrides map[string]HostLimit) {
+	rp.limiter.Configure(defaults, overrides)
+}
+
+// SetImageHostLimit changes the per-host limit of image downloads, which is separate from the
+// limits of feed fetches
+func (rp *RSSParser) SetImageHostLimit(limit HostLimit) {
+	rp.imageLimiter.Configure(limit, nil)
 }
 
 // GetProxyURL returns the current proxy URL
@@ -103,21 +178,140 @@ type FeedItem struct {
 	URL         string
 	Author      string
 	PublishedAt *time.Time
-	Content     string
+	Content     string // cleaned Markdown, cut to ContentExcerptRunes
+	FullContent string // the whole cleaned Markdown when Content had to be cut, else ""
 	ImageURLs   []string
+	Categories  []string // feed categories/tags of the item
+	Language    string   // ISO 639-1 code detected from the cleaned text, "" when undetected
+	Enclosures  []Enclosure
+}
+
+// ContentExcerptRunes is how much of an article is stored in clean_content and hashed for
+// dedup and revision detection (rune-safe for CJK); longer articles keep their full text in
+// FullContent
+const ContentExcerptRunes = 2500
+
+// SetBody sets the item's cleaned Markdown, keeping the full text when it exceeds the excerpt
+func (item *FeedItem) SetBody(markdown string) {
+	item.Content = TruncateRunes(markdown, ContentExcerptRunes)
+	item.FullContent = ""
+	if len(item.Content) < len(markdown) {
+		item.FullContent = markdown
+	}
+}
+
+// Body returns the item's full cleaned Markdown
+func (item *FeedItem) Body() string {
+	if item.FullContent != "" {
+		return item.FullContent
+	}
+	return item.Content
+}
+
+// FeedValidators are the HTTP cache validators used for conditional GET
+type FeedValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// FetchResult is the outcome of a conditional feed fetch.
+// When NotModified is true the server answered 304 and Items is empty.
+type FetchResult struct {
+	Items       []*FeedItem
+	NotModified bool
+	Validators  FeedValidators
+	StatusCode  int         // HTTP status of the response
+	Bytes       int64       // response body bytes read
+	WebSub      WebSubLinks // hub/self links advertised for push subscriptions, if any
+}
+
+// DefaultMaxFeedBytes bounds feed bodies when no limit is configured; real feeds stay far below
+const DefaultMaxFeedBytes = 20 << 20
+
+// FeedTooLargeError is returned for a feed body over the configured limit
+type FeedTooLargeError struct {
+	Limit int64
+}
+
+func (e *FeedTooLargeError) Error() string {
+	return fmt.Sprintf("feed body exceeds the size limit of %d bytes", e.Limit)
 }
 
 // ParseFeed parses an RSS/Atom feed and returns items
-func (rp *RSSParser) ParseFeed(feedURL string) ([]*FeedItem, error) {
-	feed, err := rp.parser.ParseURL(feedURL)
+func (rp *RSSParser) ParseFeed(ctx context.Context, feedURL string) ([]*FeedItem, error) {
+	result, err := rp.FetchFeed(ctx, feedURL, FeedValidators{})
 	if err != nil {
 		return nil, err
 	}
+	return result.Items, nil
+}
 
-	if feed == nil {
-		return nil, fmt.Errorf("feed is nil")
+// FetchFeed downloads a feed with If-None-Match / If-Modified-Since taken from validators.
+// A 304 response short-circuits before the body is read or parsed.
+// When the server responded but the fetch still failed (non-2xx or unparsable body),
+// a result carrying StatusCode and Bytes is returned alongside the error.
+func (rp *RSSParser) FetchFeed(ctx context.Context, feedURL string, validators FeedValidators) (*FetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
 	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := rp.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return &FetchResult{NotModified: true, Validators: validators, StatusCode: resp.StatusCode}, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &FetchResult{StatusCode: resp.StatusCode}, HTTPStatusError(resp)
+	}
+
+	rp.mu.Lock()
+	limit := rp.maxFeed
+	rp.mu.Unlock()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return &FetchResult{StatusCode: resp.StatusCode, Bytes: int64(len(body))}, err
+	}
+	if int64(len(body)) > limit {
+		return &FetchResult{StatusCode: resp.StatusCode, Bytes: int64(len(body))}, &FeedTooLargeError{Limit: limit}
+	}
+	feed, err := rp.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return &FetchResult{StatusCode: resp.StatusCode, Bytes: int64(len(body))}, err
+	}
+
+	return &FetchResult{
+		Items: feedToItems(feed),
+		Validators: FeedValidators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+		StatusCode: resp.StatusCode,
+		Bytes:      int64(len(body)),
+		WebSub:     FindWebSubLinks(resp.Header, body),
+	}, nil
+}
+
+// HTTPClient returns the current client under lock — SetProxyURL may swap it concurrently
+func (rp *RSSParser) HTTPClient() *http.Client {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.parser.Client
+}
+
+// feedToItems converts a parsed gofeed feed into FeedItems
+func feedToItems(feed *gofeed.Feed) []*FeedItem {
 	// Extract feed-level author as fallback
 	feedAuthor := ""
 	if feed.Author != nil && feed.Author.Name != "" {
@@ -142,6 +336,12 @@ func (rp *RSSParser) ParseFeed(feedURL string) ([]*FeedItem, error) {
 			URL:         item.Link,
 			Author:      author,
 			Content:     item.Content,
+			Categories:  item.Categories,
+			Enclosures:  feedItemEnclosures(item),
+		}
+		// Podcast and video feeds often keep the show notes out of the description
+		if feedItem.Description == "" && feedItem.Content == "" {
+			feedItem.Description = mediaShowNotes(item)
 		}
 
 		// Extract published time
@@ -157,7 +357,7 @@ func (rp *RSSParser) ParseFeed(feedURL string) ([]*FeedItem, error) {
 		items = append(items, feedItem)
 	}
 
-	return items, nil
+	return items
 }
 
 // ExtractImageURLs extracts all image URLs from HTML content
@@ -203,31 +403,16 @@ func CleanContent(content string) string {
 
 	// Clean up excessive blank lines (3+ consecutive newlines → 2)
 	markdown = regexp.MustCompile(`\n{3,}`).ReplaceAllString(markdown, "\n\n")
-	markdown = strings.TrimSpace(markdown)
-
-	// Truncate to 2500 runes (rune-safe for CJK) — LLM context window budget
-	runes := []rune(markdown)
-	if len(runes) > 2500 {
-		markdown = string(runes[:2500])
-	}
-
-	return markdown
+	return strings.TrimSpace(markdown)
 }
 
-// NormalizeURL normalizes a URL for consistency
+// NormalizeURL canonicalizes an article URL: tracking parameters, fragment and redirector
+// wrappers are removed and the host lowercased, following the per-domain rules set with SetURLRules
 func NormalizeURL(rawURL string) string {
-	u, err := url.Parse(rawURL)
-	if err != nil {
-		return rawURL
-	}
-
-	u.RawQuery = ""
-	u.Fragment = ""
-
-	return u.String()
+	return defaultCanonicalizer.Canonicalize(rawURL)
 }
 
-// GenerateContentHash generates a hash for content (URL or title+content)
+// GenerateContentHash generates a hash for content (canonical URL or title+content)
 func GenerateContentHash(url, title, content string) string {
 	// Use URL as primary hash source
 	if url != "" {
@@ -248,15 +433,20 @@ func SanitizeFeedItem(item *FeedItem) *FeedItem {
 	if rawHTML == "" {
 		rawHTML = item.Description
 	}
-	// Image extraction MUST happen before CleanContent — HTML-to-Markdown strips all <img> tags
-	item.ImageURLs = ExtractImageURLs(rawHTML)
+	// Image extraction MUST happen before CleanContent — HTML-to-Markdown strips all <img> tags.
+	// Images supplied by a source adapter (e.g. a Reddit thumbnail) are kept when the HTML has none.
+	if images := ExtractImageURLs(rawHTML); len(images) > 0 {
+		item.ImageURLs = images
+	}
 
 	item.Description = CleanContent(item.Description)
 	if item.Content != "" {
-		item.Content = CleanContent(item.Content)
+		item.SetBody(CleanContent(item.Content))
 	} else {
-		item.Content = item.Description
+		item.SetBody(item.Description)
 	}
+	item.Language = DetectLanguage(item.Title + "\n" + item.Content)
+	item.Enclosures = sanitizeEnclosures(item.Enclosures, item.URL)
 
 	return item
 }
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchFeedSizeLimit(t *testing.T) {
+	feed := `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title>` +
+		`<item><title>A</title><link>https://example.com/a</link><description>` +
+		strings.Repeat("x", 2000) + `</description></item></channel></rss>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feed))
+	}))
+	defer server.Close()
+
+	rp := NewRSSParser()
+	rp.SetHostLimits(HostLimit{}, nil)
+	if _, err := rp.FetchFeed(context.Background(), server.URL, FeedValidators{}); err != nil {
+		t.Fatalf("FetchFeed() under the default limit error = %v", err)
+	}
+
+	rp.SetMaxFeedBytes(1024)
+	result, err := rp.FetchFeed(context.Background(), server.URL, FeedValidators{})
+	var tooLarge *FeedTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+		t.Fatalf("FetchFeed() over the limit error = %v, want FeedTooLargeError", err)
+	}
+	if result == nil || result.StatusCode != http.StatusOK {
+		t.Errorf("FetchFeed() over the limit result = %+v, want the status recorded", result)
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix marks a value sealed by a SecretBox (and the format version)
+const sealedPrefix = "enc:v1:"
+
+// ErrNoSecretKey is returned when credentials are sealed or opened without a secret key
+var ErrNoSecretKey = errors.New("no secret key configured: set security.secret_key or JUNKFILTER_SECRET_KEY to store credentials")
+
+// SecretBox encrypts credentials at rest with AES-256-GCM. A nil box has no key: it refuses to
+// seal or open anything.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a box keyed by the SHA-256 of secret, which should be a long random value
+func NewSecretBox(secret string) (*SecretBox, error) {
+	if secret == "" {
+		return nil, ErrNoSecretKey
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts plaintext under a random nonce
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	if b == nil {
+		return "", ErrNoSecretKey
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal
+func (b *SecretBox) Open(sealed string) (string, error) {
+	if b == nil {
+		return "", ErrNoSecretKey
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) {
+		return "", errors.New("credential is not encrypted")
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", errors.New("malformed encrypted credential")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt credential: was the secret key changed?")
+	}
+	return string(plaintext), nil
+}
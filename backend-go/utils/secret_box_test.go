@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSecretBoxRoundTrip(t *testing.T) {
+	box, err := NewSecretBox("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("NewSecretBox() error = %v", err)
+	}
+
+	sealed, err := box.Seal("hunter2")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, "hunter2") {
+		t.Fatalf("Seal() = %q, want an opaque sealed value", sealed)
+	}
+	if again, _ := box.Seal("hunter2"); again == sealed {
+		t.Error("sealing twice gave the same ciphertext, want a fresh nonce")
+	}
+	if plain, err := box.Open(sealed); err != nil || plain != "hunter2" {
+		t.Errorf("Open() = %q, %v; want hunter2", plain, err)
+	}
+
+	other, _ := NewSecretBox("another key")
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("Open() with another key succeeded")
+	}
+	if _, err := box.Open("hunter2"); err == nil {
+		t.Error("Open() of a plain value succeeded")
+	}
+}
+
+func TestSecretBoxWithoutKey(t *testing.T) {
+	if _, err := NewSecretBox(""); !errors.Is(err, ErrNoSecretKey) {
+		t.Errorf("NewSecretBox(\"\") error = %v, want ErrNoSecretKey", err)
+	}
+	var box *SecretBox
+	if _, err := box.Seal("x"); !errors.Is(err, ErrNoSecretKey) {
+		t.Errorf("nil Seal() error = %v, want ErrNoSecretKey", err)
+	}
+	if _, err := box.Open(sealedPrefix + "AAAA"); !errors.Is(err, ErrNoSecretKey) {
+		t.Errorf("nil Open() error = %v, want ErrNoSecretKey", err)
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"github.com/spaolacci/murmur3"
+)
+
+// minSimHashFeatures is the fewest shingles a text needs for a meaningful fingerprint;
+// very short texts collide too easily to be called near-duplicates
+const minSimHashFeatures = 16
+
+// SimHash fingerprints text so that similar texts get fingerprints a small Hamming distance
+// apart. Features are overlapping pairs of tokens, where a token is a lowercased word or a
+// single CJK character, so reordered or lightly edited copies stay close while unrelated
+// texts land about 32 bits apart. ok is false when the text is too short to fingerprint.
+func SimHash(text string) (fingerprint uint64, ok bool) {
+	tokens := simHashTokens(text)
+	if len(tokens) < 2 {
+		return 0, false
+	}
+
+	weights := make(map[string]int, len(tokens))
+	for i := 0; i+1 < len(tokens); i++ {
+		weights[tokens[i]+" "+tokens[i+1]]++
+	}
+	if len(weights) < minSimHashFeatures {
+		return 0, false
+	}
+
+	var v [64]int
+	for feature, weight := range weights {
+		h := murmur3.Sum64([]byte(feature))
+		for bit := 0; bit < 64; bit++ {
+			if h&(1<<uint(bit)) != 0 {
+				v[bit] += weight
+			} else {
+				v[bit] -= weight
+			}
+		}
+	}
+
+	for bit := 0; bit < 64; bit++ {
+		if v[bit] > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint, true
+}
+
+// HammingDistance counts the bits in which two fingerprints differ
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// simHashTokens splits text into lowercased words; CJK characters are tokens on their own
+// since those scripts don't separate words with spaces
+func simHashTokens(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const simHashArticle = `The Go team released a new version of the toolchain this week. It brings faster builds,
+a revamped garbage collector tuned for latency, and iterator functions that work with range loops.
+Benchmarks on large services show noticeably lower tail latency and smaller heaps, while existing
+programs keep compiling unchanged thanks to the compatibility promise.`
+
+func TestSimHashNearDuplicates(t *testing.T) {
+	original, ok := SimHash(simHashArticle)
+	if !ok {
+		t.Fatal("article should be long enough to fingerprint")
+	}
+
+	// Re-wrapped lines, other punctuation and case don't change the tokens at all
+	reformatted, _ := SimHash(strings.ToUpper(strings.ReplaceAll(simHashArticle, "\n", " — ")))
+	if d := HammingDistance(original, reformatted); d != 0 {
+		t.Errorf("reformatted copy is %d bits away, want 0", d)
+	}
+
+	// A mirror that adds an attribution stays close
+	mirror, _ := SimHash(simHashArticle + " (via the Go blog)")
+	if d := HammingDistance(original, mirror); d > 10 {
+		t.Errorf("mirror is %d bits away, want a near-duplicate", d)
+	}
+
+	unrelated, _ := SimHash(`Sourdough needs a lively starter, patience and a hot oven. Mix flour and water,
+let the dough rest, fold it every half hour, shape a tight boule and bake it covered for the first twenty
+minutes so the crust stays thin while the crumb opens up nicely before the lid comes off.`)
+	if d := HammingDistance(original, unrelated); d < 16 {
+		t.Errorf("unrelated text is only %d bits away", d)
+	}
+}
+
+func TestSimHashSkipsShortText(t *testing.T) {
+	if _, ok := SimHash("Too short to say anything."); ok {
+		t.Error("short text should not be fingerprinted")
+	}
+	if _, ok := SimHash(strings.Repeat("这是一个关于近似重复检测的中文句子。", 3)); !ok {
+		t.Error("CJK text should be tokenized per character and fingerprinted")
+	}
+}
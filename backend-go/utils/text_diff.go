@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Diff segment kinds
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffCells bounds the LCS table; larger inputs are diffed as a whole replacement
+const maxDiffCells = 1 << 20
+
+// DiffSegment is a run of text that is unchanged, added or removed between two versions
+type DiffSegment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// GenerateBodyHash fingerprints what a reader sees of an item. Unlike GenerateContentHash,
+// which identifies the item by its URL, it changes whenever the author edits the title or text.
+func GenerateBodyHash(title, content string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(title+"|"+content)))
+}
+
+// DiffText compares two versions of an article sentence by sentence
+func DiffText(oldText, newText string) []DiffSegment {
+	a, b := splitSentences(oldText), splitSentences(newText)
+
+	var segments []DiffSegment
+	add := func(op, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(segments); n > 0 && segments[n-1].Op == op {
+			segments[n-1].Text += text
+			return
+		}
+		segments = append(segments, DiffSegment{Op: op, Text: text})
+	}
+
+	if len(a)*len(b) > maxDiffCells {
+		add(DiffDelete, oldText)
+		add(DiffInsert, newText)
+		return segments
+	}
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(DiffEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(DiffDelete, a[i])
+			i++
+		default:
+			add(DiffInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add(DiffDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		add(DiffInsert, b[j])
+	}
+	return segments
+}
+
+// ChangeRatio is the share of text touched by a diff: removed plus added runes over the
+// runes of both versions. 0 means identical, 1 means nothing in common.
+func ChangeRatio(segments []DiffSegment) float64 {
+	var changed, total int
+	for _, seg := range segments {
+		n := utf8.RuneCountInString(seg.Text)
+		switch seg.Op {
+		case DiffEqual:
+			total += 2 * n
+		default:
+			changed += n
+			total += n
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(changed) / float64(total)
+}
+
+// splitSentences cuts text after sentence punctuation (Latin and CJK) and newlines,
+// keeping delimiters and trailing whitespace so the pieces concatenate back to the input
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !strings.ContainsRune(".!?。！？\n", r) {
+			continue
+		}
+		// Latin punctuation only ends a sentence before whitespace: "3.14", "e.g."
+		if strings.ContainsRune(".!?", r) && i < len(text) && text[i] != ' ' && text[i] != '\n' {
+			continue
+		}
+		for i < len(text) && (text[i] == ' ' || text[i] == '\n') {
+			i++
+		}
+		sentences = append(sentences, text[start:i])
+		start = i
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitSentencesRoundTrips(t *testing.T) {
+	text := "Go 1.23 is out. It adds range-over-func!\nSee the notes? 中文句子。第二句！"
+	sentences := splitSentences(text)
+	if got := strings.Join(sentences, ""); got != text {
+		t.Fatalf("sentences don't concatenate back:\n%q\n%q", got, text)
+	}
+	if len(sentences) != 5 {
+		t.Errorf("got %d sentences %q, want 5", len(sentences), sentences)
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	old := "First sentence. Second sentence. Third sentence."
+	updated := "First sentence. Second sentence, revised. Third sentence. A new ending."
+
+	segments := DiffText(old, updated)
+	var rebuiltOld, rebuiltNew strings.Builder
+	for _, seg := range segments {
+		if seg.Op != DiffInsert {
+			rebuiltOld.WriteString(seg.Text)
+		}
+		if seg.Op != DiffDelete {
+			rebuiltNew.WriteString(seg.Text)
+		}
+	}
+	if rebuiltOld.String() != old || rebuiltNew.String() != updated {
+		t.Fatalf("diff doesn't reproduce both versions: %+v", segments)
+	}
+	if segments[0].Op != DiffEqual || segments[0].Text != "First sentence. " {
+		t.Errorf("first segment = %+v, want the unchanged first sentence", segments[0])
+	}
+
+	ratio := ChangeRatio(segments)
+	if ratio <= 0 || ratio >= 1 {
+		t.Errorf("ChangeRatio = %v, want a partial change", ratio)
+	}
+	if r := ChangeRatio(DiffText(old, old)); r != 0 {
+		t.Errorf("ChangeRatio of identical text = %v", r)
+	}
+	if r := ChangeRatio(DiffText("Alpha.", "Beta.")); r != 1 {
+		t.Errorf("ChangeRatio of rewritten text = %v", r)
+	}
+}
+
+func TestGenerateBodyHashTracksEdits(t *testing.T) {
+	base := GenerateBodyHash("Title", "Body")
+	if GenerateBodyHash("Title", "Body") != base {
+		t.Error("body hash is not stable")
+	}
+	if GenerateBodyHash("Title", "Body, edited") == base || GenerateBodyHash("New title", "Body") == base {
+		t.Error("body hash should change when the title or text changes")
+	}
+}
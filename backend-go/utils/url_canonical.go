@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// URLRule adjusts canonicalization for one domain
+type URLRule struct {
+	Domain           string   `json:"domain"`            // host name; ".example.com" also matches its subdomains
+	KeepParams       []string `json:"keep_params"`       // when set, only these query parameters are kept
+	DropParams       []string `json:"drop_params"`       // removed on top of the tracking parameters
+	KeepFragment     bool     `json:"keep_fragment"`     // for sites that route articles in the fragment (#!/post/1)
+	RedirectParam    string   `json:"redirect_param"`    // the domain is a redirector whose target URL is in this parameter
+	ResolveCanonical bool     `json:"resolve_canonical"` // fetch the article page and follow its rel=canonical link
+}
+
+// trackingParams are dropped from every URL; utm_* parameters are matched by prefix. Only
+// unambiguous trackers belong here: names such as ref, source or from carry content or paging on
+// some sites, so they are dropped per domain through URLRule.DropParams.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "gclsrc": true, "dclid": true, "msclkid": true, "yclid": true,
+	"twclid": true, "igshid": true, "mc_cid": true, "mc_eid": true, "_hsenc": true, "_hsmi": true,
+	"mkt_tok": true, "ref_src": true, "ref_url": true, "spm": true, "spm_id_from": true, "scm": true,
+	"vd_source": true, "share_source": true, "share_medium": true, "wt_mc": true,
+}
+
+// knownRedirectors are link wrappers whose target URL sits in a query parameter, keyed by
+// host and path, or by host alone when every path redirects
+var knownRedirectors = map[string]string{
+	"www.google.com/url":            "q",
+	"google.com/url":                "q",
+	"l.facebook.com/l.php":          "u",
+	"lm.facebook.com/l.php":         "u",
+	"out.reddit.com":                "url",
+	"www.youtube.com/redirect":      "q",
+	"link.zhihu.com":                "target",
+	"link.juejin.cn":                "target",
+	"t.umblr.com/redirect":          "z",
+	"slack-redir.net/link":          "url",
+	"steamcommunity.com/linkfilter": "url",
+}
+
+// maxRedirectorDepth bounds unwrapping of redirectors that point at other redirectors
+const maxRedirectorDepth = 3
+
+// URLCanonicalizer reduces article URLs to one form per article: host lowercased, default port,
+// fragment and tracking parameters removed, redirector links unwrapped. Other query parameters
+// are kept (sorted), since many sites identify articles with them (?p=123, ?id=...).
+type URLCanonicalizer struct {
+	mu    sync.RWMutex
+	rules map[string]URLRule // by lowercased domain
+}
+
+// NewURLCanonicalizer creates a canonicalizer with per-domain rules
+func NewURLCanonicalizer(rules []URLRule) *URLCanonicalizer {
+	uc := &URLCanonicalizer{}
+	uc.SetRules(rules)
+	return uc
+}
+
+// SetRules replaces the per-domain rules
+func (uc *URLCanonicalizer) SetRules(rules []URLRule) {
+	byDomain := make(map[string]URLRule, len(rules))
+	for _, rule := range rules {
+		byDomain[strings.ToLower(rule.Domain)] = rule
+	}
+	uc.mu.Lock()
+	uc.rules = byDomain
+	uc.mu.Unlock()
+}
+
+// ruleFor returns the rule of host or, failing that, of its closest parent domain
+func (uc *URLCanonicalizer) ruleFor(host string) (URLRule, bool) {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+
+	if rule, ok := uc.rules[host]; ok {
+		return rule, true
+	}
+	for i := strings.IndexByte(host, '.'); i >= 0; {
+		if rule, ok := uc.rules[host[i:]]; ok {
+			return rule, true
+		}
+		next := strings.IndexByte(host[i+1:], '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return URLRule{}, false
+}
+
+// Canonicalize returns the canonical form of rawURL; unparseable input is returned as is
+func (uc *URLCanonicalizer) Canonicalize(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+
+	for depth := 0; ; depth++ {
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = canonicalHost(u.Scheme, u.Host)
+		rule, _ := uc.ruleFor(u.Hostname())
+
+		if depth < maxRedirectorDepth {
+			if target, ok := redirectTarget(u, rule); ok {
+				u = target
+				continue
+			}
+		}
+
+		u.RawQuery = canonicalQuery(u.RawQuery, rule)
+		u.ForceQuery = false
+		if !rule.KeepFragment {
+			u.Fragment = ""
+			u.RawFragment = ""
+		}
+		return u.String()
+	}
+}
+
+// ResolvesCanonical reports whether the rule for rawURL's domain asks to follow rel=canonical
+func (uc *URLCanonicalizer) ResolvesCanonical(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	rule, ok := uc.ruleFor(strings.ToLower(u.Hostname()))
+	return ok && rule.ResolveCanonical
+}
+
+// canonicalHost lowercases a host and drops the scheme's default port and a trailing dot
+func canonicalHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	if (scheme == "http" && strings.HasSuffix(host, ":80")) || (scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndexByte(host, ':')]
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// redirectTarget returns the absolute http(s) URL wrapped by a redirector link
+func redirectTarget(u *url.URL, rule URLRule) (*url.URL, bool) {
+	param := rule.RedirectParam
+	if param == "" {
+		param = knownRedirectors[u.Host+strings.TrimSuffix(u.Path, "/")]
+	}
+	if param == "" {
+		param = knownRedirectors[u.Host]
+	}
+	if param == "" {
+		return nil, false
+	}
+	target, err := url.Parse(u.Query().Get(param))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, false
+	}
+	return target, true
+}
+
+// canonicalQuery drops tracking and rule-excluded parameters and sorts the rest. Parameters
+// are kept in their original encoding, so "?123" doesn't become "?123=".
+func canonicalQuery(rawQuery string, rule URLRule) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if keepParam(strings.ToLower(name), rule) {
+			kept = append(kept, pair)
+		}
+	}
+	sort.Strings(kept)
+	return strings.Join(kept, "&")
+}
+
+func keepParam(name string, rule URLRule) bool {
+	if len(rule.KeepParams) > 0 {
+		return containsFold(rule.KeepParams, name)
+	}
+	if strings.HasPrefix(name, "utm_") || trackingParams[name] {
+		return false
+	}
+	return !containsFold(rule.DropParams, name)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// LegacyURL is canonicalURL in the form the rules before url_rules stored it, with the whole
+// query string and the fragment dropped; "" when that is canonicalURL itself
+func LegacyURL(canonicalURL string) string {
+	u, err := url.Parse(canonicalURL)
+	if err != nil || (u.RawQuery == "" && !u.ForceQuery) {
+		return ""
+	}
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// ExtractCanonicalURL returns the page's <link rel="canonical"> resolved against pageURL,
+// or "" when it declares none
+func ExtractCanonicalURL(pageHTML, pageURL string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return ""
+	}
+
+	var canonical string
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		if !hasRelToken(sel.AttrOr("rel", ""), "canonical") {
+			return true
+		}
+		resolved, err := base.Parse(strings.TrimSpace(sel.AttrOr("href", "")))
+		if err == nil && (resolved.Scheme == "http" || resolved.Scheme == "https") {
+			canonical = resolved.String()
+		}
+		return false
+	})
+	return canonical
+}
+
+// defaultCanonicalizer backs NormalizeURL; its rules come from the url_rules table
+var defaultCanonicalizer = NewURLCanonicalizer(nil)
+
+// SetURLRules replaces the per-domain rules used by NormalizeURL
+func SetURLRules(rules []URLRule) {
+	defaultCanonicalizer.SetRules(rules)
+}
+
+// ResolvesCanonical reports whether NormalizeURL's rules ask to follow rawURL's rel=canonical link
+func ResolvesCanonical(rawURL string) bool {
+	return defaultCanonicalizer.ResolvesCanonical(rawURL)
+}
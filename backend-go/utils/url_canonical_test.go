@@ -0,0 +1,92 @@
+package utils
+
+import "testing"
+
+func TestCanonicalizeDefaults(t *testing.T) {
+	uc := NewURLCanonicalizer(nil)
+	cases := []struct {
+		in, want string
+	}{
+		// Article IDs in the query survive; tracking parameters don't
+		{"https://example.com/index.php?p=123&utm_source=rss&utm_medium=feed", "https://example.com/index.php?p=123"},
+		{"https://example.com/read?id=2&fbclid=abc&from=home", "https://example.com/read?from=home&id=2"},
+		{"https://forum.example.com/thread?ref_src=twsrc&source=12&from=40", "https://forum.example.com/thread?from=40&source=12"},
+		{"https://item.taobao.com/item.htm?spm=a1z10&id=42", "https://item.taobao.com/item.htm?id=42"},
+		{"HTTPS://Blog.Example.COM:443/Post/1#comments", "https://blog.example.com/Post/1"},
+		{"http://example.com:80/a?", "http://example.com/a"},
+		{"https://example.com/a?123", "https://example.com/a?123"},
+		{"https://example.com/a?q=%E4%B8%AD%E6%96%87", "https://example.com/a?q=%E4%B8%AD%E6%96%87"},
+		// Redirectors are unwrapped, then the target is canonicalized too
+		{"https://www.google.com/url?q=https%3A%2F%2Fexample.com%2Fpost%3Futm_source%3Dg&sa=D", "https://example.com/post"},
+		{"https://link.zhihu.com/?target=https%3A//example.com/x", "https://example.com/x"},
+		{"https://out.reddit.com/t3_abc?url=https%3A%2F%2Fexample.com%2Fy&token=1", "https://example.com/y"},
+		{"https://out.reddit.com/t3_abc?url=javascript:alert(1)", "https://out.reddit.com/t3_abc?url=javascript:alert(1)"},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := uc.Canonicalize(tc.in); got != tc.want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestCanonicalizeDomainRules(t *testing.T) {
+	uc := NewURLCanonicalizer([]URLRule{
+		{Domain: "news.example.com", KeepParams: []string{"aid"}},
+		{Domain: ".spa.example", KeepFragment: true, DropParams: []string{"session"}},
+		{Domain: "go.example.net", RedirectParam: "to"},
+		{Domain: "blog.example.org", DropParams: []string{"ref", "source", "from"}},
+	})
+	cases := []struct {
+		in, want string
+	}{
+		{"https://news.example.com/a?aid=7&page=2&utm_source=x", "https://news.example.com/a?aid=7"},
+		{"https://app.spa.example/#!/post/9?x=1", "https://app.spa.example/#!/post/9?x=1"},
+		{"https://app.spa.example/p?session=abc&id=1", "https://app.spa.example/p?id=1"},
+		// ".spa.example" covers subdomains only
+		{"https://spa.example/p#top", "https://spa.example/p"},
+		{"https://go.example.net/r?to=https://news.example.com/a?aid=8", "https://news.example.com/a?aid=8"},
+		{"https://blog.example.org/read?ref=hn&source=rss&from=home&id=3", "https://blog.example.org/read?id=3"},
+	}
+	for _, tc := range cases {
+		if got := uc.Canonicalize(tc.in); got != tc.want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLegacyURL(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"https://example.com/index.php?p=123", "https://example.com/index.php"},
+		{"https://spa.example/app?x=1#!/post/1", "https://spa.example/app"},
+		{"https://example.com/post/1", ""},
+		{"https://spa.example/app#!/post/1", ""},
+	}
+	for _, tc := range cases {
+		if got := LegacyURL(tc.in); got != tc.want {
+			t.Errorf("LegacyURL(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestResolvesCanonical(t *testing.T) {
+	uc := NewURLCanonicalizer([]URLRule{{Domain: ".medium.com", ResolveCanonical: true}})
+	if !uc.ResolvesCanonical("https://someone.Medium.com/post-1") {
+		t.Error("subdomain of a resolve_canonical rule should resolve")
+	}
+	if uc.ResolvesCanonical("https://example.com/post-1") {
+		t.Error("domains without a rule should not resolve")
+	}
+}
+
+func TestExtractCanonicalURL(t *testing.T) {
+	page := `<html><head><link rel="stylesheet" href="/s.css"><link rel="Canonical" href="/posts/hello"></head></html>`
+	if got := ExtractCanonicalURL(page, "https://mirror.example.com/p?id=3"); got != "https://mirror.example.com/posts/hello" {
+		t.Errorf("ExtractCanonicalURL = %q", got)
+	}
+	if got := ExtractCanonicalURL(`<link rel="canonical" href="javascript:void(0)">`, "https://example.com/"); got != "" {
+		t.Errorf("non-http canonical should be ignored, got %q", got)
+	}
+}
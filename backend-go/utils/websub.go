@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// WebSubLinks are the rel="hub" and rel="self" links a feed advertises for push subscriptions
+type WebSubLinks struct {
+	Hub  string
+	Self string
+}
+
+// FindWebSubLinks looks for hub/self links in HTTP Link headers first, then in the feed body
+// (<link rel="hub"> in Atom, <atom:link rel="hub"> in RSS), as WebSub discovery prescribes
+func FindWebSubLinks(header http.Header, body []byte) WebSubLinks {
+	links := parseLinkHeaders(header.Values("Link"))
+	if links.Hub != "" && links.Self != "" {
+		return links
+	}
+
+	fromBody := findFeedLinks(body)
+	if links.Hub == "" {
+		links.Hub = fromBody.Hub
+	}
+	if links.Self == "" {
+		links.Self = fromBody.Self
+	}
+	return links
+}
+
+// parseLinkHeaders parses RFC 8288 values such as `<https://hub.example>; rel="hub"`
+func parseLinkHeaders(values []string) WebSubLinks {
+	var links WebSubLinks
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			segments := strings.Split(part, ";")
+			target := strings.TrimSpace(segments[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+			for _, param := range segments[1:] {
+				key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				rel := strings.Trim(strings.TrimSpace(val), `"`)
+				if hasRelToken(rel, "hub") && links.Hub == "" {
+					links.Hub = target
+				}
+				if hasRelToken(rel, "self") && links.Self == "" {
+					links.Self = target
+				}
+			}
+		}
+	}
+	return links
+}
+
+// findFeedLinks scans the feed header for <link rel="hub|self" href="...">, stopping at the first item
+func findFeedLinks(body []byte) WebSubLinks {
+	var links WebSubLinks
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return links
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "item", "entry":
+			return links
+		case "link":
+			var rel, href string
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "rel":
+					rel = attr.Value
+				case "href":
+					href = strings.TrimSpace(attr.Value)
+				}
+			}
+			if href == "" {
+				continue
+			}
+			if hasRelToken(rel, "hub") && links.Hub == "" {
+				links.Hub = href
+			}
+			if hasRelToken(rel, "self") && links.Self == "" {
+				links.Self = href
+			}
+		}
+	}
+}
+
+// VerifyHubSignature checks an X-Hub-Signature header ("sha1=<hex>", "sha256=<hex>", ...)
+// against the HMAC of body keyed with the subscription secret
+func VerifyHubSignature(secret string, body []byte, signature string) bool {
+	method, digest, ok := strings.Cut(strings.TrimSpace(signature), "=")
+	if !ok || secret == "" {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// ParseFeedBody parses a feed document that did not come from a poll, such as a WebSub
+// content distribution. A fresh gofeed parser is used so pushes never share parser state
+// with the polling workers.
+func ParseFeedBody(body io.Reader) ([]*FeedItem, error) {
+	feed, err := gofeed.NewParser().Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	return feedToItems(feed), nil
+}
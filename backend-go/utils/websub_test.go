@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestFindWebSubLinksInFeedBody(t *testing.T) {
+	rss := []byte(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Blog</title>
+    <atom:link rel="hub" href="https://pubsubhubbub.appspot.com/"/>
+    <atom:link rel="self" type="application/rss+xml" href="https://blog.example.com/feed"/>
+    <item><title>Post</title><link>https://blog.example.com/post</link></item>
+  </channel>
+</rss>`)
+
+	links := FindWebSubLinks(http.Header{}, rss)
+	if links.Hub != "https://pubsubhubbub.appspot.com/" || links.Self != "https://blog.example.com/feed" {
+		t.Errorf("unexpected links: %+v", links)
+	}
+
+	noHub := []byte(`<feed xmlns="http://www.w3.org/2005/Atom"><link rel="alternate" href="https://x.example.com"/>
+<entry><link rel="hub" href="https://not-a-feed-hub.example.com"/></entry></feed>`)
+	if links := FindWebSubLinks(http.Header{}, noHub); links.Hub != "" {
+		t.Errorf("hub links inside entries must be ignored, got %+v", links)
+	}
+}
+
+func TestFindWebSubLinksPrefersHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Add("Link", `<https://hub.example.com/>; rel="hub", <https://example.com/feed.xml>; rel="self"`)
+	body := []byte(`<feed xmlns="http://www.w3.org/2005/Atom"><link rel="hub" href="https://other-hub.example.com/"/></feed>`)
+
+	links := FindWebSubLinks(header, body)
+	if links.Hub != "https://hub.example.com/" || links.Self != "https://example.com/feed.xml" {
+		t.Errorf("unexpected links: %+v", links)
+	}
+}
+
+func TestVerifyHubSignature(t *testing.T) {
+	body := []byte(`<feed>pushed</feed>`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !VerifyHubSignature("s3cret", body, signature) {
+		t.Error("valid signature rejected")
+	}
+	if VerifyHubSignature("other", body, signature) {
+		t.Error("signature with the wrong secret accepted")
+	}
+	if VerifyHubSignature("s3cret", []byte(`<feed>tampered</feed>`), signature) {
+		t.Error("signature over a different body accepted")
+	}
+	if VerifyHubSignature("s3cret", body, "") || VerifyHubSignature("s3cret", body, "md5=abcd") {
+		t.Error("missing or unsupported signatures must be rejected")
+	}
+}
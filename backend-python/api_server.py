@@ -18,6 +18,7 @@ from fastapi.middleware.cors import CORSMiddleware
 from pydantic import BaseModel
 
 from config import settings
+from utils.markdown_excerpt import stream_body_fields
 from main import Database, Redis
 from agents.content_evaluator import ContentEvaluationAgent
 from agents.task_analyzer import TaskAnalyzerAgent
@@ -902,10 +903,11 @@ async def requeue_all_pending():
         raise HTTPException(status_code=503, detail="DB or Redis not available")
 
     rows = await db.fetch(
-        """SELECT id, task_id, title, original_url, clean_content,
-                  published_at, platform, author_name, content_hash
-           FROM content WHERE status = 'PENDING'
-           ORDER BY created_at ASC"""
+        """SELECT c.id, c.task_id, c.title, c.original_url, COALESCE(c.full_markdown, c.clean_content) AS body,
+                  c.published_at, c.platform, c.author_name, c.content_hash, COALESCE(s.stream_payload, '') AS stream_payload
+           FROM content c LEFT JOIN sources s ON s.id = c.source_id
+           WHERE c.status = 'PENDING'
+           ORDER BY c.created_at ASC"""
     )
 
     queued = 0
@@ -915,7 +917,7 @@ async def requeue_all_pending():
             "task_id": str(row["task_id"]),
             "title": row["title"] or "",
             "url": row["original_url"] or "",
-            "content": row["clean_content"] or "",
+            **stream_body_fields(row["body"] or "", row["stream_payload"]),
             "published_at": row["published_at"].isoformat() if row["published_at"] else "",
             "platform": row["platform"] or "blog",
             "author_name": row["author_name"] or "",
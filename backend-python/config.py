@@ -58,6 +58,7 @@ class Settings(BaseSettings):
     batch_size: int = 10
     llm_max_workers: int = 1  # 串行，不并发
     llm_max_eval_attempts: int = 3  # 每篇文章最多尝试 LLM 评估次数，超限标记 DISCARDED
+    eval_full_body_max_chars: int = 8000  # stream_payload=reference 的来源：从全文截取的评估输入上限
 
     # LLM 配置 (OpenAI)
     llm_provider: str = "openai"
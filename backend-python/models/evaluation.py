@@ -27,6 +27,19 @@ class EvaluationResult(BaseModel):
     evaluator_version: str = "mock-v1"
 
 
+class Enclosure(BaseModel):
+    """Media file attached to a feed item (podcast audio, video)"""
+    url: str
+    type: str = ""  # MIME type as declared by the feed
+    medium: str = ""  # Media RSS medium when no type is given
+    length: int = 0  # bytes, 0 when unknown
+    duration: int = 0  # seconds, 0 when unknown
+
+    def kind(self) -> str:
+        kind = self.type.split("/", 1)[0].lower() if self.type else self.medium.lower()
+        return kind if kind in ("audio", "video", "image") else ""
+
+
 class StreamMessage(BaseModel):
     """Stream message from ingestion queue"""
     content_id: int
@@ -38,3 +51,18 @@ class StreamMessage(BaseModel):
     platform: str
     author_name: str
     content_hash: str
+    language: str = ""  # ISO 639-1 code detected at ingestion, "" when undetected
+    enclosures: list[Enclosure] = []  # audio/video files; content is then the show notes
+    full_body: bool = False  # content is empty: the article is read from content.full_markdown
+    body_runes: int = 0  # length of the whole article
+
+    def evaluation_content(self) -> str:
+        """Content as sent to the evaluator: show notes of podcast episodes and videos are
+        introduced as such, so they are judged as a description of the episode"""
+        media = [e for e in self.enclosures if e.kind() in ("audio", "video")]
+        if not media:
+            return self.content
+        kind = "Podcast episode" if media[0].kind() == "audio" else "Video"
+        if media[0].duration > 0:
+            kind += f" ({media[0].duration // 60} min)"
+        return f"[{kind} — show notes]\n\n{self.content}"
@@ -6,6 +6,7 @@ import json
 import asyncpg
 import redis.asyncio as aioredis
 from config import settings
+from utils.markdown_excerpt import stream_body_fields
 
 async def repush():
     db = await asyncpg.connect(
@@ -18,9 +19,10 @@ async def repush():
     redis = aioredis.from_url(settings.redis_url, decode_responses=True)
 
     rows = await db.fetch(
-        """SELECT id, task_id, title, original_url, clean_content,
-                  published_at, platform, author_name, content_hash
-           FROM content WHERE status = 'PENDING'"""
+        """SELECT c.id, c.task_id, c.title, c.original_url, COALESCE(c.full_markdown, c.clean_content) AS body,
+                  c.published_at, c.platform, c.author_name, c.content_hash, COALESCE(s.stream_payload, '') AS stream_payload
+           FROM content c LEFT JOIN sources s ON s.id = c.source_id
+           WHERE c.status = 'PENDING'"""
     )
 
     count = 0
@@ -30,7 +32,7 @@ async def repush():
             "task_id": str(row["task_id"]),
             "title": row["title"] or "",
             "url": row["original_url"] or "",
-            "content": row["clean_content"] or "",
+            **stream_body_fields(row["body"] or "", row["stream_payload"]),
             "published_at": row["published_at"].isoformat() if row["published_at"] else "",
             "platform": row["platform"] or "blog",
             "author_name": row["author_name"] or "",
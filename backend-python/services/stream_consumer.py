@@ -7,6 +7,7 @@ from typing import Optional
 import redis.asyncio as aioredis
 import asyncpg
 from models.evaluation import StreamMessage
+from utils.markdown_excerpt import evaluation_excerpt, stream_body_fields
 from services.db_service import DBService
 from agents.content_evaluator import ContentEvaluationAgent
 from config import settings
@@ -19,6 +20,16 @@ logger = logging.getLogger(__name__)
 _LLM_CONFIG_CACHE_TTL = 60
 
 
+def _json_list(value) -> list:
+    """A JSONB list column as a list: asyncpg returns JSONB as text unless a codec is set"""
+    if isinstance(value, str):
+        try:
+            value = json.loads(value)
+        except ValueError:
+            return []
+    return value if isinstance(value, list) else []
+
+
 class StreamConsumer:
     """Consumer for Redis Stream ingestion_queue"""
 
@@ -215,6 +226,14 @@ class StreamConsumer:
                     logger.warning(f"Skipping content {message.content_id}: not found in database (stale message)")
                     continue
 
+                # Reference payloads carry no text: read the article and take our own excerpt
+                if message.full_body:
+                    body = await self.db_pool.fetchval(
+                        "SELECT COALESCE(full_markdown, clean_content) FROM content WHERE id = $1",
+                        message.content_id,
+                    )
+                    message.content = evaluation_excerpt(body or "", settings.eval_full_body_max_chars)
+
                 # BUG7: time-based rate limiting — enforce minimum interval before every LLM call
                 wait = settings.llm_request_interval - (time.time() - self._last_llm_call_time)
                 if wait > 0:
@@ -358,7 +377,7 @@ class StreamConsumer:
             result = await asyncio.to_thread(
                 self.evaluator_agent.run,
                 message.title,
-                message.content,
+                message.evaluation_content(),
                 message.url,
             )
 
@@ -395,10 +414,13 @@ class StreamConsumer:
         """将所有未超限的 PENDING 内容重新推入 stream（含首次尝试的新文章）"""
         try:
             rows = await self.db_pool.fetch(
-                """SELECT id, task_id, title, original_url, clean_content, published_at, platform, author_name, content_hash
-                   FROM content
-                   WHERE status = 'PENDING' AND eval_attempts >= 0 AND eval_attempts < $1
-                   ORDER BY created_at ASC LIMIT $2""",
+                """SELECT c.id, c.task_id, c.title, c.original_url, COALESCE(c.full_markdown, c.clean_content) AS body,
+                          c.published_at, c.platform, c.author_name, c.content_hash, c.language, c.enclosures,
+                          COALESCE(s.stream_payload, '') AS stream_payload
+                   FROM content c
+                   LEFT JOIN sources s ON s.id = c.source_id
+                   WHERE c.status = 'PENDING' AND c.eval_attempts >= 0 AND c.eval_attempts < $1
+                   ORDER BY c.created_at ASC LIMIT $2""",
                 settings.llm_max_eval_attempts,
                 self.batch_size,
             )
@@ -408,11 +430,13 @@ class StreamConsumer:
                     "task_id": str(row["task_id"]),
                     "title": row["title"] or "",
                     "url": row["original_url"] or "",
-                    "content": row["clean_content"] or "",
+                    **stream_body_fields(row["body"] or "", row["stream_payload"]),
                     "published_at": row["published_at"].isoformat() if row["published_at"] else "",
                     "platform": row["platform"] or "blog",
                     "author_name": row["author_name"] or "",
                     "content_hash": row["content_hash"] or "",
+                    "language": row["language"] or "",
+                    "enclosures": json.loads(row["enclosures"]) if row["enclosures"] else [],
                 }, ensure_ascii=False)
                 await self.redis.xadd(self.stream_name, {"data": msg_data})
             if rows:
@@ -424,7 +448,8 @@ class StreamConsumer:
         """Check notification settings from DB to decide whether to notify"""
         try:
             row = await self.db_pool.fetchrow(
-                "SELECT min_innovation_score, min_depth_score, notify_on_interesting, watched_source_ids, enabled FROM notification_settings WHERE id = 1"
+                "SELECT min_innovation_score, min_depth_score, notify_on_interesting, watched_source_ids, enabled, "
+                "watched_folder_ids, watched_tags FROM notification_settings WHERE id = 1"
             )
             if not row:
                 # Default behavior if no settings
@@ -433,14 +458,18 @@ class StreamConsumer:
             if not row["enabled"]:
                 return False
 
-            # Check watched sources filter
-            watched = row["watched_source_ids"]
-            if watched and isinstance(watched, list) and len(watched) > 0:
+            # Check watched sources / folders / tags filter (any match counts)
+            watched = _json_list(row["watched_source_ids"])
+            watched_folders = _json_list(row["watched_folder_ids"])
+            watched_tags = _json_list(row["watched_tags"])
+            if watched or watched_folders or watched_tags:
                 # Get source_id for this content
                 content_row = await self.db_pool.fetchrow(
                     "SELECT source_id FROM content WHERE id = $1", message.content_id
                 )
-                if content_row and content_row["source_id"] not in watched:
+                if content_row and not await self._source_watched(
+                    content_row["source_id"], watched, watched_folders, watched_tags
+                ):
                     return False
 
             # Check decision-based rule
@@ -459,6 +488,27 @@ class StreamConsumer:
             # Fallback to default
             return result.decision == "INTERESTING" or (result.innovation_score >= 8 and result.depth_score >= 7)
 
+    async def _source_watched(self, source_id, watched, watched_folders, watched_tags) -> bool:
+        """Whether a source is listed, sits in a watched folder (or one of its subfolders) or carries a watched tag"""
+        if source_id in watched:
+            return True
+        if not watched_folders and not watched_tags:
+            return False
+        return bool(await self.db_pool.fetchval(
+            """WITH RECURSIVE subtree AS (
+                   SELECT id FROM source_folders WHERE id = ANY($2::bigint[])
+                   UNION
+                   SELECT f.id FROM source_folders f JOIN subtree ON f.parent_id = subtree.id
+               )
+               SELECT EXISTS (
+                   SELECT 1 FROM sources
+                   WHERE id = $1 AND (folder_id IN (SELECT id FROM subtree) OR tags && $3::text[])
+               )""",
+            source_id,
+            [int(f) for f in watched_folders],
+            [str(t) for t in watched_tags],
+        ))
+
     async def _create_notification(self, message: StreamMessage, result):
         """Create a notification for high-value evaluated content"""
         try:
@@ -0,0 +1,24 @@
+"""Checks utils.markdown_excerpt against the Go backend's excerpts.
+
+Run from backend-python: python -m unittest discover tests
+"""
+import json
+import unittest
+from pathlib import Path
+
+from utils.markdown_excerpt import evaluation_excerpt
+
+# Shared with utils/markdown_chunker_test.go in the Go backend
+FIXTURE = Path(__file__).resolve().parents[2] / "backend-go" / "utils" / "testdata" / "evaluation_excerpt.json"
+
+
+class EvaluationExcerptTest(unittest.TestCase):
+    def test_matches_go_fixture(self):
+        cases = json.loads(FIXTURE.read_text(encoding="utf-8"))
+        for case in cases:
+            with self.subTest(case["name"]):
+                self.assertEqual(evaluation_excerpt(case["markdown"], case["max_runes"]), case["excerpt"])
+
+
+if __name__ == "__main__":
+    unittest.main()
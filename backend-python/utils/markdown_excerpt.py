@@ -0,0 +1,105 @@
+"""Section-aware excerpts of Markdown articles for evaluation.
+
+Mirrors utils.EvaluationExcerpt in the Go backend, which builds the excerpt of "excerpt" stream
+messages; "reference" messages carry no text and the consumer builds it here, with its own budget.
+"""
+
+MIN_SECTION_CHARS = 120
+STREAM_EXCERPT_CHARS = 2500  # models.StreamExcerptRunes: the excerpt of "excerpt" messages
+STREAM_PAYLOAD_REFERENCE = "reference"
+_FENCE = ("```", "~~~")
+
+
+def _is_heading(line: str) -> bool:
+    """Whether a stripped line is an ATX heading with text, as atxHeading in the Go backend"""
+    level = len(line) - len(line.lstrip("#"))
+    if level == 0 or level > 6 or (level < len(line) and line[level] not in " \t"):
+        return False
+    return line[level:].strip().rstrip("#").strip() != ""
+
+
+def split_sections(markdown: str) -> list[str]:
+    """Split Markdown at ATX headings outside fenced code blocks, dropping blank sections"""
+    sections, current, fence = [], [], ""
+    for line in markdown.split("\n"):
+        stripped = line.strip()
+        if fence:
+            if stripped.startswith(fence):
+                fence = ""
+        elif stripped.startswith(_FENCE):
+            fence = stripped[:3]
+        elif _is_heading(stripped):
+            text = "\n".join(current).strip()
+            if text:
+                sections.append(text)
+            current = []
+        current.append(line)
+    text = "\n".join(current).strip()
+    if text:
+        sections.append(text)
+    return sections
+
+
+def _cut_at_boundary(text: str, max_chars: int) -> str:
+    if len(text) <= max_chars:
+        return text
+    head = text[:max_chars]
+    for sep in ("\n\n", "\n", "。", "！", "？", ". ", "! ", "? "):
+        i = head.rfind(sep)
+        if i >= max_chars // 2:
+            return head[: i + len(sep)].strip()
+    return head
+
+
+def _cut_with_ellipsis(text: str, max_chars: int) -> str:
+    if len(text) <= max_chars:
+        return text
+    if max_chars < 2:
+        return ""
+    return _cut_at_boundary(text, max_chars - 1) + "…"
+
+
+def evaluation_excerpt(markdown: str, max_chars: int) -> str:
+    """Shorten an article to at most max_chars characters, keeping every section's heading and
+    opening paragraphs; the room short sections don't use goes to the longer ones"""
+    markdown = markdown.strip()
+    if len(markdown) <= max_chars:
+        return markdown
+    sections = split_sections(markdown)
+    count = min(len(sections), (max_chars + 2) // (MIN_SECTION_CHARS + 2))
+    if count <= 1:
+        return _cut_with_ellipsis(markdown, max_chars)
+    sections = sections[:count]
+
+    budget = max_chars - 2 * (count - 1)
+    allocation = [0] * count
+    open_ = list(range(count))
+    while open_:
+        share = budget // len(open_)
+        longer = []
+        for i in open_:
+            if len(sections[i]) <= share:
+                allocation[i] = len(sections[i])
+                budget -= len(sections[i])
+            else:
+                longer.append(i)
+        if len(longer) == len(open_):
+            for i in longer:
+                allocation[i] = share
+            break
+        open_ = longer
+
+    parts = [_cut_with_ellipsis(s, allocation[i]) for i, s in enumerate(sections)]
+    return "\n\n".join(p for p in parts if p)
+
+
+def stream_body_fields(body: str, stream_payload: str) -> dict:
+    """The content, full_body and body_runes of a stream message for an article, chosen as
+    models.Content.StreamMessage in the Go backend does: "reference" sources send no text, the
+    others a section-aware excerpt of the whole article"""
+    full_body = stream_payload == STREAM_PAYLOAD_REFERENCE
+    return {
+        "content": "" if full_body else evaluation_excerpt(body, STREAM_EXCERPT_CHARS),
+        "full_body": full_body,
+        "body_runes": len(body),
+    }
@@ -1,5 +1,5 @@
 import { defineStore } from 'pinia'
-import { ref, computed } from 'vue'
+import { ref, computed, watch } from 'vue'
 
 export const useReaderStore = defineStore('reader', () => {
   const articles = ref([])
@@ -9,7 +9,8 @@ export const useReaderStore = defineStore('reader', () => {
   const expandedSources = ref({})
   const isLoading = ref(false)
 
-  const API_BASE_URL = `${import.meta.env.VITE_API_URL || 'http://localhost:8080'}/api`
+  const API_ORIGIN = import.meta.env.VITE_API_URL || 'http://localhost:8080'
+  const API_BASE_URL = `${API_ORIGIN}/api`
 
   /**
    * AI score label mapping based on average score
@@ -67,7 +68,12 @@ export const useReaderStore = defineStore('reader', () => {
       decision: evaluation.decision || 'BOOKMARK',
       keyConcepts: evaluation.key_concepts || [],
       reasoning: evaluation.reasoning || '',
-      imageUrls: item.image_urls || [],
+      // 图片代理返回 /api/images/:hash 这样的相对路径，需要加上后端地址
+      imageUrls: (item.image_urls || []).map(u => (u.startsWith('/') ? `${API_ORIGIN}${u}` : u)),
+      // 播客/视频的音视频附件（enclosure），正文此时是节目简介
+      media: (item.enclosures || [])
+        .map(e => ({ ...e, kind: (e.type || '').split('/')[0] || e.medium || '' }))
+        .filter(e => e.kind === 'audio' || e.kind === 'video'),
     }
   }
 
@@ -145,6 +151,23 @@ export const useReaderStore = defineStore('reader', () => {
     selectedArticleId.value = id
   }
 
+  // 列表接口的 clean_content 最多 2500 字，更长的文章打开时再从详情接口取全文
+  const EXCERPT_RUNES = 2500
+  const loadFullContent = async (article) => {
+    if (!article || article.fullContentLoaded || [...article.content].length < EXCERPT_RUNES) return
+    article.fullContentLoaded = true
+    try {
+      const response = await fetch(`${API_BASE_URL}/content/${article.id}`)
+      if (!response.ok) throw new Error(`HTTP ${response.status}`)
+      const data = await response.json()
+      if (data.full_content) article.content = data.full_content
+    } catch (err) {
+      console.error('[Reader] Failed to load full article:', err)
+      article.fullContentLoaded = false
+    }
+  }
+  watch(selectedArticle, loadFullContent)
+
   const selectSource = (source) => {
     if (selectedSource.value === source && source !== 'all') {
       // Toggle expand/collapse
@@ -0,0 +1,6 @@
+-- Migration: Add HTTP cache validators to sources table
+-- Stored from the last successful fetch and replayed as If-None-Match / If-Modified-Since,
+-- so unchanged feeds answer 304 and are not downloaded or parsed again
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS etag VARCHAR(500);
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS last_modified VARCHAR(100);
@@ -0,0 +1,8 @@
+-- Migration: Add fetch health tracking to sources table
+-- consecutive_failures / last_error / next_retry_at drive exponential backoff in the fetcher;
+-- auto_disabled_at is set when a source is disabled after too many consecutive failures
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS consecutive_failures INT NOT NULL DEFAULT 0;
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMP;
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS auto_disabled_at TIMESTAMP;
@@ -0,0 +1,26 @@
+-- Migration: Create fetch_runs table
+-- One row per poll of a source: HTTP outcome plus per-item counters (new / duplicate / too short / author filtered),
+-- written by the Go fetcher and exposed via /api/sources/:id/fetch-history and /api/fetch-runs
+
+CREATE TABLE IF NOT EXISTS fetch_runs (
+    id BIGSERIAL PRIMARY KEY,
+    source_id BIGINT REFERENCES sources(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    status VARCHAR(20) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    http_status INT NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    items_seen INT NOT NULL DEFAULT 0,
+    items_new INT NOT NULL DEFAULT 0,
+    items_duplicate INT NOT NULL DEFAULT 0,
+    items_too_short INT NOT NULL DEFAULT 0,
+    items_author_filtered INT NOT NULL DEFAULT 0,
+    items_errored INT NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_fetch_runs_source_started ON fetch_runs (source_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_fetch_runs_started ON fetch_runs (started_at DESC);
@@ -0,0 +1,5 @@
+-- Migration: Add fetch_full_text flag to sources table
+-- For excerpt-only feeds: the fetcher downloads each article page and extracts the main content
+-- before applying the short-content filter
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS fetch_full_text BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- Migration: Add adapter_config to sources table
+-- Platform-specific settings for non-RSS sources (github / hackernews / reddit / json),
+-- e.g. {"kind": "commits", "branch": "main"} or JSONPath field mappings for generic JSON APIs
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS adapter_config JSONB;
@@ -0,0 +1,19 @@
+-- Migration: Create websub_subscriptions table
+-- One row per source whose feed advertises a WebSub hub (rel="hub"). The hub pushes new entries to
+-- /websub/callback/:sourceID, signed with the per-subscription secret; polling drops to a long fallback interval
+
+CREATE TABLE IF NOT EXISTS websub_subscriptions (
+    source_id BIGINT PRIMARY KEY REFERENCES sources(id) ON DELETE CASCADE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT 'pending',
+    lease_seconds INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    last_push_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_websub_subscriptions_expires ON websub_subscriptions (state, expires_at);
@@ -0,0 +1,6 @@
+-- Migration: Add fetch_token to sources table
+-- Fencing token of the newest shard lease that wrote fetch results for the source. When several
+-- backend replicas run, a replica whose lease was taken over (e.g. after a long GC pause) holds an
+-- older token and its late writes are rejected.
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS fetch_token BIGINT NOT NULL DEFAULT 0;
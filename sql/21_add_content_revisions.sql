@@ -0,0 +1,21 @@
+-- Migration: Track revisions of feed items edited after ingestion
+-- content_hash only identifies an item by its URL; body_hash fingerprints title + clean_content so the
+-- fetcher can tell when an already-ingested article changed. Once an item has been edited,
+-- content_revisions holds every version (revision 1 = as first ingested) and the content row the latest.
+
+ALTER TABLE content ADD COLUMN IF NOT EXISTS body_hash VARCHAR(64);
+ALTER TABLE content ADD COLUMN IF NOT EXISTS revision INT NOT NULL DEFAULT 1;
+ALTER TABLE fetch_runs ADD COLUMN IF NOT EXISTS items_revised INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS content_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    content_id BIGINT NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    title TEXT,
+    clean_content TEXT,
+    body_hash VARCHAR(64),
+    change_ratio REAL NOT NULL DEFAULT 0,
+    requeued BOOLEAN NOT NULL DEFAULT FALSE,
+    detected_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (content_id, revision)
+);
@@ -0,0 +1,10 @@
+-- Migration: SimHash fingerprints for near-duplicate detection
+-- simhash holds the 64-bit fingerprint of clean_content (as signed BIGINT); copies of an earlier item
+-- (syndication, mirrors, aggregator reposts) are stored with status DUPLICATE and canonical_id pointing
+-- at the original instead of being evaluated again. The lookup index itself lives in Redis (simhash:* keys).
+
+ALTER TABLE content ADD COLUMN IF NOT EXISTS simhash BIGINT;
+ALTER TABLE content ADD COLUMN IF NOT EXISTS canonical_id BIGINT REFERENCES content(id) ON DELETE SET NULL;
+ALTER TABLE fetch_runs ADD COLUMN IF NOT EXISTS items_near_duplicate INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_content_canonical_id ON content(canonical_id) WHERE canonical_id IS NOT NULL;
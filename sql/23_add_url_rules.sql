@@ -0,0 +1,17 @@
+-- Migration: Per-domain URL canonicalization rules
+-- Article URLs are canonicalized before dedup and hashing: tracking parameters (utm_*, fbclid, spm, ...)
+-- and fragments are dropped, other query parameters kept. A rule overrides this for one domain
+-- (".example.com" also matches subdomains): keep only some parameters, drop more, keep the fragment,
+-- unwrap a redirector, or follow the article page's rel=canonical link. Managed through /api/url-rules.
+
+CREATE TABLE IF NOT EXISTS url_rules (
+    id BIGSERIAL PRIMARY KEY,
+    domain VARCHAR(255) NOT NULL UNIQUE,
+    keep_params TEXT[] NOT NULL DEFAULT '{}',
+    drop_params TEXT[] NOT NULL DEFAULT '{}',
+    keep_fragment BOOLEAN NOT NULL DEFAULT FALSE,
+    redirect_param VARCHAR(100),
+    resolve_canonical BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,35 @@
+-- Migration: Pre-evaluation filter rules
+-- A rule is a JSON condition tree over an item's title/content/author/url/category (keyword, regex,
+-- length, language and age operators, combined with all/any); matching items are skipped before dedup,
+-- so they never reach the evaluator. source_id NULL makes a rule global. Every skip is logged in
+-- filter_skips with the rule that matched; fetch_runs counts them in items_rule_filtered.
+
+CREATE TABLE IF NOT EXISTS filter_rules (
+    id BIGSERIAL PRIMARY KEY,
+    source_id BIGINT REFERENCES sources(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    condition JSONB NOT NULL,
+    hit_count BIGINT NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_filter_rules_source ON filter_rules (source_id);
+
+CREATE TABLE IF NOT EXISTS filter_skips (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT REFERENCES filter_rules(id) ON DELETE SET NULL,
+    rule_name VARCHAR(200) NOT NULL,
+    source_id BIGINT REFERENCES sources(id) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    skipped_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_filter_skips_source_skipped ON filter_skips (source_id, skipped_at DESC);
+CREATE INDEX IF NOT EXISTS idx_filter_skips_rule_skipped ON filter_skips (rule_id, skipped_at DESC);
+CREATE INDEX IF NOT EXISTS idx_filter_skips_skipped ON filter_skips (skipped_at DESC);
+
+ALTER TABLE fetch_runs ADD COLUMN IF NOT EXISTS items_rule_filtered INT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Migration: Content language and per-source language policy
+-- content.language holds the ISO 639-1 code detected at ingestion (NULL when undetected) and is
+-- carried to the evaluator in the stream message. sources.allowed_languages lists the languages a
+-- source may ingest (NULL or empty allows all); other detected languages are skipped and counted
+-- in fetch_runs.items_language_filtered.
+
+ALTER TABLE content ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+CREATE INDEX IF NOT EXISTS idx_content_language ON content(language);
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS allowed_languages TEXT[];
+
+ALTER TABLE fetch_runs ADD COLUMN IF NOT EXISTS items_language_filtered INT NOT NULL DEFAULT 0;
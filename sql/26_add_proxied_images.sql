@@ -0,0 +1,11 @@
+-- Migration: Image proxy
+-- content.image_urls keeps the original hotlinked URLs; the API serves them as /api/images/:hash,
+-- fetched through the RSS proxy with the article's site as Referer and cached on local disk.
+-- proxied_images maps each hash (first 128 bits of SHA-256 of the URL, hex) back to its URL.
+
+CREATE TABLE IF NOT EXISTS proxied_images (
+    hash VARCHAR(32) PRIMARY KEY,
+    url TEXT NOT NULL,
+    page_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
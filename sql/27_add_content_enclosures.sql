@@ -0,0 +1,7 @@
+-- Migration: Media enclosures on content
+-- content.enclosures is a JSONB array of the item's media files (RSS <enclosure>, Atom
+-- rel="enclosure" links and Media RSS media:content), each {url, type, medium, length, duration}
+-- with length in bytes and duration in seconds. NULL when the item has none. Podcast episodes and
+-- videos are evaluated on their show notes, which only need to pass a shorter length check.
+
+ALTER TABLE content ADD COLUMN IF NOT EXISTS enclosures JSONB;
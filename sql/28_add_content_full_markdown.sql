@@ -0,0 +1,11 @@
+-- Migration: Full article Markdown and per-source stream payload
+-- content.clean_content keeps the first 2500 runes of an article, which body hashes and revision
+-- detection are computed on. content.full_markdown holds the whole article when it is longer
+-- (NULL otherwise) for the reader, search and the evaluator.
+-- sources.stream_payload selects what evaluation messages carry: 'excerpt' (NULL, the default)
+-- for a section-aware excerpt of the article, 'reference' for no text, the evaluator reading
+-- full_markdown itself.
+
+ALTER TABLE content ADD COLUMN IF NOT EXISTS full_markdown TEXT;
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS stream_payload VARCHAR(16);
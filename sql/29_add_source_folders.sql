@@ -0,0 +1,29 @@
+-- Migration: Source folders and tags
+-- source_folders is a tree (parent_id NULL for top-level folders); a source sits in at most one
+-- folder. Deleting a folder moves its sources and subfolders up to its parent, which the API does
+-- explicitly; ON DELETE SET NULL / CASCADE only guard against direct deletes.
+-- sources.tags holds free-form, lowercase tags.
+-- notification_settings.watched_folder_ids / watched_tags widen watched_source_ids: an item is
+-- watched when its source is listed, sits in a watched folder (or below) or carries a watched tag.
+
+CREATE TABLE IF NOT EXISTS source_folders (
+    id BIGSERIAL PRIMARY KEY,
+    parent_id BIGINT REFERENCES source_folders(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sibling folders have distinct names, case-insensitively
+CREATE UNIQUE INDEX IF NOT EXISTS idx_source_folders_sibling_name
+    ON source_folders (COALESCE(parent_id, 0), lower(name));
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS folder_id BIGINT REFERENCES source_folders(id) ON DELETE SET NULL;
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_sources_folder_id ON sources(folder_id);
+CREATE INDEX IF NOT EXISTS idx_sources_tags ON sources USING GIN (tags);
+
+ALTER TABLE notification_settings ADD COLUMN IF NOT EXISTS watched_folder_ids JSONB DEFAULT '[]';
+ALTER TABLE notification_settings ADD COLUMN IF NOT EXISTS watched_tags JSONB DEFAULT '[]';
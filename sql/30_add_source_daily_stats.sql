@@ -0,0 +1,30 @@
+-- Migration: Daily per-source quality rollups
+-- One row per source and day, materialized by the fetcher from content, evaluation and fetch_runs
+-- so /api/sources/:id/stats and the leaderboard never scan content. Items count on the day they
+-- were ingested, evaluations included; the last few days are recomputed on every refresh, older
+-- rows are final (fetch_runs, the source of the duplicate/too-short/filtered counts, is pruned
+-- after 30 days). Scores are stored as sums so averages over any range stay exact.
+
+CREATE TABLE IF NOT EXISTS source_daily_stats (
+    source_id BIGINT NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    ingested INT NOT NULL DEFAULT 0,
+    evaluated INT NOT NULL DEFAULT 0,
+    interesting INT NOT NULL DEFAULT 0,
+    bookmarked INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    innovation_sum BIGINT NOT NULL DEFAULT 0,
+    depth_sum BIGINT NOT NULL DEFAULT 0,
+    high_scores INT NOT NULL DEFAULT 0,
+    last_high_score_at TIMESTAMP,
+    duplicates INT NOT NULL DEFAULT 0,
+    too_short INT NOT NULL DEFAULT 0,
+    filtered INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_source_daily_stats_day ON source_daily_stats (day);
+
+-- The rollup scans content by ingestion day
+CREATE INDEX IF NOT EXISTS idx_content_ingested_at ON content (ingested_at);
@@ -0,0 +1,7 @@
+-- Migration: Per-source HTTP options
+-- Proxy (http/https/socks5), custom headers, cookies, basic/bearer auth and TLS skip-verify for
+-- private or geo-blocked sources. Every secret (passwords, tokens, header and cookie values) is
+-- stored sealed with AES-256-GCM under security.secret_key, never in plain text; NULL means the
+-- source is fetched with the global settings.
+
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS http_options JSONB;
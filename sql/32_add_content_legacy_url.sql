@@ -0,0 +1,11 @@
+-- Migration: Mark content canonicalized by the rules before url_rules
+-- Until migration 23 article URLs were stored with their whole query string stripped; now only
+-- tracking parameters are dropped, so an article with other parameters gets a new original_url and
+-- content_hash. The stripped query strings are lost, so these rows can't be re-canonicalized:
+-- they are marked instead, and while ingestion.dedup.legacy_url_fallback is on, a new URL whose
+-- stripped form is a marked row's original_url counts as a duplicate of it if the row holds the
+-- same article (same body hash, or same title for rows without one).
+-- Existing rows take the TRUE default; rows inserted from now on are FALSE.
+
+ALTER TABLE content ADD COLUMN IF NOT EXISTS legacy_url BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE content ALTER COLUMN legacy_url SET DEFAULT FALSE;
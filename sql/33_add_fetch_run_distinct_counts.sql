@@ -0,0 +1,9 @@
+-- Migration: Count skipped items once, not once per poll
+-- Feeds list the same items on every poll, so items_duplicate, items_too_short and the filtered
+-- counters grow with the poll rate. The distinct_* columns only count the items a source got that
+-- outcome for the first time (remembered in Redis for 7 days; items the source ingested itself
+-- never count as its duplicates), and source_daily_stats sums these instead.
+
+ALTER TABLE fetch_runs ADD COLUMN IF NOT EXISTS distinct_duplicate INT NOT NULL DEFAULT 0;
+ALTER TABLE fetch_runs ADD COLUMN IF NOT EXISTS distinct_too_short INT NOT NULL DEFAULT 0;
+ALTER TABLE fetch_runs ADD COLUMN IF NOT EXISTS distinct_filtered INT NOT NULL DEFAULT 0;